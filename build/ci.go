@@ -75,6 +75,7 @@ var (
 		"COPYING",
 		executablePath("abigen"),
 		executablePath("bootnode"),
+		executablePath("devp2p"),
 		executablePath("evm"),
 		executablePath("geth"),
 		executablePath("puppeth"),
@@ -35,6 +35,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethclient"
 	"git.pirl.io/bitcoiin/go-bitcoiin/internal/debug"
+	"git.pirl.io/bitcoiin/go-bitcoiin/internal/tracing"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
 	"git.pirl.io/bitcoiin/go-bitcoiin/node"
@@ -71,10 +72,14 @@ var (
 		utils.EthashDatasetDirFlag,
 		utils.EthashDatasetsInMemoryFlag,
 		utils.EthashDatasetsOnDiskFlag,
+		utils.EthashCPUAffinityFlag,
+		utils.EthashHugePagesFlag,
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
+		utils.TxPoolSnapshotAgeFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -82,12 +87,17 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPriorityFlag,
+		utils.TxPoolPrioritySlotsFlag,
+		utils.TxPoolPriorityQueueFlag,
+		utils.TxPoolPriorityLifetimeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
 		utils.WhitelistFlag,
+		utils.SyncFromFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -96,10 +106,20 @@ var (
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.MaxUploadFlag,
+		utils.MaxDownloadFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerLegacyThreadsFlag,
 		utils.MinerNotifyFlag,
+		utils.MinerStratumFlag,
+		utils.MinerStratumDiffFlag,
+		utils.MinerPoolFlag,
+		utils.MinerPoolSchemeFlag,
+		utils.MinerPoolWindowFlag,
+		utils.MinerPoolFeeFlag,
+		utils.MinerPoolThresholdFlag,
+		utils.MinerPoolIntervalFlag,
 		utils.MinerGasTargetFlag,
 		utils.MinerLegacyGasTargetFlag,
 		utils.MinerGasLimitFlag,
@@ -111,9 +131,12 @@ var (
 		utils.MinerLegacyExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerTxOrderFlag,
+		utils.MinerAllowlistFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
+		utils.DNSDiscoveryFlag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
@@ -127,6 +150,9 @@ var (
 		utils.ConstantinopleOverrideFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCReadyMinPeersFlag,
+		utils.RPCReadyMaxHeadAgeFlag,
+		utils.RPCReadySyncedFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
 		utils.FakePoWFlag,
@@ -148,6 +174,10 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.GRPCEnabledFlag,
+		utils.GRPCListenAddrFlag,
+		utils.GRPCPortFlag,
+		utils.GRPCApiFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.RPCGlobalGasCap,
@@ -211,6 +241,7 @@ func init() {
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Flags = append(app.Flags, whisperFlags...)
 	app.Flags = append(app.Flags, metricsFlags...)
+	app.Flags = append(app.Flags, tracing.Flags...)
 
 	app.Before = func(ctx *cli.Context) error {
 		logdir := ""
@@ -220,6 +251,9 @@ func init() {
 		if err := debug.Setup(ctx, logdir); err != nil {
 			return err
 		}
+		if err := tracing.Setup(ctx); err != nil {
+			return err
+		}
 		// Cap the cache allowance and tune the garbage collector
 		var mem gosigar.Mem
 		if err := mem.Get(); err == nil {
@@ -247,6 +281,7 @@ func init() {
 
 	app.After = func(ctx *cli.Context) error {
 		debug.Exit()
+		tracing.Exit()
 		console.Stdin.Close() // Resets terminal mode.
 		return nil
 	}
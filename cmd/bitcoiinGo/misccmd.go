@@ -22,6 +22,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/cmd/utils"
 	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
@@ -49,14 +50,33 @@ Regular users do not need to execute it.
 		Name:      "makedag",
 		Usage:     "Generate ethash mining DAG (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
-		Category:  "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			makedagBenchmarkFlag,
+			makedagDurationFlag,
+			utils.EthashCPUAffinityFlag,
+			utils.EthashHugePagesFlag,
+		},
+		Category: "MISCELLANEOUS COMMANDS",
 		Description: `
 The makedag command generates an ethash DAG in <outputDir>.
 
+With --benchmark, the command also measures the CPU mining hashrate over the
+DAG with an increasing number of threads, honouring --ethash.affinity and
+--ethash.hugepages, and reports the hashes per second of each configuration.
+
 This command exists to support the system testing project.
 Regular users do not need to execute it.
 `,
 	}
+	makedagBenchmarkFlag = cli.BoolFlag{
+		Name:  "benchmark",
+		Usage: "Measure the mining hashrate over the DAG per thread count",
+	}
+	makedagDurationFlag = cli.DurationFlag{
+		Name:  "benchmark.duration",
+		Usage: "Duration of the hashrate measurement of each thread count",
+		Value: 10 * time.Second,
+	}
 	versionCommand = cli.Command{
 		Action:    utils.MigrateFlags(version),
 		Name:      "version",
@@ -101,11 +121,40 @@ func makedag(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeDataset(block, args[1])
+	benchmark, duration, config := makedagBenchmark(ctx, args[1])
+	if !benchmark {
+		ethash.MakeDataset(block, args[1])
+		return nil
+	}
+	// Measure powers of two thread counts up to the number of CPUs
+	var threads []int
+	for count := 1; count < runtime.NumCPU(); count *= 2 {
+		threads = append(threads, count)
+	}
+	threads = append(threads, runtime.NumCPU())
 
+	rates := ethash.BenchmarkDataset(config, block, threads, duration)
+
+	fmt.Printf("Ethash hashrate (affinity: %v, hugepages: %v)\n", config.CPUAffinity, config.HugePages)
+	fmt.Printf("%8s %14s %14s\n", "Threads", "Hashes/s", "Per thread")
+	for i, count := range threads {
+		fmt.Printf("%8d %14.0f %14.0f\n", count, rates[i], rates[i]/float64(count))
+	}
 	return nil
 }
 
+// makedagBenchmark retrieves whether the makedag command should measure the
+// hashrate, for how long per thread count and with which ethash settings. The
+// benchmark flags are local to the command, the ethash ones are global.
+func makedagBenchmark(ctx *cli.Context, dir string) (bool, time.Duration, ethash.Config) {
+	config := ethash.Config{
+		DatasetDir:  dir,
+		CPUAffinity: ctx.GlobalBool(utils.EthashCPUAffinityFlag.Name),
+		HugePages:   ctx.GlobalBool(utils.EthashHugePagesFlag.Name),
+	}
+	return ctx.Bool(makedagBenchmarkFlag.Name), ctx.Duration(makedagDurationFlag.Name), config
+}
+
 func version(ctx *cli.Context) error {
 	fmt.Println(strings.Title(clientIdentifier))
 	fmt.Println("Version:", params.VersionWithMeta)
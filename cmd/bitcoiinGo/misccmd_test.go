@@ -0,0 +1,91 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/cmd/utils"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Tests that the makedag benchmark settings are picked up both from the local
+// flags of the command and from the global ethash flags.
+func TestMakedagBenchmarkFlags(t *testing.T) {
+	tests := []struct {
+		args      []string
+		benchmark bool
+		duration  time.Duration
+		config    ethash.Config
+	}{
+		{
+			args:     []string{"makedag", "0", "dag"},
+			duration: makedagDurationFlag.Value,
+			config:   ethash.Config{DatasetDir: "dag"},
+		},
+		{
+			args:      []string{"makedag", "--benchmark", "--benchmark.duration", "3s", "0", "dag"},
+			benchmark: true,
+			duration:  3 * time.Second,
+			config:    ethash.Config{DatasetDir: "dag"},
+		},
+		{
+			args:      []string{"makedag", "--benchmark", "--ethash.affinity", "--ethash.hugepages", "0", "dag"},
+			benchmark: true,
+			duration:  makedagDurationFlag.Value,
+			config:    ethash.Config{DatasetDir: "dag", CPUAffinity: true, HugePages: true},
+		},
+		{
+			args:      []string{"--ethash.hugepages", "makedag", "--benchmark", "0", "dag"},
+			benchmark: true,
+			duration:  makedagDurationFlag.Value,
+			config:    ethash.Config{DatasetDir: "dag", HugePages: true},
+		},
+	}
+	for i, tt := range tests {
+		var (
+			benchmark bool
+			duration  time.Duration
+			config    ethash.Config
+		)
+		// Run the real command definition, but capture the settings instead of
+		// generating a DAG
+		command := makedagCommand
+		command.Action = utils.MigrateFlags(func(ctx *cli.Context) error {
+			benchmark, duration, config = makedagBenchmark(ctx, ctx.Args()[1])
+			return nil
+		})
+		app := cli.NewApp()
+		app.Flags = []cli.Flag{utils.EthashCPUAffinityFlag, utils.EthashHugePagesFlag}
+		app.Commands = []cli.Command{command}
+
+		if err := app.Run(append([]string{"bitcoiinGo"}, tt.args...)); err != nil {
+			t.Fatalf("test %d: failed to run command: %v", i, err)
+		}
+		if benchmark != tt.benchmark {
+			t.Errorf("test %d: benchmark mismatch: have %v, want %v", i, benchmark, tt.benchmark)
+		}
+		if duration != tt.duration {
+			t.Errorf("test %d: duration mismatch: have %v, want %v", i, duration, tt.duration)
+		}
+		if config != tt.config {
+			t.Errorf("test %d: config mismatch: have %+v, want %+v", i, config, tt.config)
+		}
+	}
+}
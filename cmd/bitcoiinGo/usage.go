@@ -26,6 +26,7 @@ import (
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/cmd/utils"
 	"git.pirl.io/bitcoiin/go-bitcoiin/internal/debug"
+	"git.pirl.io/bitcoiin/go-bitcoiin/internal/tracing"
 	cli "gopkg.in/urfave/cli.v1"
 )
 
@@ -83,6 +84,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
+			utils.SyncFromFlag,
 		},
 	},
 	{
@@ -101,6 +103,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.EthashDatasetDirFlag,
 			utils.EthashDatasetsInMemoryFlag,
 			utils.EthashDatasetsOnDiskFlag,
+			utils.EthashCPUAffinityFlag,
+			utils.EthashHugePagesFlag,
 		},
 	},
 	//{
@@ -120,6 +124,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolSnapshotFlag,
+			utils.TxPoolSnapshotAgeFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -127,6 +133,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPriorityFlag,
+			utils.TxPoolPrioritySlotsFlag,
+			utils.TxPoolPriorityQueueFlag,
+			utils.TxPoolPriorityLifetimeFlag,
 		},
 	},
 	{
@@ -159,10 +169,17 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.GRPCEnabledFlag,
+			utils.GRPCListenAddrFlag,
+			utils.GRPCPortFlag,
+			utils.GRPCApiFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCReadyMinPeersFlag,
+			utils.RPCReadyMaxHeadAgeFlag,
+			utils.RPCReadySyncedFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -177,9 +194,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.MaxUploadFlag,
+			utils.MaxDownloadFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
+			utils.DNSDiscoveryFlag,
 			utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
@@ -191,6 +211,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MiningEnabledFlag,
 			utils.MinerThreadsFlag,
 			utils.MinerNotifyFlag,
+			utils.MinerStratumFlag,
+			utils.MinerStratumDiffFlag,
+			utils.MinerPoolFlag,
+			utils.MinerPoolSchemeFlag,
+			utils.MinerPoolWindowFlag,
+			utils.MinerPoolFeeFlag,
+			utils.MinerPoolThresholdFlag,
+			utils.MinerPoolIntervalFlag,
 			utils.MinerGasPriceFlag,
 			utils.MinerGasTargetFlag,
 			utils.MinerGasLimitFlag,
@@ -198,6 +226,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerTxOrderFlag,
+			utils.MinerAllowlistFlag,
 		},
 	},
 	{
@@ -222,6 +252,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
 	},
+	{
+		Name:  "TRACING",
+		Flags: tracing.Flags,
+	},
 	{
 		Name: "METRICS AND STATS",
 		Flags: []cli.Flag{
@@ -0,0 +1,256 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+// nodeSource is the discovery table walked by the crawler.
+type nodeSource interface {
+	LookupRandom() []*enode.Node
+	Resolve(*enode.Node) *enode.Node
+}
+
+// nodeProber checks a node via RLPx, collecting its metadata.
+type nodeProber interface {
+	probe(n *enode.Node) (*probeResult, error)
+}
+
+// crawler walks the discovery table, checking the liveness of every node found
+// and of the previously known ones that are due for revalidation.
+type crawler struct {
+	input   nodeSet
+	output  nodeSet
+	disc    nodeSource
+	prober  nodeProber
+	workers int
+	closed  chan struct{}
+
+	revalidateInterval time.Duration
+}
+
+// crawlResult is the outcome of checking a single node.
+type crawlResult struct {
+	node  *enode.Node
+	alive bool
+	info  *probeResult
+	err   error
+}
+
+const (
+	nodeRemoved = iota
+	nodeSkipped
+	nodeAdded
+	nodeUpdated
+)
+
+// newCrawler creates a crawler refreshing the given node set. A nil prober
+// limits the liveness checks to the discovery protocol.
+func newCrawler(input nodeSet, disc nodeSource, prober nodeProber, workers int, revalidate time.Duration) *crawler {
+	c := &crawler{
+		input:              input,
+		output:             make(nodeSet, len(input)),
+		disc:               disc,
+		prober:             prober,
+		workers:            workers,
+		closed:             make(chan struct{}),
+		revalidateInterval: revalidate,
+	}
+	// Known nodes are retained unless a check removes them
+	for id, n := range input {
+		c.output[id] = n
+	}
+	return c
+}
+
+// run crawls the network until the timeout expires, returning the updated node
+// set. Known nodes which weren't due or couldn't be checked in time are retained
+// unchanged.
+func (c *crawler) run(timeout time.Duration) nodeSet {
+	var (
+		timeoutTimer = time.NewTimer(timeout)
+		statusTicker = time.NewTicker(8 * time.Second)
+		found        = make(chan *enode.Node)
+		work         = make(chan *enode.Node)
+		results      = make(chan crawlResult)
+		seen         = make(map[enode.ID]bool)
+		pending      []*enode.Node
+		running      int
+		added        int
+		updated      int
+		removed      int
+	)
+	defer timeoutTimer.Stop()
+	defer statusTicker.Stop()
+
+	// Queue the known nodes due for revalidation first
+	for id, n := range c.input {
+		seen[id] = true
+		if time.Since(n.LastCheck) >= c.revalidateInterval {
+			pending = append(pending, n.N.Node)
+		}
+	}
+	go c.discover(found)
+	for i := 0; i < c.workers; i++ {
+		go c.worker(work, results)
+	}
+loop:
+	for {
+		var (
+			next  *enode.Node
+			workc chan *enode.Node
+		)
+		if len(pending) > 0 {
+			next, workc = pending[0], work
+		}
+		select {
+		case n := <-found:
+			if !seen[n.ID()] {
+				seen[n.ID()] = true
+				pending = append(pending, n)
+			}
+		case workc <- next:
+			pending = pending[1:]
+			running++
+		case r := <-results:
+			running--
+			switch c.updateNode(r) {
+			case nodeAdded:
+				added++
+			case nodeUpdated:
+				updated++
+			case nodeRemoved:
+				removed++
+			}
+		case <-statusTicker.C:
+			log.Info("Crawling in progress", "added", added, "updated", updated, "removed", removed, "queued", len(pending), "nodes", len(c.output))
+		case <-timeoutTimer.C:
+			break loop
+		}
+	}
+	close(c.closed)
+
+	// Wait for the running checks to finish
+	for ; running > 0; running-- {
+		c.updateNode(<-results)
+	}
+	log.Info("Crawling finished", "added", added, "updated", updated, "removed", removed, "nodes", len(c.output))
+	return c.output
+}
+
+// discover feeds the nodes found by random lookups into the crawler loop.
+func (c *crawler) discover(found chan<- *enode.Node) {
+	for {
+		nodes := c.disc.LookupRandom()
+		for _, n := range nodes {
+			select {
+			case found <- n:
+			case <-c.closed:
+				return
+			}
+		}
+		// Avoid spinning if the lookups don't find anything (e.g. while the
+		// table is still empty)
+		wait := time.Duration(0)
+		if len(nodes) == 0 {
+			wait = time.Second
+		}
+		select {
+		case <-time.After(wait):
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// worker checks the nodes handed to it until the crawler is closed.
+func (c *crawler) worker(work <-chan *enode.Node, results chan<- crawlResult) {
+	for {
+		select {
+		case n := <-work:
+			results <- c.check(n)
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// check tests the liveness of a node. Nodes listening for TCP connections are
+// probed via RLPx, the others are resolved via discovery.
+func (c *crawler) check(n *enode.Node) crawlResult {
+	r := crawlResult{node: n}
+	if n.TCP() == 0 || c.prober == nil {
+		if resolved := c.disc.Resolve(n); resolved != nil {
+			r.node, r.alive = resolved, true
+		}
+		return r
+	}
+	r.info, r.err = c.prober.probe(n)
+	if _, ok := r.err.(p2p.DiscReason); ok || r.info != nil {
+		// The node completed the encryption handshake, it's online even if
+		// it didn't want to talk to us
+		r.alive = true
+	}
+	return r
+}
+
+// updateNode records the outcome of a node check in the output set.
+func (c *crawler) updateNode(r crawlResult) int {
+	id := r.node.ID()
+	n := c.output[id]
+	n.LastCheck = truncNow()
+
+	status := nodeUpdated
+	if !r.alive {
+		n.Score /= 2
+		if n.Score <= 0 {
+			delete(c.output, id)
+			if n.N.Node == nil {
+				return nodeSkipped // Never seen alive
+			}
+			return nodeRemoved
+		}
+		c.output[id] = n
+		return nodeSkipped
+	}
+	if n.N.Node == nil || r.node.Seq() >= n.Seq {
+		n.N, n.Seq = enrNode{r.node}, r.node.Seq()
+	}
+	n.Score++
+	if n.FirstResponse.IsZero() {
+		n.FirstResponse = n.LastCheck
+		status = nodeAdded
+	}
+	n.LastResponse = n.LastCheck
+	if r.info != nil {
+		n.Client, n.Caps = r.info.Client, r.info.Caps
+		if r.info.Eth != nil {
+			n.Eth = r.info.Eth
+		}
+	}
+	c.output[id] = n
+	return status
+}
+
+func truncNow() time.Time {
+	return time.Now().UTC().Truncate(1 * time.Second)
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+)
+
+// testDiscovery is a fake discovery table returning a fixed set of nodes.
+type testDiscovery struct {
+	mu    sync.Mutex
+	nodes []*enode.Node
+}
+
+func (d *testDiscovery) LookupRandom() []*enode.Node {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	nodes := d.nodes
+	d.nodes = nil
+	return nodes
+}
+
+func (d *testDiscovery) Resolve(n *enode.Node) *enode.Node {
+	return nil
+}
+
+// testProber is a fake prober answering with canned results.
+type testProber map[enode.ID]error
+
+func (p testProber) probe(n *enode.Node) (*probeResult, error) {
+	err, ok := p[n.ID()]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &ethInfo{Version: 63, NetworkID: 1, TD: (*hexutil.Big)(big.NewInt(100))}
+	return &probeResult{Client: "test/v1", Caps: []string{"eth/63"}, Eth: info}, nil
+}
+
+func newCrawlTestNode(t *testing.T, ip byte) *enode.Node {
+	key, _ := crypto.GenerateKey()
+
+	var r enr.Record
+	r.Set(enr.IP(net.IP{10, 0, 0, ip}))
+	r.Set(enr.TCP(30303))
+	enode.SignV4(&r, key)
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// Tests that the crawler adds responsive nodes with their metadata, keeps known
+// nodes that aren't due for revalidation and drops known nodes that went away.
+func TestCrawlerRun(t *testing.T) {
+	var (
+		fresh    = newCrawlTestNode(t, 1) // found, answers the eth handshake
+		refused  = newCrawlTestNode(t, 2) // found, disconnects right away
+		gone     = newCrawlTestNode(t, 3) // known, due and unreachable
+		recent   = newCrawlTestNode(t, 4) // known, not due
+		silent   = newCrawlTestNode(t, 5) // found, unreachable
+		old      = time.Now().Add(-time.Hour)
+		inputSet = nodeSet{
+			gone.ID():   {Seq: gone.Seq(), N: enrNode{gone}, Score: 1, LastCheck: old},
+			recent.ID(): {Seq: recent.Seq(), N: enrNode{recent}, Score: 5, LastCheck: time.Now()},
+		}
+		disc   = &testDiscovery{nodes: []*enode.Node{fresh, refused, silent, recent}}
+		prober = testProber{fresh.ID(): nil, refused.ID(): p2p.DiscTooManyPeers}
+	)
+	c := newCrawler(inputSet, disc, prober, 4, 10*time.Minute)
+	output := c.run(500 * time.Millisecond)
+
+	if len(output) != 3 {
+		t.Fatalf("wrong output size: have %d, want 3", len(output))
+	}
+	if n, ok := output[fresh.ID()]; !ok {
+		t.Error("responsive node missing")
+	} else {
+		if n.Score != 1 || n.FirstResponse.IsZero() || n.LastResponse.IsZero() {
+			t.Errorf("responsive node not marked alive: %+v", n)
+		}
+		if n.Client != "test/v1" || n.Eth == nil || n.Eth.Version != 63 {
+			t.Errorf("responsive node metadata missing: %+v", n)
+		}
+	}
+	if n, ok := output[refused.ID()]; !ok {
+		t.Error("refusing node missing")
+	} else if n.Eth != nil {
+		t.Errorf("refusing node has eth metadata: %+v", n.Eth)
+	}
+	if n, ok := output[recent.ID()]; !ok || n.Score != 5 {
+		t.Errorf("recently checked node changed: %+v", n)
+	}
+	if _, ok := output[gone.ID()]; ok {
+		t.Error("unreachable known node not removed")
+	}
+	if _, ok := output[silent.ID()]; ok {
+		t.Error("unreachable new node added")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/discover"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	crawlCommand = cli.Command{
+		Name:      "crawl",
+		Usage:     "Updates a nodes.json file with random nodes found in the DHT",
+		ArgsUsage: "<nodes.json>",
+		Action:    crawlNodes,
+		Flags: []cli.Flag{
+			crawlTimeoutFlag,
+			crawlBootnodesFlag,
+			crawlListenAddrFlag,
+			crawlWorkersFlag,
+			crawlRevalidateFlag,
+			crawlNoProbeFlag,
+		},
+	}
+)
+
+var (
+	crawlTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for the crawl",
+		Value: 30 * time.Minute,
+	}
+	crawlBootnodesFlag = cli.StringFlag{
+		Name:  "bootnodes",
+		Usage: "Comma separated nodes used for bootstrapping (defaults to the mainnet bootnodes)",
+	}
+	crawlListenAddrFlag = cli.StringFlag{
+		Name:  "addr",
+		Usage: "Listening address of the discovery protocol",
+		Value: ":0",
+	}
+	crawlWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of concurrent node checks",
+		Value: 16,
+	}
+	crawlRevalidateFlag = cli.DurationFlag{
+		Name:  "revalidate",
+		Usage: "Minimum time between checks of a known node",
+		Value: 10 * time.Minute,
+	}
+	crawlNoProbeFlag = cli.BoolFlag{
+		Name:  "noprobe",
+		Usage: "Only check liveness via discovery, skipping the RLPx and eth handshakes",
+	}
+)
+
+// crawlNodes performs crawlCommand.
+func crawlNodes(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need nodes file as argument")
+	}
+	nodesFile := ctx.Args().First()
+
+	// Refresh the existing node set if there is one
+	var inputSet nodeSet
+	if common.FileExist(nodesFile) {
+		inputSet = loadNodesJSON(nodesFile)
+		if err := inputSet.verify(); err != nil {
+			return fmt.Errorf("invalid node set %v: %v", nodesFile, err)
+		}
+	}
+	disc, err := startDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+	defer disc.Close()
+
+	var prober nodeProber
+	if !ctx.Bool(crawlNoProbeFlag.Name) {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		p, err := newProber(key, ctx.Int(crawlWorkersFlag.Name), 10*time.Second)
+		if err != nil {
+			return err
+		}
+		defer p.stop()
+		prober = p
+	}
+	c := newCrawler(inputSet, disc, prober, ctx.Int(crawlWorkersFlag.Name), ctx.Duration(crawlRevalidateFlag.Name))
+	output := c.run(ctx.Duration(crawlTimeoutFlag.Name))
+	writeNodesJSON(nodesFile, output)
+	return nil
+}
+
+// startDiscovery starts a discovery v4 node with an ephemeral key, which is
+// bootstrapped from the nodes given on the command line.
+func startDiscovery(ctx *cli.Context) (*discover.Table, error) {
+	bootnodes, err := parseBootnodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	db, err := enode.OpenDB("")
+	if err != nil {
+		return nil, err
+	}
+	ln := enode.NewLocalNode(db, key)
+
+	addr, err := net.ResolveUDPAddr("udp", ctx.String(crawlListenAddrFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return discover.ListenUDP(conn, ln, discover.Config{
+		PrivateKey: key,
+		Bootnodes:  bootnodes,
+	})
+}
+
+// parseBootnodes parses the bootstrap nodes given on the command line.
+func parseBootnodes(ctx *cli.Context) ([]*enode.Node, error) {
+	s := params.MainnetBootnodes
+	if ctx.IsSet(crawlBootnodesFlag.Name) {
+		s = strings.Split(ctx.String(crawlBootnodesFlag.Name), ",")
+	}
+	nodes := make([]*enode.Node, len(s))
+	for i, record := range s {
+		n, err := parseNode(strings.TrimSpace(record))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootstrap node: %v", err)
+		}
+		nodes[i] = n
+	}
+	if len(nodes) == 0 {
+		log.Warn("Crawling without bootstrap nodes")
+	}
+	return nodes, nil
+}
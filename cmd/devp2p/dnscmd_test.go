@@ -0,0 +1,91 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+)
+
+// Tests that a crawled node set can be turned into a signed tree and exported
+// as DNS TXT records.
+func TestDNSSignAndExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "devp2p-dns-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	treedir := filepath.Join(dir, "nodes.example.org")
+	if err := os.Mkdir(treedir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Create a node set and a signing key
+	ns := make(nodeSet)
+	for i := 0; i < 20; i++ {
+		key, _ := crypto.GenerateKey()
+
+		var r enr.Record
+		r.Set(enr.IP(net.IP{10, 0, 0, byte(i)}))
+		r.Set(enr.TCP(30303))
+		enode.SignV4(&r, key)
+		n, _ := enode.New(enode.ValidSchemes, &r)
+		ns.add(n)
+	}
+	_, nodesFile := treeDefinitionFiles(treedir)
+	writeNodesJSON(nodesFile, ns)
+
+	if loaded := loadNodesJSON(nodesFile); !reflect.DeepEqual(loaded.nodes(), ns.nodes()) {
+		t.Fatal("node set changed by JSON round trip")
+	}
+	key, _ := crypto.GenerateKey()
+	keyfile := filepath.Join(dir, "key")
+	if err := crypto.SaveECDSA(keyfile, key); err != nil {
+		t.Fatal(err)
+	}
+	// Sign the tree and export it
+	if err := app.Run([]string{"devp2p", "dns", "sign", treedir, keyfile}); err != nil {
+		t.Fatalf("failed to sign tree: %v", err)
+	}
+	txtfile := filepath.Join(dir, "txt.json")
+	if err := app.Run([]string{"devp2p", "dns", "to-txt", treedir, txtfile}); err != nil {
+		t.Fatalf("failed to export tree: %v", err)
+	}
+	def := loadTreeDefinition(treedir)
+	if def.Meta.Seq != 1 {
+		t.Errorf("wrong tree sequence number: have %d, want 1", def.Meta.Seq)
+	}
+	var txt map[string]string
+	if err := common.LoadJSON(txtfile, &txt); err != nil {
+		t.Fatalf("invalid TXT output: %v", err)
+	}
+	if _, ok := txt["nodes.example.org"]; !ok {
+		t.Errorf("tree root missing from TXT records")
+	}
+	if len(txt) <= len(ns) {
+		t.Errorf("too few TXT records: have %d, want more than %d", len(txt), len(ns))
+	}
+}
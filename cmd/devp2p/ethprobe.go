@@ -0,0 +1,249 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/forkid"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+var (
+	errNoEthProtocol = errors.New("eth protocol not supported")
+	errProbeTimeout  = errors.New("eth handshake timeout")
+	errInvalidStatus = errors.New("invalid eth handshake")
+)
+
+// ethInfo is the chain metadata announced by a node in its eth handshake.
+type ethInfo struct {
+	Version   uint           `json:"version"`
+	NetworkID uint64         `json:"networkId"`
+	TD        *hexutil.Big   `json:"td"`
+	Head      common.Hash    `json:"head"`
+	Genesis   common.Hash    `json:"genesis"`
+	ForkHash  hexutil.Bytes  `json:"forkHash,omitempty"` // Only announced on eth/64 and later
+	ForkNext  hexutil.Uint64 `json:"forkNext,omitempty"` // Only announced on eth/64 and later
+}
+
+// probeResult is the outcome of probing a single node.
+type probeResult struct {
+	Client string   // Client name from the devp2p handshake
+	Caps   []string // Capabilities from the devp2p handshake
+	Eth    *ethInfo // Chain metadata from the eth handshake, nil if it failed
+}
+
+// Status messages of the eth protocol versions, as sent in the handshake.
+type (
+	ethStatus struct {
+		ProtocolVersion uint32
+		NetworkID       uint64
+		TD              *big.Int
+		Head            common.Hash
+		Genesis         common.Hash
+	}
+	ethStatus64 struct {
+		ProtocolVersion uint32
+		NetworkID       uint64
+		TD              *big.Int
+		Head            common.Hash
+		Genesis         common.Hash
+		ForkID          forkid.ID
+	}
+)
+
+// prober connects to nodes via RLPx and reads their eth handshake to collect
+// client and chain metadata. It doesn't answer the handshake, so the remote
+// side eventually drops the connection, which is torn down right after anyway.
+type prober struct {
+	srv     *p2p.Server
+	timeout time.Duration
+
+	lock    sync.Mutex
+	pending map[enode.ID]chan *probeResult // Eth handshakes waited for
+}
+
+// newProber starts a devp2p server handling at most the given number of
+// concurrent probes.
+func newProber(key *ecdsa.PrivateKey, maxProbes int, timeout time.Duration) (*prober, error) {
+	p := &prober{
+		timeout: timeout,
+		pending: make(map[enode.ID]chan *probeResult),
+	}
+	var protos []p2p.Protocol
+	for i, version := range eth.ProtocolVersions {
+		version := version // Closure for the run function below
+		protos = append(protos, p2p.Protocol{
+			Name:    eth.ProtocolName,
+			Version: version,
+			Length:  eth.ProtocolLengths[i],
+			Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return p.runEth(version, peer, rw)
+			},
+		})
+	}
+	p.srv = &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    maxProbes,
+		Name:        "devp2p-crawler",
+		NoDiscovery: true,
+		NoDial:      true,
+		Protocols:   protos,
+		Logger:      log.New("module", "crawler"),
+	}}
+	if err := p.srv.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// stop terminates the devp2p server of the prober.
+func (p *prober) stop() {
+	p.srv.Stop()
+}
+
+// probe connects to the given node and collects its metadata. A non-nil result
+// is returned whenever the node accepted the connection, even if the eth
+// handshake failed afterwards.
+func (p *prober) probe(n *enode.Node) (*probeResult, error) {
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()), p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	resc := make(chan *probeResult, 1)
+
+	p.lock.Lock()
+	p.pending[n.ID()] = resc
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.pending, n.ID())
+		p.lock.Unlock()
+	}()
+	fd.SetDeadline(time.Now().Add(p.timeout))
+	if err := p.srv.SetupConn(fd, 0, n); err != nil {
+		return nil, err
+	}
+	fd.SetDeadline(time.Time{})
+
+	// Connection established, don't wait for the eth handshake of nodes which
+	// don't support the protocol at all
+	if peer := p.peer(n.ID()); peer != nil {
+		defer peer.Disconnect(p2p.DiscQuitting)
+
+		result := &probeResult{Client: peer.Name(), Caps: capStrings(peer.Caps())}
+		if !hasCap(peer.Caps(), eth.ProtocolName) {
+			return result, errNoEthProtocol
+		}
+	}
+	select {
+	case result := <-resc:
+		if result.Eth == nil {
+			return result, errInvalidStatus
+		}
+		return result, nil
+	case <-time.After(p.timeout):
+		return nil, errProbeTimeout
+	}
+}
+
+// peer retrieves the connected peer with the given ID.
+func (p *prober) peer(id enode.ID) *p2p.Peer {
+	for _, peer := range p.srv.Peers() {
+		if peer.ID() == id {
+			return peer
+		}
+	}
+	return nil
+}
+
+// runEth reads the status message of a remote eth protocol and delivers it to
+// the pending probe.
+func (p *prober) runEth(version uint, peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+	info, err := readEthStatus(version, rw)
+	result := &probeResult{Client: peer.Name(), Caps: capStrings(peer.Caps()), Eth: info}
+
+	p.lock.Lock()
+	if resc, ok := p.pending[peer.ID()]; ok {
+		select {
+		case resc <- result:
+		default:
+		}
+	}
+	p.lock.Unlock()
+
+	return err
+}
+
+func capStrings(caps []p2p.Cap) []string {
+	strs := make([]string, len(caps))
+	for i, c := range caps {
+		strs[i] = c.String()
+	}
+	return strs
+}
+
+func hasCap(caps []p2p.Cap, name string) bool {
+	for _, c := range caps {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readEthStatus reads and decodes the first message of an eth protocol, which
+// must be the status message of the handshake.
+func readEthStatus(version uint, rw p2p.MsgReadWriter) (*ethInfo, error) {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	defer msg.Discard()
+
+	if msg.Code != eth.StatusMsg {
+		return nil, fmt.Errorf("first msg has code %x (!= %x)", msg.Code, eth.StatusMsg)
+	}
+	info := &ethInfo{Version: version}
+	if version >= 64 {
+		var status ethStatus64
+		if err := msg.Decode(&status); err != nil {
+			return nil, err
+		}
+		info.NetworkID, info.TD, info.Head, info.Genesis = status.NetworkID, (*hexutil.Big)(status.TD), status.Head, status.Genesis
+		info.ForkHash, info.ForkNext = status.ForkID.Hash[:], hexutil.Uint64(status.ForkID.Next)
+	} else {
+		var status ethStatus
+		if err := msg.Decode(&status); err != nil {
+			return nil, err
+		}
+		info.NetworkID, info.TD, info.Head, info.Genesis = status.NetworkID, (*hexutil.Big)(status.TD), status.Head, status.Genesis
+	}
+	return info, nil
+}
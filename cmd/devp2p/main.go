@@ -0,0 +1,59 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// devp2p is a utility for node discovery and peer-to-peer network operations.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/cmd/utils"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+
+var app *cli.App
+
+func init() {
+	app = utils.NewApp(gitCommit, "go-bitcoiin devp2p tool")
+	app.Flags = []cli.Flag{
+		cli.IntFlag{
+			Name:  "verbosity",
+			Usage: "log verbosity (0-9)",
+			Value: int(log.LvlInfo),
+		},
+	}
+	app.Before = func(ctx *cli.Context) error {
+		handler := log.StreamHandler(os.Stderr, log.TerminalFormat(true))
+		log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.GlobalInt("verbosity")), handler))
+		return nil
+	}
+	app.Commands = []cli.Command{
+		crawlCommand,
+		dnsCommand,
+	}
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
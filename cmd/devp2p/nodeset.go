@@ -0,0 +1,161 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+)
+
+const jsonIndent = "    "
+
+// nodeSet is the nodes.json file format. It holds a set of node records
+// as a JSON object.
+type nodeSet map[enode.ID]nodeJSON
+
+type nodeJSON struct {
+	Seq uint64  `json:"seq"`
+	N   enrNode `json:"record"`
+
+	// The score tracks how many liveness checks were performed. It is incremented by one
+	// every time the node passes a check, and halved every time it doesn't.
+	Score int `json:"score,omitempty"`
+	// These two track the time of last successful contact.
+	FirstResponse time.Time `json:"firstResponse,omitempty"`
+	LastResponse  time.Time `json:"lastResponse,omitempty"`
+	// This one tracks the time of our last attempt to contact the node.
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+
+	// These are collected from the handshakes of the last successful RLPx probe.
+	Client string   `json:"client,omitempty"`
+	Caps   []string `json:"caps,omitempty"`
+	Eth    *ethInfo `json:"eth,omitempty"`
+}
+
+// enrNode wraps a node for JSON encoding in the "enr:" text form of its record,
+// which unlike the enode:// URL retains the signature and all entries.
+type enrNode struct {
+	*enode.Node
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n enrNode) MarshalText() ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(n.Record())
+	if err != nil {
+		return nil, err
+	}
+	return []byte("enr:" + base64.RawURLEncoding.EncodeToString(enc)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *enrNode) UnmarshalText(text []byte) error {
+	node, err := parseNode(string(text))
+	if err != nil {
+		return err
+	}
+	n.Node = node
+	return nil
+}
+
+// parseNode parses a node record in "enr:" text form, or an enode:// URL.
+func parseNode(source string) (*enode.Node, error) {
+	if !strings.HasPrefix(source, "enr:") {
+		return enode.ParseV4(source)
+	}
+	enc, err := base64.RawURLEncoding.DecodeString(source[4:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid node record: %v", err)
+	}
+	var r enr.Record
+	if err := rlp.DecodeBytes(enc, &r); err != nil {
+		return nil, fmt.Errorf("invalid node record: %v", err)
+	}
+	return enode.New(enode.ValidSchemes, &r)
+}
+
+func loadNodesJSON(file string) nodeSet {
+	var nodes nodeSet
+	if err := common.LoadJSON(file, &nodes); err != nil {
+		exit(err)
+	}
+	return nodes
+}
+
+func writeNodesJSON(file string, nodes nodeSet) {
+	nodesJSON, err := json.MarshalIndent(nodes, "", jsonIndent)
+	if err != nil {
+		exit(err)
+	}
+	if file == "-" {
+		os.Stdout.Write(nodesJSON)
+		return
+	}
+	if err := ioutil.WriteFile(file, nodesJSON, 0644); err != nil {
+		exit(err)
+	}
+}
+
+// verify checks that the records of the set are present and match their keys.
+func (ns nodeSet) verify() error {
+	for id, n := range ns {
+		if n.N.Node == nil {
+			return fmt.Errorf("node %v: missing record", id)
+		}
+		if n.N.ID() != id {
+			return fmt.Errorf("node %v: record has different ID %v", id, n.N.ID())
+		}
+		if n.N.Seq() != n.Seq {
+			return fmt.Errorf("node %v: 'seq' does not match seq %d from record", id, n.N.Seq())
+		}
+	}
+	return nil
+}
+
+// nodes returns the nodes of the set, sorted by ID.
+func (ns nodeSet) nodes() []*enode.Node {
+	result := make([]*enode.Node, 0, len(ns))
+	for _, n := range ns {
+		result = append(result, n.N.Node)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].ID().Bytes(), result[j].ID().Bytes()) < 0
+	})
+	return result
+}
+
+// add inserts the given nodes, keeping the newer record of nodes already present.
+func (ns nodeSet) add(nodes ...*enode.Node) {
+	for _, n := range nodes {
+		v := ns[n.ID()]
+		if v.N.Node == nil || n.Seq() > v.Seq {
+			v.Seq, v.N = n.Seq(), enrNode{n}
+		}
+		ns[n.ID()] = v
+	}
+}
@@ -49,6 +49,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics/influxdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/miner"
 	"git.pirl.io/bitcoiin/go-bitcoiin/node"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/discv5"
@@ -168,7 +169,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "snap")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
@@ -194,6 +195,10 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	SyncFromFlag = cli.StringFlag{
+		Name:  "syncfrom",
+		Usage: "Trusted block to start an empty chain from instead of genesis (<hash>@<number>[@<total difficulty>])",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -244,6 +249,14 @@ var (
 		Usage: "Number of recent ethash mining DAGs to keep on disk (1+GB each)",
 		Value: eth.DefaultConfig.Ethash.DatasetsOnDisk,
 	}
+	EthashCPUAffinityFlag = cli.BoolFlag{
+		Name:  "ethash.affinity",
+		Usage: "Pin each CPU mining thread to its own core (Linux only)",
+	}
+	EthashHugePagesFlag = cli.BoolFlag{
+		Name:  "ethash.hugepages",
+		Usage: "Back the mining DAG with NUMA interleaved hugepages (Linux only)",
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = cli.StringFlag{
 		Name:  "txpool.locals",
@@ -263,6 +276,15 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolSnapshotFlag = cli.StringFlag{
+		Name:  "txpool.snapshot",
+		Usage: "Disk snapshot of remote transactions saved on shutdown and reloaded on startup (disabled if empty)",
+	}
+	TxPoolSnapshotAgeFlag = cli.DurationFlag{
+		Name:  "txpool.snapshotage",
+		Usage: "Maximum age of a remote transaction snapshot to be reloaded",
+		Value: core.DefaultTxPoolConfig.SnapshotMaxAge,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -298,6 +320,25 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolPriorityFlag = cli.StringFlag{
+		Name:  "txpool.priority",
+		Usage: "Comma separated accounts granted the priority tier allowances (price checks still apply)",
+	}
+	TxPoolPrioritySlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.priorityslots",
+		Usage: "Minimum number of executable transaction slots guaranteed per priority account",
+		Value: eth.DefaultConfig.TxPool.PriorityAccountSlots,
+	}
+	TxPoolPriorityQueueFlag = cli.Uint64Flag{
+		Name:  "txpool.priorityqueue",
+		Usage: "Maximum number of non-executable transaction slots permitted per priority account",
+		Value: eth.DefaultConfig.TxPool.PriorityAccountQueue,
+	}
+	TxPoolPriorityLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.prioritylifetime",
+		Usage: "Maximum amount of time non-executable priority transactions are queued",
+		Value: eth.DefaultConfig.TxPool.PriorityLifetime,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -343,6 +384,43 @@ var (
 		Name:  "miner.notify",
 		Usage: "Comma separated HTTP URL list to notify of new work packages",
 	}
+	MinerStratumFlag = cli.StringFlag{
+		Name:  "miner.stratum",
+		Usage: "Listening address of the stratum server for remote miners (e.g. 0.0.0.0:8008)",
+	}
+	MinerStratumDiffFlag = cli.Uint64Flag{
+		Name:  "miner.stratum.diff",
+		Usage: "Difficulty of shares accepted from stratum workers (0 = block difficulty)",
+	}
+	MinerPoolFlag = cli.BoolFlag{
+		Name:  "miner.pool",
+		Usage: "Account stratum shares and split block rewards between the workers' addresses",
+	}
+	MinerPoolSchemeFlag = cli.StringFlag{
+		Name:  "miner.pool.scheme",
+		Usage: `Reward split scheme of the mining pool ("pplns" or "prop")`,
+		Value: eth.DefaultConfig.MinerPool.Scheme,
+	}
+	MinerPoolWindowFlag = cli.Uint64Flag{
+		Name:  "miner.pool.window",
+		Usage: "Number of last shares rewarded by the pplns scheme",
+		Value: eth.DefaultConfig.MinerPool.Window,
+	}
+	MinerPoolFeeFlag = cli.Float64Flag{
+		Name:  "miner.pool.fee",
+		Usage: "Percentage of block rewards retained by the etherbase",
+		Value: eth.DefaultConfig.MinerPool.Fee,
+	}
+	MinerPoolThresholdFlag = BigFlag{
+		Name:  "miner.pool.threshold",
+		Usage: "Minimum balance in wei before a payout is issued",
+		Value: eth.DefaultConfig.MinerPool.Threshold,
+	}
+	MinerPoolIntervalFlag = cli.DurationFlag{
+		Name:  "miner.pool.interval",
+		Usage: "Time interval between automatic payouts (0 = manual payouts only)",
+		Value: eth.DefaultConfig.MinerPool.Interval,
+	}
 	MinerGasTargetFlag = cli.Uint64Flag{
 		Name:  "miner.gastarget",
 		Usage: "Target gas floor for mined blocks",
@@ -395,6 +473,15 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerTxOrderFlag = cli.StringFlag{
+		Name:  "miner.txorder",
+		Usage: `Transaction ordering of mined blocks ("price", "fifo" or "allowlist")`,
+		Value: miner.TxOrderPrice,
+	}
+	MinerAllowlistFlag = cli.StringFlag{
+		Name:  "miner.allowlist",
+		Usage: "Comma separated accounts whose transactions are mined first with the allowlist ordering",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -458,6 +545,18 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCReadyMinPeersFlag = cli.IntFlag{
+		Name:  "rpc.ready.minpeers",
+		Usage: "Minimum number of peers required by the HTTP-RPC /ready endpoint",
+	}
+	RPCReadyMaxHeadAgeFlag = cli.DurationFlag{
+		Name:  "rpc.ready.maxheadage",
+		Usage: "Maximum age of the chain head allowed by the HTTP-RPC /ready endpoint (0 = disabled)",
+	}
+	RPCReadySyncedFlag = cli.BoolFlag{
+		Name:  "rpc.ready.synced",
+		Usage: "Report the node as not ready via the HTTP-RPC /ready endpoint while syncing",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -490,6 +589,25 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	GRPCEnabledFlag = cli.BoolFlag{
+		Name:  "grpc",
+		Usage: "Enable the gRPC server",
+	}
+	GRPCListenAddrFlag = cli.StringFlag{
+		Name:  "grpcaddr",
+		Usage: "gRPC server listening interface",
+		Value: node.DefaultGRPCHost,
+	}
+	GRPCPortFlag = cli.IntFlag{
+		Name:  "grpcport",
+		Usage: "gRPC server listening port",
+		Value: node.DefaultGRPCPort,
+	}
+	GRPCApiFlag = cli.StringFlag{
+		Name:  "grpcapi",
+		Usage: "API's offered over the gRPC interface",
+		Value: "",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -510,6 +628,16 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	MaxUploadFlag = cli.IntFlag{
+		Name:  "maxupload",
+		Usage: "Maximum upload bandwidth shared by all peers in KB/s (0 = unlimited)",
+		Value: 0,
+	}
+	MaxDownloadFlag = cli.IntFlag{
+		Name:  "maxdownload",
+		Usage: "Maximum download bandwidth shared by all peers in KB/s (0 = unlimited)",
+		Value: 0,
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -551,6 +679,10 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	DNSDiscoveryFlag = cli.StringFlag{
+		Name:  "discovery.dns",
+		Usage: "Comma separated enrtree:// URLs of DNS discovery lists to find peers from",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
@@ -777,6 +909,17 @@ func setNAT(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// setDNSDiscovery configures the DNS discovery lists to find peers from.
+func setDNSDiscovery(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
+		if urls := ctx.GlobalString(DNSDiscoveryFlag.Name); urls == "" {
+			cfg.DiscoveryURLs = nil // Lists from the config file disabled
+		} else {
+			cfg.DiscoveryURLs = splitAndTrim(urls)
+		}
+	}
+}
+
 // splitAndTrim splits input separated by a comma
 // and trims excessive white space from the substrings.
 func splitAndTrim(input string) []string {
@@ -809,6 +952,15 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCReadyMinPeersFlag.Name) {
+		cfg.HTTPReadiness.MinPeers = ctx.GlobalInt(RPCReadyMinPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCReadyMaxHeadAgeFlag.Name) {
+		cfg.HTTPReadiness.MaxHeadAge = ctx.GlobalDuration(RPCReadyMaxHeadAgeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCReadySyncedFlag.Name) {
+		cfg.HTTPReadiness.RequireSynced = ctx.GlobalBool(RPCReadySyncedFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -832,6 +984,24 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setGRPC creates the gRPC listener interface string from the set command line
+// flags, returning empty if the gRPC endpoint is disabled.
+func setGRPC(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GRPCEnabledFlag.Name) && cfg.GRPCHost == "" {
+		cfg.GRPCHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GRPCListenAddrFlag.Name) {
+			cfg.GRPCHost = ctx.GlobalString(GRPCListenAddrFlag.Name)
+		}
+	}
+
+	if ctx.GlobalIsSet(GRPCPortFlag.Name) {
+		cfg.GRPCPort = ctx.GlobalInt(GRPCPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(GRPCApiFlag.Name) {
+		cfg.GRPCModules = splitAndTrim(ctx.GlobalString(GRPCApiFlag.Name))
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -958,6 +1128,12 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(MaxUploadFlag.Name) {
+		cfg.MaxUploadRate = ctx.GlobalInt(MaxUploadFlag.Name) * 1024
+	}
+	if ctx.GlobalIsSet(MaxDownloadFlag.Name) {
+		cfg.MaxDownloadRate = ctx.GlobalInt(MaxDownloadFlag.Name) * 1024
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -995,6 +1171,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setGRPC(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 
@@ -1053,6 +1230,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalString(TxPoolSnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSnapshotAgeFlag.Name) {
+		cfg.SnapshotMaxAge = ctx.GlobalDuration(TxPoolSnapshotAgeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1074,6 +1257,47 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPriorityFlag.Name) {
+		priority := strings.Split(ctx.GlobalString(TxPoolPriorityFlag.Name), ",")
+		for _, account := range priority {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --txpool.priority: %s", trimmed)
+			} else {
+				cfg.Priority = append(cfg.Priority, common.HexToAddress(trimmed))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolPrioritySlotsFlag.Name) {
+		cfg.PriorityAccountSlots = ctx.GlobalUint64(TxPoolPrioritySlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPriorityQueueFlag.Name) {
+		cfg.PriorityAccountQueue = ctx.GlobalUint64(TxPoolPriorityQueueFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPriorityLifetimeFlag.Name) {
+		cfg.PriorityLifetime = ctx.GlobalDuration(TxPoolPriorityLifetimeFlag.Name)
+	}
+}
+
+// setMinerPool applies mining pool related command line flags to the config.
+func setMinerPool(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(MinerPoolFlag.Name) {
+		cfg.MinerPool.Enabled = ctx.GlobalBool(MinerPoolFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPoolSchemeFlag.Name) {
+		cfg.MinerPool.Scheme = ctx.GlobalString(MinerPoolSchemeFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPoolWindowFlag.Name) {
+		cfg.MinerPool.Window = ctx.GlobalUint64(MinerPoolWindowFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPoolFeeFlag.Name) {
+		cfg.MinerPool.Fee = ctx.GlobalFloat64(MinerPoolFeeFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPoolThresholdFlag.Name) {
+		cfg.MinerPool.Threshold = GlobalBig(ctx, MinerPoolThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPoolIntervalFlag.Name) {
+		cfg.MinerPool.Interval = ctx.GlobalDuration(MinerPoolIntervalFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1095,6 +1319,18 @@ func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EthashDatasetsOnDiskFlag.Name) {
 		cfg.Ethash.DatasetsOnDisk = ctx.GlobalInt(EthashDatasetsOnDiskFlag.Name)
 	}
+	if ctx.GlobalIsSet(EthashCPUAffinityFlag.Name) {
+		cfg.Ethash.CPUAffinity = ctx.GlobalBool(EthashCPUAffinityFlag.Name)
+	}
+	if ctx.GlobalIsSet(EthashHugePagesFlag.Name) {
+		cfg.Ethash.HugePages = ctx.GlobalBool(EthashHugePagesFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumFlag.Name) {
+		cfg.Ethash.StratumAddr = ctx.GlobalString(MinerStratumFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumDiffFlag.Name) {
+		cfg.Ethash.StratumShareDiff = ctx.GlobalUint64(MinerStratumDiffFlag.Name)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
@@ -1174,6 +1410,17 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+func setSyncFrom(ctx *cli.Context, cfg *eth.Config) {
+	if !ctx.GlobalIsSet(SyncFromFlag.Name) {
+		return
+	}
+	checkpoint, err := downloader.ParseSyncCheckpoint(ctx.GlobalString(SyncFromFlag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", SyncFromFlag.Name, err)
+	}
+	cfg.SyncFrom = checkpoint
+}
+
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
@@ -1186,6 +1433,8 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 	setWhitelist(ctx, cfg)
+	setSyncFrom(ctx, cfg)
+	setDNSDiscovery(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
@@ -1248,6 +1497,22 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.MinerNoverify = ctx.Bool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerTxOrderFlag.Name) {
+		cfg.MinerTxOrder = ctx.GlobalString(MinerTxOrderFlag.Name)
+		if _, err := miner.NewTxOrderer(cfg.MinerTxOrder, nil); err != nil {
+			Fatalf("Invalid --%s: %v", MinerTxOrderFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(MinerAllowlistFlag.Name) {
+		for _, account := range strings.Split(ctx.GlobalString(MinerAllowlistFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --%s: %s", MinerAllowlistFlag.Name, trimmed)
+			} else {
+				cfg.MinerAllowlist = append(cfg.MinerAllowlist, common.HexToAddress(trimmed))
+			}
+		}
+	}
+	setMinerPool(ctx, cfg)
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
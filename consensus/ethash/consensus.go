@@ -591,17 +591,7 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 	}
 	// If slow-but-light PoW verification was requested (or DAG not yet ready), use an ethash cache
 	if !fulldag {
-		cache := ethash.cache(number)
-
-		size := datasetSize(number)
-		if ethash.config.PowMode == ModeTest {
-			size = 32 * 1024
-		}
-		digest, result = hashimotoLight(size, cache.cache, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
-
-		// Caches are unmapped in a finalizer. Ensure that the cache stays alive
-		// until after the call to hashimotoLight so it's not unmapped while being used.
-		runtime.KeepAlive(cache)
+		digest, result = ethash.hashimotoLight(number, ethash.SealHash(header).Bytes(), header.Nonce.Uint64())
 	}
 
 
@@ -628,6 +618,23 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 	return nil
 }
 
+// hashimotoLight computes the PoW digest and result of a seal hash and nonce at
+// the given block height, using the verification cache of its epoch.
+func (ethash *Ethash) hashimotoLight(number uint64, hash []byte, nonce uint64) ([]byte, []byte) {
+	cache := ethash.cache(number)
+
+	size := datasetSize(number)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
+	}
+	digest, result := hashimotoLight(size, cache.cache, hash, nonce)
+
+	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
+	// until after the call to hashimotoLight so it's not unmapped while being used.
+	runtime.KeepAlive(cache)
+	return digest, result
+}
+
 // Prepare implements consensus.Engine, initializing the difficulty field of a
 // header to conform to the ethash protocol. The changes are done inline.
 func (ethash *Ethash) Prepare(chain consensus.ChainReader, header *types.Header) error {
@@ -683,6 +690,17 @@ var (
 // reward. The total reward consists of the static block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
 func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+	reward, uncleRewards := BlockRewards(config, header, uncles)
+	for i, uncle := range uncles {
+		state.AddBalance(uncle.Coinbase, uncleRewards[i])
+	}
+	state.AddBalance(header.Coinbase, reward)
+}
+
+// BlockRewards calculates the mining rewards of the given block, returning the
+// reward of the block's coinbase (static reward plus uncle inclusion rewards)
+// and the rewards of the coinbases of each included uncle.
+func BlockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, []*big.Int) {
 	// Select the correct block reward based on chain progression
 
 	blockReward := ByzantiumBlockReward
@@ -697,19 +715,15 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 
 	// Accumulate the rewards for the miner and any included uncles
 	reward := new(big.Int).Set(blockReward)
-	r := new(big.Int)
-	for _, uncle := range uncles {
-		r.Add(uncle.Number, big8)
+	uncleRewards := make([]*big.Int, len(uncles))
+	for i, uncle := range uncles {
+		r := new(big.Int).Add(uncle.Number, big8)
 		r.Sub(r, header.Number)
 		r.Mul(r, blockReward)
 		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
+		uncleRewards[i] = r
 
-		r.Div(blockReward, big32)
-		reward.Add(reward, r)
+		reward.Add(reward, new(big.Int).Div(blockReward, big32))
 	}
-	state.AddBalance(header.Coinbase, reward)
-
-
-
-}
\ No newline at end of file
+	return reward, uncleRewards
+}
@@ -37,6 +37,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/consensus"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
@@ -50,7 +51,7 @@ var (
 	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 
 	// sharedEthash is a full instance that can be shared between multiple users.
-	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal}, nil, false)
+	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal, "", 0, false, false}, nil, false)
 
 	// algorithmRevision is the data structure version used for file naming.
 	algorithmRevision = 23
@@ -277,12 +278,14 @@ func (c *cache) finalizer() {
 
 // dataset wraps an ethash dataset with some metadata to allow easier concurrent use.
 type dataset struct {
-	epoch   uint64    // Epoch for which this cache is relevant
-	dump    *os.File  // File descriptor of the memory mapped cache
-	mmap    mmap.MMap // Memory map itself to unmap before releasing
-	dataset []uint32  // The actual cache data content
-	once    sync.Once // Ensures the cache is generated only once
-	done    uint32    // Atomic flag to determine generation status
+	epoch     uint64    // Epoch for which this cache is relevant
+	dump      *os.File  // File descriptor of the memory mapped cache
+	mmap      mmap.MMap // Memory map itself to unmap before releasing
+	hugepages bool      // Whether to move the dataset into hugepages once generated
+	huge      []byte    // Hugepage backed memory region to unmap before releasing
+	dataset   []uint32  // The actual cache data content
+	once      sync.Once // Ensures the cache is generated only once
+	done      uint32    // Atomic flag to determine generation status
 }
 
 // newDataset creates a new ethash mining dataset and returns it as a plain Go
@@ -291,12 +294,26 @@ func newDataset(epoch uint64) interface{} {
 	return &dataset{epoch: epoch}
 }
 
+// newHugeDataset creates a new ethash mining dataset backed by hugepages and
+// returns it as a plain Go interface to be usable in an LRU cache.
+func newHugeDataset(epoch uint64) interface{} {
+	return &dataset{epoch: epoch, hugepages: true}
+}
+
 // generate ensures that the dataset content is generated before use.
 func (d *dataset) generate(dir string, limit int, test bool) {
 	d.once.Do(func() {
 		// Mark the dataset generated after we're done. This is needed for remote
 		defer atomic.StoreUint32(&d.done, 1)
 
+		// Move the dataset into hugepages before anyone gets to use it
+		if d.hugepages {
+			defer d.remap()
+		}
+		// Ensure that any memory mapping is cleaned up when the dataset becomes
+		// unused.
+		runtime.SetFinalizer(d, (*dataset).finalizer)
+
 		csize := cacheSize(d.epoch*epochLength + 1)
 		dsize := datasetSize(d.epoch*epochLength + 1)
 		seed := seedHash(d.epoch*epochLength + 1)
@@ -322,10 +339,6 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
 		logger := log.New("epoch", d.epoch)
 
-		// We're about to mmap the file, ensure that the mapping is cleaned up when the
-		// cache becomes unused.
-		runtime.SetFinalizer(d, (*dataset).finalizer)
-
 		// Try to load the file from disk and memory map it
 		var err error
 		d.dump, d.mmap, d.dataset, err = memoryMap(path)
@@ -355,6 +368,33 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 	})
 }
 
+// remap copies the dataset into an anonymous memory region backed by hugepages,
+// releasing the original storage. Random DAG accesses of the miner thrash the
+// TLB with regular pages, hugepages considerably reduce the misses. On failure
+// the dataset is left where it is.
+func (d *dataset) remap() {
+	mem, err := allocHugePages(len(d.dataset) * 4)
+	if err != nil {
+		log.Warn("Failed to back ethash dataset with hugepages", "epoch", d.epoch, "err", err)
+		return
+	}
+	// The region may exceed 4GB, view it as uint32s the same way as memoryMapFile
+	header := *(*reflect.SliceHeader)(unsafe.Pointer(&mem))
+	header.Len = len(d.dataset)
+	header.Cap = len(d.dataset)
+
+	buffer := *(*[]uint32)(unsafe.Pointer(&header))
+	copy(buffer, d.dataset)
+
+	if d.mmap != nil {
+		d.mmap.Unmap()
+		d.dump.Close()
+		d.mmap, d.dump = nil, nil
+	}
+	d.huge, d.dataset = mem, buffer
+	log.Debug("Moved ethash dataset into hugepages", "epoch", d.epoch, "size", len(mem))
+}
+
 // generated returns whether this particular dataset finished generating already
 // or not (it may not have been started at all). This is useful for remote miners
 // to default to verification caches instead of blocking on DAG generations.
@@ -369,6 +409,10 @@ func (d *dataset) finalizer() {
 		d.dump.Close()
 		d.mmap, d.dump = nil, nil
 	}
+	if d.huge != nil {
+		munmapAnonymous(d.huge)
+		d.huge = nil
+	}
 }
 
 // MakeCache generates a new ethash cache and optionally stores it to disk.
@@ -383,6 +427,54 @@ func MakeDataset(block uint64, dir string) {
 	d.generate(dir, math.MaxInt32, false)
 }
 
+// BenchmarkDataset generates or loads the ethash dataset of a block, honouring
+// the dataset, hugepage and affinity settings of the config, and measures the
+// hashes per second achieved by searching nonces over it with each of the given
+// thread counts for the given duration.
+func BenchmarkDataset(config Config, block uint64, threads []int, duration time.Duration) []float64 {
+	d := &dataset{epoch: block / epochLength, hugepages: config.HugePages}
+	d.generate(config.DatasetDir, math.MaxInt32, config.PowMode == ModeTest)
+
+	rates := make([]float64, len(threads))
+	for i, count := range threads {
+		var (
+			hashes uint64
+			pend   sync.WaitGroup
+			abort  = make(chan struct{})
+		)
+		start := time.Now()
+		for id := 0; id < count; id++ {
+			pend.Add(1)
+			go func(id int, nonce uint64) {
+				defer pend.Done()
+
+				if config.CPUAffinity {
+					pinMiner(id)
+				}
+				hash := make([]byte, common.HashLength)
+				for attempts := uint64(1); ; attempts++ {
+					hashimotoFull(d.dataset, hash, nonce+attempts)
+					if attempts%(1<<10) == 0 {
+						select {
+						case <-abort:
+							atomic.AddUint64(&hashes, attempts)
+							return
+						default:
+						}
+					}
+				}
+			}(id, uint64(rand.Int63()))
+		}
+		time.Sleep(duration)
+		close(abort)
+		pend.Wait()
+
+		rates[i] = float64(atomic.LoadUint64(&hashes)) / time.Since(start).Seconds()
+	}
+	runtime.KeepAlive(d)
+	return rates
+}
+
 // Mode defines the type and amount of PoW verification an ethash engine makes.
 type Mode uint
 
@@ -403,6 +495,12 @@ type Config struct {
 	DatasetsInMem  int
 	DatasetsOnDisk int
 	PowMode        Mode
+
+	StratumAddr      string `toml:",omitempty"` // TCP address of the stratum server for remote miners (empty = disabled)
+	StratumShareDiff uint64 `toml:",omitempty"` // Difficulty of shares accepted from stratum workers (0 = block difficulty)
+
+	CPUAffinity bool `toml:",omitempty"` // Pin each local mining thread to its own CPU (Linux only)
+	HugePages   bool `toml:",omitempty"` // Back mining datasets with NUMA interleaved hugepages (Linux only)
 }
 
 // sealTask wraps a seal block with relative result channel for remote sealer thread.
@@ -455,6 +553,8 @@ type Ethash struct {
 	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
 	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
 	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	stratum      *stratumServer   // Stratum server pushing work to remote miners, if enabled
+	shareFeed    event.Feed       // Feed of shares accepted from stratum workers
 
 	// The fields below are hooks for testing
 	shared    *Ethash       // Shared PoW verifier to avoid cache regeneration
@@ -480,10 +580,14 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 	if config.DatasetDir != "" && config.DatasetsOnDisk > 0 {
 		log.Info("Disk storage enabled for ethash DAGs", "dir", config.DatasetDir, "count", config.DatasetsOnDisk)
 	}
+	newDatasetFn := newDataset
+	if config.HugePages {
+		newDatasetFn = newHugeDataset
+	}
 	ethash := &Ethash{
 		config:       config,
 		caches:       newlru("cache", config.CachesInMem, newCache),
-		datasets:     newlru("dataset", config.DatasetsInMem, newDataset),
+		datasets:     newlru("dataset", config.DatasetsInMem, newDatasetFn),
 		update:       make(chan struct{}),
 		hashrate:     metrics.NewMeterForced(),
 		workCh:       make(chan *sealTask),
@@ -497,6 +601,19 @@ func New(config Config, notify []string, noverify bool) *Ethash {
 	return ethash
 }
 
+// StartStratum opens the stratum server configured for the engine, if any. It is
+// kept out of New so that a failure to listen can abort the node startup.
+func (ethash *Ethash) StartStratum() error {
+	ethash.lock.Lock()
+	running := ethash.stratum != nil
+	ethash.lock.Unlock()
+
+	if ethash.config.StratumAddr == "" || running {
+		return nil
+	}
+	return ethash.startStratum(ethash.config.StratumAddr, ethash.config.StratumShareDiff)
+}
+
 // NewTester creates a small sized ethash PoW scheme useful only for testing
 // purposes.
 func NewTester(notify []string, noverify bool) *Ethash {
@@ -580,6 +697,10 @@ func (ethash *Ethash) Close() error {
 		ethash.exitCh <- errc
 		err = <-errc
 		close(ethash.exitCh)
+
+		if ethash.stratum != nil {
+			ethash.stratum.close()
+		}
 	})
 	return err
 }
@@ -54,6 +54,31 @@ func TestTestMode(t *testing.T) {
 	}
 }
 
+// Tests that datasets moved into hugepages retain their content, and that they
+// can be mined over by pinned threads.
+func TestHugePageDataset(t *testing.T) {
+	plain := &dataset{epoch: 0}
+	plain.generate("", 0, true)
+
+	huge := &dataset{epoch: 0, hugepages: true}
+	huge.generate("", 0, true)
+
+	if len(huge.dataset) != len(plain.dataset) {
+		t.Fatalf("dataset length mismatch: have %d, want %d", len(huge.dataset), len(plain.dataset))
+	}
+	for i := range plain.dataset {
+		if huge.dataset[i] != plain.dataset[i] {
+			t.Fatalf("dataset item %d mismatch: have %x, want %x", i, huge.dataset[i], plain.dataset[i])
+		}
+	}
+	rates := BenchmarkDataset(Config{PowMode: ModeTest, CPUAffinity: true, HugePages: true}, 0, []int{1, 2}, 50*time.Millisecond)
+	for i, rate := range rates {
+		if rate <= 0 {
+			t.Errorf("benchmark %d: no hashes computed", i)
+		}
+	}
+}
+
 // This test checks that cache lru logic doesn't crash under load.
 // It reproduces https://git.pirl.io/bitcoiin/go-bitcoiin/issues/14943
 func TestCacheFileEvict(t *testing.T) {
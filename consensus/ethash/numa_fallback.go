@@ -0,0 +1,38 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux
+
+package ethash
+
+import "errors"
+
+var errNoPlatformSupport = errors.New("not supported on this platform")
+
+// pinThread fails on platforms without thread affinity support.
+func pinThread(id int) error {
+	return errNoPlatformSupport
+}
+
+// allocHugePages fails on platforms without hugepage support.
+func allocHugePages(size int) ([]byte, error) {
+	return nil, errNoPlatformSupport
+}
+
+// munmapAnonymous fails on platforms without hugepage support.
+func munmapAnonymous(mem []byte) error {
+	return errNoPlatformSupport
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux
+
+package ethash
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// hugePageSize is the size of the default hugepages on x86-64 and arm64.
+	hugePageSize = 2 * 1024 * 1024
+
+	// mpolInterleave is the NUMA memory policy spreading pages round-robin over
+	// a set of nodes.
+	mpolInterleave = 3
+)
+
+// pinThread binds the calling OS thread to the id-th CPU the process is allowed
+// to run on, wrapping around if there are more threads than CPUs. The thread is
+// left unpinned if the allowed CPU set is empty.
+func pinThread(id int) error {
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &allowed); err != nil {
+		return err
+	}
+	cpus := make([]int, 0, allowed.Count())
+	for cpu := 0; len(cpus) < cap(cpus); cpu++ {
+		if allowed.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	if len(cpus) == 0 {
+		return nil
+	}
+	var pinned unix.CPUSet
+	pinned.Set(cpus[id%len(cpus)])
+
+	return unix.SchedSetaffinity(0, &pinned)
+}
+
+// allocHugePages allocates an anonymous memory region of at least size bytes,
+// backed by reserved hugepages if there are enough of them, or by transparent
+// hugepages otherwise. On multi-socket machines the pages are interleaved over
+// all NUMA nodes, so the mining threads of every socket see the same latency.
+func allocHugePages(size int) ([]byte, error) {
+	size = (size + hugePageSize - 1) / hugePageSize * hugePageSize
+
+	mem, err := mmapAnonymous(size, unix.MAP_HUGETLB)
+	if err != nil {
+		if mem, err = mmapAnonymous(size, 0); err != nil {
+			return nil, err
+		}
+		if err := unix.Madvise(mem, unix.MADV_HUGEPAGE); err != nil {
+			munmapAnonymous(mem)
+			return nil, err
+		}
+	}
+	if nodes, err := onlineNodes(); err == nil && len(nodes) > 1 {
+		// Pages still work without the policy, only with uneven latencies
+		if err := interleave(mem, nodes); err != nil {
+			log.Warn("Failed to interleave ethash dataset over NUMA nodes", "nodes", len(nodes), "err", err)
+		}
+	}
+	return mem, nil
+}
+
+// mmapAnonymous maps a private anonymous memory region of the given size.
+func mmapAnonymous(size int, flags int) ([]byte, error) {
+	return unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS|flags)
+}
+
+// munmapAnonymous releases a memory region mapped by mmapAnonymous.
+func munmapAnonymous(mem []byte) error {
+	return unix.Munmap(mem)
+}
+
+// interleave sets the NUMA policy of a memory region to spread its pages over
+// the given nodes. It must be called before the pages are first touched.
+func interleave(mem []byte, nodes []int) error {
+	var mask [16]uint64 // Up to 1024 nodes
+	for _, node := range nodes {
+		if node < len(mask)*64 {
+			mask[node/64] |= 1 << uint(node%64)
+		}
+	}
+	if _, _, errno := unix.Syscall6(unix.SYS_MBIND, uintptr(unsafe.Pointer(&mem[0])), uintptr(len(mem)), mpolInterleave, uintptr(unsafe.Pointer(&mask[0])), uintptr(len(mask)*64+1), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// onlineNodes retrieves the NUMA nodes of the machine.
+func onlineNodes() ([]int, error) {
+	blob, err := ioutil.ReadFile("/sys/devices/system/node/online")
+	if err != nil {
+		return nil, err
+	}
+	return parseIndexList(strings.TrimSpace(string(blob)))
+}
+
+// parseIndexList parses a kernel index list of the form "0-3,8,10-11".
+func parseIndexList(list string) ([]int, error) {
+	var indices []int
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		for index := first; index <= last; index++ {
+			indices = append(indices, index)
+		}
+	}
+	return indices, nil
+}
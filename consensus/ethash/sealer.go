@@ -145,6 +145,10 @@ func (ethash *Ethash) mine(block *types.Block, id int, seed uint64, abort chan s
 	)
 	logger := log.New("miner", id)
 	logger.Trace("Started ethash search for new nonces", "seed", seed)
+
+	if ethash.config.CPUAffinity {
+		pinMiner(id)
+	}
 search:
 	for {
 		select {
@@ -186,6 +190,16 @@ search:
 	runtime.KeepAlive(dataset)
 }
 
+// pinMiner locks the calling goroutine to its OS thread and binds the thread to
+// a CPU of its own. The thread is never unlocked, so it's destroyed along with
+// its affinity when the mining goroutine exits.
+func pinMiner(id int) {
+	runtime.LockOSThread()
+	if err := pinThread(id); err != nil {
+		log.Warn("Failed to pin mining thread", "miner", id, "err", err)
+	}
+}
+
 // remote is a standalone goroutine to handle remote mining related stuff.
 func (ethash *Ethash) remote(notify []string, noverify bool) {
 	var (
@@ -315,6 +329,15 @@ func (ethash *Ethash) remote(notify []string, noverify bool) {
 			// Notify and requested URLs of the new work availability
 			notifyWork()
 
+			// Push the new work to all connected stratum workers
+			ethash.lock.Lock()
+			stratum := ethash.stratum
+			ethash.lock.Unlock()
+
+			if stratum != nil {
+				stratum.setWork(work.block)
+			}
+
 		case work := <-ethash.fetchWorkCh:
 			// Return current mining work to remote miner.
 			if currentBlock == nil {
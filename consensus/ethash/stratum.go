@@ -0,0 +1,681 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
+)
+
+const (
+	stratumMaxLineSize  = 4096             // Maximum size of a single stratum message
+	stratumReadTimeout  = 10 * time.Minute // Idle time after which a worker is dropped
+	stratumWriteTimeout = 10 * time.Second // Maximum time allowed to push a message to a worker
+	stratumExtranonce   = 2                // Number of nonce bytes assigned by the server in stratum mode
+
+	stratumMaxJobShares     = 16384       // Maximum number of shares tracked for a single job
+	stratumMaxSessionShares = 1024        // Maximum number of shares a single worker may submit for a job
+	stratumSubmitLimit      = 50          // Maximum number of submissions accepted from a worker per window
+	stratumSubmitWindow     = time.Second // Time window over which worker submissions are rate limited
+)
+
+var (
+	// stratumDiffOne is the share difficulty which NiceHash style stratum miners
+	// interpret as difficulty 1.
+	stratumDiffOne = new(big.Int).Lsh(big.NewInt(1), 32)
+
+	stratumSharesAcceptedMeter = metrics.NewRegisteredMeter("ethash/stratum/shares/accepted", nil)
+	stratumSharesRejectedMeter = metrics.NewRegisteredMeter("ethash/stratum/shares/rejected", nil)
+	stratumBlocksMeter         = metrics.NewRegisteredMeter("ethash/stratum/blocks", nil)
+)
+
+var (
+	errStratumUnauthorized = errors.New("unauthorized worker")
+	errStratumInvalidLogin = errors.New("invalid login, expected <address>[.<worker>]")
+	errStratumInvalidParam = errors.New("invalid parameters")
+	errStratumUnknownCall  = errors.New("method not supported")
+	errStratumStaleShare   = errors.New("stale or unknown job")
+	errStratumDuplicate    = errors.New("duplicate share")
+	errStratumLowDiffShare = errors.New("low difficulty share")
+	errStratumJobFull      = errors.New("too many shares for job")
+	errStratumRateLimited  = errors.New("too many submissions")
+)
+
+// Share is a proof-of-work share accepted from a stratum worker.
+type Share struct {
+	Login      common.Address // Address the worker is mining for
+	Worker     string         // Name of the rig within the login
+	Number     uint64         // Number of the block the work package was sealing
+	SealHash   common.Hash    // Seal hash of the work package
+	Difficulty *big.Int       // Share difficulty the submission was validated against
+	Block      bool           // Whether the share was accepted as a block solution
+}
+
+// SubscribeShares registers a subscription for shares accepted by the stratum
+// server, allowing pool accounting to be layered on top of the sealer.
+func (ethash *Ethash) SubscribeShares(ch chan<- Share) event.Subscription {
+	return ethash.shareFeed.Subscribe(ch)
+}
+
+// stratumDialect is the wire protocol spoken by a connected worker.
+type stratumDialect int
+
+const (
+	dialectUnknown  stratumDialect = iota
+	dialectEthProxy                // Ethereum JSON-RPC over TCP (eth_submitLogin, eth_getWork, ...)
+	dialectStratum                 // EthereumStratum/1.0.0 (mining.subscribe, mining.notify, ...)
+)
+
+// stratumJob is a mining work package handed out to stratum workers.
+type stratumJob struct {
+	sealhash   common.Hash
+	seedhash   common.Hash
+	number     uint64
+	difficulty *big.Int // Difficulty of the block being sealed
+	shareDiff  *big.Int // Difficulty of shares accepted for this job
+
+	shares  map[uint64]struct{}     // Nonces already submitted for this job
+	workers map[*stratumSession]int // Number of shares submitted by each worker
+}
+
+// id returns the job identifier used in stratum mode.
+func (job *stratumJob) id() string {
+	return hex.EncodeToString(job.sealhash[:])
+}
+
+// stratumRequest is a message received from a stratum worker.
+type stratumRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	Worker string            `json:"worker"`
+}
+
+// stratumResponse is a reply sent to a stratum worker.
+type stratumResponse struct {
+	ID      json.RawMessage `json:"id"`
+	Version string          `json:"jsonrpc,omitempty"`
+	Result  interface{}     `json:"result"`
+	Error   interface{}     `json:"error"`
+}
+
+// stratumNotification is an unsolicited message pushed to EthereumStratum workers.
+type stratumNotification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumPush is an unsolicited work package pushed to eth-proxy workers.
+type stratumPush struct {
+	ID      int       `json:"id"`
+	Version string    `json:"jsonrpc"`
+	Result  [4]string `json:"result"`
+}
+
+// stratumServer is a TCP server feeding remote mining rigs with work through the
+// stratum protocols, validating their shares against a lower share difficulty
+// and forwarding full solutions to the remote sealer.
+type stratumServer struct {
+	ethash    *Ethash
+	api       *API
+	listener  net.Listener
+	shareDiff *big.Int // Configured share difficulty, nil if shares must solve blocks
+
+	current  *stratumJob
+	jobs     map[common.Hash]*stratumJob
+	sessions map[*stratumSession]struct{}
+	nonces   uint64 // Counter to hand out extranonces to sessions
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	lock sync.Mutex
+}
+
+// startStratum opens the stratum listener on the given address and starts
+// accepting workers.
+func (ethash *Ethash) startStratum(addr string, shareDiff uint64) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &stratumServer{
+		ethash:   ethash,
+		api:      &API{ethash},
+		listener: listener,
+		jobs:     make(map[common.Hash]*stratumJob),
+		sessions: make(map[*stratumSession]struct{}),
+		quit:     make(chan struct{}),
+	}
+	if shareDiff > 0 {
+		server.shareDiff = new(big.Int).SetUint64(shareDiff)
+	}
+	ethash.lock.Lock()
+	ethash.stratum = server
+	ethash.lock.Unlock()
+
+	server.wg.Add(1)
+	go server.loop()
+
+	log.Info("Stratum server started", "addr", listener.Addr(), "sharediff", shareDiff)
+	return nil
+}
+
+// loop accepts incoming worker connections until the server is closed.
+func (s *stratumServer) loop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			log.Warn("Stratum accept failed", "err", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		session := s.newSession(conn)
+
+		s.wg.Add(2)
+		go session.readLoop()
+		go session.writeLoop()
+	}
+}
+
+// close terminates the listener and disconnects all workers.
+func (s *stratumServer) close() {
+	close(s.quit)
+	s.listener.Close()
+
+	s.lock.Lock()
+	for session := range s.sessions {
+		session.conn.Close()
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+// setWork installs a new work package, dropping jobs for blocks which became
+// too old to be accepted and pushing the job to all authorized workers.
+func (s *stratumServer) setWork(block *types.Block) {
+	job := &stratumJob{
+		sealhash:   s.ethash.SealHash(block.Header()),
+		seedhash:   common.BytesToHash(SeedHash(block.NumberU64())),
+		number:     block.NumberU64(),
+		difficulty: new(big.Int).Set(block.Difficulty()),
+		shareDiff:  block.Difficulty(),
+		shares:     make(map[uint64]struct{}),
+		workers:    make(map[*stratumSession]int),
+	}
+	if s.shareDiff != nil && s.shareDiff.Cmp(job.difficulty) < 0 {
+		job.shareDiff = s.shareDiff
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for hash, old := range s.jobs {
+		if old.number+staleThreshold <= job.number {
+			delete(s.jobs, hash)
+		}
+	}
+	s.jobs[job.sealhash] = job
+	s.current = job
+
+	for session := range s.sessions {
+		if session.isAuthorized() {
+			session.queueJob(job)
+		}
+	}
+}
+
+// currentJob returns the latest work package, if any.
+func (s *stratumServer) currentJob() *stratumJob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.current
+}
+
+// submit validates a share submitted by a worker against its job's share target,
+// forwarding it to the remote sealer if it also satisfies the block difficulty.
+func (s *stratumServer) submit(session *stratumSession, sealhash common.Hash, nonce uint64) error {
+	s.lock.Lock()
+	job := s.jobs[sealhash]
+	if job == nil {
+		s.lock.Unlock()
+		return errStratumStaleShare
+	}
+	if _, ok := job.shares[nonce]; ok {
+		s.lock.Unlock()
+		return errStratumDuplicate
+	}
+	if len(job.shares) >= stratumMaxJobShares || job.workers[session] >= stratumMaxSessionShares {
+		s.lock.Unlock()
+		return errStratumJobFull
+	}
+	job.shares[nonce] = struct{}{}
+	job.workers[session]++
+	s.lock.Unlock()
+
+	digest, result := s.ethash.hashimotoLight(job.number, sealhash.Bytes(), nonce)
+	value := new(big.Int).SetBytes(result)
+	if value.Cmp(new(big.Int).Div(two256, job.shareDiff)) > 0 {
+		return errStratumLowDiffShare
+	}
+	log.Trace("Stratum share accepted", "worker", session.name(), "number", job.number, "sealhash", sealhash, "nonce", nonce)
+
+	share := Share{
+		Number:     job.number,
+		SealHash:   sealhash,
+		Difficulty: new(big.Int).Set(job.shareDiff),
+	}
+	session.lock.Lock()
+	share.Login, share.Worker = common.HexToAddress(session.login), session.worker
+	session.lock.Unlock()
+
+	if value.Cmp(new(big.Int).Div(two256, job.difficulty)) <= 0 {
+		if s.api.SubmitWork(types.EncodeNonce(nonce), sealhash, common.BytesToHash(digest)) {
+			log.Info("Stratum worker found block", "worker", session.name(), "number", job.number, "sealhash", sealhash)
+			stratumBlocksMeter.Mark(1)
+			share.Block = true
+		} else {
+			log.Warn("Stratum block solution rejected", "worker", session.name(), "number", job.number, "sealhash", sealhash)
+		}
+	}
+	s.ethash.shareFeed.Send(share)
+	return nil
+}
+
+// stratumSession is a single connected mining rig.
+type stratumSession struct {
+	server *stratumServer
+	conn   net.Conn
+	jobCh  chan *stratumJob // Pending job to push to the worker, replaced by newer ones
+
+	dialect    stratumDialect
+	login      string // Address the worker is mining for
+	worker     string // Name of the rig within the login
+	extranonce string // Hex encoded nonce prefix assigned to the session in stratum mode
+	authorized bool
+	lastDiff   *big.Int // Share difficulty last announced in stratum mode
+
+	submitStart time.Time // Start of the current rate limiting window
+	submits     int       // Number of submissions within the current window
+
+	lock      sync.Mutex // Protects the session metadata above
+	writeLock sync.Mutex // Serialises writes to the connection
+}
+
+// newSession registers a freshly accepted worker connection.
+func (s *stratumServer) newSession(conn net.Conn) *stratumSession {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session := &stratumSession{
+		server:     s,
+		conn:       conn,
+		jobCh:      make(chan *stratumJob, 1),
+		extranonce: fmt.Sprintf("%0*x", stratumExtranonce*2, s.nonces%(1<<(8*stratumExtranonce))),
+	}
+	s.nonces++
+	s.sessions[session] = struct{}{}
+	return session
+}
+
+// allowSubmit reports whether the worker may submit another share, counting it
+// against the rate limit of the current window.
+func (sess *stratumSession) allowSubmit(now time.Time) bool {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if now.Sub(sess.submitStart) >= stratumSubmitWindow {
+		sess.submitStart, sess.submits = now, 0
+	}
+	if sess.submits >= stratumSubmitLimit {
+		return false
+	}
+	sess.submits++
+	return true
+}
+
+// name returns a printable identifier of the worker.
+func (sess *stratumSession) name() string {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	if sess.worker == "" {
+		return sess.login
+	}
+	return sess.login + "." + sess.worker
+}
+
+// isAuthorized reports whether the worker has logged in.
+func (sess *stratumSession) isAuthorized() bool {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	return sess.authorized
+}
+
+// queueJob schedules a job for pushing, replacing any job not yet sent.
+func (sess *stratumSession) queueJob(job *stratumJob) {
+	select {
+	case <-sess.jobCh:
+	default:
+	}
+	select {
+	case sess.jobCh <- job:
+	default:
+	}
+}
+
+// readLoop processes requests from the worker until the connection drops.
+func (sess *stratumSession) readLoop() {
+	defer sess.server.wg.Done()
+	defer func() {
+		sess.server.lock.Lock()
+		delete(sess.server.sessions, sess)
+		sess.server.lock.Unlock()
+
+		sess.conn.Close()
+		close(sess.jobCh)
+	}()
+	reader := bufio.NewReaderSize(sess.conn, stratumMaxLineSize)
+	for {
+		sess.conn.SetReadDeadline(time.Now().Add(stratumReadTimeout))
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			log.Debug("Stratum worker disconnected", "worker", sess.name(), "addr", sess.conn.RemoteAddr(), "err", err)
+			return
+		}
+		if isPrefix {
+			log.Debug("Stratum message too large", "addr", sess.conn.RemoteAddr())
+			return
+		}
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var req stratumRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			log.Debug("Malformed stratum message", "addr", sess.conn.RemoteAddr(), "err", err)
+			return
+		}
+		if err := sess.handle(&req); err != nil {
+			log.Debug("Failed to reply to stratum worker", "worker", sess.name(), "err", err)
+			return
+		}
+	}
+}
+
+// writeLoop pushes new jobs to the worker as they become available.
+func (sess *stratumSession) writeLoop() {
+	defer sess.server.wg.Done()
+
+	for job := range sess.jobCh {
+		if err := sess.sendJob(job); err != nil {
+			log.Debug("Failed to push stratum job", "worker", sess.name(), "err", err)
+			sess.conn.Close()
+		}
+	}
+}
+
+// handle dispatches a single worker request, replying with its outcome.
+func (sess *stratumSession) handle(req *stratumRequest) error {
+	var (
+		result interface{}
+		err    error
+	)
+	switch req.Method {
+	case "eth_submitLogin":
+		result, err = sess.handleLogin(dialectEthProxy, req)
+	case "mining.subscribe":
+		result, err = sess.handleSubscribe(req)
+	case "mining.authorize":
+		result, err = sess.handleLogin(dialectStratum, req)
+	case "mining.extranonce.subscribe":
+		result = true
+	case "eth_getWork":
+		result, err = sess.handleGetWork()
+	case "eth_submitWork", "mining.submit":
+		result, err = sess.handleSubmit(req)
+	case "eth_submitHashrate":
+		result, err = sess.handleHashrate(req)
+	default:
+		err = errStratumUnknownCall
+	}
+	if req.Method == "eth_submitWork" || req.Method == "mining.submit" {
+		if err == nil {
+			stratumSharesAcceptedMeter.Mark(1)
+		} else {
+			stratumSharesRejectedMeter.Mark(1)
+			log.Debug("Stratum share rejected", "worker", sess.name(), "err", err)
+		}
+	}
+	if err := sess.reply(req.ID, result, err); err != nil {
+		return err
+	}
+	// Push the current job to freshly authorized workers
+	if req.Method == "eth_submitLogin" || req.Method == "mining.authorize" {
+		if job := sess.server.currentJob(); job != nil && sess.isAuthorized() {
+			sess.queueJob(job)
+		}
+	}
+	return nil
+}
+
+// handleSubscribe opens an EthereumStratum session, assigning its extranonce.
+func (sess *stratumSession) handleSubscribe(req *stratumRequest) (interface{}, error) {
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	sess.dialect = dialectStratum
+	return []interface{}{
+		[]string{"mining.notify", sess.extranonce, "EthereumStratum/1.0.0"},
+		sess.extranonce,
+	}, nil
+}
+
+// handleLogin authorizes the worker to mine for an address, given in the form
+// <address>[.<worker>].
+func (sess *stratumSession) handleLogin(dialect stratumDialect, req *stratumRequest) (interface{}, error) {
+	var login string
+	if len(req.Params) == 0 || json.Unmarshal(req.Params[0], &login) != nil {
+		return false, errStratumInvalidParam
+	}
+	worker := req.Worker
+	if idx := strings.Index(login, "."); idx >= 0 {
+		login, worker = login[:idx], login[idx+1:]
+	}
+	if !common.IsHexAddress(login) {
+		return false, errStratumInvalidLogin
+	}
+	sess.lock.Lock()
+	defer sess.lock.Unlock()
+
+	sess.dialect = dialect
+	sess.login = common.HexToAddress(login).Hex()
+	sess.worker = worker
+	sess.authorized = true
+
+	log.Debug("Stratum worker authorized", "login", sess.login, "worker", sess.worker, "addr", sess.conn.RemoteAddr())
+	return true, nil
+}
+
+// handleGetWork returns the current work package to eth-proxy workers.
+func (sess *stratumSession) handleGetWork() (interface{}, error) {
+	if !sess.isAuthorized() {
+		return nil, errStratumUnauthorized
+	}
+	job := sess.server.currentJob()
+	if job == nil {
+		return nil, errNoMiningWork
+	}
+	work := proxyWork(job)
+	return work[:3], nil
+}
+
+// handleSubmit decodes a share in either dialect and validates it.
+func (sess *stratumSession) handleSubmit(req *stratumRequest) (interface{}, error) {
+	if !sess.isAuthorized() {
+		return false, errStratumUnauthorized
+	}
+	if !sess.allowSubmit(time.Now()) {
+		return false, errStratumRateLimited
+	}
+	var params []string
+	for _, raw := range req.Params {
+		var param string
+		if err := json.Unmarshal(raw, &param); err != nil {
+			return false, errStratumInvalidParam
+		}
+		params = append(params, param)
+	}
+	var (
+		sealhash common.Hash
+		nonce    string
+	)
+	switch {
+	case req.Method == "eth_submitWork" && len(params) == 3:
+		// [nonce, sealhash, mixdigest]
+		nonce, sealhash = params[0], common.HexToHash(params[1])
+
+	case req.Method == "mining.submit" && len(params) >= 3:
+		// [login, job id, nonce (, sealhash, mixdigest)]
+		sealhash, nonce = common.HexToHash(params[1]), params[2]
+
+	default:
+		return false, errStratumInvalidParam
+	}
+	if !strings.HasPrefix(nonce, "0x") && len(nonce) < 16 {
+		sess.lock.Lock()
+		nonce = sess.extranonce + nonce
+		sess.lock.Unlock()
+	}
+	value, err := strconv.ParseUint(strings.TrimPrefix(nonce, "0x"), 16, 64)
+	if err != nil || len(strings.TrimPrefix(nonce, "0x")) != 16 {
+		return false, errStratumInvalidParam
+	}
+	if err := sess.server.submit(sess, sealhash, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// handleHashrate forwards a worker's self reported hashrate to the remote sealer.
+func (sess *stratumSession) handleHashrate(req *stratumRequest) (interface{}, error) {
+	if !sess.isAuthorized() {
+		return false, errStratumUnauthorized
+	}
+	var (
+		rate hexutil.Uint64
+		id   common.Hash
+	)
+	if len(req.Params) != 2 || json.Unmarshal(req.Params[0], &rate) != nil || json.Unmarshal(req.Params[1], &id) != nil {
+		return false, errStratumInvalidParam
+	}
+	return sess.server.api.SubmitHashRate(rate, id), nil
+}
+
+// reply sends the result of a request back to the worker in its dialect.
+func (sess *stratumSession) reply(id json.RawMessage, result interface{}, err error) error {
+	sess.lock.Lock()
+	dialect := sess.dialect
+	sess.lock.Unlock()
+
+	res := &stratumResponse{ID: id, Result: result}
+	if dialect != dialectStratum {
+		res.Version = "2.0"
+	}
+	if err != nil {
+		if dialect == dialectStratum {
+			res.Error = []interface{}{-1, err.Error(), nil}
+		} else {
+			res.Error = map[string]interface{}{"code": -1, "message": err.Error()}
+		}
+	}
+	return sess.write(res)
+}
+
+// sendJob pushes a work package to the worker in its dialect.
+func (sess *stratumSession) sendJob(job *stratumJob) error {
+	sess.lock.Lock()
+	dialect := sess.dialect
+	announceDiff := sess.lastDiff == nil || sess.lastDiff.Cmp(job.shareDiff) != 0
+	if dialect == dialectStratum {
+		sess.lastDiff = job.shareDiff
+	}
+	sess.lock.Unlock()
+
+	if dialect != dialectStratum {
+		return sess.write(&stratumPush{Version: "2.0", Result: proxyWork(job)})
+	}
+	if announceDiff {
+		diff, _ := new(big.Float).Quo(new(big.Float).SetInt(job.shareDiff), new(big.Float).SetInt(stratumDiffOne)).Float64()
+		if err := sess.write(&stratumNotification{Method: "mining.set_difficulty", Params: []interface{}{diff}}); err != nil {
+			return err
+		}
+	}
+	return sess.write(&stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{job.id(), hex.EncodeToString(job.seedhash[:]), hex.EncodeToString(job.sealhash[:]), true},
+	})
+}
+
+// write serialises a single message onto the connection.
+func (sess *stratumSession) write(msg interface{}) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sess.writeLock.Lock()
+	defer sess.writeLock.Unlock()
+
+	sess.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	_, err = sess.conn.Write(append(blob, '\n'))
+	return err
+}
+
+// proxyWork assembles the eth-proxy work package of a job, using the share
+// target instead of the block target.
+func proxyWork(job *stratumJob) [4]string {
+	return [4]string{
+		job.sealhash.Hex(),
+		job.seedhash.Hex(),
+		common.BytesToHash(new(big.Int).Div(two256, job.shareDiff).Bytes()).Hex(),
+		hexutil.EncodeUint64(job.number),
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+)
+
+// stratumTestClient is a minimal line based JSON client talking to a stratum server.
+type stratumTestClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newStratumTestClient(t *testing.T, ethash *Ethash) *stratumTestClient {
+	conn, err := net.Dial("tcp", ethash.stratum.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial stratum server: %v", err)
+	}
+	return &stratumTestClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *stratumTestClient) send(id int, method string, params ...interface{}) {
+	blob, _ := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+	if _, err := c.conn.Write(append(blob, '\n')); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+}
+
+func (c *stratumTestClient) read() map[string]interface{} {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("failed to read stratum message: %v", err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.t.Fatalf("failed to decode stratum message %s: %v", line, err)
+	}
+	return msg
+}
+
+// findNonce searches for the first nonce with the given prefix whose PoW value
+// satisfies (or, if miss is set, fails) the block target but meets the share one.
+func findNonce(ethash *Ethash, number uint64, sealhash common.Hash, prefix uint64, share, block *big.Int, miss bool) uint64 {
+	shareTarget, blockTarget := new(big.Int).Div(two256, share), new(big.Int).Div(two256, block)
+	for nonce := prefix; ; nonce++ {
+		_, result := ethash.hashimotoLight(number, sealhash.Bytes(), nonce)
+		value := new(big.Int).SetBytes(result)
+		if value.Cmp(shareTarget) > 0 {
+			continue
+		}
+		if (value.Cmp(blockTarget) <= 0) != miss {
+			return nonce
+		}
+	}
+}
+
+// Tests that eth-proxy workers receive work with the share target, and that
+// shares are validated and full solutions forwarded to the sealer.
+func TestStratumEthProxy(t *testing.T) {
+	ethash := NewTester(nil, false)
+	ethash.SetThreads(-1) // Disable local mining
+	defer ethash.Close()
+
+	if err := ethash.startStratum("127.0.0.1:0", 4); err != nil {
+		t.Fatalf("failed to start stratum server: %v", err)
+	}
+	client := newStratumTestClient(t, ethash)
+	defer client.conn.Close()
+
+	client.send(1, "eth_submitLogin", "0x0000000000000000000000000000000000000001.rig")
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("login failed: %v", res)
+	}
+	// Push a new work package and wait for the notification
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(64)}
+	block := types.NewBlockWithHeader(header)
+	results := make(chan *types.Block, 1)
+	ethash.Seal(nil, block, results, nil)
+
+	work := client.read()["result"].([]interface{})
+	sealhash := ethash.SealHash(header)
+	if work[0] != sealhash.Hex() {
+		t.Fatalf("work hash mismatch: have %v, want %v", work[0], sealhash.Hex())
+	}
+	if want := common.BytesToHash(new(big.Int).Div(two256, big.NewInt(4)).Bytes()).Hex(); work[2] != want {
+		t.Fatalf("share target mismatch: have %v, want %v", work[2], want)
+	}
+	// Submit a share which doesn't solve the block, it should be accepted only
+	share := findNonce(ethash, 1, sealhash, 0, big.NewInt(4), header.Difficulty, true)
+	client.send(2, "eth_submitWork", fmt.Sprintf("0x%016x", share), sealhash.Hex(), common.Hash{}.Hex())
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("valid share rejected: %v", res)
+	}
+	client.send(3, "eth_submitWork", fmt.Sprintf("0x%016x", share), sealhash.Hex(), common.Hash{}.Hex())
+	if res := client.read(); res["result"] != false {
+		t.Fatalf("duplicate share accepted: %v", res)
+	}
+	select {
+	case <-results:
+		t.Fatalf("share sealed a block")
+	default:
+	}
+	// Submit a full solution and ensure it reaches the sealer
+	solution := findNonce(ethash, 1, sealhash, 0, big.NewInt(4), header.Difficulty, false)
+	client.send(4, "eth_submitWork", fmt.Sprintf("0x%016x", solution), sealhash.Hex(), common.Hash{}.Hex())
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("block solution rejected: %v", res)
+	}
+	select {
+	case sealed := <-results:
+		if sealed.Nonce() != solution {
+			t.Fatalf("sealed nonce mismatch: have %d, want %d", sealed.Nonce(), solution)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("block solution not forwarded")
+	}
+}
+
+// Tests that EthereumStratum workers get an extranonce, difficulty and job
+// notifications, and that their partial nonces are extended correctly.
+func TestStratumNiceHash(t *testing.T) {
+	ethash := NewTester(nil, false)
+	ethash.SetThreads(-1) // Disable local mining
+	defer ethash.Close()
+
+	if err := ethash.startStratum("127.0.0.1:0", 2); err != nil {
+		t.Fatalf("failed to start stratum server: %v", err)
+	}
+	client := newStratumTestClient(t, ethash)
+	defer client.conn.Close()
+
+	client.send(1, "mining.subscribe", "test/1.0", "EthereumStratum/1.0.0")
+	res := client.read()
+	extranonce := res["result"].([]interface{})[1].(string)
+	if len(extranonce) != 2*stratumExtranonce {
+		t.Fatalf("extranonce length mismatch: have %q", extranonce)
+	}
+	client.send(2, "mining.authorize", "0x0000000000000000000000000000000000000001.rig", "x")
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("authorize failed: %v", res)
+	}
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(16)}
+	ethash.Seal(nil, types.NewBlockWithHeader(header), make(chan *types.Block, 1), nil)
+
+	if msg := client.read(); msg["method"] != "mining.set_difficulty" {
+		t.Fatalf("expected difficulty announcement, got %v", msg)
+	}
+	notify := client.read()
+	if notify["method"] != "mining.notify" {
+		t.Fatalf("expected job notification, got %v", notify)
+	}
+	jobID := notify["params"].([]interface{})[0].(string)
+
+	var prefix uint64
+	fmt.Sscanf(extranonce, "%x", &prefix)
+	prefix <<= 64 - 8*stratumExtranonce
+
+	sealhash := ethash.SealHash(header)
+	share := findNonce(ethash, 1, sealhash, prefix, big.NewInt(2), header.Difficulty, true)
+	client.send(3, "mining.submit", "0x0000000000000000000000000000000000000001.rig", jobID, fmt.Sprintf("%016x", share)[2*stratumExtranonce:])
+	if res := client.read(); res["result"] != true {
+		t.Fatalf("valid share rejected: %v", res)
+	}
+	client.send(4, "mining.submit", "0x0000000000000000000000000000000000000001.rig", "00", "000000000000")
+	if res := client.read(); res["result"] != false || res["error"] == nil {
+		t.Fatalf("stale share accepted: %v", res)
+	}
+}
+
+// Tests that the shares tracked for a job are capped both per worker and in
+// total, so flooding workers can't grow the duplicate sets without bounds.
+func TestStratumShareLimits(t *testing.T) {
+	ethash := NewTester(nil, false)
+	ethash.SetThreads(-1) // Disable local mining
+	defer ethash.Close()
+
+	if err := ethash.startStratum("127.0.0.1:0", 0); err != nil {
+		t.Fatalf("failed to start stratum server: %v", err)
+	}
+	server := ethash.stratum
+
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(64)}
+	server.setWork(types.NewBlockWithHeader(header))
+	sealhash := ethash.SealHash(header)
+
+	conn1, peer1 := net.Pipe()
+	defer conn1.Close()
+	defer peer1.Close()
+	conn2, peer2 := net.Pipe()
+	defer conn2.Close()
+	defer peer2.Close()
+
+	first, second := server.newSession(conn1), server.newSession(conn2)
+
+	// Fill up the per worker allowance and ensure further shares are refused
+	for nonce := uint64(0); nonce < stratumMaxSessionShares; nonce++ {
+		if err := server.submit(first, sealhash, nonce); err == errStratumJobFull {
+			t.Fatalf("share %d: rejected below the worker limit", nonce)
+		}
+	}
+	if err := server.submit(first, sealhash, stratumMaxSessionShares); err != errStratumJobFull {
+		t.Fatalf("share above worker limit: have %v, want %v", err, errStratumJobFull)
+	}
+	if err := server.submit(second, sealhash, stratumMaxSessionShares); err == errStratumJobFull {
+		t.Fatalf("other worker limited by first worker's shares")
+	}
+	// Fill up the job and ensure no worker can add more shares
+	server.lock.Lock()
+	job := server.jobs[sealhash]
+	for nonce := uint64(1 << 32); len(job.shares) < stratumMaxJobShares; nonce++ {
+		job.shares[nonce] = struct{}{}
+	}
+	server.lock.Unlock()
+
+	if err := server.submit(second, sealhash, stratumMaxSessionShares+1); err != errStratumJobFull {
+		t.Fatalf("share above job limit: have %v, want %v", err, errStratumJobFull)
+	}
+}
+
+// Tests that workers submitting too fast are throttled until the next window.
+func TestStratumSubmitRateLimit(t *testing.T) {
+	session := new(stratumSession)
+
+	start := time.Now()
+	for i := 0; i < stratumSubmitLimit; i++ {
+		if !session.allowSubmit(start) {
+			t.Fatalf("submission %d: rejected below the rate limit", i)
+		}
+	}
+	if session.allowSubmit(start.Add(stratumSubmitWindow / 2)) {
+		t.Fatalf("submission above the rate limit allowed")
+	}
+	if !session.allowSubmit(start.Add(stratumSubmitWindow)) {
+		t.Fatalf("submission rejected in a new window")
+	}
+}
+
+// Tests that a stratum server which can't listen reports the failure instead of
+// silently leaving remote miners without work.
+func TestStartStratumListenFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to open listener: %v", err)
+	}
+	defer listener.Close()
+
+	ethash := NewTester(nil, false)
+	defer ethash.Close()
+
+	ethash.config.StratumAddr = listener.Addr().String()
+	if err := ethash.StartStratum(); err == nil {
+		t.Fatalf("stratum server started on a busy address")
+	}
+}
@@ -44,6 +44,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
 	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
 	"github.com/hashicorp/golang-lru"
+	opentracing "github.com/opentracing/opentracing-go"
 )
 
 var (
@@ -115,6 +116,7 @@ type BlockChain struct {
 	checkpoint       int          // checkpoint counts towards the new checkpoint
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
+	historyTail      uint64       // First block with body and receipts after a checkpoint sync (atomic)
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
@@ -134,8 +136,11 @@ type BlockChain struct {
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
-	badBlocks      *lru.Cache              // Bad block cache
-	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
+	badBlocks      *lru.Cache               // Bad block cache
+	whitelist      map[uint64]common.Hash   // Block hashes required in the canonical chain, keyed by number
+	blacklist      map[common.Hash]struct{} // Block hashes never to be imported, along with their descendants
+	listLock       sync.RWMutex             // Lock protecting the whitelist and blacklist
+	shouldPreserve func(*types.Block) bool  // Function used to determine whether should preserve the given block.
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -201,6 +206,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Make sure the chain agrees with the locally configured block lists
+	bc.loadBlockLists()
+
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -260,6 +268,9 @@ func (bc *BlockChain) loadLastState() error {
 		}
 	}
 
+	// Restore the start of the available history
+	atomic.StoreUint64(&bc.historyTail, rawdb.ReadHistoryTail(bc.db))
+
 	// Issue a status log for the user
 	currentFastBlock := bc.CurrentFastBlock()
 
@@ -348,6 +359,84 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	return nil
 }
 
+// InsertCheckpoint initializes an empty chain from a trusted checkpoint block,
+// whose state must already be present in the database. The headers are the
+// ancestors of the checkpoint in descending order and td is the total difficulty
+// of the checkpoint. The bodies belong to the most recent ancestors and must
+// cover the ones referenced by uncle validation. Other bodies and all receipts
+// of the blocks before the checkpoint are not available afterwards.
+func (bc *BlockChain) InsertCheckpoint(block *types.Block, receipts types.Receipts, headers []*types.Header, bodies []*types.Body, td *big.Int) error {
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if head := bc.CurrentBlock().NumberU64(); head != 0 {
+		return fmt.Errorf("chain not empty, head is #%d", head)
+	}
+	// Make sure the checkpoint can be built upon
+	if _, err := trie.NewSecure(block.Root(), bc.stateCache.TrieDB(), 0); err != nil {
+		return err
+	}
+	if len(bodies) > len(headers) {
+		return fmt.Errorf("more checkpoint ancestor bodies (%d) than headers (%d)", len(bodies), len(headers))
+	}
+	if err := SetReceiptsData(bc.chainConfig, block, receipts); err != nil {
+		return fmt.Errorf("failed to set receipts data: %v", err)
+	}
+	// Write the checkpoint and the headers of its ancestors as the canonical chain
+	batch := bc.db.NewBatch()
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteTd(batch, block.Hash(), block.NumberU64(), td)
+	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
+	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	rawdb.WriteTxLookupEntries(batch, block)
+
+	child, childTd := block.Header(), td
+	for i, header := range headers {
+		if header.Hash() != child.ParentHash || header.Number.Uint64()+1 != child.Number.Uint64() {
+			return fmt.Errorf("non contiguous checkpoint ancestor #%d [%x…]", header.Number, header.Hash().Bytes()[:4])
+		}
+		parentTd := new(big.Int).Sub(childTd, child.Difficulty)
+		if parentTd.Sign() <= 0 {
+			return fmt.Errorf("invalid checkpoint total difficulty %v", td)
+		}
+		rawdb.WriteHeader(batch, header)
+		rawdb.WriteTd(batch, header.Hash(), header.Number.Uint64(), parentTd)
+		rawdb.WriteCanonicalHash(batch, header.Hash(), header.Number.Uint64())
+		if i < len(bodies) {
+			if types.DeriveSha(types.Transactions(bodies[i].Transactions)) != header.TxHash || types.CalcUncleHash(bodies[i].Uncles) != header.UncleHash {
+				return fmt.Errorf("invalid body for checkpoint ancestor #%d [%x…]", header.Number, header.Hash().Bytes()[:4])
+			}
+			rawdb.WriteBody(batch, header.Hash(), header.Number.Uint64(), bodies[i])
+		}
+		child, childTd = header, parentTd
+	}
+	rawdb.WriteHistoryTail(batch, block.NumberU64())
+	rawdb.WriteHeadBlockHash(batch, block.Hash())
+	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Everything is persisted, switch over to the checkpoint
+	bc.mu.Lock()
+	bc.hc.SetCurrentHeader(block.Header())
+	bc.currentBlock.Store(block)
+	bc.currentFastBlock.Store(block)
+	atomic.StoreUint64(&bc.historyTail, block.NumberU64())
+	bc.mu.Unlock()
+
+	log.Info("Initialized chain from checkpoint", "number", block.Number(), "hash", block.Hash(), "td", td, "ancestors", len(headers))
+	return nil
+}
+
+// HistoryTail returns the number of the first block whose body and receipts are
+// available. It is non-zero only if the chain was synced from a checkpoint.
+func (bc *BlockChain) HistoryTail() uint64 {
+	return atomic.LoadUint64(&bc.historyTail)
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (bc *BlockChain) GasLimit() uint64 {
 	return bc.CurrentBlock().GasLimit()
@@ -708,7 +797,7 @@ func (bc *BlockChain) Stop() {
 		triedb := bc.stateCache.TrieDB()
 
 		for _, offset := range []uint64{0, 1, triesInMemory - 1} {
-			if number := bc.CurrentBlock().NumberU64(); number > offset {
+			if number := bc.CurrentBlock().NumberU64(); number > offset && number-offset >= bc.HistoryTail() {
 				recent := bc.GetBlockByNumber(number - offset)
 
 				log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
@@ -1115,6 +1204,12 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
 	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
 
+	// Trace the import, the stages of each block being recorded as child spans
+	span := opentracing.StartSpan("core.insertChain")
+	span.SetTag("blocks", len(chain))
+	span.SetTag("first", chain[0].NumberU64())
+	defer span.Finish()
+
 	// A queued approach to delivering events. This is generally
 	// faster than direct delivery and requires much less mutex
 	// acquiring.
@@ -1132,10 +1227,18 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		headers[i] = block.Header()
 		seals[i] = verifySeals
 	}
+	verifySpan := opentracing.StartSpan("core.verifyHeaders", opentracing.ChildOf(span.Context()))
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
+	abort, results = traceHeaderVerification(verifySpan, abort, results, len(headers))
 	defer close(abort)
 	// pirlguard
+	guardSpan := opentracing.StartSpan("core.pirlGuard", opentracing.ChildOf(span.Context()))
 	errChain := bc.checkChainForAttack(chain)
+	if errChain != nil {
+		guardSpan.SetTag("error", true)
+		guardSpan.LogKV("err", errChain)
+	}
+	guardSpan.Finish()
 
 	// Peek the error for the first block to decide the directing import logic
 	it := newInsertIterator(chain, results, bc.Validator())
@@ -1197,6 +1300,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 			bc.reportBlock(block, nil, ErrBlacklistedHash)
 			return it.index, events, coalescedLogs, ErrBlacklistedHash
 		}
+		if err := bc.checkBlockLists(block.Header()); err != nil {
+			bc.reportBlock(block, nil, err)
+			return it.index, events, coalescedLogs, err
+		}
 		// Retrieve the parent block and it's state to execute on top
 		start := time.Now()
 
@@ -1210,14 +1317,19 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		}
 		// Process block using the parent state as reference point.
 		t0 := time.Now()
+		stageSpan := startBlockSpan(span, "core.processBlock", block)
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		finishBlockSpan(stageSpan, err)
 		t1 := time.Now()
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return it.index, events, coalescedLogs, err
 		}
 		// Validate the state using the default validator
-		if err := bc.Validator().ValidateState(block, parent, state, receipts, usedGas); err != nil {
+		stageSpan = startBlockSpan(span, "core.validateState", block)
+		err = bc.Validator().ValidateState(block, parent, state, receipts, usedGas)
+		finishBlockSpan(stageSpan, err)
+		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return it.index, events, coalescedLogs, err
 		}
@@ -1225,7 +1337,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		proctime := time.Since(start)
 
 		// Write the block to the chain and get the status.
+		stageSpan = startBlockSpan(span, "core.writeBlock", block)
 		status, err := bc.WriteBlockWithState(block, receipts, state)
+		finishBlockSpan(stageSpan, err)
 		t3 := time.Now()
 		if err != nil {
 			return it.index, events, coalescedLogs, err
@@ -1285,6 +1399,59 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 	return it.index, events, coalescedLogs, err
 }
 
+// startBlockSpan starts a tracing span for a single stage of importing a block.
+func startBlockSpan(parent opentracing.Span, name string, block *types.Block) opentracing.Span {
+	span := opentracing.StartSpan(name, opentracing.ChildOf(parent.Context()))
+	span.SetTag("number", block.NumberU64())
+	span.SetTag("hash", block.Hash().Hex())
+	return span
+}
+
+// finishBlockSpan terminates a block import stage span, recording any failure.
+func finishBlockSpan(span opentracing.Span, err error) {
+	if err != nil {
+		span.SetTag("error", true)
+		span.LogKV("err", err)
+	}
+	span.Finish()
+}
+
+// traceHeaderVerification wraps an asynchronous header verification, so that its
+// span is finished once all n results arrived or the verification was aborted.
+// The results are forwarded into a buffered channel, never blocking the verifier
+// on a consumer that stopped reading.
+func traceHeaderVerification(span opentracing.Span, abort chan<- struct{}, results <-chan error, n int) (chan<- struct{}, <-chan error) {
+	var (
+		traceAbort   = make(chan struct{})
+		traceResults = make(chan error, n)
+	)
+	go func() {
+		defer close(abort)
+
+		failed := 0
+		for i := 0; i < n; i++ {
+			select {
+			case err := <-results:
+				if err != nil {
+					failed++
+				}
+				traceResults <- err
+			case <-traceAbort:
+				span.SetTag("aborted", true)
+				span.Finish()
+				return
+			}
+		}
+		if failed > 0 {
+			span.SetTag("error", true)
+			span.SetTag("failed", failed)
+		}
+		span.Finish()
+		<-traceAbort
+	}()
+	return traceAbort, traceResults
+}
+
 // insertSidechain is called when an import batch hits upon a pruned ancestor
 // error, which happens when a sidechain with a sufficiently old fork-block is
 // found.
@@ -1620,6 +1787,11 @@ Error: %v
 // because nonces can be verified sparsely, not needing to check each.
 func (bc *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
 	start := time.Now()
+	for i, header := range chain {
+		if err := bc.checkBlockLists(header); err != nil {
+			return i, err
+		}
+	}
 	if i, err := bc.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
 		return i, err
 	}
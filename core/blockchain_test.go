@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -34,6 +35,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	jaeger "github.com/uber/jaeger-client-go"
 )
 
 // So we can deterministically seed different blockchains
@@ -1744,3 +1746,257 @@ func TestLowDiffLongChain(t *testing.T) {
 		header = chain.GetHeader(header.ParentHash, number-1)
 	}
 }
+
+// Tests that an empty chain can be initialized from a checkpoint block and its
+// ancestor headers, and that it can be extended from there, including blocks
+// referencing uncles from before the checkpoint.
+func TestInsertCheckpoint(t *testing.T) {
+	var (
+		gendb   = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 200, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{byte(i)})
+	})
+	// Fork off a sibling of block #198, and include it as an uncle in the first
+	// block after the checkpoint
+	forks, _ := GenerateChain(gspec.Config, blocks[196], ethash.NewFaker(), gendb, 1, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0xff})
+	})
+	uncle := forks[0].Header()
+
+	rest, restReceipts := GenerateChain(gspec.Config, blocks[199], ethash.NewFaker(), gendb, 100, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{byte(i)})
+		if i == 0 {
+			block.AddUncle(uncle)
+		}
+	})
+	blocks, receipts = append(blocks, rest...), append(receipts, restReceipts...)
+	// Create an empty chain containing the state of the checkpoint only
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	for _, key := range gendb.Keys() {
+		if len(key) == common.HashLength {
+			value, _ := gendb.Get(key)
+			db.Put(key, value)
+		}
+	}
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	checkpoint := blocks[199]
+	td := new(big.Int).Set(genesis.Difficulty())
+	for _, block := range blocks[:200] {
+		td.Add(td, block.Difficulty())
+	}
+	var (
+		headers []*types.Header
+		bodies  []*types.Body
+	)
+	for i := 198; i >= 100; i-- {
+		headers = append(headers, blocks[i].Header())
+	}
+	for i := 198; i > 198-7; i-- {
+		bodies = append(bodies, blocks[i].Body())
+	}
+	if err := chain.InsertCheckpoint(checkpoint, receipts[199], headers[:1], bodies, td); err == nil {
+		t.Fatalf("checkpoint inserted with more bodies than headers")
+	}
+	if err := chain.InsertCheckpoint(checkpoint, receipts[199], headers, bodies, td); err != nil {
+		t.Fatalf("failed to insert checkpoint: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != checkpoint.Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), checkpoint.NumberU64())
+	}
+	if tail := chain.HistoryTail(); tail != checkpoint.NumberU64() {
+		t.Fatalf("history tail mismatch: have %d, want %d", tail, checkpoint.NumberU64())
+	}
+	if header := chain.GetHeaderByNumber(101); header == nil || header.Hash() != blocks[100].Hash() {
+		t.Fatalf("ancestor header missing")
+	}
+	if block := chain.GetBlockByNumber(101); block != nil {
+		t.Fatalf("ancestor body available")
+	}
+	if block := chain.GetBlockByNumber(193); block == nil || block.Hash() != blocks[192].Hash() {
+		t.Fatalf("ancestor body needed for uncle validation missing")
+	}
+	wantTd := new(big.Int).Sub(td, checkpoint.Difficulty())
+	if have := chain.GetTd(blocks[198].Hash(), 199); have == nil || have.Cmp(wantTd) != 0 {
+		t.Fatalf("ancestor td mismatch: have %v, want %v", have, wantTd)
+	}
+	if err := chain.InsertCheckpoint(checkpoint, receipts[199], headers, bodies, td); err == nil {
+		t.Fatalf("checkpoint inserted into non-empty chain")
+	}
+	// Continue importing blocks on top of the checkpoint
+	if _, err := chain.InsertChain(blocks[200:]); err != nil {
+		t.Fatalf("failed to extend checkpoint: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head mismatch after import: have #%d, want #%d", head.NumberU64(), len(blocks))
+	}
+}
+
+// Tests that blacklisted blocks and their descendants are rejected, rewinding the
+// chain if needed, and that the blacklist survives a restart.
+func TestBlockBlacklist(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *BlockGen) {})
+
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if err := chain.AddBlacklistedBlock(genesis.Hash()); err == nil {
+		t.Fatalf("genesis blacklisted")
+	}
+	if err := chain.AddBlacklistedBlock(blocks[5].Hash()); err != nil {
+		t.Fatalf("failed to blacklist block: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[4].Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), blocks[4].NumberU64())
+	}
+	if _, err := chain.InsertChain(blocks[5:]); err != ErrBlacklistedHash {
+		t.Fatalf("blacklisted block import error mismatch: have %v, want %v", err, ErrBlacklistedHash)
+	}
+	headers := make([]*types.Header, 0, 4)
+	for _, block := range blocks[6:] {
+		headers = append(headers, block.Header())
+	}
+	if _, err := chain.InsertHeaderChain(headers, 1); err != ErrBlacklistedHash {
+		t.Fatalf("descendant header import error mismatch: have %v, want %v", err, ErrBlacklistedHash)
+	}
+	chain.Stop()
+
+	// Restart the chain and ensure the blacklist is still enforced
+	chain, _ = NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if list := chain.Blacklist(); len(list) != 1 || list[0] != blocks[5].Hash() {
+		t.Fatalf("blacklist mismatch: have %x, want [%x]", list, blocks[5].Hash())
+	}
+	if _, err := chain.InsertChain(blocks[5:]); err != ErrBlacklistedHash {
+		t.Fatalf("blacklisted block import error mismatch: have %v, want %v", err, ErrBlacklistedHash)
+	}
+	if !chain.RemoveBlacklistedBlock(blocks[5].Hash()) {
+		t.Fatalf("failed to remove blacklisted block")
+	}
+	if _, err := chain.InsertChain(blocks[5:]); err != nil {
+		t.Fatalf("failed to insert unblocked chain: %v", err)
+	}
+}
+
+// Tests that blocks conflicting with the whitelist are rejected, rewinding the
+// chain if it's on a different branch, and that the whitelist survives a restart.
+func TestBlockWhitelist(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x01})
+	})
+	forks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 8, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{0x02})
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Whitelist the fork, the heavier canonical chain should be rewound
+	if err := chain.AddWhitelistedBlock(forks[4].NumberU64(), forks[4].Hash()); err != nil {
+		t.Fatalf("failed to whitelist block: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.NumberU64() != forks[4].NumberU64()-1 {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), forks[4].NumberU64()-1)
+	}
+	chain.Stop()
+
+	// Restart the chain and ensure only the whitelisted branch is accepted
+	chain, _ = NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if list := chain.Whitelist(); len(list) != 1 || list[forks[4].NumberU64()] != forks[4].Hash() {
+		t.Fatalf("whitelist mismatch: have %v", list)
+	}
+	if _, err := chain.InsertChain(blocks); err != ErrWhitelistMismatch {
+		t.Fatalf("conflicting block import error mismatch: have %v, want %v", err, ErrWhitelistMismatch)
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert whitelisted chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != forks[len(forks)-1].Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), forks[len(forks)-1].NumberU64())
+	}
+}
+
+// Tests that the header verification span covers all asynchronous verification
+// results, not only the first one, and that aborts are passed to the engine.
+func TestTraceHeaderVerification(t *testing.T) {
+	reporter := jaeger.NewInMemoryReporter()
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), reporter)
+	defer closer.Close()
+
+	// reported waits until the given number of spans were finished
+	reported := func(count int) []*jaeger.Span {
+		for i := 0; i < 100 && reporter.SpansSubmitted() < count; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		var spans []*jaeger.Span
+		for _, span := range reporter.GetSpans() {
+			spans = append(spans, span.(*jaeger.Span))
+		}
+		return spans
+	}
+	hasTag := func(span *jaeger.Span, key string) bool {
+		for _, tag := range jaeger.BuildJaegerThrift(span).Tags {
+			if tag.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+	// Deliver the results one by one, the span must stay open until the last
+	abort, results := make(chan struct{}), make(chan error, 2)
+	traceAbort, traceResults := traceHeaderVerification(tracer.StartSpan("complete"), abort, results, 2)
+
+	results <- nil
+	if err := <-traceResults; err != nil {
+		t.Fatalf("first result mismatch: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := reporter.SpansSubmitted(); n != 0 {
+		t.Fatalf("span finished before all results arrived")
+	}
+	results <- errors.New("invalid header")
+	if err := <-traceResults; err == nil {
+		t.Fatalf("second result mismatch: have nil, want error")
+	}
+	if spans := reported(1); len(spans) != 1 || !hasTag(spans[0], "error") {
+		t.Fatalf("finished span mismatch: %v", spans)
+	}
+	close(traceAbort)
+	select {
+	case <-abort:
+	case <-time.After(time.Second):
+		t.Fatalf("verification abort not forwarded")
+	}
+	// Abort a verification midway, the span must be finished right away
+	abort, results = make(chan struct{}), make(chan error, 2)
+	traceAbort, _ = traceHeaderVerification(tracer.StartSpan("aborted"), abort, results, 2)
+
+	close(traceAbort)
+	select {
+	case <-abort:
+	case <-time.After(time.Second):
+		t.Fatalf("verification abort not forwarded")
+	}
+	if spans := reported(2); len(spans) != 2 || !hasTag(spans[1], "aborted") {
+		t.Fatalf("aborted span mismatch: %v", spans)
+	}
+}
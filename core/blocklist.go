@@ -0,0 +1,203 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/rawdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+)
+
+// errGenesisBlockList is returned when trying to whitelist or blacklist a hash
+// conflicting with the genesis block.
+var errGenesisBlockList = errors.New("cannot override the genesis block")
+
+// loadBlockLists restores the persisted block whitelist and blacklist, rewinding
+// the chain if it contradicts any of them.
+func (bc *BlockChain) loadBlockLists() {
+	bc.whitelist = rawdb.ReadBlockWhitelist(bc.db)
+	if bc.whitelist == nil {
+		bc.whitelist = make(map[uint64]common.Hash)
+	}
+	bc.blacklist = make(map[common.Hash]struct{})
+	for _, hash := range rawdb.ReadBlockBlacklist(bc.db) {
+		bc.blacklist[hash] = struct{}{}
+	}
+	for number, hash := range bc.whitelist {
+		bc.rewindWhitelisted(number, hash)
+	}
+	for hash := range bc.blacklist {
+		bc.rewindBlacklisted(hash)
+	}
+}
+
+// checkBlockLists verifies that a header is neither blacklisted nor the child of
+// a blacklisted block, and that it doesn't conflict with the whitelist.
+func (bc *BlockChain) checkBlockLists(header *types.Header) error {
+	bc.listLock.RLock()
+	defer bc.listLock.RUnlock()
+
+	hash := header.Hash()
+	if _, ok := bc.blacklist[hash]; ok {
+		return ErrBlacklistedHash
+	}
+	if _, ok := bc.blacklist[header.ParentHash]; ok {
+		return ErrBlacklistedHash
+	}
+	if want, ok := bc.whitelist[header.Number.Uint64()]; ok && want != hash {
+		return ErrWhitelistMismatch
+	}
+	return nil
+}
+
+// WhitelistedHash returns the hash the canonical chain is required to contain
+// at the given height, if any.
+func (bc *BlockChain) WhitelistedHash(number uint64) (common.Hash, bool) {
+	bc.listLock.RLock()
+	defer bc.listLock.RUnlock()
+
+	hash, ok := bc.whitelist[number]
+	return hash, ok
+}
+
+// Whitelist returns the block number to hash mappings the canonical chain is
+// required to contain.
+func (bc *BlockChain) Whitelist() map[uint64]common.Hash {
+	bc.listLock.RLock()
+	defer bc.listLock.RUnlock()
+
+	whitelist := make(map[uint64]common.Hash, len(bc.whitelist))
+	for number, hash := range bc.whitelist {
+		whitelist[number] = hash
+	}
+	return whitelist
+}
+
+// AddWhitelistedBlock requires the canonical chain to contain the given hash at
+// the given height, rewinding the local chain if it is on a different branch.
+// The whitelist is persisted across restarts.
+func (bc *BlockChain) AddWhitelistedBlock(number uint64, hash common.Hash) error {
+	if number == 0 && hash != bc.genesisBlock.Hash() {
+		return errGenesisBlockList
+	}
+	bc.listLock.Lock()
+	bc.whitelist[number] = hash
+	rawdb.WriteBlockWhitelist(bc.db, bc.whitelist)
+	bc.listLock.Unlock()
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.rewindWhitelisted(number, hash)
+	return nil
+}
+
+// RemoveWhitelistedBlock drops the whitelist entry at the given height, returning
+// whether there was one.
+func (bc *BlockChain) RemoveWhitelistedBlock(number uint64) bool {
+	bc.listLock.Lock()
+	defer bc.listLock.Unlock()
+
+	if _, ok := bc.whitelist[number]; !ok {
+		return false
+	}
+	delete(bc.whitelist, number)
+	rawdb.WriteBlockWhitelist(bc.db, bc.whitelist)
+	return true
+}
+
+// Blacklist returns the hashes of the blocks that are never imported, sorted.
+func (bc *BlockChain) Blacklist() []common.Hash {
+	bc.listLock.RLock()
+	defer bc.listLock.RUnlock()
+
+	return bc.blacklistHashes()
+}
+
+// blacklistHashes flattens the blacklist into a sorted slice. It assumes that the
+// list lock is held.
+func (bc *BlockChain) blacklistHashes() []common.Hash {
+	hashes := make([]common.Hash, 0, len(bc.blacklist))
+	for hash := range bc.blacklist {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	return hashes
+}
+
+// AddBlacklistedBlock marks a block and all its descendants as invalid, so they
+// are never imported. If the block is already part of the canonical chain, the
+// chain is rewound to its parent. The blacklist is persisted across restarts.
+func (bc *BlockChain) AddBlacklistedBlock(hash common.Hash) error {
+	if hash == bc.genesisBlock.Hash() {
+		return errGenesisBlockList
+	}
+	bc.listLock.Lock()
+	bc.blacklist[hash] = struct{}{}
+	rawdb.WriteBlockBlacklist(bc.db, bc.blacklistHashes())
+	bc.listLock.Unlock()
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.rewindBlacklisted(hash)
+	return nil
+}
+
+// RemoveBlacklistedBlock drops a hash from the blacklist, returning whether it
+// was present.
+func (bc *BlockChain) RemoveBlacklistedBlock(hash common.Hash) bool {
+	bc.listLock.Lock()
+	defer bc.listLock.Unlock()
+
+	if _, ok := bc.blacklist[hash]; !ok {
+		return false
+	}
+	delete(bc.blacklist, hash)
+	rawdb.WriteBlockBlacklist(bc.db, bc.blacklistHashes())
+	return true
+}
+
+// rewindWhitelisted rewinds the local chain below the given height if the head
+// header chain contains a block there conflicting with the whitelisted hash.
+func (bc *BlockChain) rewindWhitelisted(number uint64, hash common.Hash) {
+	if number == 0 || number > bc.CurrentHeader().Number.Uint64() {
+		return
+	}
+	if canon := rawdb.ReadCanonicalHash(bc.db, number); canon != (common.Hash{}) && canon != hash {
+		log.Warn("Canonical chain conflicts with whitelist, rewinding", "number", number, "hash", canon, "want", hash)
+		bc.SetHead(number - 1)
+	}
+}
+
+// rewindBlacklisted rewinds the local chain to the parent of the given block if
+// it is part of the head header chain.
+func (bc *BlockChain) rewindBlacklisted(hash common.Hash) {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil || *number == 0 || *number > bc.CurrentHeader().Number.Uint64() {
+		return
+	}
+	if rawdb.ReadCanonicalHash(bc.db, *number) == hash {
+		log.Warn("Canonical chain contains blacklisted block, rewinding", "number", *number, "hash", hash)
+		bc.SetHead(*number - 1)
+	}
+}
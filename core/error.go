@@ -29,6 +29,10 @@ var (
 	// ErrBlacklistedHash is returned if a block to import is on the blacklist.
 	ErrBlacklistedHash = errors.New("blacklisted hash")
 
+	// ErrWhitelistMismatch is returned if a block to import conflicts with the
+	// hash whitelisted at its height.
+	ErrWhitelistMismatch = errors.New("whitelisted hash mismatch")
+
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
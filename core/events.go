@@ -24,6 +24,10 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// DroppedTxsEvent is posted when a batch of transactions leave the transaction
+// pool without being included in the chain.
+type DroppedTxsEvent struct{ Txs []*DroppedTx }
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
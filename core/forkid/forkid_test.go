@@ -0,0 +1,126 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package forkid
+
+import (
+	"hash/crc32"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+)
+
+var (
+	// testGenesis is the genesis hash of the test chain.
+	testGenesis = common.Hash{0x01}
+
+	// testConfig is a chain config with forks at genesis, block 5 and block 10.
+	testConfig = &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		EIP150Block:    big.NewInt(5),
+		EIP155Block:    big.NewInt(5),
+		ByzantiumBlock: big.NewInt(10),
+	}
+)
+
+// testSums returns the checksums of the test chain before and after each fork.
+func testSums() [3][4]byte {
+	hash := crc32.ChecksumIEEE(testGenesis[:])
+	genesis := checksumToBytes(hash)
+	hash = checksumUpdate(hash, 5)
+	first := checksumToBytes(hash)
+	hash = checksumUpdate(hash, 10)
+	return [3][4]byte{genesis, first, checksumToBytes(hash)}
+}
+
+// Tests that the fork ID checksums the passed forks and announces the next one.
+func TestCreation(t *testing.T) {
+	sums := testSums()
+	tests := []struct {
+		head uint64
+		want ID
+	}{
+		{0, ID{Hash: sums[0], Next: 5}},
+		{4, ID{Hash: sums[0], Next: 5}},
+		{5, ID{Hash: sums[1], Next: 10}},
+		{9, ID{Hash: sums[1], Next: 10}},
+		{10, ID{Hash: sums[2], Next: 0}},
+		{1000, ID{Hash: sums[2], Next: 0}},
+	}
+	for i, tt := range tests {
+		if have := newID(testConfig, testGenesis, tt.head); have != tt.want {
+			t.Errorf("test %d: fork ID mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that remote fork IDs are accepted or rejected according to the rules
+// of EIP-2124.
+func TestValidation(t *testing.T) {
+	sums := testSums()
+	tests := []struct {
+		head uint64
+		id   ID
+		err  error
+	}{
+		// Same fork state, no or not yet passed remote announcement
+		{7, ID{Hash: sums[1], Next: 10}, nil},
+		{7, ID{Hash: sums[1], Next: 0}, nil},
+		{7, ID{Hash: sums[1], Next: 8}, nil},
+
+		// Same fork state, remote announces a fork already passed locally
+		{7, ID{Hash: sums[1], Next: 6}, ErrLocalIncompatibleOrStale},
+
+		// Remote is syncing and knows about the next local fork
+		{7, ID{Hash: sums[0], Next: 5}, nil},
+
+		// Remote is syncing but is unaware of the passed local fork
+		{7, ID{Hash: sums[0], Next: 0}, ErrRemoteStale},
+		{7, ID{Hash: sums[0], Next: 6}, ErrRemoteStale},
+
+		// Local is syncing, remote is ahead on a compatible chain
+		{7, ID{Hash: sums[2], Next: 0}, nil},
+		{0, ID{Hash: sums[2], Next: 0}, nil},
+
+		// Remote is on an unknown chain
+		{7, ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}, Next: 0}, ErrLocalIncompatibleOrStale},
+	}
+	for i, tt := range tests {
+		head := tt.head
+		filter := newFilter(testConfig, testGenesis, func() uint64 { return head })
+		if err := filter(tt.id); err != tt.err {
+			t.Errorf("test %d: validation error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that forks are sorted and deduplicated, genesis rules are skipped and
+// ethash chains include the forks hard coded into the client.
+func TestGatherForks(t *testing.T) {
+	if forks, want := gatherForks(testConfig), []uint64{5, 10}; !reflect.DeepEqual(forks, want) {
+		t.Errorf("fork list mismatch: have %v, want %v", forks, want)
+	}
+	config := *testConfig
+	config.Ethash = new(params.EthashConfig)
+
+	want := []uint64{5, 10, uint64(params.PirlGuardActivationBlock), params.ForkBlockDoDo}
+	if forks := gatherForks(&config); !reflect.DeepEqual(forks, want) {
+		t.Errorf("ethash fork list mismatch: have %v, want %v", forks, want)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math/big"
+	"sort"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
@@ -126,6 +127,94 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// ReadHistoryTail retrieves the number of the first block whose body and receipts
+// are available. It is zero unless the chain was synced from a checkpoint.
+func ReadHistoryTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(historyTailKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteHistoryTail stores the number of the first block whose body and receipts
+// are available.
+func WriteHistoryTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(historyTailKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store history tail", "err", err)
+	}
+}
+
+// whitelistEntry is a single block number to hash mapping of the whitelist.
+type whitelistEntry struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// ReadBlockWhitelist retrieves the block number to hash mappings the canonical
+// chain is required to contain.
+func ReadBlockWhitelist(db DatabaseReader) map[uint64]common.Hash {
+	data, _ := db.Get(blockWhitelistKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []whitelistEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		log.Error("Invalid block whitelist RLP", "err", err)
+		return nil
+	}
+	whitelist := make(map[uint64]common.Hash)
+	for _, entry := range entries {
+		whitelist[entry.Number] = entry.Hash
+	}
+	return whitelist
+}
+
+// WriteBlockWhitelist stores the block number to hash mappings the canonical
+// chain is required to contain.
+func WriteBlockWhitelist(db DatabaseWriter, whitelist map[uint64]common.Hash) {
+	entries := make([]whitelistEntry, 0, len(whitelist))
+	for number, hash := range whitelist {
+		entries = append(entries, whitelistEntry{Number: number, Hash: hash})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Number < entries[j].Number })
+
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to RLP encode block whitelist", "err", err)
+	}
+	if err := db.Put(blockWhitelistKey, data); err != nil {
+		log.Crit("Failed to store block whitelist", "err", err)
+	}
+}
+
+// ReadBlockBlacklist retrieves the hashes of the blocks that must never be
+// imported.
+func ReadBlockBlacklist(db DatabaseReader) []common.Hash {
+	data, _ := db.Get(blockBlacklistKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := rlp.DecodeBytes(data, &hashes); err != nil {
+		log.Error("Invalid block blacklist RLP", "err", err)
+		return nil
+	}
+	return hashes
+}
+
+// WriteBlockBlacklist stores the hashes of the blocks that must never be
+// imported.
+func WriteBlockBlacklist(db DatabaseWriter, hashes []common.Hash) {
+	data, err := rlp.EncodeToBytes(hashes)
+	if err != nil {
+		log.Crit("Failed to RLP encode block blacklist", "err", err)
+	}
+	if err := db.Put(blockBlacklistKey, data); err != nil {
+		log.Crit("Failed to store block blacklist", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
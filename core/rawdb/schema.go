@@ -41,6 +41,15 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// historyTailKey tracks the first block with a body after a checkpoint sync.
+	historyTailKey = []byte("HistoryTail")
+
+	// blockWhitelistKey tracks the block hashes the canonical chain must contain.
+	blockWhitelistKey = []byte("BlockWhitelist")
+
+	// blockBlacklistKey tracks the block hashes that must never be imported.
+	blockBlacklistKey = []byte("BlockBlacklist")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"github.com/hashicorp/golang-lru"
+)
+
+// droppedCacheSize is the number of recently dropped transactions the pool
+// remembers for later queries.
+const droppedCacheSize = 4096
+
+// TxDropReason is the reason a transaction was removed from the pool without
+// being included in the chain.
+type TxDropReason uint8
+
+const (
+	TxDropReplaced    TxDropReason = iota // Replaced by a transaction with the same nonce
+	TxDropUnderpriced                     // Evicted to make room for better paying transactions
+	TxDropExpired                         // Queued for longer than the account's lifetime
+	TxDropRateLimited                     // Exceeded the account or global pool allowances
+	TxDropUnpayable                       // Sender can't afford it or it exceeds the block gas limit
+	TxDropInvalidated                     // Nonce consumed by a different transaction in the chain
+	TxDropReorged                         // Rolled back by a reorg and rejected on reinjection
+)
+
+// String implements fmt.Stringer.
+func (reason TxDropReason) String() string {
+	switch reason {
+	case TxDropReplaced:
+		return "replaced"
+	case TxDropUnderpriced:
+		return "underpriced"
+	case TxDropExpired:
+		return "expired"
+	case TxDropRateLimited:
+		return "ratelimited"
+	case TxDropUnpayable:
+		return "unpayable"
+	case TxDropInvalidated:
+		return "invalidated"
+	case TxDropReorged:
+		return "reorged"
+	default:
+		return "unknown"
+	}
+}
+
+// DroppedTx is a transaction removed from the pool, along with the reason.
+type DroppedTx struct {
+	Tx          *types.Transaction
+	Reason      TxDropReason
+	Replacement common.Hash // Hash of the replacing transaction, if any
+	Time        time.Time
+}
+
+// txDropTracker remembers the recently dropped transactions of the pool and
+// batches them up for notification.
+type txDropTracker struct {
+	recent *lru.Cache   // Recently dropped transactions by hash
+	batch  []*DroppedTx // Drops accumulated since the last notification
+}
+
+// newTxDropTracker creates a tracker remembering the last few dropped transactions.
+func newTxDropTracker() *txDropTracker {
+	recent, _ := lru.New(droppedCacheSize)
+	return &txDropTracker{recent: recent}
+}
+
+// add records a dropped transaction.
+func (t *txDropTracker) add(tx *types.Transaction, reason TxDropReason, replacement common.Hash) {
+	drop := &DroppedTx{Tx: tx, Reason: reason, Replacement: replacement, Time: time.Now()}
+
+	t.recent.Add(tx.Hash(), drop)
+	t.batch = append(t.batch, drop)
+}
+
+// get retrieves a recently dropped transaction, or nil if unknown.
+func (t *txDropTracker) get(hash common.Hash) *DroppedTx {
+	if drop, ok := t.recent.Get(hash); ok {
+		return drop.(*DroppedTx)
+	}
+	return nil
+}
+
+// flush returns the drops accumulated since the last call.
+func (t *txDropTracker) flush() []*DroppedTx {
+	batch := t.batch
+	t.batch = nil
+	return batch
+}
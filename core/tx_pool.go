@@ -111,6 +111,20 @@ const (
 	TxStatusIncluded
 )
 
+// String implements fmt.Stringer.
+func (status TxStatus) String() string {
+	switch status {
+	case TxStatusQueued:
+		return "queued"
+	case TxStatusPending:
+		return "pending"
+	case TxStatusIncluded:
+		return "included"
+	default:
+		return "unknown"
+	}
+}
+
 // blockChain provides the state of blockchain and current gas limit to do
 // some pre checks in tx pool and event subscribers.
 type blockChain interface {
@@ -128,6 +142,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	Snapshot       string        // Snapshot of remote transactions to survive node restarts (empty = disabled)
+	SnapshotMaxAge time.Duration // Maximum age of a remote transaction snapshot to be reloaded
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -137,6 +154,11 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Priority             []common.Address // Addresses granted the larger allowances of the priority tier
+	PriorityAccountSlots uint64           // Number of executable transaction slots guaranteed per priority account
+	PriorityAccountQueue uint64           // Maximum number of non-executable transaction slots permitted per priority account
+	PriorityLifetime     time.Duration    // Maximum amount of time non-executable priority transactions are queued
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -145,6 +167,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	SnapshotMaxAge: time.Hour,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -154,6 +178,10 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PriorityAccountSlots: 256,
+	PriorityAccountQueue: 1024,
+	PriorityLifetime:     12 * time.Hour,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -164,6 +192,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.Snapshot != "" && conf.SnapshotMaxAge < time.Second {
+		log.Warn("Sanitizing invalid txpool snapshot age", "provided", conf.SnapshotMaxAge, "updated", DefaultTxPoolConfig.SnapshotMaxAge)
+		conf.SnapshotMaxAge = DefaultTxPoolConfig.SnapshotMaxAge
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -192,6 +224,18 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.PriorityAccountSlots < conf.AccountSlots {
+		log.Warn("Sanitizing invalid txpool priority account slots", "provided", conf.PriorityAccountSlots, "updated", conf.AccountSlots)
+		conf.PriorityAccountSlots = conf.AccountSlots
+	}
+	if conf.PriorityAccountQueue < conf.AccountQueue {
+		log.Warn("Sanitizing invalid txpool priority account queue", "provided", conf.PriorityAccountQueue, "updated", conf.AccountQueue)
+		conf.PriorityAccountQueue = conf.AccountQueue
+	}
+	if conf.PriorityLifetime < conf.Lifetime {
+		log.Warn("Sanitizing invalid txpool priority lifetime", "provided", conf.PriorityLifetime, "updated", conf.Lifetime)
+		conf.PriorityLifetime = conf.Lifetime
+	}
 	return conf
 }
 
@@ -208,6 +252,7 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -218,8 +263,18 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	priority *accountSet // Set of accounts granted the priority tier allowances
+	journal  *txJournal  // Journal of local transaction to back up to disk
+	snapshot *txSnapshot // Snapshot of remote transactions to back up to disk on shutdown
+
+	drops    *txDropTracker           // Recently dropped transactions and pending notifications
+	included map[common.Hash]struct{} // Transactions included by the blocks being reset to (nil if unknown)
+
+	dropQueue  [][]*DroppedTx // Dropped transaction batches waiting to be announced, in order
+	dropLock   sync.Mutex     // Lock protecting the drop queue, independent of the pool lock
+	dropNotify chan struct{}  // Notification channel to wake the drop announcer
+	dropQuit   chan struct{}  // Quit channel to stop the drop announcer
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -250,12 +305,20 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		all:         newTxLookup(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
+		drops:       newTxDropTracker(),
+		dropNotify:  make(chan struct{}, 1),
+		dropQuit:    make(chan struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
+	pool.priority = newAccountSet(pool.signer)
+	for _, addr := range config.Priority {
+		log.Info("Setting new priority account", "address", addr)
+		pool.priority.add(addr)
+	}
 	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -270,12 +333,21 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction snapshots are enabled, reload the last one
+	if config.Snapshot != "" {
+		pool.snapshot = newTxSnapshot(config.Snapshot, config.SnapshotMaxAge)
+
+		if err := pool.snapshot.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load transaction snapshot", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
 	// Start the event loop and return
-	pool.wg.Add(1)
+	pool.wg.Add(2)
 	go pool.loop()
+	go pool.dropLoop()
 
 	return pool
 }
@@ -341,12 +413,14 @@ func (pool *TxPool) loop() {
 					continue
 				}
 				// Any non-locals old enough should be removed
-				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
+				if time.Since(pool.beats[addr]) > pool.accountLifetime(addr) {
 					for _, tx := range pool.queue[addr].Flatten() {
+						pool.dropTx(tx, TxDropExpired, common.Hash{})
 						pool.removeTx(tx.Hash(), true)
 					}
 				}
 			}
+			pool.notifyDropped()
 			pool.mu.Unlock()
 
 		// Handle local transaction journal rotation
@@ -375,8 +449,16 @@ func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 // of the transaction pool is valid with regard to the chain state.
 func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
-
+	var (
+		reinject types.Transactions
+		included types.Transactions
+		known    bool // Whether the included transactions could be collected
+	)
+	if oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included, known = block.Transactions(), true
+		}
+	}
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
 		oldNum := oldHead.Number.Uint64()
@@ -386,7 +468,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
-			var discarded, included types.Transactions
+			var discarded types.Transactions
 
 			var (
 				rem = pool.chain.GetBlock(oldHead.Hash(), oldHead.Number.Uint64())
@@ -418,8 +500,17 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 					return
 				}
 			}
-			reinject = types.TxDifference(discarded, included)
+			reinject, known = types.TxDifference(discarded, included), true
+		}
+	}
+	// Track the included transactions to tell them apart from the ones invalidated
+	// by a competing transaction with the same nonce
+	if known {
+		pool.included = make(map[common.Hash]struct{}, len(included))
+		for _, tx := range included {
+			pool.included[tx.Hash()] = struct{}{}
 		}
+		defer func() { pool.included = nil }()
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -437,7 +528,12 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	for i, err := range pool.addTxsLocked(reinject, false) {
+		if err != nil && pool.all.Get(reinject[i].Hash()) == nil {
+			log.Trace("Dropped reorged transaction", "hash", reinject[i].Hash(), "err", err)
+			pool.dropTx(reinject[i], TxDropReorged, common.Hash{})
+		}
+	}
 
 	// validate the pool of pending transactions, this will remove
 	// any transactions that have been included in the block or
@@ -462,11 +558,21 @@ func (pool *TxPool) Stop() {
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	close(pool.dropQuit)
 	pool.wg.Wait()
 
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		pool.mu.RLock()
+		remotes := pool.remote()
+		pool.mu.RUnlock()
+
+		if err := pool.snapshot.save(remotes); err != nil {
+			log.Warn("Failed to save transaction snapshot", "err", err)
+		}
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -476,6 +582,12 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDroppedTxsEvent registers a subscription of DroppedTxsEvent and
+// starts sending event to the given channel.
+func (pool *TxPool) SubscribeDroppedTxsEvent(ch chan<- DroppedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -492,11 +604,19 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
+		pool.dropTx(tx, TxDropUnderpriced, common.Hash{})
 		pool.removeTx(tx.Hash(), false)
 	}
+	pool.notifyDropped()
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// PriceBump returns the minimum price bump percentage required to replace an
+// already pooled transaction.
+func (pool *TxPool) PriceBump() uint64 {
+	return pool.config.PriceBump
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -567,6 +687,64 @@ func (pool *TxPool) Locals() []common.Address {
 	return pool.locals.flatten()
 }
 
+// AddPriority grants the priority tier allowances to the given account.
+func (pool *TxPool) AddPriority(addr common.Address) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	log.Info("Setting new priority account", "address", addr)
+	pool.priority.add(addr)
+}
+
+// RemovePriority moves the given account back into the default tier, dropping
+// any of its queued transactions exceeding the default allowance.
+func (pool *TxPool) RemovePriority(addr common.Address) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if !pool.priority.contains(addr) {
+		return
+	}
+	log.Info("Removing priority account", "address", addr)
+	pool.priority.remove(addr)
+	pool.promoteExecutables([]common.Address{addr})
+}
+
+// Priorities retrieves the accounts currently granted the priority tier.
+func (pool *TxPool) Priorities() []common.Address {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.priority.flatten()
+}
+
+// accountSlots returns the number of executable transaction slots guaranteed to
+// the given account by its tier.
+func (pool *TxPool) accountSlots(addr common.Address) uint64 {
+	if pool.priority.contains(addr) {
+		return pool.config.PriorityAccountSlots
+	}
+	return pool.config.AccountSlots
+}
+
+// accountQueue returns the number of non-executable transaction slots permitted
+// to the given account by its tier.
+func (pool *TxPool) accountQueue(addr common.Address) uint64 {
+	if pool.priority.contains(addr) {
+		return pool.config.PriorityAccountQueue
+	}
+	return pool.config.AccountQueue
+}
+
+// accountLifetime returns the maximum amount of time the non-executable
+// transactions of the given account may be queued.
+func (pool *TxPool) accountLifetime(addr common.Address) time.Duration {
+	if pool.priority.contains(addr) {
+		return pool.config.PriorityLifetime
+	}
+	return pool.config.Lifetime
+}
+
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -583,6 +761,23 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, both pending and
+// queued, grouped by origin account and sorted by nonce.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -662,6 +857,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
+			pool.dropTx(tx, TxDropUnderpriced, common.Hash{})
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
@@ -679,6 +875,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.dropTx(old, TxDropReplaced, hash)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
@@ -729,6 +926,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.dropTx(old, TxDropReplaced, hash)
 	}
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
@@ -767,6 +965,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.dropTx(tx, TxDropUnderpriced, common.Hash{})
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -775,6 +974,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.dropTx(old, TxDropReplaced, hash)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
@@ -831,6 +1031,7 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 		from, _ := types.Sender(pool.signer, tx) // already validated
 		pool.promoteExecutables([]common.Address{from})
 	}
+	pool.notifyDropped()
 	return nil
 }
 
@@ -864,6 +1065,7 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
 		}
 		pool.promoteExecutables(addrs)
 	}
+	pool.notifyDropped()
 	return errs
 }
 
@@ -893,6 +1095,78 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// Dropped returns a recently dropped transaction along with the reason it was
+// removed from the pool, or nil if the pool doesn't remember it.
+func (pool *TxPool) Dropped(hash common.Hash) *DroppedTx {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.drops.get(hash)
+}
+
+// dropTx records a transaction removed from the pool without being included in
+// the chain, to be announced on the next notifyDropped.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) dropTx(tx *types.Transaction, reason TxDropReason, replacement common.Hash) {
+	pool.drops.add(tx, reason, replacement)
+}
+
+// dropStale records a transaction removed due to its nonce being used up in the
+// chain, unless it was included by the blocks the pool is being reset to. If
+// the included transactions are unknown (e.g. deep reorg), nothing is recorded.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) dropStale(tx *types.Transaction) {
+	if pool.included == nil {
+		return
+	}
+	if _, ok := pool.included[tx.Hash()]; !ok {
+		pool.dropTx(tx, TxDropInvalidated, common.Hash{})
+	}
+}
+
+// notifyDropped queues all the transactions dropped since the last call to be
+// announced by the drop announcer, without blocking on the subscribers.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) notifyDropped() {
+	drops := pool.drops.flush()
+	if len(drops) == 0 {
+		return
+	}
+	pool.dropLock.Lock()
+	pool.dropQueue = append(pool.dropQueue, drops)
+	pool.dropLock.Unlock()
+
+	select {
+	case pool.dropNotify <- struct{}{}:
+	default:
+	}
+}
+
+// dropLoop announces the queued dropped transaction batches to the subscribers,
+// one at a time and in the order they were dropped.
+func (pool *TxPool) dropLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case <-pool.dropNotify:
+			pool.dropLock.Lock()
+			queue := pool.dropQueue
+			pool.dropQueue = nil
+			pool.dropLock.Unlock()
+
+			for _, drops := range queue {
+				pool.dropFeed.Send(DroppedTxsEvent{drops})
+			}
+		case <-pool.dropQuit:
+			return
+		}
+	}
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -962,6 +1236,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			log.Trace("Removed old queued transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
+			pool.dropStale(tx)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas)
 		drops, _ := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -971,6 +1246,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
+			pool.dropTx(tx, TxDropUnpayable, common.Hash{})
 		}
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
@@ -982,11 +1258,12 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 		// Drop all transactions over the allowed limit
 		if !pool.locals.contains(addr) {
-			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
+			for _, tx := range list.Cap(int(pool.accountQueue(addr))) {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.dropTx(tx, TxDropRateLimited, common.Hash{})
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -1010,7 +1287,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		spammers := prque.New(nil)
 		for addr, list := range pool.pending {
 			// Only evict transactions from high rollers
-			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.accountSlots(addr) {
 				spammers.Push(addr, int64(list.Len()))
 			}
 		}
@@ -1026,15 +1303,22 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				// Calculate the equalization threshold for all current offenders
 				threshold := pool.pending[offender.(common.Address)].Len()
 
-				// Iteratively reduce all offenders until below limit or threshold reached
-				for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
+				// Iteratively reduce all offenders until below limit or threshold reached,
+				// never going below the guaranteed allowance of an account's tier
+				for reduced := true; pending > pool.config.GlobalSlots && reduced; {
+					reduced = false
 					for i := 0; i < len(offenders)-1; i++ {
 						list := pool.pending[offenders[i]]
+						if list.Len() <= threshold || uint64(list.Len()) <= pool.accountSlots(offenders[i]) {
+							continue
+						}
+						reduced = true
 						for _, tx := range list.Cap(list.Len() - 1) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
 							pool.all.Remove(hash)
 							pool.priced.Removed()
+							pool.dropTx(tx, TxDropRateLimited, common.Hash{})
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1049,14 +1333,20 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 		// If still above threshold, reduce to limit or min allowance
 		if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-			for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
+			for reduced := true; pending > pool.config.GlobalSlots && reduced; {
+				reduced = false
 				for _, addr := range offenders {
 					list := pool.pending[addr]
+					if uint64(list.Len()) <= pool.accountSlots(addr) {
+						continue
+					}
+					reduced = true
 					for _, tx := range list.Cap(list.Len() - 1) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
 						pool.priced.Removed()
+						pool.dropTx(tx, TxDropRateLimited, common.Hash{})
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1076,14 +1366,22 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		queued += uint64(list.Len())
 	}
 	if queued > pool.config.GlobalQueue {
-		// Sort all accounts with queued transactions by heartbeat
+		// Sort all accounts with queued transactions by heartbeat, placing priority
+		// accounts in front so that they are dropped last
 		addresses := make(addressesByHeartbeat, 0, len(pool.queue))
+		priority := make(addressesByHeartbeat, 0)
 		for addr := range pool.queue {
-			if !pool.locals.contains(addr) { // don't drop locals
+			switch {
+			case pool.locals.contains(addr): // don't drop locals
+			case pool.priority.contains(addr):
+				priority = append(priority, addressByHeartbeat{addr, pool.beats[addr]})
+			default:
 				addresses = append(addresses, addressByHeartbeat{addr, pool.beats[addr]})
 			}
 		}
 		sort.Sort(addresses)
+		sort.Sort(priority)
+		addresses = append(priority, addresses...)
 
 		// Drop transactions until the total is below the limit or only locals remain
 		for drop := queued - pool.config.GlobalQueue; drop > 0 && len(addresses) > 0; {
@@ -1095,6 +1393,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
+					pool.dropTx(tx, TxDropRateLimited, common.Hash{})
 					pool.removeTx(tx.Hash(), true)
 				}
 				drop -= size
@@ -1104,12 +1403,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				pool.dropTx(txs[i], TxDropRateLimited, common.Hash{})
 				pool.removeTx(txs[i].Hash(), true)
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			}
 		}
 	}
+	// Notify subsystems of all the transactions dropped along the way
+	pool.notifyDropped()
 }
 
 // demoteUnexecutables removes invalid and processed transactions from the pools
@@ -1126,6 +1428,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Removed old pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
+			pool.dropStale(tx)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -1135,6 +1438,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.dropTx(tx, TxDropUnpayable, common.Hash{})
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -1207,6 +1511,12 @@ func (as *accountSet) add(addr common.Address) {
 	as.cache = nil
 }
 
+// remove deletes an address from the set.
+func (as *accountSet) remove(addr common.Address) {
+	delete(as.accounts, addr)
+	as.cache = nil
+}
+
 // flatten returns the list of addresses within this set, also caching it for later
 // reuse. The returned slice should not be changed!
 func (as *accountSet) flatten() []common.Address {
@@ -17,12 +17,14 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -33,6 +35,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
 )
 
 // testTxPoolConfig is a transaction pool configuration without stateful disk
@@ -1130,6 +1133,92 @@ func TestTransactionPendingMinimumAllowance(t *testing.T) {
 	}
 }
 
+// Tests that if the transaction count belonging to multiple accounts go above
+// the global pending limit, priority accounts retain their larger allowance
+// while the default tier is reduced to its own minimum.
+func TestTransactionPendingPriorityAllowance(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the limit enforcement with
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 1
+	config.AccountSlots = 4
+	config.PriorityAccountSlots = 8
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Create a number of test accounts, fund them and mark the first as priority
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	priority := crypto.PubkeyToAddress(keys[0].PublicKey)
+	pool.AddPriority(priority)
+
+	// Generate and queue a batch of transactions
+	txs := types.Transactions{}
+	for _, key := range keys {
+		for j := 0; j < 16; j++ {
+			txs = append(txs, transaction(uint64(j), 100000, key))
+		}
+	}
+	// Import the batch and verify that limits have been enforced per tier
+	pool.AddRemotes(txs)
+
+	for addr, list := range pool.pending {
+		want := int(config.AccountSlots)
+		if addr == priority {
+			want = int(config.PriorityAccountSlots)
+		}
+		if list.Len() != want {
+			t.Errorf("addr %x: total pending transactions mismatch: have %d, want %d", addr, list.Len(), want)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that priority accounts may queue more transactions than the default
+// tier, and that revoking the priority caps their queue again.
+func TestTransactionQueuePriorityLimiting(t *testing.T) {
+	t.Parallel()
+
+	// Create a test account, fund it and grant it priority
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	account, _ := deriveSender(transaction(0, 0, key))
+	pool.currentState.AddBalance(account, big.NewInt(1000000))
+	pool.AddPriority(account)
+
+	// Queue up more transactions than the default tier allows
+	for i := uint64(1); i <= testTxPoolConfig.AccountQueue+5; i++ {
+		if err := pool.AddRemote(transaction(i, 100000, key)); err != nil {
+			t.Fatalf("tx %d: failed to add transaction: %v", i, err)
+		}
+	}
+	if have, want := pool.queue[account].Len(), int(testTxPoolConfig.AccountQueue+5); have != want {
+		t.Fatalf("priority queue size mismatch: have %d, want %d", have, want)
+	}
+	// Revoke the priority and ensure the queue is capped to the default tier
+	pool.RemovePriority(account)
+	if have, want := pool.queue[account].Len(), int(testTxPoolConfig.AccountQueue); have != want {
+		t.Fatalf("default queue size mismatch: have %d, want %d", have, want)
+	}
+	if len(pool.Priorities()) != 0 {
+		t.Fatalf("priority accounts not cleared: %v", pool.Priorities())
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that setting the transaction pool gas price to a higher value correctly
 // discards everything cheaper than that and moves any gapped transactions back
 // from the pending pool to the queue.
@@ -1567,6 +1656,119 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that transactions leaving the pool without being included in the chain
+// are announced along with the reason, and remembered for later queries.
+func TestTransactionDropNotifications(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan DroppedTxsEvent, 32)
+	sub := pool.SubscribeDroppedTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(account, big.NewInt(1000000))
+
+	checkDropped := func(tx *types.Transaction, reason TxDropReason, replacement common.Hash) {
+		t.Helper()
+		select {
+		case ev := <-events:
+			if len(ev.Txs) != 1 {
+				t.Fatalf("dropped transaction count mismatch: have %d, want 1", len(ev.Txs))
+			}
+			drop := ev.Txs[0]
+			if drop.Tx.Hash() != tx.Hash() {
+				t.Errorf("dropped transaction mismatch: have %x, want %x", drop.Tx.Hash(), tx.Hash())
+			}
+			if drop.Reason != reason {
+				t.Errorf("drop reason mismatch: have %v, want %v", drop.Reason, reason)
+			}
+			if drop.Replacement != replacement {
+				t.Errorf("replacement mismatch: have %x, want %x", drop.Replacement, replacement)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop of %x not announced", tx.Hash())
+		}
+	}
+	// Replace a pending transaction with a better paying one
+	original, replacement := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.AddRemote(original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	checkDropped(original, TxDropReplaced, replacement.Hash())
+
+	// Queue a transaction and make it unaffordable
+	expensive := pricedTransaction(2, 100000, big.NewInt(5), key)
+	if err := pool.AddRemote(expensive); err != nil {
+		t.Fatalf("failed to add expensive transaction: %v", err)
+	}
+	pool.currentState.AddBalance(account, big.NewInt(-600000))
+	pool.lockedReset(nil, nil)
+	checkDropped(expensive, TxDropUnpayable, common.Hash{})
+
+	// Use up the nonce of the pending transaction with one not in the pool
+	pool.currentState.SetNonce(account, 1)
+
+	parent := &types.Header{Number: big.NewInt(0)}
+	pool.lockedReset(parent, &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: 1000000})
+	checkDropped(replacement, TxDropInvalidated, common.Hash{})
+
+	// Ensure the recently dropped transactions can be queried
+	if drop := pool.Dropped(original.Hash()); drop == nil || drop.Reason != TxDropReplaced {
+		t.Errorf("replaced transaction not remembered: %v", drop)
+	}
+	if drop := pool.Dropped(replacement.Hash()); drop == nil || drop.Reason != TxDropInvalidated {
+		t.Errorf("invalidated transaction not remembered: %v", drop)
+	}
+	if drop := pool.Dropped(common.Hash{}); drop != nil {
+		t.Errorf("unknown transaction reported dropped: %v", drop)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that dropped transaction batches are announced in the order they were
+// dropped, even if the subscriber lags behind.
+func TestTransactionDropNotificationOrder(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	events := make(chan DroppedTxsEvent)
+	sub := pool.SubscribeDroppedTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	account := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(account, big.NewInt(1000000000))
+
+	// Replace the same transaction repeatedly without consuming the announcements
+	var txs []*types.Transaction
+	for i := 0; i < 8; i++ {
+		tx := pricedTransaction(0, 100000, big.NewInt(int64(1)<<uint(i)), key)
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+		txs = append(txs, tx)
+	}
+	for i, tx := range txs[:len(txs)-1] {
+		select {
+		case ev := <-events:
+			if len(ev.Txs) != 1 || ev.Txs[0].Tx.Hash() != tx.Hash() {
+				t.Fatalf("drop %d: announcement out of order", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop %d not announced", i)
+		}
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
@@ -1680,6 +1882,88 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are snapshotted on shutdown and reloaded on
+// startup, unless the snapshot grew too old.
+func TestTransactionSnapshotting(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary path for the snapshot
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, "remotes.rlp")
+
+	// Create the original pool and fill it with remote transactions
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), remote),
+		pricedTransaction(1, 100000, big.NewInt(1), remote),
+		pricedTransaction(3, 100000, big.NewInt(1), remote),
+	}
+	for i, err := range pool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add remote transaction: %v", i, err)
+		}
+	}
+	pool.Stop()
+
+	// Create a new pool and ensure both pending and queued transactions survived
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("transaction count mismatch: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if _, err := os.Stat(snapshot); !os.IsNotExist(err) {
+		t.Fatalf("snapshot not deleted after loading: %v", err)
+	}
+	pool.Stop()
+
+	// Age the snapshot beyond the allowance and ensure it's dropped
+	input, err := os.Open(snapshot)
+	if err != nil {
+		t.Fatalf("failed to open snapshot: %v", err)
+	}
+	stream := rlp.NewStream(input, 0)
+	stream.Decode(new(uint64))
+
+	output := new(bytes.Buffer)
+	rlp.Encode(output, uint64(time.Now().Add(-2*config.SnapshotMaxAge).Unix()))
+	for {
+		tx := new(types.Transaction)
+		if err := stream.Decode(tx); err != nil {
+			break
+		}
+		rlp.Encode(output, tx)
+	}
+	input.Close()
+	if err := ioutil.WriteFile(snapshot, output.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to rewrite snapshot: %v", err)
+	}
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("stale transactions resurrected: have %d/%d, want %d/%d", pending, queued, 0, 0)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
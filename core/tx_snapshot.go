@@ -0,0 +1,144 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+)
+
+// txSnapshot is a one-off dump of the remote transactions of the pool, written
+// on shutdown and reloaded on startup so the node doesn't have to wait for its
+// peers to re-gossip them. Contrary to the local journal, the snapshot expires:
+// if the node was down for too long, its contents are dropped.
+//
+// The snapshot is an RLP stream of the creation time (unix seconds) followed by
+// the transactions.
+type txSnapshot struct {
+	path   string        // Filesystem path to store the transactions at
+	maxAge time.Duration // Maximum age of a snapshot to be reloaded
+}
+
+// newTxSnapshot creates a new remote transaction snapshot.
+func newTxSnapshot(path string, maxAge time.Duration) *txSnapshot {
+	return &txSnapshot{
+		path:   path,
+		maxAge: maxAge,
+	}
+}
+
+// load parses a transaction snapshot from disk, loading its contents into the
+// specified pool if it is recent enough. The snapshot is deleted afterwards to
+// avoid resurrecting the same transactions after a crash.
+func (snap *txSnapshot) load(add func([]*types.Transaction) []error) error {
+	// Skip the parsing if the snapshot file doesn't exist at all
+	if _, err := os.Stat(snap.path); os.IsNotExist(err) {
+		return nil
+	}
+	input, err := os.Open(snap.path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(snap.path)
+	defer input.Close()
+
+	stream := rlp.NewStream(input, 0)
+
+	// Ensure the snapshot is not stale
+	var created uint64
+	if err := stream.Decode(&created); err != nil {
+		return err
+	}
+	if age := time.Since(time.Unix(int64(created), 0)); age > snap.maxAge {
+		log.Info("Dropped stale transaction snapshot", "age", common.PrettyDuration(age), "limit", snap.maxAge)
+		return nil
+	}
+	// Inject all transactions from the snapshot into the pool
+	total, dropped := 0, 0
+
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Trace("Failed to add snapshotted transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	var (
+		failure error
+		batch   types.Transactions
+	)
+	for {
+		// Parse the next transaction and terminate on error
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		// New transaction parsed, queue up for later, import if threshold is reached
+		total++
+
+		if batch = append(batch, tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded remote transaction snapshot", "transactions", total, "dropped", dropped)
+
+	return failure
+}
+
+// save writes the given transactions into a fresh snapshot.
+func (snap *txSnapshot) save(all map[common.Address]types.Transactions) error {
+	output, err := os.OpenFile(snap.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err = rlp.Encode(output, uint64(time.Now().Unix())); err != nil {
+		output.Close()
+		return err
+	}
+	saved := 0
+	for _, txs := range all {
+		for _, tx := range txs {
+			if err = rlp.Encode(output, tx); err != nil {
+				output.Close()
+				return err
+			}
+		}
+		saved += len(txs)
+	}
+	if err = output.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(snap.path+".new", snap.path); err != nil {
+		return err
+	}
+	log.Info("Saved remote transaction snapshot", "transactions", saved, "accounts", len(all))
+	return nil
+}
@@ -99,6 +99,13 @@ const defaultContent: () => Content = () => ({
 		diskRead:       [],
 		diskWrite:      [],
 	},
+	miner: {
+		canonicalBlocks: [],
+		uncleBlocks:     [],
+		lostBlocks:      [],
+		reward:          [],
+		gasUsed:         [],
+	},
 	logs: {
 		chunks:        [],
 		endTop:        false,
@@ -130,6 +137,13 @@ const updaters = {
 		diskRead:       appender(200),
 		diskWrite:      appender(200),
 	},
+	miner: {
+		canonicalBlocks: appender(200),
+		uncleBlocks:     appender(200),
+		lostBlocks:      appender(200),
+		reward:          appender(200),
+		gasUsed:         appender(200),
+	},
 	logs: logInserter(5),
 };
 
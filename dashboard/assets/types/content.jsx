@@ -23,6 +23,7 @@ export type Content = {
 	txpool:  TxPool,
 	network: Network,
 	system:  System,
+	miner:   Miner,
 	logs:    Logs,
 };
 
@@ -65,6 +66,14 @@ export type System = {
 	diskWrite:      ChartEntries,
 };
 
+export type Miner = {
+	canonicalBlocks: ChartEntries,
+	uncleBlocks:     ChartEntries,
+	lostBlocks:      ChartEntries,
+	reward:          ChartEntries,
+	gasUsed:         ChartEntries,
+};
+
 export type Record = {
 	t:   string,
 	lvl: Object,
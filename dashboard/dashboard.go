@@ -53,6 +53,9 @@ const (
 	systemCPUSampleLimit      = 200 // Maximum number of system cpu data samples
 	diskReadSampleLimit       = 200 // Maximum number of disk read data samples
 	diskWriteSampleLimit      = 200 // Maximum number of disk write data samples
+	minedBlocksSampleLimit    = 200 // Maximum number of mined block outcome data samples
+	minerRewardSampleLimit    = 200 // Maximum number of miner reward data samples
+	minerGasSampleLimit       = 200 // Maximum number of mined gas data samples
 )
 
 var nextID uint32 // Next connection id
@@ -105,6 +108,13 @@ func New(config *Config, commit string, logdir string) *Dashboard {
 				DiskRead:       emptyChartEntries(now, diskReadSampleLimit, config.Refresh),
 				DiskWrite:      emptyChartEntries(now, diskWriteSampleLimit, config.Refresh),
 			},
+			Miner: &MinerMessage{
+				CanonicalBlocks: emptyChartEntries(now, minedBlocksSampleLimit, config.Refresh),
+				UncleBlocks:     emptyChartEntries(now, minedBlocksSampleLimit, config.Refresh),
+				LostBlocks:      emptyChartEntries(now, minedBlocksSampleLimit, config.Refresh),
+				Reward:          emptyChartEntries(now, minerRewardSampleLimit, config.Refresh),
+				GasUsed:         emptyChartEntries(now, minerGasSampleLimit, config.Refresh),
+			},
 		},
 		logdir: logdir,
 	}
@@ -285,6 +295,11 @@ func (db *Dashboard) collectData() {
 		collectNetworkEgress  = meterCollector("p2p/OutboundTraffic")
 		collectDiskRead       = meterCollector("eth/db/chaindata/disk/read")
 		collectDiskWrite      = meterCollector("eth/db/chaindata/disk/write")
+		collectCanonical      = meterCollector("miner/blocks/canonical")
+		collectUncle          = meterCollector("miner/blocks/uncle")
+		collectLost           = meterCollector("miner/blocks/lost")
+		collectReward         = meterCollector("miner/reward")
+		collectGas            = meterCollector("miner/gas")
 
 		prevNetworkIngress = collectNetworkIngress()
 		prevNetworkEgress  = collectNetworkEgress()
@@ -292,6 +307,11 @@ func (db *Dashboard) collectData() {
 		prevSystemCPUUsage = systemCPUUsage
 		prevDiskRead       = collectDiskRead()
 		prevDiskWrite      = collectDiskWrite()
+		prevCanonical      = collectCanonical()
+		prevUncle          = collectUncle()
+		prevLost           = collectLost()
+		prevReward         = collectReward()
+		prevGas            = collectGas()
 
 		frequency = float64(db.config.Refresh / time.Second)
 		numCPU    = float64(runtime.NumCPU())
@@ -311,6 +331,11 @@ func (db *Dashboard) collectData() {
 				curSystemCPUUsage = systemCPUUsage
 				curDiskRead       = collectDiskRead()
 				curDiskWrite      = collectDiskWrite()
+				curCanonical      = collectCanonical()
+				curUncle          = collectUncle()
+				curLost           = collectLost()
+				curReward         = collectReward()
+				curGas            = collectGas()
 
 				deltaNetworkIngress = float64(curNetworkIngress - prevNetworkIngress)
 				deltaNetworkEgress  = float64(curNetworkEgress - prevNetworkEgress)
@@ -318,6 +343,11 @@ func (db *Dashboard) collectData() {
 				deltaSystemCPUUsage = curSystemCPUUsage.Delta(prevSystemCPUUsage)
 				deltaDiskRead       = curDiskRead - prevDiskRead
 				deltaDiskWrite      = curDiskWrite - prevDiskWrite
+				deltaCanonical      = curCanonical - prevCanonical
+				deltaUncle          = curUncle - prevUncle
+				deltaLost           = curLost - prevLost
+				deltaReward         = curReward - prevReward
+				deltaGas            = curGas - prevGas
 			)
 			prevNetworkIngress = curNetworkIngress
 			prevNetworkEgress = curNetworkEgress
@@ -325,6 +355,11 @@ func (db *Dashboard) collectData() {
 			prevSystemCPUUsage = curSystemCPUUsage
 			prevDiskRead = curDiskRead
 			prevDiskWrite = curDiskWrite
+			prevCanonical = curCanonical
+			prevUncle = curUncle
+			prevLost = curLost
+			prevReward = curReward
+			prevGas = curGas
 
 			now := time.Now()
 
@@ -361,7 +396,28 @@ func (db *Dashboard) collectData() {
 				Time:  now,
 				Value: float64(deltaDiskWrite) / frequency,
 			}
+			canonicalBlocks := &ChartEntry{
+				Time:  now,
+				Value: float64(deltaCanonical),
+			}
+			uncleBlocks := &ChartEntry{
+				Time:  now,
+				Value: float64(deltaUncle),
+			}
+			lostBlocks := &ChartEntry{
+				Time:  now,
+				Value: float64(deltaLost),
+			}
+			minerReward := &ChartEntry{
+				Time:  now,
+				Value: float64(deltaReward),
+			}
+			minerGas := &ChartEntry{
+				Time:  now,
+				Value: float64(deltaGas),
+			}
 			sys := db.history.System
+			miner := db.history.Miner
 			db.lock.Lock()
 			sys.ActiveMemory = append(sys.ActiveMemory[1:], activeMemory)
 			sys.VirtualMemory = append(sys.VirtualMemory[1:], virtualMemory)
@@ -371,6 +427,11 @@ func (db *Dashboard) collectData() {
 			sys.SystemCPU = append(sys.SystemCPU[1:], systemCPU)
 			sys.DiskRead = append(sys.DiskRead[1:], diskRead)
 			sys.DiskWrite = append(sys.DiskWrite[1:], diskWrite)
+			miner.CanonicalBlocks = append(miner.CanonicalBlocks[1:], canonicalBlocks)
+			miner.UncleBlocks = append(miner.UncleBlocks[1:], uncleBlocks)
+			miner.LostBlocks = append(miner.LostBlocks[1:], lostBlocks)
+			miner.Reward = append(miner.Reward[1:], minerReward)
+			miner.GasUsed = append(miner.GasUsed[1:], minerGas)
 			db.lock.Unlock()
 
 			db.sendToAll(&Message{
@@ -384,6 +445,13 @@ func (db *Dashboard) collectData() {
 					DiskRead:       ChartEntries{diskRead},
 					DiskWrite:      ChartEntries{diskWrite},
 				},
+				Miner: &MinerMessage{
+					CanonicalBlocks: ChartEntries{canonicalBlocks},
+					UncleBlocks:     ChartEntries{uncleBlocks},
+					LostBlocks:      ChartEntries{lostBlocks},
+					Reward:          ChartEntries{minerReward},
+					GasUsed:         ChartEntries{minerGas},
+				},
 			})
 		}
 	}
@@ -28,6 +28,7 @@ type Message struct {
 	TxPool  *TxPoolMessage  `json:"txpool,omitempty"`
 	Network *NetworkMessage `json:"network,omitempty"`
 	System  *SystemMessage  `json:"system,omitempty"`
+	Miner   *MinerMessage   `json:"miner,omitempty"`
 	Logs    *LogsMessage    `json:"logs,omitempty"`
 }
 
@@ -70,6 +71,16 @@ type SystemMessage struct {
 	DiskWrite      ChartEntries `json:"diskWrite,omitempty"`
 }
 
+// MinerMessage contains the outcome of the locally mined blocks settled during
+// each refresh interval, as recorded by the miner statistics.
+type MinerMessage struct {
+	CanonicalBlocks ChartEntries `json:"canonicalBlocks,omitempty"` // Blocks that made it into the canonical chain
+	UncleBlocks     ChartEntries `json:"uncleBlocks,omitempty"`     // Blocks included as uncles
+	LostBlocks      ChartEntries `json:"lostBlocks,omitempty"`      // Blocks neither canonical nor included
+	Reward          ChartEntries `json:"reward,omitempty"`          // Rewards earned in gwei, fees included
+	GasUsed         ChartEntries `json:"gasUsed,omitempty"`         // Gas used by the canonical blocks
+}
+
 // LogsMessage wraps up a log chunk. If Source isn't present, the chunk is a stream chunk.
 type LogsMessage struct {
 	Source *LogFile        `json:"source,omitempty"` // Attributes of the log file.
@@ -35,6 +35,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
 	"git.pirl.io/bitcoiin/go-bitcoiin/internal/ethapi"
+	"git.pirl.io/bitcoiin/go-bitcoiin/miner"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
@@ -155,6 +156,166 @@ func (api *PrivateMinerAPI) GetHashrate() uint64 {
 	return api.e.miner.HashRate()
 }
 
+// GetStats returns the outcome and earnings of the blocks sealed by this node
+// between the given block numbers, both inclusive, along with their totals and
+// the orphan rate. Blocks are only reported once they are deep enough in the
+// chain for their fate to be known.
+func (api *PrivateMinerAPI) GetStats(fromBlock, toBlock rpc.BlockNumber) (map[string]interface{}, error) {
+	from, to := api.resolveNumber(fromBlock), api.resolveNumber(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	stats := api.e.stats.Range(from, to)
+
+	blocks := make([]map[string]interface{}, 0, len(stats.Blocks))
+	for _, block := range stats.Blocks {
+		blocks = append(blocks, map[string]interface{}{
+			"number":  hexutil.Uint64(block.Number),
+			"hash":    block.Hash,
+			"status":  block.Status,
+			"reward":  (*hexutil.Big)(block.Reward),
+			"fees":    (*hexutil.Big)(block.Fees),
+			"gasUsed": hexutil.Uint64(block.GasUsed),
+			"uncles":  hexutil.Uint64(block.Uncles),
+			"time":    hexutil.Uint64(block.Time),
+		})
+	}
+	return map[string]interface{}{
+		"fromBlock":  hexutil.Uint64(from),
+		"toBlock":    hexutil.Uint64(to),
+		"canonical":  hexutil.Uint64(stats.Canonical),
+		"uncles":     hexutil.Uint64(stats.Uncles),
+		"lost":       hexutil.Uint64(stats.Lost),
+		"orphanRate": stats.OrphanRate(),
+		"reward":     (*hexutil.Big)(stats.Reward),
+		"fees":       (*hexutil.Big)(stats.Fees),
+		"gasUsed":    hexutil.Uint64(stats.GasUsed),
+		"blocks":     blocks,
+	}, nil
+}
+
+// resolveNumber converts an RPC block number into an absolute one, resolving
+// the latest and pending tags to the current head.
+func (api *PrivateMinerAPI) resolveNumber(number rpc.BlockNumber) uint64 {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return api.e.blockchain.CurrentBlock().NumberU64()
+	}
+	return uint64(number.Int64())
+}
+
+// PrivatePoolAPI provides private RPC methods to inspect the share accounting
+// and the payout ledger of the mining pool.
+type PrivatePoolAPI struct {
+	pool *miner.Pool
+}
+
+// NewPrivatePoolAPI creates a new RPC service exposing the mining pool ledger.
+func NewPrivatePoolAPI(pool *miner.Pool) *PrivatePoolAPI {
+	return &PrivatePoolAPI{pool: pool}
+}
+
+// poolBalance converts a ledger balance into its RPC representation.
+func poolBalance(balance *miner.PoolBalance) map[string]interface{} {
+	return map[string]interface{}{
+		"unpaid": (*hexutil.Big)(balance.Unpaid),
+		"paid":   (*hexutil.Big)(balance.Paid),
+	}
+}
+
+// GetBalance returns the unpaid and paid ledger balance of an address.
+func (api *PrivatePoolAPI) GetBalance(addr common.Address) map[string]interface{} {
+	return poolBalance(api.pool.Balance(addr))
+}
+
+// GetBalances returns the ledger balances of all pool members.
+func (api *PrivatePoolAPI) GetBalances() map[common.Address]interface{} {
+	balances := make(map[common.Address]interface{})
+	for addr, balance := range api.pool.Balances() {
+		balances[addr] = poolBalance(balance)
+	}
+	return balances
+}
+
+// GetRound returns the share difficulty submitted by each member since the
+// last block found by the pool.
+func (api *PrivatePoolAPI) GetRound() map[common.Address]*hexutil.Big {
+	round := make(map[common.Address]*hexutil.Big)
+	for addr, diff := range api.pool.Round() {
+		round[addr] = (*hexutil.Big)(diff)
+	}
+	return round
+}
+
+// GetBlocks returns the last count blocks whose rewards were split between the
+// pool members, newest first.
+func (api *PrivatePoolAPI) GetBlocks(count hexutil.Uint64) []map[string]interface{} {
+	blocks := make([]map[string]interface{}, 0)
+	for _, block := range api.pool.Blocks(uint64(count)) {
+		credits := make(map[common.Address]*hexutil.Big)
+		for _, credit := range block.Credits {
+			credits[credit.Address] = (*hexutil.Big)(credit.Amount)
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"number":  hexutil.Uint64(block.Number),
+			"hash":    block.Hash,
+			"status":  block.Status,
+			"reward":  (*hexutil.Big)(block.Reward),
+			"fee":     (*hexutil.Big)(block.Fee),
+			"credits": credits,
+			"time":    hexutil.Uint64(block.Time),
+		})
+	}
+	return blocks
+}
+
+// GetPayments returns the last count payout transactions, newest first.
+func (api *PrivatePoolAPI) GetPayments(count hexutil.Uint64) []map[string]interface{} {
+	payments := make([]map[string]interface{}, 0)
+	for _, payment := range api.pool.Payments(uint64(count)) {
+		payments = append(payments, map[string]interface{}{
+			"address": payment.Address,
+			"amount":  (*hexutil.Big)(payment.Amount),
+			"txHash":  payment.TxHash,
+			"time":    hexutil.Uint64(payment.Time),
+		})
+	}
+	return payments
+}
+
+// Payout pays out every balance above the payout threshold immediately,
+// returning the hashes of the issued transactions.
+func (api *PrivatePoolAPI) Payout() ([]common.Hash, error) {
+	return api.pool.Payout()
+}
+
+// PrivateTxPoolAPI provides private RPC methods to manage the account tiers of
+// the transaction pool.
+type PrivateTxPoolAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateTxPoolAPI creates a new RPC service which manages the transaction pool.
+func NewPrivateTxPoolAPI(e *Ethereum) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{e: e}
+}
+
+// AddPriority grants the larger allowances of the priority tier to an account.
+func (api *PrivateTxPoolAPI) AddPriority(addr common.Address) bool {
+	api.e.TxPool().AddPriority(addr)
+	return true
+}
+
+// RemovePriority moves an account back into the default tier.
+func (api *PrivateTxPoolAPI) RemovePriority(addr common.Address) bool {
+	api.e.TxPool().RemovePriority(addr)
+	return true
+}
+
+// Priorities returns the accounts currently granted the priority tier.
+func (api *PrivateTxPoolAPI) Priorities() []common.Address {
+	return api.e.TxPool().Priorities()
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -248,6 +409,50 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// AddWhitelistedBlock requires the canonical chain to contain the given block
+// hash at the given height. Peers disagreeing are dropped and the local chain is
+// rewound if it is on a different branch.
+func (api *PrivateAdminAPI) AddWhitelistedBlock(number hexutil.Uint64, hash common.Hash) (bool, error) {
+	if err := api.eth.BlockChain().AddWhitelistedBlock(uint64(number), hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveWhitelistedBlock drops the whitelisted hash at the given height.
+func (api *PrivateAdminAPI) RemoveWhitelistedBlock(number hexutil.Uint64) bool {
+	return api.eth.BlockChain().RemoveWhitelistedBlock(uint64(number))
+}
+
+// WhitelistedBlocks returns the block hashes the canonical chain must contain,
+// keyed by block number.
+func (api *PrivateAdminAPI) WhitelistedBlocks() map[hexutil.Uint64]common.Hash {
+	whitelist := make(map[hexutil.Uint64]common.Hash)
+	for number, hash := range api.eth.BlockChain().Whitelist() {
+		whitelist[hexutil.Uint64(number)] = hash
+	}
+	return whitelist
+}
+
+// AddBlacklistedBlock marks a block and all its descendants as invalid so they
+// are never imported, rewinding the local chain if it contains the block.
+func (api *PrivateAdminAPI) AddBlacklistedBlock(hash common.Hash) (bool, error) {
+	if err := api.eth.BlockChain().AddBlacklistedBlock(hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveBlacklistedBlock drops a block hash from the blacklist.
+func (api *PrivateAdminAPI) RemoveBlacklistedBlock(hash common.Hash) bool {
+	return api.eth.BlockChain().RemoveBlacklistedBlock(hash)
+}
+
+// BlacklistedBlocks returns the hashes of the blocks that are never imported.
+func (api *PrivateAdminAPI) BlacklistedBlocks() []common.Hash {
+	return api.eth.BlockChain().Blacklist()
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
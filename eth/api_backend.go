@@ -18,6 +18,7 @@ package eth
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/accounts"
@@ -83,7 +84,11 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+	block := b.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	if tail := b.eth.blockchain.HistoryTail(); block == nil && uint64(blockNr) < tail {
+		return nil, fmt.Errorf("block #%d unavailable, chain was synced from checkpoint #%d", blockNr, tail)
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
@@ -177,6 +182,14 @@ func (b *EthAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (
 	return b.eth.txPool.State().GetNonce(addr), nil
 }
 
+func (b *EthAPIBackend) GetPoolStatus(hashes []common.Hash) []core.TxStatus {
+	return b.eth.txPool.Status(hashes)
+}
+
+func (b *EthAPIBackend) GetPoolPriceBump() uint64 {
+	return b.eth.txPool.PriceBump()
+}
+
 func (b *EthAPIBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats()
 }
@@ -189,6 +202,14 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeDroppedTxsEvent(ch)
+}
+
+func (b *EthAPIBackend) GetDroppedTransaction(hash common.Hash) *core.DroppedTx {
+	return b.eth.txPool.Dropped(hash)
+}
+
 func (b *EthAPIBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -22,8 +22,13 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/vm"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -88,3 +93,50 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the priority tier of the transaction pool is managed through the
+// private txpool namespace.
+func TestTxPoolPriorities(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		account = common.Address{0x01}
+	)
+	gspec.MustCommit(db)
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+
+	pool := core.NewTxPool(config, gspec.Config, blockchain)
+	defer pool.Stop()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("txpool", NewPrivateTxPoolAPI(&Ethereum{txPool: pool})); err != nil {
+		t.Fatalf("failed to register txpool API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var ok bool
+	if err := client.Call(&ok, "txpool_addPriority", account); err != nil || !ok {
+		t.Fatalf("failed to add priority account: %v", err)
+	}
+	var priorities []common.Address
+	if err := client.Call(&priorities, "txpool_priorities"); err != nil {
+		t.Fatalf("failed to retrieve priority accounts: %v", err)
+	}
+	if len(priorities) != 1 || priorities[0] != account {
+		t.Fatalf("priority accounts mismatch: have %v, want [%x]", priorities, account)
+	}
+	if err := client.Call(&ok, "txpool_removePriority", account); err != nil || !ok {
+		t.Fatalf("failed to remove priority account: %v", err)
+	}
+	if len(pool.Priorities()) != 0 {
+		t.Fatalf("priority accounts not cleared: %v", pool.Priorities())
+	}
+}
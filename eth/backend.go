@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/accounts"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
@@ -37,8 +38,10 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/vm"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/downloader"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/ethgrpc"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/filters"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/gasprice"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/snap"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/internal/ethapi"
@@ -46,9 +49,12 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/miner"
 	"git.pirl.io/bitcoiin/go-bitcoiin/node"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/dnsdisc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc/grpc"
 )
 
 type LesServer interface {
@@ -71,6 +77,7 @@ type Ethereum struct {
 	blockchain      *core.BlockChain
 	protocolManager *ProtocolManager
 	lesServer       LesServer
+	dialCandidates  *dnsdisc.Client // DNS discovery lists, nil if none configured
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
@@ -85,6 +92,8 @@ type Ethereum struct {
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
+	stats     *miner.Stats
+	pool      *miner.Pool
 	gasPrice  *big.Int
 	etherbase common.Address
 
@@ -139,6 +148,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 	}
 
+	if engine, ok := eth.engine.(*ethash.Ethash); ok {
+		if err := engine.StartStratum(); err != nil {
+			engine.Close()
+			return nil, fmt.Errorf("failed to start stratum server: %v", err)
+		}
+	}
 	log.Info("Initialising Ethereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
 	if !config.SkipBcVersionCheck {
@@ -168,19 +183,59 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	// Persist any whitelisted blocks, rewinding if we're on a different branch
+	for number, hash := range config.Whitelist {
+		if err := eth.blockchain.AddWhitelistedBlock(number, hash); err != nil {
+			return nil, fmt.Errorf("invalid whitelist entry #%d: %v", number, err)
+		}
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = ctx.ResolvePath(config.TxPool.Snapshot)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
 
-	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, config.Whitelist); err != nil {
+	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if config.SyncFrom != nil {
+		if eth.blockchain.CurrentBlock().NumberU64() > 0 {
+			log.Warn("Blockchain not empty, checkpoint sync disabled", "checkpoint", config.SyncFrom)
+		} else {
+			eth.protocolManager.syncFrom = config.SyncFrom
+		}
+	}
 
-	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, config.MinerRecommit, config.MinerGasFloor, config.MinerGasCeil, eth.isLocalBlock)
+	if len(config.DiscoveryURLs) > 0 {
+		if eth.dialCandidates, err = dnsdisc.NewClient(dnsdisc.Config{}, config.DiscoveryURLs...); err != nil {
+			return nil, err
+		}
+	}
+	minerConfig := &miner.Config{
+		Recommit:    config.MinerRecommit,
+		GasFloor:    config.MinerGasFloor,
+		GasCeil:     config.MinerGasCeil,
+		TxOrder:     config.MinerTxOrder,
+		TxAllowlist: config.MinerAllowlist,
+	}
+	if eth.miner, err = miner.New(eth, minerConfig, eth.chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock); err != nil {
+		return nil, err
+	}
 	eth.miner.SetExtra(makeExtraData(config.MinerExtraData))
+	eth.stats = miner.NewStats(eth.miner, chainDb, eth.blockchain)
+
+	if config.MinerPool.Enabled {
+		if config.Ethash.StratumAddr == "" {
+			log.Warn("Mining pool enabled without a stratum server, no shares will be accounted")
+		}
+		if eth.pool, err = miner.NewPool(config.MinerPool, eth.miner, eth.engine, chainDb, eth.blockchain, eth.txPool, eth.accountManager, eth.Etherbase, config.MinerGasPrice); err != nil {
+			return nil, err
+		}
+	}
 
 	eth.APIBackend = &EthAPIBackend{eth, nil}
 	gpoParams := config.GPO
@@ -246,6 +301,12 @@ func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainCo
 			DatasetDir:     config.DatasetDir,
 			DatasetsInMem:  config.DatasetsInMem,
 			DatasetsOnDisk: config.DatasetsOnDisk,
+
+			StratumAddr:      config.StratumAddr,
+			StratumShareDiff: config.StratumShareDiff,
+
+			CPUAffinity: config.CPUAffinity,
+			HugePages:   config.HugePages,
 		}, notify, noverify)
 		engine.SetThreads(-1) // Disable CPU mining
 		return engine
@@ -260,6 +321,15 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the mining pool APIs if share accounting is enabled
+	if s.pool != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "pool",
+			Version:   "1.0",
+			Service:   NewPrivatePoolAPI(s.pool),
+		})
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -282,11 +352,21 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateMinerAPI(s),
 			Public:    false,
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(s),
+			Public:    false,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
 			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   gasprice.NewPublicGasPriceAPI(s.APIBackend.gpo),
+			Public:    true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -309,6 +389,12 @@ func (s *Ethereum) APIs() []rpc.API {
 	}...)
 }
 
+// GRPCServices implements node.GRPCService, returning the gRPC services served
+// from the same backend as the RPC APIs.
+func (s *Ethereum) GRPCServices() []grpc.Service {
+	return []grpc.Service{ethgrpc.NewService(s.APIBackend, false)}
+}
+
 func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
 	s.blockchain.ResetWithGenesisBlock(gb)
 }
@@ -473,18 +559,50 @@ func (s *Ethereum) EthVersion() int                    { return int(s.protocolMa
 func (s *Ethereum) NetVersion() uint64                 { return s.networkID }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// HeadTime implements node.ChainStatusReporter, returning the timestamp of the
+// current head header.
+func (s *Ethereum) HeadTime() time.Time {
+	return time.Unix(int64(s.blockchain.CurrentHeader().Time), 0)
+}
+
+// Syncing implements node.ChainStatusReporter, reporting whether the initial
+// synchronisation hasn't completed yet or the downloader is currently running.
+func (s *Ethereum) Syncing() bool {
+	if atomic.LoadUint32(&s.protocolManager.acceptTxs) == 0 {
+		return true
+	}
+	return s.protocolManager.downloader.Synchronising()
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
-	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+	protos := make([]p2p.Protocol, len(s.protocolManager.SubProtocols))
+	for i, proto := range s.protocolManager.SubProtocols {
+		protos[i] = proto
+		protos[i].Attributes = []enr.Entry{s.currentEthEntry()}
+		protos[i].NodeFilter = ethNodeFilter(s.protocolManager.forkFilter)
+	}
+	// The dialer draws candidates from every protocol's source, so hand the shared
+	// one out only once to avoid dialing the same nodes for each eth version.
+	if s.dialCandidates != nil && len(protos) > 0 {
+		protos[0].DialCandidates = s.dialCandidates
+	}
+	// The snap protocol only serves and retrieves state, it doesn't need the eth
+	// record entry nor the dial candidates.
+	protos = append(protos, snap.MakeProtocols(s.blockchain.StateCache(), s.protocolManager.downloader.SnapSyncer())...)
+	if s.lesServer != nil {
+		protos = append(protos, s.lesServer.Protocols()...)
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+	return protos
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
 // Ethereum protocol implementation.
 func (s *Ethereum) Start(srvr *p2p.Server) error {
+	// Keep the fork ID of the local node record up to date
+	s.startEthEntryUpdate(srvr.LocalNode())
+
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
@@ -504,12 +622,23 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.dialCandidates != nil {
+		s.dialCandidates.Start()
+	}
+	s.stats.Start()
+	if s.pool != nil {
+		s.pool.Start()
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
+	if s.pool != nil {
+		s.pool.Stop()
+	}
+	s.stats.Stop()
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
@@ -517,6 +646,9 @@ func (s *Ethereum) Stop() error {
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
+	if s.dialCandidates != nil {
+		s.dialCandidates.Stop()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.eventMux.Stop()
@@ -30,6 +30,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/core"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/downloader"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/gasprice"
+	"git.pirl.io/bitcoiin/go-bitcoiin/miner"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
 )
 
@@ -53,6 +54,7 @@ var DefaultConfig = Config{
 	MinerGasCeil:   8000000,
 	MinerGasPrice:  big.NewInt(params.GWei),
 	MinerRecommit:  3 * time.Second,
+	MinerPool:      miner.DefaultPoolConfig,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
@@ -90,6 +92,12 @@ type Config struct {
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Trusted block to initialize an empty chain from instead of syncing from genesis
+	SyncFrom *downloader.SyncCheckpoint `toml:",omitempty"`
+
+	// DNS discovery lists (enrtree:// URLs) to find peers from
+	DiscoveryURLs []string `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
@@ -111,6 +119,9 @@ type Config struct {
 	MinerGasPrice  *big.Int
 	MinerRecommit  time.Duration
 	MinerNoverify  bool
+	MinerTxOrder   string           `toml:",omitempty"`
+	MinerAllowlist []common.Address `toml:",omitempty"`
+	MinerPool      miner.PoolConfig
 
 	// Ethash options
 	Ethash ethash.Config
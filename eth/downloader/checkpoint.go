@@ -0,0 +1,408 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+)
+
+const (
+	// checkpointAncestors is the number of headers retrieved before a sync checkpoint,
+	// which is enough to serve the BLOCKHASH opcode of the blocks after it.
+	checkpointAncestors = 256
+
+	// checkpointUncleDepth is the number of ancestors of a sync checkpoint whose
+	// bodies are retrieved too, which is enough to validate the uncles of the
+	// blocks after it.
+	checkpointUncleDepth = 7
+)
+
+var errCheckpointMismatch = errors.New("checkpoint mismatch")
+
+// SyncCheckpoint is a trusted block to start a new chain from instead of genesis.
+// The total difficulty of the block can't be verified without the entire header
+// chain. If it is not given, it is derived from the head advertised by the peer
+// serving the checkpoint, which has to be trusted for it then.
+type SyncCheckpoint struct {
+	Hash   common.Hash
+	Number uint64
+	Td     *big.Int // Trusted total difficulty of the block (nil = ask the peer)
+}
+
+// ParseSyncCheckpoint parses a checkpoint in the HASH@NUMBER format, optionally
+// followed by the trusted total difficulty as HASH@NUMBER@TD.
+func ParseSyncCheckpoint(s string) (*SyncCheckpoint, error) {
+	parts := strings.Split(s, "@")
+	if len(parts) != 2 && len(parts) != 3 {
+		return nil, fmt.Errorf("invalid checkpoint %q, want HASH@NUMBER[@TD]", s)
+	}
+	hash, number := strings.TrimPrefix(parts[0], "0x"), parts[1]
+	if len(hash) != 2*common.HashLength {
+		return nil, fmt.Errorf("invalid checkpoint hash %q", parts[0])
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint hash %q", parts[0])
+	}
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil || n == 0 {
+		return nil, fmt.Errorf("invalid checkpoint number %q", number)
+	}
+	cp := &SyncCheckpoint{Hash: common.HexToHash(hash), Number: n}
+	if len(parts) == 3 {
+		td, ok := new(big.Int).SetString(parts[2], 10)
+		if !ok || td.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid checkpoint total difficulty %q", parts[2])
+		}
+		cp.Td = td
+	}
+	return cp, nil
+}
+
+// String implements the stringer interface.
+func (cp *SyncCheckpoint) String() string {
+	if cp.Td == nil {
+		return fmt.Sprintf("%#x@%d", cp.Hash, cp.Number)
+	}
+	return fmt.Sprintf("%#x@%d@%v", cp.Hash, cp.Number, cp.Td)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (cp SyncCheckpoint) MarshalText() ([]byte, error) {
+	return []byte(cp.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (cp *SyncCheckpoint) UnmarshalText(text []byte) error {
+	parsed, err := ParseSyncCheckpoint(string(text))
+	if err != nil {
+		return err
+	}
+	*cp = *parsed
+	return nil
+}
+
+// SyncCheckpoint initializes the empty local chain from a trusted checkpoint,
+// retrieved from the given peer. Only the checkpoint block with its receipts and
+// state, the headers of its direct ancestors and the bodies of the few needed to
+// validate uncles are downloaded, the history before it stays unavailable.
+// Afterwards, the chain can be synced as usual.
+func (d *Downloader) SyncCheckpoint(id string, cp *SyncCheckpoint, snapSync bool) error {
+	err := d.syncCheckpoint(id, cp, snapSync)
+	switch err {
+	case nil, errBusy:
+
+	case errTimeout, errBadPeer, errUnsyncedPeer, errInvalidChain, errInvalidBody,
+		errInvalidReceipt, errCheckpointMismatch:
+		log.Warn("Checkpoint sync failed, dropping peer", "peer", id, "err", err)
+		if d.dropPeer != nil {
+			d.dropPeer(id)
+		}
+	default:
+		log.Warn("Checkpoint sync failed, retrying", "err", err)
+	}
+	return err
+}
+
+func (d *Downloader) syncCheckpoint(id string, cp *SyncCheckpoint, snapSync bool) (err error) {
+	if !atomic.CompareAndSwapInt32(&d.synchronising, 0, 1) {
+		return errBusy
+	}
+	defer atomic.StoreInt32(&d.synchronising, 0)
+
+	for _, ch := range []chan dataPack{d.headerCh, d.bodyCh, d.receiptCh} {
+		for empty := false; !empty; {
+			select {
+			case <-ch:
+			default:
+				empty = true
+			}
+		}
+	}
+	d.cancelLock.Lock()
+	d.cancelCh = make(chan struct{})
+	d.cancelPeer = id
+	d.cancelLock.Unlock()
+
+	defer d.Cancel() // No matter what, we can't leave the cancel channel open
+
+	d.snapSync = snapSync
+	p := d.peers.Peer(id)
+	if p == nil {
+		return errUnknownPeer
+	}
+	d.mux.Post(StartEvent{})
+	defer func() {
+		if err != nil {
+			d.mux.Post(FailedEvent{err})
+		} else {
+			d.mux.Post(DoneEvent{})
+		}
+	}()
+	log.Info("Syncing from checkpoint", "peer", id, "checkpoint", cp)
+
+	// A peer claiming less work than the checkpoint can't have it on its chain
+	if _, td := p.peer.Head(); cp.Td != nil && td.Cmp(cp.Td) < 0 {
+		return errUnsyncedPeer
+	}
+	headers, err := d.fetchCheckpointHeaders(p, cp)
+	if err != nil {
+		return err
+	}
+	td := cp.Td
+	if td == nil {
+		if td, err = d.fetchCheckpointTd(p, headers[0]); err != nil {
+			return err
+		}
+	}
+	depth := checkpointUncleDepth + 1
+	if depth > len(headers) {
+		depth = len(headers)
+	}
+	bodies, err := d.fetchCheckpointBodies(p, headers[:depth])
+	if err != nil {
+		return err
+	}
+	block := types.NewBlockWithHeader(headers[0]).WithBody(bodies[0].Transactions, bodies[0].Uncles)
+	receipts, err := d.fetchCheckpointReceipts(p, headers[0])
+	if err != nil {
+		return err
+	}
+	sync := d.syncState(block.Root())
+	if err := sync.Wait(); err != nil {
+		return err
+	}
+	return d.blockchain.InsertCheckpoint(block, receipts, headers[1:], bodies[1:], td)
+}
+
+// fetchCheckpointHeaders retrieves the checkpoint header and its ancestors in
+// descending order, verifying that they are linked by their hashes.
+func (d *Downloader) fetchCheckpointHeaders(p *peerConnection, cp *SyncCheckpoint) ([]*types.Header, error) {
+	var (
+		headers []*types.Header
+		next    = cp.Hash
+		want    = int(cp.Number)
+	)
+	if want > checkpointAncestors+1 {
+		want = checkpointAncestors + 1
+	}
+	for len(headers) < want {
+		amount := want - len(headers)
+		if amount > MaxHeaderFetch {
+			amount = MaxHeaderFetch
+		}
+		batch, err := d.requestHeaders(p, func() error { return p.peer.RequestHeadersByHash(next, amount, 0, true) })
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 && len(headers) == 0 {
+			// The peer doesn't know the checkpoint, it's on another chain
+			return nil, errCheckpointMismatch
+		}
+		if len(batch) == 0 || len(batch) > amount {
+			return nil, errBadPeer
+		}
+		for _, header := range batch {
+			if header.Hash() != next {
+				return nil, errInvalidChain
+			}
+			if len(headers) == 0 && header.Number.Uint64() != cp.Number {
+				return nil, errCheckpointMismatch
+			}
+			if len(headers) > 0 && header.Number.Uint64()+1 != headers[len(headers)-1].Number.Uint64() {
+				return nil, errInvalidChain
+			}
+			headers = append(headers, header)
+			next = header.ParentHash
+		}
+	}
+	// If the ancestors reach back to the genesis, make sure it's ours
+	if last := headers[len(headers)-1]; last.Number.Uint64() == 1 && d.lightchain.GetHeaderByHash(last.ParentHash) == nil {
+		return nil, errCheckpointMismatch
+	}
+	return headers, nil
+}
+
+// fetchCheckpointTd derives the total difficulty of the checkpoint from the
+// advertised head of the peer, by subtracting the difficulties of the headers
+// between the checkpoint and the head.
+func (d *Downloader) fetchCheckpointTd(p *peerConnection, checkpoint *types.Header) (*big.Int, error) {
+	hash, td := p.peer.Head()
+	if hash == checkpoint.Hash() {
+		return new(big.Int).Set(td), nil
+	}
+	head, err := d.requestHeaders(p, func() error { return p.peer.RequestHeadersByHash(hash, 1, 0, false) })
+	if err != nil {
+		return nil, err
+	}
+	if len(head) != 1 || head[0].Hash() != hash {
+		return nil, errBadPeer
+	}
+	if head[0].Number.Uint64() <= checkpoint.Number.Uint64() {
+		return nil, errUnsyncedPeer
+	}
+	var (
+		cpTd = new(big.Int).Set(td)
+		prev = checkpoint
+	)
+	for prev.Hash() != hash {
+		from := prev.Number.Uint64() + 1
+		amount := head[0].Number.Uint64() - prev.Number.Uint64()
+		if amount > uint64(MaxHeaderFetch) {
+			amount = uint64(MaxHeaderFetch)
+		}
+		batch, err := d.requestHeaders(p, func() error { return p.peer.RequestHeadersByNumber(from, int(amount), 0, false) })
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 || uint64(len(batch)) > amount {
+			return nil, errBadPeer
+		}
+		for _, header := range batch {
+			if header.ParentHash != prev.Hash() {
+				// The peer may have reorged since announcing its head
+				return nil, errInvalidChain
+			}
+			cpTd.Sub(cpTd, header.Difficulty)
+			prev = header
+		}
+	}
+	if cpTd.Cmp(checkpoint.Difficulty) < 0 {
+		return nil, errBadPeer
+	}
+	return cpTd, nil
+}
+
+// fetchCheckpointBodies retrieves the bodies of the given headers, verifying
+// them against the transaction and uncle hashes.
+func (d *Downloader) fetchCheckpointBodies(p *peerConnection, headers []*types.Header) ([]*types.Body, error) {
+	hashes := make([]common.Hash, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash()
+	}
+	go p.peer.RequestBodies(hashes)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.bodyCh:
+			if packet.PeerId() != p.id {
+				break
+			}
+			pack := packet.(*bodyPack)
+			if len(pack.transactions) != len(headers) || len(pack.uncles) != len(headers) {
+				return nil, errBadPeer
+			}
+			bodies := make([]*types.Body, len(headers))
+			for i, header := range headers {
+				txs, uncles := types.Transactions(pack.transactions[i]), pack.uncles[i]
+				if types.DeriveSha(txs) != header.TxHash || types.CalcUncleHash(uncles) != header.UncleHash {
+					return nil, errInvalidBody
+				}
+				bodies[i] = &types.Body{Transactions: txs, Uncles: uncles}
+			}
+			return bodies, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint bodies timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.headerCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
+// fetchCheckpointReceipts retrieves the receipts of the checkpoint block.
+func (d *Downloader) fetchCheckpointReceipts(p *peerConnection, header *types.Header) (types.Receipts, error) {
+	go p.peer.RequestReceipts([]common.Hash{header.Hash()})
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelReceiptFetch
+
+		case packet := <-d.receiptCh:
+			if packet.PeerId() != p.id {
+				break
+			}
+			pack := packet.(*receiptPack)
+			if len(pack.receipts) != 1 {
+				return nil, errBadPeer
+			}
+			if types.DeriveSha(types.Receipts(pack.receipts[0])) != header.ReceiptHash {
+				return nil, errInvalidReceipt
+			}
+			return types.Receipts(pack.receipts[0]), nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint receipts timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.headerCh:
+		case <-d.bodyCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
+// requestHeaders sends a header request to the peer and waits for its response.
+func (d *Downloader) requestHeaders(p *peerConnection, request func() error) ([]*types.Header, error) {
+	go request()
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			return packet.(*headerPack).headers, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for headers timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
@@ -29,11 +29,13 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/rawdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/snap"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	opentracing "github.com/opentracing/opentracing-go"
 )
 
 var (
@@ -106,6 +108,9 @@ type Downloader struct {
 	peers      *peerSet // Set of active peers from which download can proceed
 	stateDB    ethdb.Database
 
+	snapSync bool         // Whether to retrieve the state in ranges during fast sync (per sync cycle)
+	snap     *snap.Syncer // Range based state syncer for the snap protocol
+
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
@@ -204,6 +209,9 @@ type BlockChain interface {
 
 	// InsertReceiptChain inserts a batch of receipts into the local chain.
 	InsertReceiptChain(types.Blocks, []types.Receipts) (int, error)
+
+	// InsertCheckpoint initializes the empty local chain from a checkpoint block.
+	InsertCheckpoint(*types.Block, types.Receipts, []*types.Header, []*types.Body, *big.Int) error
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
@@ -237,11 +245,18 @@ func New(mode SyncMode, checkpoint uint64, stateDb ethdb.Database, mux *event.Ty
 		},
 		trackStateReq: make(chan *stateReq),
 	}
+	dl.snap = snap.NewSyncer(stateDb, func(id string) { dl.dropPeer(id) })
 	go dl.qosTuner()
 	go dl.stateFetcher()
 	return dl
 }
 
+// SnapSyncer returns the range based state syncer, which the snap protocol
+// handler delivers the peers and responses to.
+func (d *Downloader) SnapSyncer() *snap.Syncer {
+	return d.snap
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -397,7 +412,12 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 
 	defer d.Cancel() // No matter what, we can't leave the cancel channel open
 
-	// Set the requested sync mode, unless it's forbidden
+	// Set the requested sync mode, unless it's forbidden. Snap sync runs the fast
+	// sync pipeline, only retrieving the state in ranges instead of node by node.
+	d.snapSync = mode == SnapSync
+	if d.snapSync {
+		mode = FastSync
+	}
 	d.mode = mode
 
 	// Retrieve the origin peer and initiate the downloading process
@@ -429,17 +449,37 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		log.Debug("Synchronisation terminated", "elapsed", time.Since(start))
 	}(time.Now())
 
+	span := opentracing.StartSpan("downloader.sync")
+	span.SetTag("peer", p.id)
+	span.SetTag("mode", d.mode.String())
+	defer func() {
+		if err != nil {
+			span.SetTag("error", true)
+			span.LogKV("err", err)
+		}
+		span.Finish()
+	}()
 	// Look up the sync boundaries: the common ancestor and the target block
-	latest, err := d.fetchHeight(p)
+	var latest *types.Header
+	err = traceStage(span, "fetchHeight", func() (err error) {
+		latest, err = d.fetchHeight(p)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	height := latest.Number.Uint64()
 
-	origin, err := d.findAncestor(p, latest)
+	var origin uint64
+	err = traceStage(span, "findAncestor", func() (err error) {
+		origin, err = d.findAncestor(p, latest)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	span.SetTag("origin", origin)
+	span.SetTag("height", height)
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
 		d.syncStatsChainOrigin = origin
@@ -470,19 +510,41 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 
 	fetchers := []func() error{
-		func() error { return d.fetchHeaders(p, origin+1, pivot) }, // Headers are always retrieved
-		func() error { return d.fetchBodies(origin + 1) },          // Bodies are retrieved during normal and fast sync
-		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
-		func() error { return d.processHeaders(origin+1, pivot, td) },
+		tracedStage(span, "fetchHeaders", func() error { return d.fetchHeaders(p, origin+1, pivot) }), // Headers are always retrieved
+		tracedStage(span, "fetchBodies", func() error { return d.fetchBodies(origin + 1) }),           // Bodies are retrieved during normal and fast sync
+		tracedStage(span, "fetchReceipts", func() error { return d.fetchReceipts(origin + 1) }),       // Receipts are retrieved during fast sync
+		tracedStage(span, "processHeaders", func() error { return d.processHeaders(origin+1, pivot, td) }),
 	}
 	if d.mode == FastSync {
-		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
+		fetchers = append(fetchers, tracedStage(span, "processFastSyncContent", func() error { return d.processFastSyncContent(latest) }))
 	} else if d.mode == FullSync {
-		fetchers = append(fetchers, d.processFullSyncContent)
+		fetchers = append(fetchers, tracedStage(span, "processFullSyncContent", d.processFullSyncContent))
 	}
 	return d.spawnSync(fetchers)
 }
 
+// traceStage runs a single phase of a synchronisation cycle within a child span
+// of the cycle's tracing span.
+func traceStage(parent opentracing.Span, name string, fn func() error) error {
+	return tracedStage(parent, name, fn)()
+}
+
+// tracedStage wraps a phase of a synchronisation cycle so that its execution is
+// recorded in a child span of the cycle's tracing span.
+func tracedStage(parent opentracing.Span, name string, fn func() error) func() error {
+	return func() error {
+		span := opentracing.StartSpan("downloader."+name, opentracing.ChildOf(parent.Context()))
+		defer span.Finish()
+
+		err := fn()
+		if err != nil {
+			span.SetTag("error", true)
+			span.LogKV("err", err)
+		}
+		return err
+	}
+}
+
 // spawnSync runs d.process and all given fetcher functions to completion in
 // separate goroutines, returning the first error that appears.
 func (d *Downloader) spawnSync(fetchers []func() error) error {
@@ -1511,15 +1573,19 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 // processFastSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processFastSyncContent(latest *types.Header) error {
+	// Abort the result processing if a state sync fails. The sync is passed in, as
+	// the pivot (and with it the running sync) may change in the meantime.
+	closeOnErr := func(s *stateSync) {
+		if err := s.Wait(); err != nil && err != errCancelStateFetch {
+			d.queue.Close() // wake up Results
+		}
+	}
 	// Start syncing state of the reported head block. This should get us most of
 	// the state of the pivot block.
 	stateSync := d.syncState(latest.Root)
-	defer stateSync.Cancel()
-	go func() {
-		if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
-			d.queue.Close() // wake up Results
-		}
-	}()
+	defer func() { stateSync.Cancel() }()
+	go closeOnErr(stateSync)
+
 	// Figure out the ideal pivot block. Note, that this goalpost may move if the
 	// sync takes long enough for the chain head to move significantly.
 	pivot := uint64(0)
@@ -1572,12 +1638,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 				stateSync.Cancel()
 
 				stateSync = d.syncState(P.Header.Root)
-				defer stateSync.Cancel()
-				go func() {
-					if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
-						d.queue.Close() // wake up Results
-					}
-				}()
+				go closeOnErr(stateSync)
 				oldPivot = P
 			}
 			// Wait for completion, occasionally checking for pivot staleness
@@ -28,9 +28,14 @@ import (
 
 	"git.pirl.io/bitcoiin/go-bitcoiin"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/snap"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
 	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
 )
 
@@ -192,6 +197,42 @@ func (dl *downloadTester) FastSyncCommitHead(hash common.Hash) error {
 	return fmt.Errorf("non existent block: %x", hash[:4])
 }
 
+// InsertCheckpoint initializes the simulated chain from a checkpoint block.
+func (dl *downloadTester) InsertCheckpoint(block *types.Block, receipts types.Receipts, headers []*types.Header, bodies []*types.Body, td *big.Int) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if len(dl.ownHashes) != 1 {
+		return errors.New("chain not empty")
+	}
+	if _, err := trie.NewSecure(block.Root(), trie.NewDatabase(dl.stateDb), 0); err != nil {
+		return err
+	}
+	childTd := td
+	for i := len(headers) - 1; i >= 0; i-- {
+		dl.ownHashes = append(dl.ownHashes, headers[i].Hash())
+		dl.ownHeaders[headers[i].Hash()] = headers[i]
+	}
+	for i, header := range headers {
+		parentTd := new(big.Int).Sub(childTd, block.Difficulty())
+		if i > 0 {
+			parentTd = new(big.Int).Sub(childTd, headers[i-1].Difficulty)
+		}
+		dl.ownChainTd[header.Hash()] = parentTd
+		childTd = parentTd
+
+		if i < len(bodies) {
+			dl.ownBlocks[header.Hash()] = types.NewBlockWithHeader(header).WithBody(bodies[i].Transactions, bodies[i].Uncles)
+		}
+	}
+	dl.ownHashes = append(dl.ownHashes, block.Hash())
+	dl.ownHeaders[block.Hash()] = block.Header()
+	dl.ownBlocks[block.Hash()] = block
+	dl.ownReceipts[block.Hash()] = receipts
+	dl.ownChainTd[block.Hash()] = td
+	return nil
+}
+
 // GetTd retrieves the block's total difficulty from the canonical chain.
 func (dl *downloadTester) GetTd(hash common.Hash, number uint64) *big.Int {
 	dl.lock.RLock()
@@ -296,6 +337,30 @@ func (dl *downloadTester) newPeer(id string, version int, chain *testChain) erro
 	return dl.downloader.RegisterPeer(id, version, peer)
 }
 
+// newSnapPeer registers a new block download source, which also serves the state
+// in ranges from the given database via the snap protocol.
+func (dl *downloadTester) newSnapPeer(id string, version int, chain *testChain, db state.Database) (*snapTesterPeer, error) {
+	if err := dl.newPeer(id, version, chain); err != nil {
+		return nil, err
+	}
+	var (
+		server        = snap.MakeProtocols(db, nil)[0]
+		client        = snap.MakeProtocols(nil, dl.downloader.SnapSyncer())[0]
+		local, remote = p2p.MsgPipe()
+		node          = enode.ID(crypto.Keccak256Hash([]byte(id)))
+		peer          = &snapTesterPeer{MsgReadWriter: remote, client: local}
+	)
+	go client.Run(p2p.NewPeer(node, id, nil), local)
+
+	// The client registers the peer before reading its first message, so wait
+	// for an unsolicited (and ignored) response to be consumed
+	if err := p2p.Send(remote, snap.AccountRangeMsg, &snap.AccountRangePacket{}); err != nil {
+		return nil, err
+	}
+	go server.Run(p2p.NewPeer(node, id, nil), peer)
+	return peer, nil
+}
+
 // dropPeer simulates a hard peer removal from the connection pool.
 func (dl *downloadTester) dropPeer(id string) {
 	dl.lock.Lock()
@@ -305,12 +370,35 @@ func (dl *downloadTester) dropPeer(id string) {
 	dl.downloader.UnregisterPeer(id)
 }
 
+// snapTesterPeer is the serving end of a snap connection to the downloader,
+// counting the account ranges requested from it.
+type snapTesterPeer struct {
+	p2p.MsgReadWriter
+	client *p2p.MsgPipeRW // Syncing end of the connection
+	ranges uint32         // Number of account ranges requested
+}
+
+// ReadMsg implements p2p.MsgReader, counting the account range requests.
+func (p *snapTesterPeer) ReadMsg() (p2p.Msg, error) {
+	msg, err := p.MsgReadWriter.ReadMsg()
+	if err == nil && msg.Code == snap.GetAccountRangeMsg {
+		atomic.AddUint32(&p.ranges, 1)
+	}
+	return msg, err
+}
+
+// close disconnects the snap peer from the downloader.
+func (p *snapTesterPeer) close() {
+	p.client.Close()
+}
+
 type downloadTesterPeer struct {
 	dl            *downloadTester
 	id            string
 	lock          sync.RWMutex
 	chain         *testChain
 	missingStates map[common.Hash]bool // State entries that fast sync should not return
+	stateRequests uint32               // Number of state entries requested from the peer
 }
 
 // Head constructs a function to retrieve a peer's current head hash
@@ -324,11 +412,10 @@ func (dlp *downloadTesterPeer) Head() (common.Hash, *big.Int) {
 // origin; associated with a particular peer in the download tester. The returned
 // function can be used to retrieve batches of headers from the particular peer.
 func (dlp *downloadTesterPeer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool) error {
+	result := dlp.chain.headersByHash(origin, amount, skip)
 	if reverse {
-		panic("reverse header requests not supported")
+		result = dlp.chain.headersByHashReverse(origin, amount, skip)
 	}
-
-	result := dlp.chain.headersByHash(origin, amount, skip)
 	go dlp.dl.downloader.DeliverHeaders(dlp.id, result)
 	return nil
 }
@@ -368,6 +455,8 @@ func (dlp *downloadTesterPeer) RequestReceipts(hashes []common.Hash) error {
 // peer in the download tester. The returned function can be used to retrieve
 // batches of node state data from the particularly requested peer.
 func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
+	atomic.AddUint32(&dlp.stateRequests, uint32(len(hashes)))
+
 	dlp.dl.lock.RLock()
 	defer dlp.dl.lock.RUnlock()
 
@@ -448,6 +537,80 @@ func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	assertOwnChain(t, tester, chain.len())
 }
 
+// Tests that snap sync retrieves the state in ranges from snap capable peers.
+// The state sync starts on the head block's root and moves to the pivot later,
+// so the difference between the two is healed node by node.
+func TestSnapSynchronisation(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	peer, err := tester.newSnapPeer("peer", 64, chain, state.NewDatabase(tester.peerDb))
+	if err != nil {
+		t.Fatalf("failed to register snap peer: %v", err)
+	}
+	defer peer.close()
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+	assertStateComplete(t, tester, chain)
+
+	if ranges := atomic.LoadUint32(&peer.ranges); ranges == 0 {
+		t.Fatalf("no account ranges requested from snap peer")
+	}
+}
+
+// Tests that state ranges a snap peer withholds are healed with the trie node
+// sync from the eth peers.
+func TestSnapSynchronisationHealing(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	peer, err := tester.newSnapPeer("peer", 64, chain, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to register snap peer: %v", err)
+	}
+	defer peer.close()
+
+	if err := tester.sync("peer", nil, SnapSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chain.len())
+	assertStateComplete(t, tester, chain)
+
+	if ranges := atomic.LoadUint32(&peer.ranges); ranges == 0 {
+		t.Fatalf("no account ranges requested from snap peer")
+	}
+	if requests := atomic.LoadUint32(&tester.peers["peer"].stateRequests); requests == 0 {
+		t.Fatalf("withheld state not healed from eth peers")
+	}
+}
+
+// assertStateComplete checks that every node of the state at the fast sync pivot
+// of the given chain is present in the tester's database.
+func assertStateComplete(t *testing.T, tester *downloadTester, chain *testChain) {
+	t.Helper()
+
+	pivot := chain.headerm[chain.chain[chain.len()-1-fsMinFullBlocks]]
+	statedb, err := state.New(pivot.Root, state.NewDatabase(tester.stateDb))
+	if err != nil {
+		t.Fatalf("pivot state %x missing: %v", pivot.Root, err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("pivot state %x incomplete: %v", pivot.Root, it.Error)
+	}
+}
+
 // Tests that if a large batch of blocks are being downloaded, it is throttled
 // until the cached blocks are retrieved.
 func TestThrottling62(t *testing.T)     { testThrottling(t, 62, FullSync) }
@@ -1605,3 +1768,137 @@ func testCheckpointEnforcement(t *testing.T, protocol int, mode SyncMode) {
 		assertOwnChain(t, tester, chain.len())
 	}
 }
+
+// Tests that an empty chain can be initialized from a trusted checkpoint, only
+// retrieving its direct ancestors, and synced onwards from there.
+// The total difficulty of the checkpoint is either trusted or derived from the
+// head of the serving peer.
+func TestCheckpointSync63(t *testing.T)       { testCheckpointSync(t, 63, true) }
+func TestCheckpointSync64(t *testing.T)       { testCheckpointSync(t, 64, true) }
+func TestCheckpointSyncPeerTd63(t *testing.T) { testCheckpointSync(t, 63, false) }
+func TestCheckpointSyncPeerTd64(t *testing.T) { testCheckpointSync(t, 64, false) }
+
+func testCheckpointSync(t *testing.T, protocol int, trustTd bool) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(blockCacheItems - 15)
+	tester.newPeer("peer", protocol, chain)
+
+	number := uint64(chain.len() / 2)
+	cp := &SyncCheckpoint{Hash: chain.chain[number], Number: number}
+	if trustTd {
+		cp.Td = chain.td(chain.chain[number])
+	}
+	if err := tester.downloader.SyncCheckpoint("peer", cp, false); err != nil {
+		t.Fatalf("checkpoint sync failed: %v", err)
+	}
+	if head := tester.CurrentBlock(); head.Hash() != cp.Hash {
+		t.Fatalf("head mismatch: have #%d [%x], want #%d [%x]", head.NumberU64(), head.Hash(), cp.Number, cp.Hash)
+	}
+	if td := tester.GetTd(cp.Hash, cp.Number); td == nil || td.Cmp(chain.td(cp.Hash)) != 0 {
+		t.Fatalf("checkpoint td mismatch: have %v, want %v", td, chain.td(cp.Hash))
+	}
+	parent := chain.chain[number-1]
+	if td := tester.GetTd(parent, number-1); td == nil || td.Cmp(chain.td(parent)) != 0 {
+		t.Fatalf("ancestor td mismatch: have %v, want %v", td, chain.td(parent))
+	}
+	// Genesis, the checkpoint and its ancestors should be the only local headers
+	if have, want := len(tester.ownHeaders), 1+1+checkpointAncestors; have != want {
+		t.Fatalf("header count mismatch: have %d, want %d", have, want)
+	}
+	// Besides genesis and the checkpoint, the bodies needed for uncle validation
+	// should be available
+	if have, want := len(tester.ownBlocks), 1+1+checkpointUncleDepth; have != want {
+		t.Fatalf("block count mismatch: have %d, want %d", have, want)
+	}
+	for i := uint64(1); i <= checkpointUncleDepth; i++ {
+		if _, ok := tester.ownBlocks[chain.chain[number-i]]; !ok {
+			t.Fatalf("body of ancestor #%d missing", number-i)
+		}
+	}
+	// Sync the rest of the chain on top of the checkpoint
+	if err := tester.sync("peer", nil, FullSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if head := tester.CurrentBlock(); head.Hash() != chain.headBlock().Hash() {
+		t.Fatalf("head mismatch after sync: have #%d, want #%d", head.NumberU64(), chain.headBlock().NumberU64())
+	}
+}
+
+// Tests that a peer not knowing the checkpoint is rejected.
+func TestCheckpointSyncMismatch(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(MaxHeaderFetch)
+	tester.newPeer("peer", 63, chain)
+
+	cp := &SyncCheckpoint{Hash: common.Hash{1}, Number: 10, Td: big.NewInt(1)}
+	if err := tester.downloader.SyncCheckpoint("peer", cp, false); err != errCheckpointMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, errCheckpointMismatch)
+	}
+	if _, ok := tester.peers["peer"]; ok {
+		t.Fatalf("mismatching peer not dropped")
+	}
+	if head := tester.CurrentBlock(); head.NumberU64() != 0 {
+		t.Fatalf("chain initialized from unknown checkpoint: head #%d", head.NumberU64())
+	}
+}
+
+// Tests that a peer whose advertised total difficulty is below the trusted one of
+// the checkpoint is not used to initialize the chain.
+func TestCheckpointSyncUnsyncedPeer(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(MaxHeaderFetch)
+	tester.newPeer("peer", 63, chain)
+
+	number := uint64(chain.len() / 2)
+	cp := &SyncCheckpoint{Hash: chain.chain[number], Number: number, Td: new(big.Int).Add(chain.td(chain.headBlock().Hash()), common.Big1)}
+	if err := tester.downloader.SyncCheckpoint("peer", cp, false); err != errUnsyncedPeer {
+		t.Fatalf("error mismatch: have %v, want %v", err, errUnsyncedPeer)
+	}
+	if head := tester.CurrentBlock(); head.NumberU64() != 0 {
+		t.Fatalf("chain initialized from unsynced peer: head #%d", head.NumberU64())
+	}
+}
+
+func TestParseSyncCheckpoint(t *testing.T) {
+	hash := "0x0102030405060708091011121314151617181920212223242526272829303132"
+	tests := []struct {
+		input string
+		want  *SyncCheckpoint
+	}{
+		{hash + "@100@12345", &SyncCheckpoint{Hash: common.HexToHash(hash), Number: 100, Td: big.NewInt(12345)}},
+		{hash[2:] + "@1@1", &SyncCheckpoint{Hash: common.HexToHash(hash), Number: 1, Td: big.NewInt(1)}},
+		{hash + "@100", &SyncCheckpoint{Hash: common.HexToHash(hash), Number: 100}},
+		{hash, nil},
+		{hash + "@100@1@1", nil},
+		{hash + "@0@1", nil},
+		{hash + "@x@1", nil},
+		{hash + "@100@0", nil},
+		{hash + "@100@x", nil},
+		{hash[:10] + "@100@1", nil},
+		{"0x" + strings.Repeat("zz", 32) + "@100@1", nil},
+	}
+	for _, tt := range tests {
+		cp, err := ParseSyncCheckpoint(tt.input)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tt.input, cp)
+			}
+			continue
+		}
+		if err != nil || cp.Hash != tt.want.Hash || cp.Number != tt.want.Number || (cp.Td == nil) != (tt.want.Td == nil) || (cp.Td != nil && cp.Td.Cmp(tt.want.Td) != 0) {
+			t.Errorf("%q: have %v (err %v), want %v", tt.input, cp, err, tt.want)
+		}
+	}
+}
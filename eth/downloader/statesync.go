@@ -25,6 +25,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/rawdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/snap"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
@@ -214,6 +215,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
+	root   common.Hash                // State root being synced
+	snap   bool                       // Whether to retrieve the state in ranges before healing it
 	sched  *trie.Sync                 // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
@@ -239,6 +242,8 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
+		root:    root,
+		snap:    d.snapSync,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewLegacyKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -252,10 +257,44 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 // it finishes, and finally notifying any goroutines waiting for the loop to
 // finish.
 func (s *stateSync) run() {
+	if s.snap {
+		if s.err = s.runSnap(); s.err != nil {
+			close(s.done)
+			return
+		}
+		// Heal the range retrieved state with the regular trie node sync
+		s.sched = state.NewStateSync(s.root, s.d.stateDB)
+	}
 	s.err = s.loop()
 	close(s.done)
 }
 
+// runSnap retrieves the bulk of the state in ranges via the snap protocol. The
+// progress is retained by the syncer, so a sync cancelled due to a pivot move
+// continues where it left off with the new root.
+func (s *stateSync) runSnap() error {
+	var (
+		cancel   = make(chan struct{})
+		finished = make(chan struct{})
+	)
+	defer close(finished)
+	go func() {
+		select {
+		case <-s.cancel:
+		case <-s.d.cancelCh:
+		case <-finished:
+		}
+		close(cancel)
+	}()
+	if err := s.d.snap.Sync(s.root, cancel); err != nil {
+		if err == snap.ErrCancelled {
+			return errCancelStateFetch
+		}
+		return err
+	}
+	return nil
+}
+
 // Wait blocks until the sync is done or canceled.
 func (s *stateSync) Wait() error {
 	<-s.done
@@ -176,6 +176,21 @@ func (tc *testChain) headersByHash(origin common.Hash, amount int, skip int) []*
 	return tc.headersByNumber(num, amount, skip)
 }
 
+// headersByHashReverse returns headers in descending order from the given hash.
+func (tc *testChain) headersByHashReverse(origin common.Hash, amount int, skip int) []*types.Header {
+	num, ok := tc.hashToNumber(origin)
+	if !ok {
+		return nil
+	}
+	result := make([]*types.Header, 0, amount)
+	for n := int(num); n >= 0 && len(result) < amount; n -= skip + 1 {
+		if header, ok := tc.headerm[tc.chain[n]]; ok {
+			result = append(result, header)
+		}
+	}
+	return result
+}
+
 // headersByNumber returns headers in ascending order from the given number.
 func (tc *testChain) headersByNumber(origin uint64, amount int, skip int) []*types.Header {
 	result := make([]*types.Header, 0, amount)
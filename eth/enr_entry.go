@@ -0,0 +1,78 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/forkid"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+)
+
+// ethEntry is the "eth" ENR entry which advertises eth protocol
+// on the discovery network.
+type ethEntry struct {
+	ForkID forkid.ID // Fork identifier per EIP-2124
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e ethEntry) ENRKey() string {
+	return "eth"
+}
+
+// startEthEntryUpdate keeps the fork ID of the local node record up to date as
+// the chain progresses through the forks.
+func (s *Ethereum) startEthEntryUpdate(ln *enode.LocalNode) {
+	var newHead = make(chan core.ChainHeadEvent, 10)
+	sub := s.blockchain.SubscribeChainHeadEvent(newHead)
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-newHead:
+				ln.Set(s.currentEthEntry())
+			case <-sub.Err():
+				// Would be nice to sync with s.Stop, but there is no
+				// good way to do that.
+				return
+			}
+		}
+	}()
+}
+
+// currentEthEntry creates the eth entry of the local node record.
+func (s *Ethereum) currentEthEntry() *ethEntry {
+	return &ethEntry{ForkID: forkid.NewID(s.blockchain)}
+}
+
+// ethNodeFilter creates a dial filter rejecting the nodes which advertise a fork
+// ID incompatible with the local chain. Nodes without an eth entry in their
+// record are accepted, there's no telling before connecting.
+func ethNodeFilter(filter forkid.Filter) func(n *enode.Node) bool {
+	return func(n *enode.Node) bool {
+		var entry ethEntry
+		if err := n.Load(&entry); err != nil {
+			return enr.IsNotFound(err)
+		}
+		return filter(entry.ForkID) == nil
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc
+
+import "github.com/golang/protobuf/proto"
+
+// The message types below implement the messages of eth.proto. Field numbers
+// and wire types in the struct tags must be kept in sync with the schema.
+
+type Empty struct{}
+
+func (m *Empty) Reset()         { *m = Empty{} }
+func (m *Empty) String() string { return proto.CompactTextString(m) }
+func (*Empty) ProtoMessage()    {}
+
+type BlockRequest struct {
+	Hash   []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Number int64  `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+}
+
+func (m *BlockRequest) Reset()         { *m = BlockRequest{} }
+func (m *BlockRequest) String() string { return proto.CompactTextString(m) }
+func (*BlockRequest) ProtoMessage()    {}
+
+type Block struct {
+	Rlp             []byte `protobuf:"bytes,1,opt,name=rlp,proto3" json:"rlp,omitempty"`
+	TotalDifficulty []byte `protobuf:"bytes,2,opt,name=total_difficulty,json=totalDifficulty,proto3" json:"total_difficulty,omitempty"`
+}
+
+func (m *Block) Reset()         { *m = Block{} }
+func (m *Block) String() string { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()    {}
+
+type Header struct {
+	Rlp []byte `protobuf:"bytes,1,opt,name=rlp,proto3" json:"rlp,omitempty"`
+}
+
+func (m *Header) Reset()         { *m = Header{} }
+func (m *Header) String() string { return proto.CompactTextString(m) }
+func (*Header) ProtoMessage()    {}
+
+type TransactionRequest struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *TransactionRequest) Reset()         { *m = TransactionRequest{} }
+func (m *TransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionRequest) ProtoMessage()    {}
+
+type Log struct {
+	Address          []byte   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Topics           [][]byte `protobuf:"bytes,2,rep,name=topics,proto3" json:"topics,omitempty"`
+	Data             []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	BlockNumber      uint64   `protobuf:"varint,4,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	TransactionHash  []byte   `protobuf:"bytes,5,opt,name=transaction_hash,json=transactionHash,proto3" json:"transaction_hash,omitempty"`
+	TransactionIndex uint32   `protobuf:"varint,6,opt,name=transaction_index,json=transactionIndex,proto3" json:"transaction_index,omitempty"`
+	BlockHash        []byte   `protobuf:"bytes,7,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Index            uint32   `protobuf:"varint,8,opt,name=index,proto3" json:"index,omitempty"`
+	Removed          bool     `protobuf:"varint,9,opt,name=removed,proto3" json:"removed,omitempty"`
+}
+
+func (m *Log) Reset()         { *m = Log{} }
+func (m *Log) String() string { return proto.CompactTextString(m) }
+func (*Log) ProtoMessage()    {}
+
+type Logs struct {
+	Logs []*Log `protobuf:"bytes,1,rep,name=logs" json:"logs,omitempty"`
+}
+
+func (m *Logs) Reset()         { *m = Logs{} }
+func (m *Logs) String() string { return proto.CompactTextString(m) }
+func (*Logs) ProtoMessage()    {}
+
+type Receipt struct {
+	TransactionHash   []byte `protobuf:"bytes,1,opt,name=transaction_hash,json=transactionHash,proto3" json:"transaction_hash,omitempty"`
+	PostState         []byte `protobuf:"bytes,2,opt,name=post_state,json=postState,proto3" json:"post_state,omitempty"`
+	Status            uint64 `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty"`
+	CumulativeGasUsed uint64 `protobuf:"varint,4,opt,name=cumulative_gas_used,json=cumulativeGasUsed,proto3" json:"cumulative_gas_used,omitempty"`
+	GasUsed           uint64 `protobuf:"varint,5,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	ContractAddress   []byte `protobuf:"bytes,6,opt,name=contract_address,json=contractAddress,proto3" json:"contract_address,omitempty"`
+	Bloom             []byte `protobuf:"bytes,7,opt,name=bloom,proto3" json:"bloom,omitempty"`
+	Logs              []*Log `protobuf:"bytes,8,rep,name=logs" json:"logs,omitempty"`
+	BlockHash         []byte `protobuf:"bytes,9,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	BlockNumber       uint64 `protobuf:"varint,10,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	TransactionIndex  uint32 `protobuf:"varint,11,opt,name=transaction_index,json=transactionIndex,proto3" json:"transaction_index,omitempty"`
+}
+
+func (m *Receipt) Reset()         { *m = Receipt{} }
+func (m *Receipt) String() string { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()    {}
+
+type Receipts struct {
+	Receipts []*Receipt `protobuf:"bytes,1,rep,name=receipts" json:"receipts,omitempty"`
+}
+
+func (m *Receipts) Reset()         { *m = Receipts{} }
+func (m *Receipts) String() string { return proto.CompactTextString(m) }
+func (*Receipts) ProtoMessage()    {}
+
+type Topics struct {
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *Topics) Reset()         { *m = Topics{} }
+func (m *Topics) String() string { return proto.CompactTextString(m) }
+func (*Topics) ProtoMessage()    {}
+
+type LogFilter struct {
+	BlockHash []byte    `protobuf:"bytes,1,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	FromBlock int64     `protobuf:"varint,2,opt,name=from_block,json=fromBlock,proto3" json:"from_block,omitempty"`
+	ToBlock   int64     `protobuf:"varint,3,opt,name=to_block,json=toBlock,proto3" json:"to_block,omitempty"`
+	Addresses [][]byte  `protobuf:"bytes,4,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Topics    []*Topics `protobuf:"bytes,5,rep,name=topics" json:"topics,omitempty"`
+}
+
+func (m *LogFilter) Reset()         { *m = LogFilter{} }
+func (m *LogFilter) String() string { return proto.CompactTextString(m) }
+func (*LogFilter) ProtoMessage()    {}
+
+type BalanceRequest struct {
+	Address     []byte `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	BlockNumber int64  `protobuf:"varint,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+}
+
+func (m *BalanceRequest) Reset()         { *m = BalanceRequest{} }
+func (m *BalanceRequest) String() string { return proto.CompactTextString(m) }
+func (*BalanceRequest) ProtoMessage()    {}
+
+type BigInt struct {
+	Value []byte `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *BigInt) Reset()         { *m = BigInt{} }
+func (m *BigInt) String() string { return proto.CompactTextString(m) }
+func (*BigInt) ProtoMessage()    {}
+
+type CallRequest struct {
+	From        []byte `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To          []byte `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Gas         uint64 `protobuf:"varint,3,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice    []byte `protobuf:"bytes,4,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	Value       []byte `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Data        []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	BlockNumber int64  `protobuf:"varint,7,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+}
+
+func (m *CallRequest) Reset()         { *m = CallRequest{} }
+func (m *CallRequest) String() string { return proto.CompactTextString(m) }
+func (*CallRequest) ProtoMessage()    {}
+
+type CallReply struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *CallReply) Reset()         { *m = CallReply{} }
+func (m *CallReply) String() string { return proto.CompactTextString(m) }
+func (*CallReply) ProtoMessage()    {}
+
+type RawTransaction struct {
+	Rlp []byte `protobuf:"bytes,1,opt,name=rlp,proto3" json:"rlp,omitempty"`
+}
+
+func (m *RawTransaction) Reset()         { *m = RawTransaction{} }
+func (m *RawTransaction) String() string { return proto.CompactTextString(m) }
+func (*RawTransaction) ProtoMessage()    {}
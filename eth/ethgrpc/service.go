@@ -0,0 +1,427 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethgrpc implements the gRPC service of the eth namespace, described in
+// eth.proto. It is served from the same backend as the JSON-RPC APIs.
+package ethgrpc
+
+import (
+	"context"
+	"math/big"
+
+	ethereum "git.pirl.io/bitcoiin/go-bitcoiin"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/rawdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/filters"
+	"git.pirl.io/bitcoiin/go-bitcoiin/internal/ethapi"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc/grpc"
+	"github.com/golang/protobuf/proto"
+)
+
+// Backend is the chain, transaction pool and log filtering backend the service
+// is served from.
+type Backend interface {
+	ethapi.Backend
+	filters.Backend
+}
+
+// service implements the methods of the Eth gRPC service.
+type service struct {
+	backend Backend
+	chain   *ethapi.PublicBlockChainAPI
+	txpool  *ethapi.PublicTransactionPoolAPI
+	events  *filters.EventSystem
+}
+
+// NewService creates the Eth gRPC service in the public "eth" namespace.
+func NewService(backend Backend, lightMode bool) grpc.Service {
+	s := &service{
+		backend: backend,
+		chain:   ethapi.NewPublicBlockChainAPI(backend),
+		txpool:  ethapi.NewPublicTransactionPoolAPI(backend, new(ethapi.AddrLocker)),
+		events:  filters.NewEventSystem(backend.EventMux(), backend, lightMode),
+	}
+	return grpc.Service{
+		Namespace: "eth",
+		Name:      "bitcoiin.eth.Eth",
+		Public:    true,
+		Methods: []grpc.Method{
+			unary("GetBlock", func() proto.Message { return new(BlockRequest) }, s.getBlock),
+			unary("GetHeader", func() proto.Message { return new(BlockRequest) }, s.getHeader),
+			unary("GetReceipts", func() proto.Message { return new(BlockRequest) }, s.getReceipts),
+			unary("GetTransactionReceipt", func() proto.Message { return new(TransactionRequest) }, s.getTransactionReceipt),
+			unary("GetLogs", func() proto.Message { return new(LogFilter) }, s.getLogs),
+			unary("GetBalance", func() proto.Message { return new(BalanceRequest) }, s.getBalance),
+			unary("Call", func() proto.Message { return new(CallRequest) }, s.call),
+			unary("SendRawTransaction", func() proto.Message { return new(RawTransaction) }, s.sendRawTransaction),
+			{
+				Name:       "SubscribeNewHeads",
+				NewRequest: func() proto.Message { return new(Empty) },
+				Handler:    s.subscribeNewHeads,
+				Streaming:  true,
+			}, {
+				Name:       "SubscribeLogs",
+				NewRequest: func() proto.Message { return new(LogFilter) },
+				Handler:    s.subscribeLogs,
+				Streaming:  true,
+			},
+		},
+	}
+}
+
+// unary wraps a request/reply style function into a gRPC method.
+func unary(name string, newRequest func() proto.Message, fn func(context.Context, proto.Message) (proto.Message, error)) grpc.Method {
+	return grpc.Method{
+		Name:       name,
+		NewRequest: newRequest,
+		Handler: func(ctx context.Context, req proto.Message, send func(proto.Message) error) error {
+			reply, err := fn(ctx, req)
+			if err != nil {
+				return err
+			}
+			return send(reply)
+		},
+	}
+}
+
+func (s *service) getBlock(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	req := msg.(*BlockRequest)
+
+	var (
+		block *types.Block
+		err   error
+	)
+	if len(req.Hash) > 0 {
+		hash, herr := toHash(req.Hash)
+		if herr != nil {
+			return nil, herr
+		}
+		block, err = s.backend.GetBlock(ctx, hash)
+	} else {
+		block, err = s.backend.BlockByNumber(ctx, rpc.BlockNumber(req.Number))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, grpc.Errorf(grpc.NotFound, "block not found")
+	}
+	blob, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return nil, err
+	}
+	reply := &Block{Rlp: blob}
+	if td := s.backend.GetTd(block.Hash()); td != nil {
+		reply.TotalDifficulty = td.Bytes()
+	}
+	return reply, nil
+}
+
+func (s *service) getHeader(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	header, err := s.header(ctx, msg.(*BlockRequest))
+	if err != nil {
+		return nil, err
+	}
+	blob, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	return &Header{Rlp: blob}, nil
+}
+
+func (s *service) getReceipts(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	header, err := s.header(ctx, msg.(*BlockRequest))
+	if err != nil {
+		return nil, err
+	}
+	hash := header.Hash()
+	receipts, err := s.backend.GetReceipts(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	reply := &Receipts{Receipts: make([]*Receipt, len(receipts))}
+	for i, receipt := range receipts {
+		reply.Receipts[i] = newReceipt(receipt, hash, header.Number.Uint64(), uint32(i))
+	}
+	return reply, nil
+}
+
+func (s *service) getTransactionReceipt(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	hash, err := toHash(msg.(*TransactionRequest).Hash)
+	if err != nil {
+		return nil, err
+	}
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.backend.ChainDb(), hash)
+	if tx == nil {
+		return nil, grpc.Errorf(grpc.NotFound, "transaction not found")
+	}
+	receipts, err := s.backend.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) <= int(index) {
+		return nil, grpc.Errorf(grpc.NotFound, "receipt not found")
+	}
+	return newReceipt(receipts[index], blockHash, blockNumber, uint32(index)), nil
+}
+
+func (s *service) getLogs(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	req := msg.(*LogFilter)
+
+	addresses, topics, err := toCriteria(req)
+	if err != nil {
+		return nil, err
+	}
+	var filter *filters.Filter
+	if len(req.BlockHash) > 0 {
+		hash, err := toHash(req.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		filter = filters.NewBlockFilter(s.backend, hash, addresses, topics)
+	} else {
+		filter = filters.NewRangeFilter(s.backend, req.FromBlock, req.ToBlock, addresses, topics)
+	}
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Logs{Logs: newLogs(logs)}, nil
+}
+
+func (s *service) getBalance(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	req := msg.(*BalanceRequest)
+
+	address, err := toAddress(req.Address)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.chain.GetBalance(ctx, address, rpc.BlockNumber(req.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	if balance == nil {
+		return nil, grpc.Errorf(grpc.NotFound, "state not found")
+	}
+	return &BigInt{Value: balance.ToInt().Bytes()}, nil
+}
+
+func (s *service) call(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	req := msg.(*CallRequest)
+
+	args := ethapi.CallArgs{
+		Gas:      hexutil.Uint64(req.Gas),
+		GasPrice: hexutil.Big(*new(big.Int).SetBytes(req.GasPrice)),
+		Value:    hexutil.Big(*new(big.Int).SetBytes(req.Value)),
+		Data:     req.Data,
+	}
+	if len(req.From) > 0 {
+		from, err := toAddress(req.From)
+		if err != nil {
+			return nil, err
+		}
+		args.From = from
+	}
+	if len(req.To) > 0 {
+		to, err := toAddress(req.To)
+		if err != nil {
+			return nil, err
+		}
+		args.To = &to
+	}
+	result, err := s.chain.Call(ctx, args, rpc.BlockNumber(req.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	return &CallReply{Data: result}, nil
+}
+
+func (s *service) sendRawTransaction(ctx context.Context, msg proto.Message) (proto.Message, error) {
+	hash, err := s.txpool.SendRawTransaction(ctx, msg.(*RawTransaction).Rlp)
+	if err != nil {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	return &TransactionRequest{Hash: hash.Bytes()}, nil
+}
+
+func (s *service) subscribeNewHeads(ctx context.Context, msg proto.Message, send func(proto.Message) error) error {
+	headers := make(chan *types.Header)
+	sub := s.events.SubscribeNewHeads(headers)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case header := <-headers:
+			blob, err := rlp.EncodeToBytes(header)
+			if err != nil {
+				return err
+			}
+			if err := send(&Header{Rlp: blob}); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *service) subscribeLogs(ctx context.Context, msg proto.Message, send func(proto.Message) error) error {
+	addresses, topics, err := toCriteria(msg.(*LogFilter))
+	if err != nil {
+		return err
+	}
+	matches := make(chan []*types.Log)
+	sub, err := s.events.SubscribeLogs(ethereum.FilterQuery{Addresses: addresses, Topics: topics}, matches)
+	if err != nil {
+		return grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case logs := <-matches:
+			for _, log := range logs {
+				if err := send(newLog(log)); err != nil {
+					return err
+				}
+			}
+		case err := <-sub.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// header looks up the header selected by a block request.
+func (s *service) header(ctx context.Context, req *BlockRequest) (*types.Header, error) {
+	var (
+		header *types.Header
+		err    error
+	)
+	if len(req.Hash) > 0 {
+		hash, herr := toHash(req.Hash)
+		if herr != nil {
+			return nil, herr
+		}
+		header, err = s.backend.HeaderByHash(ctx, hash)
+	} else {
+		header, err = s.backend.HeaderByNumber(ctx, rpc.BlockNumber(req.Number))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, grpc.Errorf(grpc.NotFound, "header not found")
+	}
+	return header, nil
+}
+
+// toHash converts a raw hash field, rejecting malformed values.
+func toHash(b []byte) (common.Hash, error) {
+	if len(b) != common.HashLength {
+		return common.Hash{}, grpc.Errorf(grpc.InvalidArgument, "invalid hash length %d", len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+// toAddress converts a raw address field, rejecting malformed values.
+func toAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, grpc.Errorf(grpc.InvalidArgument, "invalid address length %d", len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
+
+// toCriteria converts the address and topic criteria of a log filter.
+func toCriteria(req *LogFilter) ([]common.Address, [][]common.Hash, error) {
+	addresses := make([]common.Address, len(req.Addresses))
+	for i, b := range req.Addresses {
+		address, err := toAddress(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		addresses[i] = address
+	}
+	topics := make([][]common.Hash, len(req.Topics))
+	for i, set := range req.Topics {
+		if set == nil {
+			continue
+		}
+		for _, b := range set.Hashes {
+			topic, err := toHash(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			topics[i] = append(topics[i], topic)
+		}
+	}
+	return addresses, topics, nil
+}
+
+// newReceipt converts a receipt into its gRPC message.
+func newReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, index uint32) *Receipt {
+	msg := &Receipt{
+		TransactionHash:   receipt.TxHash.Bytes(),
+		PostState:         receipt.PostState,
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		GasUsed:           receipt.GasUsed,
+		Bloom:             receipt.Bloom.Bytes(),
+		Logs:              newLogs(receipt.Logs),
+		BlockHash:         blockHash.Bytes(),
+		BlockNumber:       blockNumber,
+		TransactionIndex:  index,
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		msg.ContractAddress = receipt.ContractAddress.Bytes()
+	}
+	return msg
+}
+
+// newLogs converts a list of logs into their gRPC messages.
+func newLogs(logs []*types.Log) []*Log {
+	msgs := make([]*Log, len(logs))
+	for i, log := range logs {
+		msgs[i] = newLog(log)
+	}
+	return msgs
+}
+
+// newLog converts a log into its gRPC message.
+func newLog(log *types.Log) *Log {
+	msg := &Log{
+		Address:          log.Address.Bytes(),
+		Topics:           make([][]byte, len(log.Topics)),
+		Data:             log.Data,
+		BlockNumber:      log.BlockNumber,
+		TransactionHash:  log.TxHash.Bytes(),
+		TransactionIndex: uint32(log.TxIndex),
+		BlockHash:        log.BlockHash.Bytes(),
+		Index:            uint32(log.Index),
+		Removed:          log.Removed,
+	}
+	for i, topic := range log.Topics {
+		msg.Topics[i] = topic.Bytes()
+	}
+	return msg
+}
@@ -0,0 +1,386 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/ethgrpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/node"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(1e18)
+	testTopic   = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
+
+	// logCode is the init code of a contract emitting a single log with testTopic.
+	logCode = append(append([]byte{0x7f}, testTopic.Bytes()...), 0x60, 0x20, 0x60, 0x00, 0xa1, 0x00)
+)
+
+// testBackend is a running node serving the eth gRPC service, along with the
+// chain imported into it.
+type testBackend struct {
+	stack    *node.Node
+	eth      *eth.Ethereum
+	url      string
+	genesis  *core.Genesis
+	db       ethdb.Database // Database the chain was generated in
+	blocks   []*types.Block
+	receipts []types.Receipts
+}
+
+// newTestBackend starts a node with gRPC enabled and imports a short chain whose
+// first block deploys a log emitting contract.
+func newTestBackend(t *testing.T) *testBackend {
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	stack, err := node.New(&node.Config{GRPCHost: "127.0.0.1", GRPCModules: []string{"eth"}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	config := &eth.Config{Genesis: genesis, Ethash: ethash.Config{PowMode: ethash.ModeFake}}
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) { return eth.New(ctx, config) }); err != nil {
+		t.Fatalf("failed to register eth service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var ethservice *eth.Ethereum
+	stack.Service(&ethservice)
+
+	// Generate a chain on the side and import it into the node
+	db := ethdb.NewMemDatabase()
+	gblock := genesis.ToBlock(db)
+	blocks, receipts := core.GenerateChain(genesis.Config, gblock, ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(newLogTx(genesis.Config, 0))
+		}
+	})
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	return &testBackend{
+		stack:    stack,
+		eth:      ethservice,
+		url:      "http://" + stack.GRPCEndpoint(),
+		genesis:  genesis,
+		db:       db,
+		blocks:   blocks,
+		receipts: receipts,
+	}
+}
+
+// newLogTx creates a transaction deploying a contract which emits a log.
+func newLogTx(config *params.ChainConfig, nonce uint64) *types.Transaction {
+	signer := types.NewEIP155Signer(config.ChainID)
+	tx, _ := types.SignTx(types.NewContractCreation(nonce, new(big.Int), 100000, big.NewInt(1), logCode), signer, testKey)
+	return tx
+}
+
+// extend imports the given blocks one by one in the background, slowly enough
+// for subscriptions to observe each of them.
+func (b *testBackend) extend(blocks []*types.Block) {
+	go func() {
+		for _, block := range blocks {
+			if _, err := b.eth.BlockChain().InsertChain(types.Blocks{block}); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+}
+
+// grpcStream is an open gRPC call reading replies as they arrive.
+type grpcStream struct {
+	res *http.Response
+}
+
+// open starts a gRPC call the same way a generated client over an insecure
+// connection would.
+func (b *testBackend) open(t *testing.T, method string, req proto.Message) *grpcStream {
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+	frame := make([]byte, 5+len(body))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	copy(frame[5:], body)
+
+	httpReq, _ := http.NewRequest(http.MethodPost, b.url+"/bitcoiin.eth.Eth/"+method, bytes.NewReader(frame))
+	httpReq.Header.Set("content-type", "application/grpc")
+	res, err := client.Do(httpReq)
+	if err != nil {
+		t.Fatalf("%s: request failed: %v", method, err)
+	}
+	return &grpcStream{res: res}
+}
+
+// recv reads the next reply of the call, returning false at the end of the stream.
+func (s *grpcStream) recv(t *testing.T, reply proto.Message) bool {
+	var header [5]byte
+	if _, err := io.ReadFull(s.res.Body, header[:]); err != nil {
+		return false
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(s.res.Body, data); err != nil {
+		t.Fatalf("truncated reply: %v", err)
+	}
+	if err := proto.Unmarshal(data, reply); err != nil {
+		t.Fatalf("failed to decode reply: %v", err)
+	}
+	return true
+}
+
+// status drains the call and returns its gRPC status code.
+func (s *grpcStream) status(t *testing.T) int {
+	io.Copy(ioutil.Discard, s.res.Body)
+	s.res.Body.Close()
+
+	code, err := strconv.Atoi(s.res.Trailer.Get("grpc-status"))
+	if err != nil {
+		t.Fatalf("missing grpc-status trailer: %v", s.res.Trailer)
+	}
+	return code
+}
+
+// call performs a unary call, failing the test unless it succeeds.
+func (b *testBackend) call(t *testing.T, method string, req, reply proto.Message) {
+	stream := b.open(t, method, req)
+	if !stream.recv(t, reply) {
+		t.Fatalf("%s: no reply, status %d (%s)", method, stream.status(t), stream.res.Trailer.Get("grpc-message"))
+	}
+	if code := stream.status(t); code != 0 {
+		t.Fatalf("%s: status mismatch: have %d, want 0", method, code)
+	}
+}
+
+func TestGetBlockAndHeader(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	// Retrieve a block by number and by hash
+	want := b.blocks[1]
+	for _, req := range []*ethgrpc.BlockRequest{{Number: 2}, {Hash: want.Hash().Bytes()}} {
+		reply := new(ethgrpc.Block)
+		b.call(t, "GetBlock", req, reply)
+
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(reply.Rlp, block); err != nil {
+			t.Fatalf("failed to decode block: %v", err)
+		}
+		if block.Hash() != want.Hash() {
+			t.Errorf("block mismatch: have %x, want %x", block.Hash(), want.Hash())
+		}
+		if td := new(big.Int).SetBytes(reply.TotalDifficulty); td.Cmp(b.eth.BlockChain().GetTd(want.Hash(), 2)) != 0 {
+			t.Errorf("total difficulty mismatch: have %v", td)
+		}
+	}
+	// Retrieve the latest header
+	reply := new(ethgrpc.Header)
+	b.call(t, "GetHeader", &ethgrpc.BlockRequest{Number: -1}, reply)
+
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(reply.Rlp, header); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if header.Hash() != b.blocks[2].Hash() {
+		t.Errorf("head mismatch: have %x, want %x", header.Hash(), b.blocks[2].Hash())
+	}
+	// Unknown blocks and malformed hashes must be rejected
+	if code := b.open(t, "GetBlock", &ethgrpc.BlockRequest{Number: 100}).status(t); code != 5 {
+		t.Errorf("missing block: status mismatch: have %d, want 5", code)
+	}
+	if code := b.open(t, "GetHeader", &ethgrpc.BlockRequest{Hash: []byte{1, 2, 3}}).status(t); code != 3 {
+		t.Errorf("malformed hash: status mismatch: have %d, want 3", code)
+	}
+}
+
+func TestGetReceiptsAndLogs(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	want := b.receipts[0][0]
+
+	// Retrieve the receipts of the first block and of its single transaction
+	receipts := new(ethgrpc.Receipts)
+	b.call(t, "GetReceipts", &ethgrpc.BlockRequest{Number: 1}, receipts)
+	if len(receipts.Receipts) != 1 {
+		t.Fatalf("receipt count mismatch: have %d, want 1", len(receipts.Receipts))
+	}
+	receipt := new(ethgrpc.Receipt)
+	b.call(t, "GetTransactionReceipt", &ethgrpc.TransactionRequest{Hash: want.TxHash.Bytes()}, receipt)
+
+	for _, r := range []*ethgrpc.Receipt{receipts.Receipts[0], receipt} {
+		if !bytes.Equal(r.TransactionHash, want.TxHash.Bytes()) {
+			t.Errorf("transaction hash mismatch: have %x, want %x", r.TransactionHash, want.TxHash)
+		}
+		if !bytes.Equal(r.ContractAddress, want.ContractAddress.Bytes()) {
+			t.Errorf("contract address mismatch: have %x, want %x", r.ContractAddress, want.ContractAddress)
+		}
+		if r.GasUsed != want.GasUsed || r.BlockNumber != 1 || !bytes.Equal(r.BlockHash, b.blocks[0].Hash().Bytes()) {
+			t.Errorf("receipt fields mismatch: %v", r)
+		}
+		if len(r.Logs) != 1 || !bytes.Equal(r.Logs[0].Topics[0], testTopic.Bytes()) {
+			t.Errorf("receipt logs mismatch: %v", r.Logs)
+		}
+	}
+	// Filter the logs by topic over the whole chain
+	logs := new(ethgrpc.Logs)
+	filter := &ethgrpc.LogFilter{
+		FromBlock: 0,
+		ToBlock:   -1,
+		Topics:    []*ethgrpc.Topics{{Hashes: [][]byte{testTopic.Bytes()}}},
+	}
+	b.call(t, "GetLogs", filter, logs)
+	if len(logs.Logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want 1", len(logs.Logs))
+	}
+	if log := logs.Logs[0]; !bytes.Equal(log.Address, want.ContractAddress.Bytes()) || log.BlockNumber != 1 {
+		t.Errorf("log mismatch: %v", log)
+	}
+	// Unknown transactions must be reported as missing
+	if code := b.open(t, "GetTransactionReceipt", &ethgrpc.TransactionRequest{Hash: make([]byte, 32)}).status(t); code != 5 {
+		t.Errorf("missing transaction: status mismatch: have %d, want 5", code)
+	}
+}
+
+func TestGetBalanceAndCall(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	// The genesis balance must be reported at the genesis block
+	balance := new(ethgrpc.BigInt)
+	b.call(t, "GetBalance", &ethgrpc.BalanceRequest{Address: testAddr.Bytes(), BlockNumber: 0}, balance)
+	if have := new(big.Int).SetBytes(balance.Value); have.Cmp(testBalance) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", have, testBalance)
+	}
+	// Calling the identity precompile must echo the input
+	reply := new(ethgrpc.CallReply)
+	input := []byte{0xde, 0xad, 0xbe, 0xef}
+	b.call(t, "Call", &ethgrpc.CallRequest{From: testAddr.Bytes(), To: common.BytesToAddress([]byte{4}).Bytes(), Data: input, BlockNumber: -1}, reply)
+	if !bytes.Equal(reply.Data, input) {
+		t.Errorf("call result mismatch: have %x, want %x", reply.Data, input)
+	}
+}
+
+func TestSendRawTransaction(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	signer := types.NewEIP155Signer(b.genesis.Config.ChainID)
+	tx, _ := types.SignTx(types.NewTransaction(1, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1e9), nil), signer, testKey)
+	blob, _ := rlp.EncodeToBytes(tx)
+
+	reply := new(ethgrpc.TransactionRequest)
+	b.call(t, "SendRawTransaction", &ethgrpc.RawTransaction{Rlp: blob}, reply)
+	if !bytes.Equal(reply.Hash, tx.Hash().Bytes()) {
+		t.Errorf("transaction hash mismatch: have %x, want %x", reply.Hash, tx.Hash())
+	}
+	if b.eth.TxPool().Get(tx.Hash()) == nil {
+		t.Errorf("transaction not added to the pool")
+	}
+	// Garbage must be rejected
+	if code := b.open(t, "SendRawTransaction", &ethgrpc.RawTransaction{Rlp: []byte{0x01}}).status(t); code != 3 {
+		t.Errorf("invalid transaction: status mismatch: have %d, want 3", code)
+	}
+}
+
+func TestSubscribeNewHeads(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	stream := b.open(t, "SubscribeNewHeads", new(ethgrpc.Empty))
+	defer stream.res.Body.Close()
+
+	// Keep extending the chain until the subscription reports a new head
+	blocks, _ := core.GenerateChain(b.genesis.Config, b.blocks[2], ethash.NewFaker(), b.db, 50, nil)
+	b.extend(blocks)
+
+	imported := make(map[common.Hash]bool)
+	for _, block := range blocks {
+		imported[block.Hash()] = true
+	}
+	reply := new(ethgrpc.Header)
+	if !stream.recv(t, reply) {
+		t.Fatalf("subscription ended without a header")
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(reply.Rlp, header); err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if !imported[header.Hash()] {
+		t.Errorf("unexpected header %x", header.Hash())
+	}
+}
+
+func TestSubscribeLogs(t *testing.T) {
+	b := newTestBackend(t)
+	defer b.stack.Stop()
+
+	filter := &ethgrpc.LogFilter{Topics: []*ethgrpc.Topics{{Hashes: [][]byte{testTopic.Bytes()}}}}
+	stream := b.open(t, "SubscribeLogs", filter)
+	defer stream.res.Body.Close()
+
+	// Keep adding log emitting blocks until the subscription reports a log
+	blocks, _ := core.GenerateChain(b.genesis.Config, b.blocks[2], ethash.NewFaker(), b.db, 50, func(i int, block *core.BlockGen) {
+		block.AddTx(newLogTx(b.genesis.Config, uint64(i+1)))
+	})
+	b.extend(blocks)
+
+	log := new(ethgrpc.Log)
+	if !stream.recv(t, log) {
+		t.Fatalf("subscription ended without a log")
+	}
+	if len(log.Topics) != 1 || !bytes.Equal(log.Topics[0], testTopic.Bytes()) {
+		t.Errorf("log topics mismatch: have %x", log.Topics)
+	}
+	if log.BlockNumber <= 3 || log.Removed {
+		t.Errorf("unexpected log: %v", log)
+	}
+}
@@ -81,6 +81,7 @@ type fetcherTester struct {
 	hashes []common.Hash                // Hash chain belonging to the tester
 	blocks map[common.Hash]*types.Block // Blocks belonging to the tester
 	drops  map[string]bool              // Map of peers dropped by the fetcher
+	awards map[string]int               // Number of imported blocks credited to peers
 
 	lock sync.RWMutex
 }
@@ -91,8 +92,9 @@ func newTester() *fetcherTester {
 		hashes: []common.Hash{genesis.Hash()},
 		blocks: map[common.Hash]*types.Block{genesis.Hash(): genesis},
 		drops:  make(map[string]bool),
+		awards: make(map[string]int),
 	}
-	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer)
+	tester.fetcher = New(tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertChain, tester.dropPeer, tester.rewardPeer)
 	tester.fetcher.Start()
 
 	return tester
@@ -153,6 +155,15 @@ func (f *fetcherTester) dropPeer(peer string) {
 	f.drops[peer] = true
 }
 
+// rewardPeer is an emulator for the peer crediting, counting the imported blocks
+// delivered by each peer.
+func (f *fetcherTester) rewardPeer(peer string, block *types.Block) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.awards[peer]++
+}
+
 // makeHeaderFetcher retrieves a block header fetcher associated with a simulated peer.
 func (f *fetcherTester) makeHeaderFetcher(peer string, blocks map[common.Hash]*types.Block, drift time.Duration) headerRequesterFn {
 	closure := make(map[common.Hash]*types.Block)
@@ -524,6 +535,47 @@ func testImportDeduplication(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that only peers delivering blocks which got successfully imported are
+// credited for them, not the ones with failing imports.
+func TestImportRewards(t *testing.T) {
+	hashes, blocks := makeChain(2, 0, genesis)
+
+	// Create the tester and make the import of the chain head fail
+	tester := newTester()
+	failed := make(chan struct{}, 1)
+	tester.fetcher.insertChain = func(blocks types.Blocks) (int, error) {
+		if blocks[0].Hash() == hashes[0] {
+			failed <- struct{}{}
+			return 0, errors.New("invalid block")
+		}
+		return tester.insertChain(blocks)
+	}
+	imported := make(chan *types.Block)
+	tester.fetcher.importedHook = func(block *types.Block) { imported <- block }
+
+	// Propagate a valid block, then one failing the import
+	tester.fetcher.Enqueue("valid", blocks[hashes[1]])
+	verifyImportEvent(t, imported, true)
+
+	tester.fetcher.Enqueue("invalid", blocks[hashes[0]])
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatalf("import timeout")
+	}
+	verifyImportEvent(t, imported, false)
+
+	tester.lock.RLock()
+	defer tester.lock.RUnlock()
+
+	if tester.awards["valid"] != 1 {
+		t.Errorf("valid peer credit mismatch: have %d, want %d", tester.awards["valid"], 1)
+	}
+	if tester.awards["invalid"] != 0 {
+		t.Errorf("invalid peer credited for failed import: %d", tester.awards["invalid"])
+	}
+}
+
 // Tests that blocks with numbers much lower or higher than out current head get
 // discarded to prevent wasting resources on useless blocks from faulty peers.
 func TestDistantPropagationDiscarding(t *testing.T) {
@@ -0,0 +1,387 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/rand"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+)
+
+const (
+	txArriveTimeout = 500 * time.Millisecond // Time allowance before an announced transaction is explicitly requested
+	txFetchTimeout  = 5 * time.Second        // Maximum allotted time to return an explicitly requested transaction
+	maxTxAnnounces  = 4096                   // Maximum number of unique transactions a peer may have announced
+	maxTxRetrievals = 256                    // Maximum number of transactions to request from a peer in one go
+)
+
+// txPoolHasFn is a callback type for checking whether a transaction is already
+// known to the local pool.
+type txPoolHasFn func(common.Hash) bool
+
+// txPoolAddFn is a callback type for injecting a batch of transactions into the
+// local pool.
+type txPoolAddFn func([]*types.Transaction) []error
+
+// txRequesterFn is a callback type for sending a transaction retrieval request.
+type txRequesterFn func(string, []common.Hash) error
+
+// txAnnounce is the notification of the availability of a batch of new
+// transactions in the network.
+type txAnnounce struct {
+	origin string        // Identifier of the peer originating the notification
+	hashes []common.Hash // Batch of transaction hashes being announced
+}
+
+// txDelivery is the notification of a batch of transactions arriving from the
+// network, either as a reply to a retrieval request or as a direct broadcast.
+type txDelivery struct {
+	origin string        // Identifier of the peer delivering the transactions
+	hashes []common.Hash // Batch of transactions delivered, already added to the pool
+	direct bool          // Whether this is a reply to an explicit request
+}
+
+// txRequest is a transaction retrieval request in flight to a remote peer.
+type txRequest struct {
+	hashes []common.Hash // Transactions requested from the peer
+	time   time.Time     // Timestamp of the request
+}
+
+// TxFetcher is responsible for retrieving new transactions based on hash
+// announcements. Announced transactions are given a short time to arrive via
+// direct broadcast, after which they are requested from one of the announcing
+// peers, rescheduling to others on timeouts and disconnects.
+type TxFetcher struct {
+	notify  chan *txAnnounce
+	enqueue chan *txDelivery
+	drop    chan string
+	quit    chan struct{}
+
+	// Announce states
+	waiting   map[common.Hash]time.Time           // First announcement times of transactions not yet retrieved
+	announces map[string]map[common.Hash]struct{} // Per peer set of announced transactions not yet retrieved
+	announced map[common.Hash]map[string]struct{} // Per transaction set of peers having announced it
+	fetching  map[common.Hash]string              // Transactions currently being retrieved and their source
+	requests  map[string]*txRequest               // Per peer retrieval request in flight
+
+	// Callbacks
+	hasTx    txPoolHasFn   // Checks whether a transaction is already in the pool
+	addTxs   txPoolAddFn   // Injects a batch of transactions into the pool
+	fetchTxs txRequesterFn // Requests a batch of transactions from a peer
+
+	// Testing hooks
+	fetchingHook func(string, []common.Hash) // Method to call upon starting a transaction retrieval
+}
+
+// NewTxFetcher creates a transaction fetcher to retrieve transactions based on
+// hash announcements.
+func NewTxFetcher(hasTx txPoolHasFn, addTxs txPoolAddFn, fetchTxs txRequesterFn) *TxFetcher {
+	return &TxFetcher{
+		notify:    make(chan *txAnnounce),
+		enqueue:   make(chan *txDelivery),
+		drop:      make(chan string),
+		quit:      make(chan struct{}),
+		waiting:   make(map[common.Hash]time.Time),
+		announces: make(map[string]map[common.Hash]struct{}),
+		announced: make(map[common.Hash]map[string]struct{}),
+		fetching:  make(map[common.Hash]string),
+		requests:  make(map[string]*txRequest),
+		hasTx:     hasTx,
+		addTxs:    addTxs,
+		fetchTxs:  fetchTxs,
+	}
+}
+
+// Start boots up the announcement based transaction retrieval, processing
+// notifications and deliveries until termination is requested.
+func (f *TxFetcher) Start() {
+	go f.loop()
+}
+
+// Stop terminates the announcement based transaction retrieval, canceling all
+// pending operations.
+func (f *TxFetcher) Stop() {
+	close(f.quit)
+}
+
+// Notify announces the fetcher of the potential availability of a batch of new
+// transactions in the network.
+func (f *TxFetcher) Notify(peer string, hashes []common.Hash) error {
+	select {
+	case f.notify <- &txAnnounce{origin: peer, hashes: hashes}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Enqueue injects a batch of transactions received from the network into the
+// pool, canceling any pending retrievals of them. Direct deliveries are replies
+// to a previous request of the fetcher.
+//
+// The transactions are added to the pool on the caller's goroutine, so that a
+// slow pool only stalls the delivering peer and not the whole fetcher.
+func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool) error {
+	if direct {
+		txReplyInMeter.Mark(int64(len(txs)))
+	} else {
+		txBroadcastInMeter.Mark(int64(len(txs)))
+	}
+	f.addTxs(txs)
+
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	select {
+	case f.enqueue <- &txDelivery{origin: peer, hashes: hashes, direct: direct}:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// Drop removes all traces of a disconnected peer, rescheduling its pending
+// retrievals to other peers having announced the same transactions.
+func (f *TxFetcher) Drop(peer string) error {
+	select {
+	case f.drop <- peer:
+		return nil
+	case <-f.quit:
+		return errTerminated
+	}
+}
+
+// loop is the main fetcher loop, processing announcements and deliveries and
+// scheduling retrievals.
+func (f *TxFetcher) loop() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-f.quit:
+			return
+
+		case ann := <-f.notify:
+			txAnnounceInMeter.Mark(int64(len(ann.hashes)))
+
+			announces := f.announces[ann.origin]
+			if announces == nil {
+				announces = make(map[common.Hash]struct{})
+				f.announces[ann.origin] = announces
+			}
+			for _, hash := range ann.hashes {
+				if _, ok := announces[hash]; ok {
+					continue
+				}
+				if f.hasTx(hash) {
+					txAnnounceKnownMeter.Mark(1)
+					continue
+				}
+				if len(announces) >= maxTxAnnounces {
+					log.Debug("Peer exceeded outstanding transaction announces", "peer", ann.origin, "limit", maxTxAnnounces)
+					txAnnounceDOSMeter.Mark(1)
+					break
+				}
+				announces[hash] = struct{}{}
+				if f.announced[hash] == nil {
+					f.announced[hash] = make(map[string]struct{})
+				}
+				f.announced[hash][ann.origin] = struct{}{}
+				if _, ok := f.waiting[hash]; !ok {
+					f.waiting[hash] = time.Now()
+				}
+			}
+			if len(announces) == 0 {
+				delete(f.announces, ann.origin)
+			}
+
+		case delivery := <-f.enqueue:
+			for _, hash := range delivery.hashes {
+				f.forgetTx(hash)
+			}
+			// If this was a reply, anything requested but not delivered is unavailable
+			// at the peer, so retry it elsewhere
+			if req := f.requests[delivery.origin]; delivery.direct && req != nil {
+				for _, hash := range req.hashes {
+					if f.fetching[hash] == delivery.origin {
+						delete(f.fetching, hash)
+						f.forgetAnnounce(delivery.origin, hash)
+					}
+				}
+				delete(f.requests, delivery.origin)
+			}
+
+		case peer := <-f.drop:
+			if req := f.requests[peer]; req != nil {
+				for _, hash := range req.hashes {
+					if f.fetching[hash] == peer {
+						delete(f.fetching, hash)
+					}
+				}
+				delete(f.requests, peer)
+			}
+			for hash := range f.announces[peer] {
+				f.forgetAnnounce(peer, hash)
+			}
+
+		case <-timer.C:
+			// Expire any retrievals that took too long, retrying them elsewhere
+			for peer, req := range f.requests {
+				if time.Since(req.time) < txFetchTimeout {
+					continue
+				}
+				log.Debug("Transaction retrieval timed out", "peer", peer, "count", len(req.hashes))
+				for _, hash := range req.hashes {
+					if f.fetching[hash] == peer {
+						txFetchTimeoutMeter.Mark(1)
+						delete(f.fetching, hash)
+						f.forgetAnnounce(peer, hash)
+					}
+				}
+				delete(f.requests, peer)
+			}
+		}
+		f.scheduleFetches()
+		f.rescheduleTimer(timer)
+	}
+}
+
+// scheduleFetches requests the transactions which didn't arrive in time from
+// the idle peers that announced them, spreading the load randomly.
+func (f *TxFetcher) scheduleFetches() {
+	if len(f.waiting) == len(f.fetching) {
+		return
+	}
+	peers := make([]string, 0, len(f.announces))
+	for peer := range f.announces {
+		if _, busy := f.requests[peer]; !busy {
+			peers = append(peers, peer)
+		}
+	}
+	for _, i := range rand.Perm(len(peers)) {
+		peer := peers[i]
+
+		var hashes []common.Hash
+		for hash := range f.announces[peer] {
+			if _, ok := f.fetching[hash]; ok {
+				continue
+			}
+			if time.Since(f.waiting[hash]) < txArriveTimeout {
+				continue
+			}
+			if f.hasTx(hash) {
+				f.forgetTx(hash)
+				continue
+			}
+			f.fetching[hash] = peer
+			if hashes = append(hashes, hash); len(hashes) >= maxTxRetrievals {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+		f.requests[peer] = &txRequest{hashes: hashes, time: time.Now()}
+		txFetchOutMeter.Mark(int64(len(hashes)))
+
+		log.Trace("Fetching scheduled transactions", "peer", peer, "count", len(hashes))
+		go func(peer string, hashes []common.Hash) {
+			if f.fetchingHook != nil {
+				f.fetchingHook(peer, hashes)
+			}
+			if err := f.fetchTxs(peer, hashes); err != nil {
+				log.Debug("Failed to request transactions", "peer", peer, "err", err)
+			}
+		}(peer, hashes)
+	}
+}
+
+// rescheduleTimer resets the timer to the next announce or request timeout.
+func (f *TxFetcher) rescheduleTimer(timer *time.Timer) {
+	var (
+		now      = time.Now()
+		earliest time.Time
+	)
+	for hash, seen := range f.waiting {
+		if _, ok := f.fetching[hash]; ok {
+			continue
+		}
+		// Expired announces are waiting for a busy peer, which will trigger an event
+		deadline := seen.Add(txArriveTimeout)
+		if deadline.Before(now) {
+			continue
+		}
+		if earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	for _, req := range f.requests {
+		if deadline := req.time.Add(txFetchTimeout); earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	if earliest.IsZero() {
+		return
+	}
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(earliest.Sub(now))
+}
+
+// forgetAnnounce removes a single transaction announcement of a peer, dropping
+// the transaction altogether if no other peer announced it.
+func (f *TxFetcher) forgetAnnounce(peer string, hash common.Hash) {
+	if announces := f.announces[peer]; announces != nil {
+		delete(announces, hash)
+		if len(announces) == 0 {
+			delete(f.announces, peer)
+		}
+	}
+	if announcers := f.announced[hash]; announcers != nil {
+		delete(announcers, peer)
+		if len(announcers) == 0 {
+			delete(f.announced, hash)
+			if _, ok := f.fetching[hash]; !ok {
+				delete(f.waiting, hash)
+			}
+		}
+	}
+}
+
+// forgetTx removes all traces of a transaction that arrived.
+func (f *TxFetcher) forgetTx(hash common.Hash) {
+	for peer := range f.announced[hash] {
+		if announces := f.announces[peer]; announces != nil {
+			delete(announces, hash)
+			if len(announces) == 0 {
+				delete(f.announces, peer)
+			}
+		}
+	}
+	delete(f.announced, hash)
+	delete(f.waiting, hash)
+	delete(f.fetching, hash)
+}
@@ -0,0 +1,266 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fetcher
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+)
+
+// txFetchRequest is a retrieval request sent out by the fetcher under test.
+type txFetchRequest struct {
+	peer   string
+	hashes []common.Hash
+}
+
+// txFetcherTester is a test simulator for mocking out the transaction pool and
+// the remote peers.
+type txFetcherTester struct {
+	fetcher *TxFetcher
+
+	pool     map[common.Hash]*types.Transaction // Transactions injected into the pool
+	requests chan *txFetchRequest               // Retrieval requests sent by the fetcher
+	lock     sync.RWMutex
+}
+
+// newTxFetcherTester creates a new transaction fetcher test mocker.
+func newTxFetcherTester() *txFetcherTester {
+	tester := &txFetcherTester{
+		pool:     make(map[common.Hash]*types.Transaction),
+		requests: make(chan *txFetchRequest, 64),
+	}
+	tester.fetcher = NewTxFetcher(tester.hasTx, tester.addTxs, tester.fetchTxs)
+	tester.fetcher.Start()
+	return tester
+}
+
+// hasTx checks whether a transaction is in the simulated pool.
+func (t *txFetcherTester) hasTx(hash common.Hash) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	_, ok := t.pool[hash]
+	return ok
+}
+
+// addTxs injects transactions into the simulated pool.
+func (t *txFetcherTester) addTxs(txs []*types.Transaction) []error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, tx := range txs {
+		t.pool[tx.Hash()] = tx
+	}
+	return make([]error, len(txs))
+}
+
+// fetchTxs records a retrieval request of the fetcher.
+func (t *txFetcherTester) fetchTxs(peer string, hashes []common.Hash) error {
+	t.requests <- &txFetchRequest{peer: peer, hashes: hashes}
+	return nil
+}
+
+// makeTxs creates a batch of distinct transactions.
+func makeTxs(n int) ([]*types.Transaction, []common.Hash) {
+	txs := make([]*types.Transaction, n)
+	hashes := make([]common.Hash, n)
+	for i := 0; i < n; i++ {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+		hashes[i] = txs[i].Hash()
+	}
+	return txs, hashes
+}
+
+// verifyTxRequest waits for a retrieval request and checks its origin and size.
+func verifyTxRequest(t *testing.T, requests chan *txFetchRequest, peer string, count int) *txFetchRequest {
+	select {
+	case req := <-requests:
+		if req.peer != peer {
+			t.Fatalf("request peer mismatch: have %s, want %s", req.peer, peer)
+		}
+		if len(req.hashes) != count {
+			t.Fatalf("request size mismatch: have %d, want %d", len(req.hashes), count)
+		}
+		return req
+	case <-time.After(txArriveTimeout + time.Second):
+		t.Fatalf("retrieval timeout")
+	}
+	return nil
+}
+
+// verifyNoTxRequest checks that no retrieval request is sent for a while.
+func verifyNoTxRequest(t *testing.T, requests chan *txFetchRequest, wait time.Duration) {
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected request to %s for %d transactions", req.peer, len(req.hashes))
+	case <-time.After(wait):
+	}
+}
+
+// Tests that announced transactions are retrieved from the announcing peer if
+// they don't arrive in time, and injected into the pool once delivered.
+func TestTxFetcherAnnounce(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(10)
+	tester.fetcher.Notify("peer", hashes)
+
+	start := time.Now()
+	verifyTxRequest(t, tester.requests, "peer", len(hashes))
+	if elapsed := time.Since(start); elapsed < txArriveTimeout-50*time.Millisecond {
+		t.Fatalf("transactions requested too early: %v", elapsed)
+	}
+	tester.fetcher.Enqueue("peer", txs, true)
+
+	// Ensure the transactions are imported and not retrieved again
+	time.Sleep(50 * time.Millisecond)
+	for _, hash := range hashes {
+		if !tester.hasTx(hash) {
+			t.Fatalf("transaction %x not imported", hash)
+		}
+	}
+	tester.fetcher.Notify("other", hashes)
+	verifyNoTxRequest(t, tester.requests, txArriveTimeout+100*time.Millisecond)
+}
+
+// Tests that transactions arriving by direct broadcast are not retrieved.
+func TestTxFetcherBroadcastCancel(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(10)
+	tester.fetcher.Notify("peer", hashes)
+	tester.fetcher.Enqueue("other", txs[:5], false)
+
+	req := verifyTxRequest(t, tester.requests, "peer", 5)
+	for _, hash := range req.hashes {
+		for _, tx := range txs[:5] {
+			if tx.Hash() == hash {
+				t.Fatalf("broadcast transaction %x requested", hash)
+			}
+		}
+	}
+}
+
+// Tests that transactions not delivered by the peer, whether timed out or
+// missing from the reply, are retrieved from other announcers.
+func TestTxFetcherRetry(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(4)
+	tester.fetcher.Notify("first", hashes)
+	verifyTxRequest(t, tester.requests, "first", 4)
+
+	// Have another peer announce while the retrieval is running, and reply with a
+	// partial response
+	tester.fetcher.Notify("second", hashes)
+	verifyNoTxRequest(t, tester.requests, 100*time.Millisecond)
+	tester.fetcher.Enqueue("first", txs[:2], true)
+
+	verifyTxRequest(t, tester.requests, "second", 2)
+
+	// Don't reply at all, the retrieval should time out and not be retried as
+	// nobody else announced the transactions
+	verifyNoTxRequest(t, tester.requests, txFetchTimeout+200*time.Millisecond)
+	if tester.hasTx(hashes[2]) || tester.hasTx(hashes[3]) {
+		t.Fatalf("undelivered transactions imported")
+	}
+}
+
+// Tests that the retrievals of a dropped peer are rescheduled to others.
+func TestTxFetcherDrop(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	_, hashes := makeTxs(4)
+	tester.fetcher.Notify("first", hashes)
+	verifyTxRequest(t, tester.requests, "first", 4)
+
+	tester.fetcher.Notify("second", hashes[:2])
+	verifyNoTxRequest(t, tester.requests, 100*time.Millisecond)
+
+	tester.fetcher.Drop("first")
+	verifyTxRequest(t, tester.requests, "second", 2)
+}
+
+// Tests that retrievals are capped per request and that a peer can't announce an
+// unbounded number of transactions.
+func TestTxFetcherLimits(t *testing.T) {
+	tester := newTxFetcherTester()
+	defer tester.fetcher.Stop()
+
+	txs, hashes := makeTxs(maxTxAnnounces + 100)
+	tester.fetcher.Notify("peer", hashes)
+
+	req := verifyTxRequest(t, tester.requests, "peer", maxTxRetrievals)
+	verifyNoTxRequest(t, tester.requests, 100*time.Millisecond)
+
+	// Deliver everything requested, the next batch should be scheduled
+	requested := make(map[common.Hash]bool)
+	for _, hash := range req.hashes {
+		requested[hash] = true
+	}
+	var reply []*types.Transaction
+	for _, tx := range txs {
+		if requested[tx.Hash()] {
+			reply = append(reply, tx)
+		}
+	}
+	tester.fetcher.Enqueue("peer", reply, true)
+	req = verifyTxRequest(t, tester.requests, "peer", maxTxRetrievals)
+	for _, hash := range req.hashes {
+		requested[hash] = true
+	}
+	// Anything over the limit should have been discarded
+	for _, hash := range hashes[maxTxAnnounces:] {
+		if requested[hash] {
+			t.Fatalf("transaction %x above the announce limit requested", hash)
+		}
+	}
+}
+
+// Tests that transactions are added to the pool on the delivering goroutine, so
+// a slow pool insertion doesn't stall announcements from other peers.
+func TestTxFetcherSlowPool(t *testing.T) {
+	tester := newTxFetcherTester()
+
+	var (
+		entered = make(chan struct{})
+		release = make(chan struct{})
+	)
+	tester.fetcher.addTxs = func(txs []*types.Transaction) []error {
+		close(entered)
+		<-release
+		return tester.addTxs(txs)
+	}
+	defer tester.fetcher.Stop()
+	defer close(release)
+
+	txs, hashes := makeTxs(11)
+	go tester.fetcher.Enqueue("slow", txs[:1], false)
+	<-entered
+
+	go tester.fetcher.Notify("peer", hashes[1:])
+	verifyTxRequest(t, tester.requests, "peer", len(hashes)-1)
+}
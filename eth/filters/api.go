@@ -28,6 +28,7 @@ import (
 	ethereum "git.pirl.io/bitcoiin/go-bitcoiin"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
@@ -168,6 +169,53 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// droppedTransaction is the notification sent for a transaction leaving the
+// pool without being included in the chain.
+type droppedTransaction struct {
+	Hash        common.Hash  `json:"hash"`
+	Reason      string       `json:"reason"`
+	Replacement *common.Hash `json:"replacement,omitempty"`
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is removed from the transaction pool without being included in
+// the chain, e.g. replaced, evicted or invalidated by a reorg.
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan []*core.DroppedTx, 128)
+		droppedTxSub := api.events.SubscribeDroppedTxs(drops)
+
+		for {
+			select {
+			case txs := <-drops:
+				for _, drop := range txs {
+					notification := &droppedTransaction{Hash: drop.Tx.Hash(), Reason: drop.Reason.String()}
+					if drop.Replacement != (common.Hash{}) {
+						replacement := drop.Replacement
+						notification.Replacement = &replacement
+					}
+					notifier.Notify(rpcSub.ID, notification)
+				}
+			case <-rpcSub.Err():
+				droppedTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				droppedTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
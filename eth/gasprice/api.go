@@ -0,0 +1,80 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+)
+
+// PublicGasPriceAPI offers gas price recommendations beyond the single price of
+// eth_gasPrice.
+type PublicGasPriceAPI struct {
+	oracle *Oracle
+}
+
+// NewPublicGasPriceAPI creates a new gas price API backed by the given oracle.
+func NewPublicGasPriceAPI(oracle *Oracle) *PublicGasPriceAPI {
+	return &PublicGasPriceAPI{oracle: oracle}
+}
+
+// FeeHistoryResult is the fee history of a range of blocks.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio and the given percentiles of the gas
+// prices paid in the blockCount blocks ending with lastBlock.
+func (api *PublicGasPriceAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, percentiles []float64) (*FeeHistoryResult, error) {
+	oldest, rewards, ratios, err := api.oracle.FeeHistory(ctx, int(blockCount), lastBlock, percentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+		GasUsedRatio: ratios,
+	}
+	if rewards != nil {
+		result.Reward = make([][]*hexutil.Big, len(rewards))
+		for i, block := range rewards {
+			result.Reward[i] = make([]*hexutil.Big, len(block))
+			for j, reward := range block {
+				result.Reward[i][j] = (*hexutil.Big)(reward)
+			}
+		}
+	}
+	return result, nil
+}
+
+// SuggestGasPrices returns the recommended gas prices for slow, standard and
+// fast transaction inclusion.
+func (api *PublicGasPriceAPI) SuggestGasPrices(ctx context.Context) (map[string]*hexutil.Big, error) {
+	tiers, err := api.oracle.SuggestPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]*hexutil.Big{
+		"slow":     (*hexutil.Big)(tiers.Slow),
+		"standard": (*hexutil.Big)(tiers.Standard),
+		"fast":     (*hexutil.Big)(tiers.Fast),
+	}, nil
+}
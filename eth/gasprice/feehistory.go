@@ -0,0 +1,204 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sort"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks that can be retrieved for a
+// fee history request.
+const maxFeeHistory = 1024
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// blockFees is the fee summary of a single block.
+type blockFees struct {
+	number       uint64
+	gasUsedRatio float64
+	rewards      []*big.Int
+	err          error
+}
+
+// txGasAndPrice is the gas used and the gas price paid by a transaction.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+type txGasAndPrices []txGasAndPrice
+
+func (s txGasAndPrices) Len() int           { return len(s) }
+func (s txGasAndPrices) Less(i, j int) bool { return s[i].price.Cmp(s[j].price) < 0 }
+func (s txGasAndPrices) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// FeeHistory returns the gas used ratio and the requested percentiles of the
+// gas prices paid in a range of blocks ending with lastBlock. The percentiles
+// are weighted by the gas used by each transaction, and must be monotonically
+// increasing values within [0, 100].
+//
+// The number of the oldest block in the returned range is returned along the
+// per block data.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, percentiles []float64) (uint64, [][]*big.Int, []float64, error) {
+	if blocks < 1 {
+		return 0, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range percentiles {
+		if p < 0 || p > 100 || (i > 0 && p < percentiles[i-1]) {
+			return 0, nil, nil, fmt.Errorf("%v: #%d: %f", errInvalidPercentile, i, p)
+		}
+	}
+	// Resolve the last block of the range
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return 0, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if lastBlock >= 0 {
+		if uint64(lastBlock) > last {
+			return 0, nil, nil, fmt.Errorf("%v: requested %d, head %d", errRequestBeyondHead, lastBlock, last)
+		}
+		last = uint64(lastBlock)
+	}
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	// Gather the fee summaries of all the blocks on a bounded set of workers,
+	// aborting the pending ones if any of them fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		numbers = make(chan uint64, blocks)
+		ch      = make(chan blockFees, blocks)
+	)
+	for number := oldest; number <= last; number++ {
+		numbers <- number
+	}
+	close(numbers)
+
+	workers := runtime.NumCPU()
+	if workers > blocks {
+		workers = blocks
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for number := range numbers {
+				if err := ctx.Err(); err != nil {
+					ch <- blockFees{number: number, err: err}
+					continue
+				}
+				gpo.getBlockFees(ctx, number, percentiles, ch)
+			}
+		}()
+	}
+	var (
+		rewards = make([][]*big.Int, blocks)
+		ratios  = make([]float64, blocks)
+	)
+	for i := 0; i < blocks; i++ {
+		res := <-ch
+		if res.err != nil {
+			return 0, nil, nil, res.err
+		}
+		rewards[res.number-oldest] = res.rewards
+		ratios[res.number-oldest] = res.gasUsedRatio
+	}
+	if len(percentiles) == 0 {
+		rewards = nil
+	}
+	return oldest, rewards, ratios, nil
+}
+
+// getBlockFees calculates the fee summary of the given block and sends it to
+// the result channel.
+func (gpo *Oracle) getBlockFees(ctx context.Context, number uint64, percentiles []float64, ch chan blockFees) {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		ch <- blockFees{number: number, err: err}
+		return
+	}
+	fees := blockFees{number: number}
+	if block.GasLimit() > 0 {
+		fees.gasUsedRatio = float64(block.GasUsed()) / float64(block.GasLimit())
+	}
+	if len(percentiles) == 0 || len(block.Transactions()) == 0 {
+		fees.rewards = make([]*big.Int, len(percentiles))
+		for i := range fees.rewards {
+			fees.rewards[i] = new(big.Int)
+		}
+		ch <- fees
+		return
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil || len(receipts) != len(block.Transactions()) {
+		if err == nil {
+			err = fmt.Errorf("receipts of block #%d unavailable", number)
+		}
+		ch <- blockFees{number: number, err: err}
+		return
+	}
+	txs := make([]txGasAndPrice, len(receipts))
+	for i, tx := range block.Transactions() {
+		txs[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+	}
+	fees.rewards = pricePercentiles(txs, percentiles)
+	ch <- fees
+}
+
+// pricePercentiles calculates the gas used weighted percentiles of the prices
+// paid by the given transactions.
+func pricePercentiles(txs []txGasAndPrice, percentiles []float64) []*big.Int {
+	sort.Sort(txGasAndPrices(txs))
+
+	var total uint64
+	for _, tx := range txs {
+		total += tx.gasUsed
+	}
+	var (
+		rewards    = make([]*big.Int, len(percentiles))
+		idx        = 0
+		cumulative = txs[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(total) * p / 100)
+		for cumulative < threshold && idx < len(txs)-1 {
+			idx++
+			cumulative += txs[idx].gasUsed
+		}
+		rewards[i] = new(big.Int).Set(txs[idx].price)
+	}
+	return rewards
+}
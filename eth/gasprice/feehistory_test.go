@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/internal/ethapi"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+)
+
+// feeHistoryBackend serves empty blocks up to a fixed head, tracking how many
+// blocks are being retrieved concurrently.
+type feeHistoryBackend struct {
+	ethapi.Backend
+
+	head    uint64
+	active  int32
+	maxSeen int32
+}
+
+func (b *feeHistoryBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return &types.Header{Number: new(big.Int).SetUint64(b.head)}, nil
+}
+
+func (b *feeHistoryBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	active := atomic.AddInt32(&b.active, 1)
+	defer atomic.AddInt32(&b.active, -1)
+
+	for {
+		seen := atomic.LoadInt32(&b.maxSeen)
+		if active <= seen || atomic.CompareAndSwapInt32(&b.maxSeen, seen, active) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	header := &types.Header{Number: big.NewInt(int64(number)), GasLimit: 100, GasUsed: uint64(number) % 100}
+	return types.NewBlockWithHeader(header), nil
+}
+
+// Tests that the blocks of a fee history request are retrieved by a bounded
+// number of workers, and that the results are assembled in block order.
+func TestFeeHistoryConcurrency(t *testing.T) {
+	backend := &feeHistoryBackend{head: 2000}
+	oracle := NewOracle(backend, Config{})
+
+	oldest, rewards, ratios, err := oracle.FeeHistory(context.Background(), maxFeeHistory, rpc.LatestBlockNumber, []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if want := backend.head + 1 - maxFeeHistory; oldest != want {
+		t.Fatalf("oldest block mismatch: have %d, want %d", oldest, want)
+	}
+	if len(rewards) != maxFeeHistory || len(ratios) != maxFeeHistory {
+		t.Fatalf("result length mismatch: have %d rewards, %d ratios, want %d", len(rewards), len(ratios), maxFeeHistory)
+	}
+	for i, ratio := range ratios {
+		if want := float64((oldest+uint64(i))%100) / 100; ratio != want {
+			t.Fatalf("block %d: gas used ratio mismatch: have %v, want %v", oldest+uint64(i), ratio, want)
+		}
+	}
+	if max := atomic.LoadInt32(&backend.maxSeen); int(max) > runtime.NumCPU() {
+		t.Fatalf("too many concurrent retrievals: have %d, want at most %d", max, runtime.NumCPU())
+	}
+}
+
+// Tests that the price percentiles of a block are weighted by the gas used by
+// the individual transactions.
+func TestPricePercentiles(t *testing.T) {
+	txs := []txGasAndPrice{
+		{gasUsed: 21000, price: big.NewInt(30)},
+		{gasUsed: 21000, price: big.NewInt(10)},
+		{gasUsed: 42000, price: big.NewInt(20)},
+		{gasUsed: 84000, price: big.NewInt(40)},
+	}
+	tests := []struct {
+		percentile float64
+		price      int64
+	}{
+		{0, 10}, {12.5, 10}, {20, 20}, {37.5, 20}, {50, 30}, {51, 40}, {100, 40},
+	}
+	percentiles := make([]float64, len(tests))
+	for i, tt := range tests {
+		percentiles[i] = tt.percentile
+	}
+	rewards := pricePercentiles(txs, percentiles)
+	for i, tt := range tests {
+		if rewards[i].Int64() != tt.price {
+			t.Errorf("percentile %v: price mismatch: have %v, want %d", tt.percentile, rewards[i], tt.price)
+		}
+	}
+}
+
+// Tests that the pool depth price is the price of the transaction at which the
+// requested amount of gas is used up.
+func TestPoolDepthPrice(t *testing.T) {
+	var txs []*types.Transaction
+	for _, price := range []int64{50, 40, 30, 20} {
+		txs = append(txs, types.NewTransaction(0, common.Address{}, new(big.Int), 100000, big.NewInt(price), nil))
+	}
+	tests := []struct {
+		depth uint64
+		price *big.Int
+	}{
+		{50000, big.NewInt(50)},
+		{100000, big.NewInt(50)},
+		{100001, big.NewInt(40)},
+		{400000, big.NewInt(20)},
+		{400001, nil},
+	}
+	for _, tt := range tests {
+		price := poolDepthPrice(txs, tt.depth)
+		if (price == nil) != (tt.price == nil) || (price != nil && price.Cmp(tt.price) != 0) {
+			t.Errorf("depth %d: price mismatch: have %v, want %v", tt.depth, price, tt.price)
+		}
+	}
+}
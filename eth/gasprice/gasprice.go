@@ -31,12 +31,25 @@ import (
 
 var maxPrice = big.NewInt(500 * params.GWei)
 
+const (
+	slowBlocks     = 10 // Number of blocks the slow tier is willing to wait for inclusion
+	standardBlocks = 3  // Number of blocks the standard tier is willing to wait for inclusion
+	fastBlocks     = 1  // Number of blocks the fast tier is willing to wait for inclusion
+)
+
 type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
 }
 
+// PriceTiers are the recommended gas prices for different inclusion speeds.
+type PriceTiers struct {
+	Slow     *big.Int
+	Standard *big.Int
+	Fast     *big.Int
+}
+
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
@@ -98,7 +111,74 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		return lastPrice, nil
 	}
 
-	blockNum := head.Number.Uint64()
+	blockPrices, err := gpo.recentPrices(ctx, head.Number.Uint64())
+	if err != nil {
+		return lastPrice, err
+	}
+	price := lastPrice
+	if len(blockPrices) > 0 {
+		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
+	}
+	if price.Cmp(maxPrice) > 0 {
+		price = new(big.Int).Set(maxPrice)
+	}
+
+	gpo.cacheLock.Lock()
+	gpo.lastHead = headHash
+	gpo.lastPrice = price
+	gpo.cacheLock.Unlock()
+	return price, nil
+}
+
+// SuggestPrices returns the recommended gas prices for slow, standard and fast
+// inclusion. The tiers are derived from the minimum prices of recent blocks and
+// raised if needed to outbid the pending pool for the given number of blocks.
+func (gpo *Oracle) SuggestPrices(ctx context.Context) (*PriceTiers, error) {
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return nil, err
+	}
+	blockPrices, err := gpo.recentPrices(ctx, head.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	gpo.cacheLock.RLock()
+	lastPrice := gpo.lastPrice
+	gpo.cacheLock.RUnlock()
+
+	tiers := &PriceTiers{Slow: lastPrice, Standard: lastPrice, Fast: lastPrice}
+	if len(blockPrices) > 0 {
+		tiers.Slow = blockPrices[(len(blockPrices)-1)*(gpo.percentile/2)/100]
+		tiers.Standard = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
+		tiers.Fast = blockPrices[(len(blockPrices)-1)*((gpo.percentile+100)/2)/100]
+	}
+	// Raise the tiers to get ahead of the pending transactions
+	pending, err := gpo.backend.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*types.Transaction, len(pending))
+	copy(txs, pending)
+	sort.Sort(sort.Reverse(transactionsByGasPrice(txs)))
+
+	tiers.Slow = maxBig(tiers.Slow, poolDepthPrice(txs, slowBlocks*head.GasLimit))
+	tiers.Standard = maxBig(tiers.Standard, poolDepthPrice(txs, standardBlocks*head.GasLimit))
+	tiers.Fast = maxBig(tiers.Fast, poolDepthPrice(txs, fastBlocks*head.GasLimit))
+
+	// Keep the tiers ordered and below the sanity cap
+	tiers.Standard = maxBig(tiers.Standard, tiers.Slow)
+	tiers.Fast = maxBig(tiers.Fast, tiers.Standard)
+	for _, price := range []**big.Int{&tiers.Slow, &tiers.Standard, &tiers.Fast} {
+		if *price != nil && (*price).Cmp(maxPrice) > 0 {
+			*price = new(big.Int).Set(maxPrice)
+		}
+	}
+	return tiers, nil
+}
+
+// recentPrices retrieves the minimum gas prices of the recent non-empty blocks
+// up to the given head, sorted in ascending order.
+func (gpo *Oracle) recentPrices(ctx context.Context, blockNum uint64) ([]*big.Int, error) {
 	ch := make(chan getBlockPricesResult, gpo.checkBlocks)
 	sent := 0
 	exp := 0
@@ -113,7 +193,7 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	for exp > 0 {
 		res := <-ch
 		if res.err != nil {
-			return lastPrice, res.err
+			return nil, res.err
 		}
 		exp--
 		if res.price != nil {
@@ -131,20 +211,29 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 			blockNum--
 		}
 	}
-	price := lastPrice
-	if len(blockPrices) > 0 {
-		sort.Sort(bigIntArray(blockPrices))
-		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
-	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+	sort.Sort(bigIntArray(blockPrices))
+	return blockPrices, nil
+}
+
+// poolDepthPrice returns the gas price of the pending transaction at which the
+// given amount of gas is used up, when walking the pool from the best paying
+// transaction. Nil is returned if the pool is shallower than that.
+func poolDepthPrice(txs []*types.Transaction, depth uint64) *big.Int {
+	var gas uint64
+	for _, tx := range txs {
+		if gas += tx.Gas(); gas >= depth {
+			return tx.GasPrice()
+		}
 	}
+	return nil
+}
 
-	gpo.cacheLock.Lock()
-	gpo.lastHead = headHash
-	gpo.lastPrice = price
-	gpo.cacheLock.Unlock()
-	return price, nil
+// maxBig returns the larger of two prices, treating nil as missing.
+func maxBig(a, b *big.Int) *big.Int {
+	if b == nil || (a != nil && a.Cmp(b) >= 0) {
+		return a
+	}
+	return b
 }
 
 type getBlockPricesResult struct {
@@ -12,6 +12,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/core"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/downloader"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/gasprice"
+	"git.pirl.io/bitcoiin/go-bitcoiin/miner"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -23,10 +24,12 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		SyncFrom                *downloader.SyncCheckpoint `toml:",omitempty"`
+		DiscoveryURLs           []string                   `toml:",omitempty"`
+		LightServ               int                        `toml:",omitempty"`
+		LightPeers              int                        `toml:",omitempty"`
+		SkipBcVersionCheck      bool                       `toml:"-"`
+		DatabaseHandles         int                        `toml:"-"`
 		DatabaseCache           int
 		TrieCleanCache          int
 		TrieDirtyCache          int
@@ -39,6 +42,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerGasPrice           *big.Int
 		MinerRecommit           time.Duration
 		MinerNoverify           bool
+		MinerTxOrder            string           `toml:",omitempty"`
+		MinerAllowlist          []common.Address `toml:",omitempty"`
+		MinerPool               miner.PoolConfig
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -52,6 +58,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.SyncFrom = c.SyncFrom
+	enc.DiscoveryURLs = c.DiscoveryURLs
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
@@ -68,6 +76,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.MinerRecommit = c.MinerRecommit
 	enc.MinerNoverify = c.MinerNoverify
+	enc.MinerTxOrder = c.MinerTxOrder
+	enc.MinerAllowlist = c.MinerAllowlist
+	enc.MinerPool = c.MinerPool
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -85,10 +96,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		SyncFrom                *downloader.SyncCheckpoint `toml:",omitempty"`
+		DiscoveryURLs           []string                   `toml:",omitempty"`
+		LightServ               *int                       `toml:",omitempty"`
+		LightPeers              *int                       `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                      `toml:"-"`
+		DatabaseHandles         *int                       `toml:"-"`
 		DatabaseCache           *int
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
@@ -101,6 +114,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerGasPrice           *big.Int
 		MinerRecommit           *time.Duration
 		MinerNoverify           *bool
+		MinerTxOrder            *string          `toml:",omitempty"`
+		MinerAllowlist          []common.Address `toml:",omitempty"`
+		MinerPool               *miner.PoolConfig
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
@@ -125,6 +141,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.SyncFrom != nil {
+		c.SyncFrom = dec.SyncFrom
+	}
+	if dec.DiscoveryURLs != nil {
+		c.DiscoveryURLs = dec.DiscoveryURLs
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -173,6 +195,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinerNoverify != nil {
 		c.MinerNoverify = *dec.MinerNoverify
 	}
+	if dec.MinerTxOrder != nil {
+		c.MinerTxOrder = *dec.MinerTxOrder
+	}
+	if dec.MinerAllowlist != nil {
+		c.MinerAllowlist = dec.MinerAllowlist
+	}
+	if dec.MinerPool != nil {
+		c.MinerPool = *dec.MinerPool
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
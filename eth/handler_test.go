@@ -35,6 +35,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/dnsdisc"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
 )
 
@@ -524,7 +525,7 @@ func testCheckpointChallenge(t *testing.T, syncmode downloader.SyncMode, checkpo
 	if err != nil {
 		t.Fatalf("failed to create new blockchain: %v", err)
 	}
-	pm, err := NewProtocolManager(config, syncmode, DefaultConfig.NetworkId, new(event.TypeMux), new(testTxPool), ethash.NewFaker(), blockchain, db, nil)
+	pm, err := NewProtocolManager(config, syncmode, DefaultConfig.NetworkId, new(event.TypeMux), new(testTxPool), ethash.NewFaker(), blockchain, db)
 	if err != nil {
 		t.Fatalf("failed to start test protocol manager: %v", err)
 	}
@@ -611,7 +612,7 @@ func testBroadcastBlock(t *testing.T, totalPeers, broadcastExpected int) {
 	if err != nil {
 		t.Fatalf("failed to create new blockchain: %v", err)
 	}
-	pm, err := NewProtocolManager(config, downloader.FullSync, DefaultConfig.NetworkId, evmux, new(testTxPool), pow, blockchain, db, nil)
+	pm, err := NewProtocolManager(config, downloader.FullSync, DefaultConfig.NetworkId, evmux, new(testTxPool), pow, blockchain, db)
 	if err != nil {
 		t.Fatalf("failed to start test protocol manager: %v", err)
 	}
@@ -663,3 +664,26 @@ outer:
 		t.Errorf("block broadcast to %d peers, expected %d", receivedCount, broadcastExpected)
 	}
 }
+
+// Tests that the DNS discovery dial candidates are only attached to a single eth
+// protocol version, so the dialer doesn't consume the shared source repeatedly.
+func TestProtocolsDialCandidates(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	client, err := dnsdisc.NewClient(dnsdisc.Config{})
+	if err != nil {
+		t.Fatalf("failed to create DNS discovery client: %v", err)
+	}
+	eth := &Ethereum{protocolManager: pm, blockchain: pm.blockchain, dialCandidates: client}
+
+	sources := 0
+	for _, proto := range eth.Protocols() {
+		if proto.DialCandidates != nil {
+			sources++
+		}
+	}
+	if sources != 1 {
+		t.Fatalf("dial candidates attached to %d protocols, want 1", sources)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	mapset "github.com/deckarep/golang-set"
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/forkid"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
@@ -45,6 +46,15 @@ const (
 	// contain a single transaction, or thousands.
 	maxQueuedTxs = 128
 
+	// maxQueuedTxAnns is the maximum number of transaction announcement lists to
+	// queue up before dropping broadcasts. Announcements are cheap, but there is
+	// no point in queueing them up if the peer can't keep up.
+	maxQueuedTxAnns = 128
+
+	// maxTxAnnounceHashes is the maximum number of transaction hashes to announce
+	// in a single message, matching the outstanding announce limit of the fetcher.
+	maxTxAnnounceHashes = 4096
+
 	// maxQueuedProps is the maximum number of block propagations to queue up before
 	// dropping broadcasts. There's not much point in queueing stale blocks, so a few
 	// that might cover uncles should be enough.
@@ -85,26 +95,28 @@ type peer struct {
 	td   *big.Int
 	lock sync.RWMutex
 
-	knownTxs    mapset.Set                // Set of transaction hashes known to be known by this peer
-	knownBlocks mapset.Set                // Set of block hashes known to be known by this peer
-	queuedTxs   chan []*types.Transaction // Queue of transactions to broadcast to the peer
-	queuedProps chan *propEvent           // Queue of blocks to broadcast to the peer
-	queuedAnns  chan *types.Block         // Queue of blocks to announce to the peer
-	term        chan struct{}             // Termination channel to stop the broadcaster
+	knownTxs     mapset.Set                // Set of transaction hashes known to be known by this peer
+	knownBlocks  mapset.Set                // Set of block hashes known to be known by this peer
+	queuedTxs    chan []*types.Transaction // Queue of transactions to broadcast to the peer
+	queuedTxAnns chan []common.Hash        // Queue of transactions to announce to the peer (eth/65)
+	queuedProps  chan *propEvent           // Queue of blocks to broadcast to the peer
+	queuedAnns   chan *types.Block         // Queue of blocks to announce to the peer
+	term         chan struct{}             // Termination channel to stop the broadcaster
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 	return &peer{
-		Peer:        p,
-		rw:          rw,
-		version:     version,
-		id:          fmt.Sprintf("%x", p.ID().Bytes()[:8]),
-		knownTxs:    mapset.NewSet(),
-		knownBlocks: mapset.NewSet(),
-		queuedTxs:   make(chan []*types.Transaction, maxQueuedTxs),
-		queuedProps: make(chan *propEvent, maxQueuedProps),
-		queuedAnns:  make(chan *types.Block, maxQueuedAnns),
-		term:        make(chan struct{}),
+		Peer:         p,
+		rw:           rw,
+		version:      version,
+		id:           fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+		knownTxs:     mapset.NewSet(),
+		knownBlocks:  mapset.NewSet(),
+		queuedTxs:    make(chan []*types.Transaction, maxQueuedTxs),
+		queuedTxAnns: make(chan []common.Hash, maxQueuedTxAnns),
+		queuedProps:  make(chan *propEvent, maxQueuedProps),
+		queuedAnns:   make(chan *types.Block, maxQueuedAnns),
+		term:         make(chan struct{}),
 	}
 }
 
@@ -120,6 +132,18 @@ func (p *peer) broadcast() {
 			}
 			p.Log().Trace("Broadcast transactions", "count", len(txs))
 
+		case hashes := <-p.queuedTxAnns:
+			for i := 0; i < len(hashes); i += maxTxAnnounceHashes {
+				end := i + maxTxAnnounceHashes
+				if end > len(hashes) {
+					end = len(hashes)
+				}
+				if err := p.SendPooledTransactionHashes(hashes[i:end]); err != nil {
+					return
+				}
+			}
+			p.Log().Trace("Announced transactions", "count", len(hashes))
+
 		case prop := <-p.queuedProps:
 			if err := p.SendNewBlock(prop.block, prop.td); err != nil {
 				return
@@ -215,6 +239,38 @@ func (p *peer) AsyncSendTransactions(txs []*types.Transaction) {
 	}
 }
 
+// SendPooledTransactionHashes announces the availability of a batch of
+// transactions through a hash notification (eth/65).
+func (p *peer) SendPooledTransactionHashes(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash)
+	}
+	return p2p.Send(p.rw, NewPooledTransactionHashesMsg, hashes)
+}
+
+// AsyncSendPooledTransactionHashes queues a batch of transaction hashes for
+// announcement to a remote peer. If the peer's announcement queue is full, the
+// event is silently dropped.
+func (p *peer) AsyncSendPooledTransactionHashes(hashes []common.Hash) {
+	select {
+	case p.queuedTxAnns <- hashes:
+		for _, hash := range hashes {
+			p.knownTxs.Add(hash)
+		}
+	default:
+		p.Log().Debug("Dropping transaction announcement", "count", len(hashes))
+	}
+}
+
+// SendPooledTransactionsRLP sends a batch of transactions to the peer as a reply
+// to its retrieval request, from an already RLP encoded format.
+func (p *peer) SendPooledTransactionsRLP(hashes []common.Hash, txs []rlp.RawValue) error {
+	for _, hash := range hashes {
+		p.knownTxs.Add(hash)
+	}
+	return p2p.Send(p.rw, PooledTransactionsMsg, txs)
+}
+
 // SendNewBlockHashes announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendNewBlockHashes(hashes []common.Hash, numbers []uint64) error {
@@ -327,14 +383,35 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetReceiptsMsg, hashes)
 }
 
+// RequestTxs fetches a batch of transactions from a remote node's pool (eth/65).
+func (p *peer) RequestTxs(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
+	return p2p.Send(p.rw, GetPooledTransactionsMsg, hashes)
+}
+
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks. Since eth/64 the fork
+// identifiers of the chains are also exchanged and validated.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
 
+	var ( // safe to read after two values have been received from errc
+		status   statusData
+		status64 statusData64
+	)
 	go func() {
+		if p.version >= eth64 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData64{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				ForkID:          forkID,
+			})
+			return
+		}
 		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
@@ -344,6 +421,10 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		})
 	}()
 	go func() {
+		if p.version >= eth64 {
+			errc <- p.readStatus64(network, &status64, genesis, forkFilter)
+			return
+		}
 		errc <- p.readStatus(network, &status, genesis)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
@@ -358,11 +439,39 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
-	p.td, p.head = status.TD, status.CurrentBlock
+	if p.version >= eth64 {
+		p.td, p.head = status64.TD, status64.CurrentBlock
+	} else {
+		p.td, p.head = status.TD, status.CurrentBlock
+	}
+	return nil
+}
+
+// readStatus reads and validates a legacy status message of eth/62 and eth/63.
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) error {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	return p.checkStatus(network, status.NetworkId, genesis, status.GenesisBlock, status.ProtocolVersion)
+}
+
+// readStatus64 reads and validates an eth/64 status message, rejecting peers on
+// a fork incompatible with the local chain.
+func (p *peer) readStatus64(network uint64, status *statusData64, genesis common.Hash, forkFilter forkid.Filter) error {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	if err := p.checkStatus(network, status.NetworkId, genesis, status.GenesisBlock, status.ProtocolVersion); err != nil {
+		return err
+	}
+	if err := forkFilter(status.ForkID); err != nil {
+		return errResp(ErrForkIDRejected, "%v", err)
+	}
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+// readStatusMsg reads the first message of the peer and decodes it as a status.
+func (p *peer) readStatusMsg(status interface{}) error {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -373,18 +482,23 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if msg.Size > ProtocolMaxMsgSize {
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
-	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	// Decode the handshake
+	if err := msg.Decode(status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
-	if status.GenesisBlock != genesis {
-		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
+	return nil
+}
+
+// checkStatus makes sure the common fields of a status message match ours.
+func (p *peer) checkStatus(network, remoteNetwork uint64, genesis, remoteGenesis common.Hash, version uint32) error {
+	if remoteGenesis != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", remoteGenesis[:8], genesis[:8])
 	}
-	if status.NetworkId != network {
-		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
+	if remoteNetwork != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", remoteNetwork, network)
 	}
-	if int(status.ProtocolVersion) != p.version {
-		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	if int(version) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", version, p.version)
 	}
 	return nil
 }
@@ -0,0 +1,249 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+)
+
+// MakeProtocols constructs the P2P protocol definitions for snap. The state
+// database is used to serve remote requests, the syncer (if any) receives the
+// responses to the requests made during a local sync.
+func MakeProtocols(db state.Database, syncer *Syncer) []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure
+
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return handle(db, syncer, newPeer(version, p, rw))
+			},
+			NodeInfo: func() interface{} {
+				return nil
+			},
+			PeerInfo: func(id enode.ID) interface{} {
+				return nil
+			},
+		}
+	}
+	return protocols
+}
+
+// handle is the callback invoked to manage the life cycle of a snap peer. When
+// this function terminates, the peer is disconnected.
+func handle(db state.Database, syncer *Syncer, peer *Peer) error {
+	if syncer != nil {
+		syncer.Register(peer)
+		defer syncer.Unregister(peer.id)
+	}
+	for {
+		if err := handleMessage(db, syncer, peer); err != nil {
+			peer.logger.Debug("Message handling failed in snap", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer. The remote connection is torn down upon returning any error.
+func handleMessage(db state.Database, syncer *Syncer, peer *Peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return errMsgTooLarge
+	}
+	defer msg.Discard()
+
+	// Handle the message depending on its contents
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return decodeError(msg, err)
+		}
+		return p2p.Send(peer.rw, AccountRangeMsg, serviceAccountRange(db, &req))
+
+	case AccountRangeMsg:
+		var res AccountRangePacket
+		if err := msg.Decode(&res); err != nil {
+			return decodeError(msg, err)
+		}
+		if syncer == nil {
+			return nil
+		}
+		return syncer.OnAccounts(peer, &res)
+
+	case GetStorageRangeMsg:
+		var req GetStorageRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return decodeError(msg, err)
+		}
+		return p2p.Send(peer.rw, StorageRangeMsg, serviceStorageRange(db, &req))
+
+	case StorageRangeMsg:
+		var res StorageRangePacket
+		if err := msg.Decode(&res); err != nil {
+			return decodeError(msg, err)
+		}
+		if syncer == nil {
+			return nil
+		}
+		return syncer.OnStorage(peer, &res)
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return decodeError(msg, err)
+		}
+		return p2p.Send(peer.rw, ByteCodesMsg, serviceByteCodes(db, &req))
+
+	case ByteCodesMsg:
+		var res ByteCodesPacket
+		if err := msg.Decode(&res); err != nil {
+			return decodeError(msg, err)
+		}
+		if syncer == nil {
+			return nil
+		}
+		return syncer.OnByteCodes(peer, &res)
+
+	default:
+		return errInvalidMsgCode
+	}
+}
+
+// serviceAccountRange assembles the response to an account range query.
+func serviceAccountRange(db state.Database, req *GetAccountRangePacket) *AccountRangePacket {
+	accounts, proof := serviceRange(db.TrieDB(), req.Root, req.Origin, req.Limit, req.Bytes)
+
+	res := &AccountRangePacket{ID: req.ID, Proof: proof}
+	for _, item := range accounts {
+		res.Accounts = append(res.Accounts, &AccountData{Hash: item.Hash, Body: item.Body})
+	}
+	return res
+}
+
+// serviceStorageRange assembles the response to a storage range query.
+func serviceStorageRange(db state.Database, req *GetStorageRangePacket) *StorageRangePacket {
+	slots, proof := serviceRange(db.TrieDB(), req.Root, req.Origin, req.Limit, req.Bytes)
+	return &StorageRangePacket{ID: req.ID, Slots: slots, Proof: proof}
+}
+
+// serviceRange iterates the trie with the given root from origin up to and
+// including limit, gathering leaves until the byte limit is reached. The proof
+// contains the paths to the first and last returned leaves, or to the origin if
+// the range is empty. If the trie is unavailable, nothing is returned.
+func serviceRange(triedb *trie.Database, root, origin, limit common.Hash, bytes uint64) ([]*StorageData, [][]byte) {
+	if bytes > softResponseLimit {
+		bytes = softResponseLimit
+	}
+	tr, err := trie.New(root, triedb)
+	if err != nil {
+		log.Debug("Requested trie not available", "root", root, "err", err)
+		return nil, nil
+	}
+	var (
+		items []*StorageData
+		size  uint64
+		it    = trie.NewIterator(tr.NodeIterator(origin[:]))
+	)
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		if bytesCompare(hash, limit) > 0 {
+			break
+		}
+		items = append(items, &StorageData{Hash: hash, Body: common.CopyBytes(it.Value)})
+		if size += uint64(common.HashLength + len(it.Value)); size >= bytes {
+			break
+		}
+	}
+	if it.Err != nil {
+		log.Debug("Failed to iterate requested trie", "root", root, "err", it.Err)
+		return nil, nil
+	}
+	// Generate the Merkle proofs for the first and last leaves
+	proof := ethdb.NewMemDatabase()
+	if len(items) == 0 {
+		if err := tr.Prove(origin[:], 0, proof); err != nil {
+			return nil, nil
+		}
+	} else {
+		if err := tr.Prove(items[0].Hash[:], 0, proof); err != nil {
+			return nil, nil
+		}
+		if err := tr.Prove(items[len(items)-1].Hash[:], 0, proof); err != nil {
+			return nil, nil
+		}
+	}
+	var nodes [][]byte
+	for _, key := range proof.Keys() {
+		node, _ := proof.Get(key)
+		nodes = append(nodes, node)
+	}
+	return items, nodes
+}
+
+// serviceByteCodes assembles the response to a byte codes query.
+func serviceByteCodes(db state.Database, req *GetByteCodesPacket) *ByteCodesPacket {
+	if req.Bytes > softResponseLimit {
+		req.Bytes = softResponseLimit
+	}
+	if len(req.Hashes) > maxCodeLookups {
+		req.Hashes = req.Hashes[:maxCodeLookups]
+	}
+	var (
+		codes [][]byte
+		bytes uint64
+	)
+	for _, hash := range req.Hashes {
+		if blob, err := db.ContractCode(common.Hash{}, hash); err == nil && len(blob) > 0 {
+			codes = append(codes, blob)
+			if bytes += uint64(len(blob)); bytes > req.Bytes {
+				break
+			}
+		}
+	}
+	return &ByteCodesPacket{ID: req.ID, Codes: codes}
+}
+
+// bytesCompare compares two hashes lexicographically.
+func bytesCompare(a, b common.Hash) int {
+	return bytes.Compare(a[:], b[:])
+}
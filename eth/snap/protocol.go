@@ -0,0 +1,129 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"errors"
+	"fmt"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	snap1 = 1
+)
+
+// ProtocolName is the official short name of the protocol used during capability negotiation.
+var ProtocolName = "snap"
+
+// ProtocolVersions are the supported versions of the snap protocol (first is primary).
+var ProtocolVersions = []uint{snap1}
+
+// protocolLengths are the number of implemented message corresponding to different protocol versions.
+var protocolLengths = map[uint]uint64{snap1: 6}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 10 * 1024 * 1024
+
+// snap protocol message codes
+const (
+	GetAccountRangeMsg = 0x00
+	AccountRangeMsg    = 0x01
+	GetStorageRangeMsg = 0x02
+	StorageRangeMsg    = 0x03
+	GetByteCodesMsg    = 0x04
+	ByteCodesMsg       = 0x05
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+	errBadRequest     = errors.New("bad request")
+)
+
+// GetAccountRangePacket requests the accounts of a state trie, starting with the
+// origin hash and ending at most at the limit hash.
+type GetAccountRangePacket struct {
+	ID     uint64      // Request ID to match up responses with
+	Root   common.Hash // Root hash of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// AccountRangePacket is the response to GetAccountRangePacket. The accounts are
+// contiguous in the trie and sorted by hash. The proof contains the trie nodes on
+// the paths to the first and last account, or to the origin if the range is empty.
+// A response without accounts and proof signals that the state is unavailable.
+type AccountRangePacket struct {
+	ID       uint64         // ID of the request this is a response for
+	Accounts []*AccountData // List of consecutive accounts from the trie
+	Proof    [][]byte       // List of trie nodes proving the account range
+}
+
+// AccountData represents a single account in a range response.
+type AccountData struct {
+	Hash common.Hash  // Hash of the account
+	Body rlp.RawValue // Account body in consensus encoding
+}
+
+// GetStorageRangePacket requests the slots of a storage trie, starting with the
+// origin hash and ending at most at the limit hash. The storage trie is addressed
+// by its root hash directly, which the requester knows from the account.
+type GetStorageRangePacket struct {
+	ID     uint64      // Request ID to match up responses with
+	Root   common.Hash // Root hash of the storage trie to serve
+	Origin common.Hash // Hash of the first storage slot to retrieve
+	Limit  common.Hash // Hash of the last storage slot to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// StorageRangePacket is the response to GetStorageRangePacket, with the same
+// proof rules as AccountRangePacket.
+type StorageRangePacket struct {
+	ID    uint64         // ID of the request this is a response for
+	Slots []*StorageData // List of consecutive slots from the trie
+	Proof [][]byte       // List of trie nodes proving the slot range
+}
+
+// StorageData represents a single storage slot in a range response.
+type StorageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // Data content of the slot, as stored in the trie
+}
+
+// GetByteCodesPacket requests a batch of contract codes by hash.
+type GetByteCodesPacket struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Code hashes to retrieve the code for
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// ByteCodesPacket is the response to GetByteCodesPacket. Codes the remote side
+// doesn't have are omitted.
+type ByteCodesPacket struct {
+	ID    uint64   // ID of the request this is a response for
+	Codes [][]byte // Requested contract codes
+}
+
+// decodeError wraps an error of a message decoding.
+func decodeError(msg interface{}, err error) error {
+	return fmt.Errorf("%v - %v: %v", errDecode, msg, err)
+}
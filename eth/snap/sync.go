@@ -0,0 +1,931 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// maxHash is the last hash of the key space, used as the limit of storage
+	// range requests.
+	maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+)
+
+const (
+	// accountConcurrency is the number of chunks to split the account trie into
+	// to allow concurrent retrievals.
+	accountConcurrency = 16
+
+	// maxRequestSize is the maximum number of bytes to request from a remote peer.
+	maxRequestSize = 512 * 1024
+
+	// maxCodeRequestCount is the maximum number of bytecode blobs to request in a
+	// single query.
+	maxCodeRequestCount = 384
+
+	// requestTimeout is the maximum time a peer is allowed to spend on serving a
+	// single network request.
+	requestTimeout = 10 * time.Second
+
+	// accountCommitInterval is the number of accounts after which the account trie
+	// is flushed into the trie database, allowing it to be moved to disk.
+	accountCommitInterval = 16384
+
+	// trieMemoryLimit is the maximum amount of trie nodes to keep in memory before
+	// flushing them to disk.
+	trieMemoryLimit = 64 * 1024 * 1024
+)
+
+// ErrCancelled is returned from Sync if the operation was prematurely aborted.
+var ErrCancelled = errors.New("sync cancelled")
+
+// accountTask represents the sync task for a chunk of the account hash space.
+type accountTask struct {
+	Next common.Hash // Next account to sync in this interval
+	Last common.Hash // Last account to sync in this interval
+
+	req      *request                      // Pending request to fill this task
+	attempts map[string]struct{}           // Peers that already failed to fill this task
+	pending  map[common.Hash]*accountEntry // Accounts waiting for their storage or code
+	done     bool                          // Flag whether the range has been retrieved
+}
+
+// accountEntry is a retrieved account that can only be inserted into the account
+// trie once its storage and code are available locally. This ensures that the
+// healing phase reaches the account if its storage or code couldn't be synced.
+type accountEntry struct {
+	task *accountTask
+	hash common.Hash
+	body []byte
+
+	storage  *storageTask // Storage trie retrieval of the account, nil if done
+	needCode bool         // Flag whether the bytecode is still missing
+	failed   bool         // Flag whether the storage or code retrieval failed
+}
+
+// storageTask represents the sync task for a single storage trie.
+type storageTask struct {
+	entries []*accountEntry // Accounts sharing this storage trie
+	root    common.Hash     // Root hash of the storage trie
+	next    common.Hash     // Next slot to sync in the trie
+	trie    *trie.Trie      // Storage trie being assembled
+
+	req      *request            // Pending request to fill this task
+	attempts map[string]struct{} // Peers that already failed to fill this task
+}
+
+// codeTask represents the sync task for a single contract bytecode.
+type codeTask struct {
+	entries  []*accountEntry     // Accounts using this bytecode
+	req      *request            // Pending request to fill this task
+	attempts map[string]struct{} // Peers that already failed to fill this task
+}
+
+// request tracks a network request which is in flight.
+type request struct {
+	id    uint64 // Request ID of this request
+	peer  string // Peer to which this request is assigned
+	kind  uint64 // Message code of the request
+	timer *time.Timer
+
+	account *accountTask  // Account task for account range requests
+	storage *storageTask  // Storage task for storage range requests
+	hashes  []common.Hash // Bytecode hashes for bytecode requests
+
+	stop chan struct{} // Channel to signal the sync terminated
+}
+
+// response is a network response (or a timeout or drop, if packet is nil)
+// delivered to the sync loop.
+type response struct {
+	req    *request
+	packet interface{}
+}
+
+// Syncer is a state syncer that retrieves the account and storage tries of a
+// state in ranges from snap peers, instead of downloading them node by node.
+//
+// Every range is Merkle proven against the requested root, but a range proof
+// can't show that no leaves were withheld from its middle, and the state might
+// move on while the sync progresses. The resulting state is thus not necessarily
+// complete: it must be healed afterwards by a regular trie node sync, which
+// retrieves every node that didn't end up locally with the correct hash.
+type Syncer struct {
+	db     ethdb.Database  // Database to store the trie nodes into
+	triedb *trie.Database  // In-memory trie database to assemble tries in
+	drop   func(id string) // Callback to disconnect misbehaving peers
+
+	root     common.Hash    // Current state trie root being synced
+	tasks    []*accountTask // Current account task set being synced
+	accTrie  *trie.Trie     // Account trie assembled from the retrieved ranges
+	accRoot  common.Hash    // Root of the account trie last flushed to the trie database
+	accCount int            // Accounts inserted since the last flush
+	done     bool           // Flag whether the range retrieval is complete
+
+	storageTasks []*storageTask
+	storageIndex map[common.Hash]*storageTask
+	codeTasks    map[common.Hash]*codeTask
+
+	peers    map[string]*Peer    // Currently active peers to download from
+	idlers   map[string]struct{} // Peers not serving a request currently
+	requests map[uint64]*request // Requests currently in flight
+	update   chan struct{}       // Notification channel for possible sync progress
+	deliver  chan *response      // Delivery channel for responses and timeouts
+	reqID    uint64              // Last request ID used
+	lock     sync.RWMutex        // Protects the peer and request sets
+
+	accountSynced  uint64 // Number of accounts inserted into the trie
+	storageSynced  uint64 // Number of storage slots downloaded
+	bytecodeSynced uint64 // Number of bytecodes downloaded
+	logTime        time.Time
+}
+
+// NewSyncer creates a new snapshot syncer to download the state into db. The
+// drop callback is invoked with the id of peers that send invalid data.
+func NewSyncer(db ethdb.Database, drop func(id string)) *Syncer {
+	return &Syncer{
+		db:      db,
+		drop:    drop,
+		peers:   make(map[string]*Peer),
+		idlers:  make(map[string]struct{}),
+		update:  make(chan struct{}, 1),
+		deliver: make(chan *response),
+	}
+}
+
+// Register injects a new data source into the syncer's peerset.
+func (s *Syncer) Register(peer *Peer) {
+	s.lock.Lock()
+	s.peers[peer.id] = peer
+	s.idlers[peer.id] = struct{}{}
+	s.lock.Unlock()
+
+	s.notify()
+}
+
+// Unregister removes a data source from the syncer's peerset, failing any of its
+// pending requests.
+func (s *Syncer) Unregister(id string) {
+	s.lock.Lock()
+	delete(s.peers, id)
+	delete(s.idlers, id)
+
+	var failed []*request
+	for reqid, req := range s.requests {
+		if req.peer == id {
+			req.timer.Stop()
+			delete(s.requests, reqid)
+			failed = append(failed, req)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, req := range failed {
+		go s.deliverResponse(&response{req: req})
+	}
+	s.notify()
+}
+
+// notify signals the sync loop that peers changed.
+func (s *Syncer) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// Sync starts (or resumes a previous) sync cycle to iterate over a state trie
+// with the given root and reconstruct the nodes based on the snapshot leaves.
+// Previously downloaded segments will not be redownloaded, even if the root
+// changed in between. If no snap peers are available, Sync returns early,
+// leaving the rest of the state to the trie node sync.
+func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
+	if s.done {
+		return nil
+	}
+	if err := s.loadSyncStatus(root); err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	defer s.cleanup(stop)
+
+	log.Debug("Starting snapshot sync cycle", "root", root)
+	for !s.complete() {
+		// Without snap capable peers, leave the state to the trie node sync and
+		// resume the range retrieval in a later cycle
+		s.lock.RLock()
+		peers := len(s.peers)
+		s.lock.RUnlock()
+		if peers == 0 {
+			log.Debug("No snap peers available, deferring to trie node sync")
+			return s.flushAccounts(true)
+		}
+		s.assignAccountTasks(stop)
+		s.assignStorageTasks(stop)
+		s.assignBytecodeTasks(stop)
+		s.reportProgress(false)
+
+		select {
+		case <-s.update:
+			// Something happened (new peer, delivery, timeout), recheck tasks
+		case <-cancel:
+			return ErrCancelled
+		case res := <-s.deliver:
+			if err := s.process(res); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.flushAccounts(true); err != nil {
+		return err
+	}
+	s.done = true
+	s.reportProgress(true)
+	return nil
+}
+
+// loadSyncStatus sets up the task set for a new sync cycle, retaining the
+// progress of the previous cycles.
+func (s *Syncer) loadSyncStatus(root common.Hash) error {
+	s.root = root
+	if s.tasks != nil {
+		return nil
+	}
+	// If the state is already present, there's nothing to retrieve in ranges
+	if ok, _ := s.db.Has(root[:]); ok || root == emptyRoot {
+		s.done = true
+		return nil
+	}
+	s.triedb = trie.NewDatabase(s.db)
+	tr, err := trie.New(common.Hash{}, s.triedb)
+	if err != nil {
+		return err
+	}
+	s.accTrie = tr
+	s.storageIndex = make(map[common.Hash]*storageTask)
+	s.codeTasks = make(map[common.Hash]*codeTask)
+	s.logTime = time.Now()
+
+	step := new(big.Int).Div(new(big.Int).Lsh(common.Big1, 256), big.NewInt(accountConcurrency))
+	for i := 0; i < accountConcurrency; i++ {
+		next := new(big.Int).Mul(step, big.NewInt(int64(i)))
+		last := new(big.Int).Sub(new(big.Int).Add(next, step), common.Big1)
+		s.tasks = append(s.tasks, &accountTask{
+			Next:     common.BigToHash(next),
+			Last:     common.BigToHash(last),
+			attempts: make(map[string]struct{}),
+			pending:  make(map[common.Hash]*accountEntry),
+		})
+	}
+	return nil
+}
+
+// complete reports whether all tasks of the range retrieval are finished.
+func (s *Syncer) complete() bool {
+	for _, task := range s.tasks {
+		if !task.done || len(task.pending) > 0 {
+			return false
+		}
+	}
+	return len(s.storageTasks) == 0 && len(s.codeTasks) == 0
+}
+
+// cleanup forgets about all requests in flight at the end of a sync cycle.
+func (s *Syncer) cleanup(stop chan struct{}) {
+	close(stop)
+
+	s.lock.Lock()
+	for id, req := range s.requests {
+		req.timer.Stop()
+		delete(s.requests, id)
+		if _, ok := s.peers[req.peer]; ok {
+			s.idlers[req.peer] = struct{}{}
+		}
+	}
+	s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		task.req = nil
+	}
+	for _, task := range s.storageTasks {
+		task.req = nil
+	}
+	for _, task := range s.codeTasks {
+		task.req = nil
+	}
+}
+
+// idlePeer returns an idle peer which didn't fail the task yet. If no peer is
+// idle, ok is false. If every known peer already failed the task, the task is
+// deemed unavailable and stale is true.
+func (s *Syncer) idlePeer(attempts map[string]struct{}) (peer *Peer, ok bool, stale bool) {
+	stale = len(s.peers) > 0
+	for id := range s.peers {
+		if _, failed := attempts[id]; failed {
+			continue
+		}
+		stale = false
+		if _, idle := s.idlers[id]; idle {
+			return s.peers[id], true, false
+		}
+	}
+	return nil, false, stale
+}
+
+// track registers a request as in flight, arming its timeout.
+func (s *Syncer) track(peer *Peer, req *request) {
+	s.reqID++
+	req.id = s.reqID
+	req.peer = peer.id
+
+	if s.requests == nil {
+		s.requests = make(map[uint64]*request)
+	}
+	s.requests[req.id] = req
+	delete(s.idlers, peer.id)
+
+	req.timer = time.AfterFunc(requestTimeout, func() {
+		s.lock.Lock()
+		if s.requests[req.id] != req {
+			s.lock.Unlock()
+			return
+		}
+		delete(s.requests, req.id)
+		if _, ok := s.peers[req.peer]; ok {
+			s.idlers[req.peer] = struct{}{}
+		}
+		s.lock.Unlock()
+
+		peer.logger.Debug("Snap request timed out", "reqid", req.id)
+		s.deliverResponse(&response{req: req})
+	})
+}
+
+// send transmits a tracked request to its peer, failing it if it could not be
+// sent. Requests are sent asynchronously, as writes to the peer might block on
+// the peer's responses being consumed.
+func (s *Syncer) send(req *request, send func() error) {
+	err := send()
+	if err == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.requests[req.id] != req {
+		s.lock.Unlock()
+		return
+	}
+	req.timer.Stop()
+	delete(s.requests, req.id)
+	s.lock.Unlock()
+
+	log.Debug("Failed to send snap request", "peer", req.peer, "err", err)
+	s.deliverResponse(&response{req: req})
+}
+
+// deliverResponse pushes a response into the sync loop, unless the sync cycle
+// the request belongs to already terminated.
+func (s *Syncer) deliverResponse(res *response) {
+	select {
+	case s.deliver <- res:
+	case <-res.req.stop:
+	}
+}
+
+// assignAccountTasks attempts to match idle peers to pending account range
+// retrievals, abandoning ranges no peer can serve.
+func (s *Syncer) assignAccountTasks(stop chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, task := range s.tasks {
+		if task.done || task.req != nil {
+			continue
+		}
+		peer, ok, stale := s.idlePeer(task.attempts)
+		if stale {
+			log.Debug("Abandoning account range to healing", "from", task.Next, "to", task.Last)
+			task.done = true
+			continue
+		}
+		if !ok {
+			continue
+		}
+		req := &request{kind: GetAccountRangeMsg, account: task, stop: stop}
+		s.track(peer, req)
+		task.req = req
+
+		root, origin, limit := s.root, task.Next, task.Last
+		go s.send(req, func() error {
+			return peer.RequestAccountRange(req.id, root, origin, limit, maxRequestSize)
+		})
+	}
+}
+
+// assignStorageTasks attempts to match idle peers to pending storage trie
+// retrievals, abandoning tries no peer can serve.
+func (s *Syncer) assignStorageTasks(stop chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i := 0; i < len(s.storageTasks); i++ {
+		task := s.storageTasks[i]
+		if task.req != nil {
+			continue
+		}
+		peer, ok, stale := s.idlePeer(task.attempts)
+		if stale {
+			log.Debug("Abandoning storage trie to healing", "root", task.root)
+			s.finishStorage(task, false)
+			i--
+			continue
+		}
+		if !ok {
+			continue
+		}
+		req := &request{kind: GetStorageRangeMsg, storage: task, stop: stop}
+		s.track(peer, req)
+		task.req = req
+
+		root, origin := task.root, task.next
+		go s.send(req, func() error {
+			return peer.RequestStorageRange(req.id, root, origin, maxHash, maxRequestSize)
+		})
+	}
+}
+
+// assignBytecodeTasks attempts to match idle peers to pending bytecode
+// retrievals, abandoning codes no peer can serve.
+func (s *Syncer) assignBytecodeTasks(stop chan struct{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.idlers) > 0 {
+		var (
+			peer   *Peer
+			hashes []common.Hash
+		)
+		for hash, task := range s.codeTasks {
+			if task.req != nil {
+				continue
+			}
+			candidate, ok, stale := s.idlePeer(task.attempts)
+			if stale {
+				log.Debug("Abandoning bytecode to healing", "hash", hash)
+				s.finishCode(hash, false)
+				continue
+			}
+			if !ok || (peer != nil && candidate != peer) {
+				continue
+			}
+			peer = candidate
+			if hashes = append(hashes, hash); len(hashes) >= maxCodeRequestCount {
+				break
+			}
+		}
+		if len(hashes) == 0 {
+			return
+		}
+		req := &request{kind: GetByteCodesMsg, hashes: hashes, stop: stop}
+		s.track(peer, req)
+		for _, hash := range hashes {
+			s.codeTasks[hash].req = req
+		}
+		go s.send(req, func() error {
+			return peer.RequestByteCodes(req.id, hashes, maxRequestSize)
+		})
+	}
+}
+
+// OnAccounts is a callback method to invoke when a range of accounts are
+// received from a remote peer.
+func (s *Syncer) OnAccounts(peer *Peer, res *AccountRangePacket) error {
+	return s.onResponse(peer, res.ID, GetAccountRangeMsg, res)
+}
+
+// OnStorage is a callback method to invoke when a range of storage slots are
+// received from a remote peer.
+func (s *Syncer) OnStorage(peer *Peer, res *StorageRangePacket) error {
+	return s.onResponse(peer, res.ID, GetStorageRangeMsg, res)
+}
+
+// OnByteCodes is a callback method to invoke when a batch of contract bytecodes
+// are received from a remote peer.
+func (s *Syncer) OnByteCodes(peer *Peer, res *ByteCodesPacket) error {
+	return s.onResponse(peer, res.ID, GetByteCodesMsg, res)
+}
+
+// onResponse matches a response to its request and hands it to the sync loop.
+// Unrequested or timed out responses are ignored.
+func (s *Syncer) onResponse(peer *Peer, id uint64, kind uint64, packet interface{}) error {
+	s.lock.Lock()
+	req := s.requests[id]
+	if req == nil || req.peer != peer.id || req.kind != kind {
+		s.lock.Unlock()
+		peer.logger.Debug("Unrequested snap response", "reqid", id)
+		return nil
+	}
+	req.timer.Stop()
+	delete(s.requests, id)
+	if _, ok := s.peers[peer.id]; ok {
+		s.idlers[peer.id] = struct{}{}
+	}
+	s.lock.Unlock()
+
+	s.deliverResponse(&response{req: req, packet: packet})
+	return nil
+}
+
+// process handles a response, timeout or drop of a request.
+func (s *Syncer) process(res *response) error {
+	switch res.req.kind {
+	case GetAccountRangeMsg:
+		return s.processAccounts(res)
+	case GetStorageRangeMsg:
+		return s.processStorage(res)
+	case GetByteCodesMsg:
+		return s.processBytecodes(res)
+	}
+	return nil
+}
+
+// processAccounts verifies an account range response and schedules the storage
+// and code retrievals of the contained accounts.
+func (s *Syncer) processAccounts(res *response) error {
+	task := res.req.account
+	task.req = nil
+
+	packet, _ := res.packet.(*AccountRangePacket)
+	if packet == nil || (len(packet.Accounts) == 0 && len(packet.Proof) == 0) {
+		// Timeout, drop or state unavailable at the peer, try another one
+		task.attempts[res.req.peer] = struct{}{}
+		return nil
+	}
+	keys := make([]common.Hash, len(packet.Accounts))
+	values := make([][]byte, len(packet.Accounts))
+	for i, account := range packet.Accounts {
+		keys[i], values[i] = account.Hash, account.Body
+	}
+	if err := verifyRange(s.root, task.Next, task.Last, keys, values, packet.Proof); err != nil {
+		log.Warn("Invalid account range, dropping peer", "peer", res.req.peer, "err", err)
+		s.dropPeer(res.req.peer)
+		return nil
+	}
+	for i, hash := range keys {
+		var account state.Account
+		if err := rlp.DecodeBytes(values[i], &account); err != nil {
+			log.Warn("Invalid account in range, dropping peer", "peer", res.req.peer, "err", err)
+			s.dropPeer(res.req.peer)
+			return nil
+		}
+		if err := s.scheduleAccount(task, hash, values[i], &account); err != nil {
+			return err
+		}
+	}
+	// Advance the task, or mark it done if the range is exhausted
+	if len(keys) == 0 || keys[len(keys)-1] == task.Last {
+		task.done = true
+		return nil
+	}
+	next, overflow := incHash(keys[len(keys)-1])
+	if overflow {
+		task.done = true
+	} else {
+		task.Next = next
+	}
+	return nil
+}
+
+// scheduleAccount inserts an account into the account trie, or queues it until
+// its storage and code got retrieved.
+func (s *Syncer) scheduleAccount(task *accountTask, hash common.Hash, body []byte, account *state.Account) error {
+	entry := &accountEntry{task: task, hash: hash, body: common.CopyBytes(body)}
+
+	codeHash := common.BytesToHash(account.CodeHash)
+	if codeHash != emptyCode {
+		if ok, _ := s.db.Has(codeHash[:]); !ok {
+			entry.needCode = true
+			ct := s.codeTasks[codeHash]
+			if ct == nil {
+				ct = &codeTask{attempts: make(map[string]struct{})}
+				s.codeTasks[codeHash] = ct
+			}
+			ct.entries = append(ct.entries, entry)
+		}
+	}
+	if account.Root != emptyRoot {
+		if ok, _ := s.db.Has(account.Root[:]); !ok {
+			st := s.storageIndex[account.Root]
+			if st == nil {
+				tr, err := trie.New(common.Hash{}, s.triedb)
+				if err != nil {
+					return err
+				}
+				st = &storageTask{root: account.Root, trie: tr, attempts: make(map[string]struct{})}
+				s.storageIndex[account.Root] = st
+				s.storageTasks = append(s.storageTasks, st)
+			}
+			st.entries = append(st.entries, entry)
+			entry.storage = st
+		}
+	}
+	if entry.storage != nil || entry.needCode {
+		task.pending[hash] = entry
+		return nil
+	}
+	return s.insertAccount(entry)
+}
+
+// processStorage verifies a storage range response, completing the storage
+// trie once all its slots have been retrieved.
+func (s *Syncer) processStorage(res *response) error {
+	task := res.req.storage
+	task.req = nil
+
+	packet, _ := res.packet.(*StorageRangePacket)
+	if packet == nil || (len(packet.Slots) == 0 && len(packet.Proof) == 0) {
+		task.attempts[res.req.peer] = struct{}{}
+		return nil
+	}
+	keys := make([]common.Hash, len(packet.Slots))
+	values := make([][]byte, len(packet.Slots))
+	for i, slot := range packet.Slots {
+		keys[i], values[i] = slot.Hash, slot.Body
+	}
+	if err := verifyRange(task.root, task.next, maxHash, keys, values, packet.Proof); err != nil {
+		log.Warn("Invalid storage range, dropping peer", "peer", res.req.peer, "err", err)
+		s.dropPeer(res.req.peer)
+		return nil
+	}
+	for i, key := range keys {
+		if err := task.trie.TryUpdate(key[:], values[i]); err != nil {
+			return err
+		}
+	}
+	s.storageSynced += uint64(len(keys))
+
+	if len(keys) > 0 && keys[len(keys)-1] != maxHash {
+		task.next, _ = incHash(keys[len(keys)-1])
+		return nil
+	}
+	// All slots retrieved, the trie must match the expected root
+	if task.trie.Hash() != task.root {
+		log.Debug("Storage trie mismatch, leaving to healing", "root", task.root, "have", task.trie.Hash())
+		s.finishStorage(task, false)
+		return nil
+	}
+	root, err := task.trie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if err := s.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	return s.finishStorage(task, true)
+}
+
+// finishStorage removes a storage task from the queue, settling the accounts
+// waiting for it.
+func (s *Syncer) finishStorage(task *storageTask, ok bool) error {
+	delete(s.storageIndex, task.root)
+	for i, st := range s.storageTasks {
+		if st == task {
+			s.storageTasks = append(s.storageTasks[:i], s.storageTasks[i+1:]...)
+			break
+		}
+	}
+	for _, entry := range task.entries {
+		entry.storage = nil
+		entry.failed = entry.failed || !ok
+		if err := s.settle(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processBytecodes verifies a bytecode response and stores the retrieved codes.
+func (s *Syncer) processBytecodes(res *response) error {
+	for _, hash := range res.req.hashes {
+		if task := s.codeTasks[hash]; task != nil && task.req == res.req {
+			task.req = nil
+		}
+	}
+	packet, _ := res.packet.(*ByteCodesPacket)
+	if packet == nil {
+		for _, hash := range res.req.hashes {
+			if task := s.codeTasks[hash]; task != nil {
+				task.attempts[res.req.peer] = struct{}{}
+			}
+		}
+		return nil
+	}
+	requested := make(map[common.Hash]struct{}, len(res.req.hashes))
+	for _, hash := range res.req.hashes {
+		requested[hash] = struct{}{}
+	}
+	batch := s.db.NewBatch()
+	for _, code := range packet.Codes {
+		hash := crypto.Keccak256Hash(code)
+		if _, ok := requested[hash]; !ok {
+			log.Warn("Unrequested bytecode, dropping peer", "peer", res.req.peer, "hash", hash)
+			s.dropPeer(res.req.peer)
+			return nil
+		}
+		delete(requested, hash)
+		if err := batch.Put(hash[:], code); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for _, hash := range res.req.hashes {
+		if s.codeTasks[hash] == nil {
+			continue
+		}
+		if _, missing := requested[hash]; missing {
+			s.codeTasks[hash].attempts[res.req.peer] = struct{}{}
+			continue
+		}
+		s.bytecodeSynced++
+		if err := s.finishCode(hash, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishCode removes a bytecode task, settling the accounts waiting for it.
+func (s *Syncer) finishCode(hash common.Hash, ok bool) error {
+	task := s.codeTasks[hash]
+	delete(s.codeTasks, hash)
+
+	for _, entry := range task.entries {
+		entry.needCode = false
+		entry.failed = entry.failed || !ok
+		if err := s.settle(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settle inserts an account whose storage and code retrievals all finished into
+// the account trie, unless any of them failed.
+func (s *Syncer) settle(entry *accountEntry) error {
+	if entry.storage != nil || entry.needCode {
+		return nil
+	}
+	delete(entry.task.pending, entry.hash)
+	if entry.failed {
+		return nil
+	}
+	return s.insertAccount(entry)
+}
+
+// insertAccount adds an account to the account trie, periodically flushing the
+// trie into the trie database.
+func (s *Syncer) insertAccount(entry *accountEntry) error {
+	if err := s.accTrie.TryUpdate(entry.hash[:], entry.body); err != nil {
+		return err
+	}
+	s.accountSynced++
+	if s.accCount++; s.accCount >= accountCommitInterval {
+		return s.flushAccounts(false)
+	}
+	return nil
+}
+
+// flushAccounts commits the account trie into the trie database, dropping the
+// nodes of the previous flush and moving nodes to disk if the memory allowance
+// is exceeded. If final is set, all nodes are written to disk.
+func (s *Syncer) flushAccounts(final bool) error {
+	s.accCount = 0
+
+	root, err := s.accTrie.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if final {
+		return s.triedb.Commit(root, false)
+	}
+	s.triedb.Reference(root, common.Hash{})
+	if s.accRoot != (common.Hash{}) {
+		s.triedb.Dereference(s.accRoot)
+	}
+	s.accRoot = root
+
+	if nodes, _ := s.triedb.Size(); nodes > trieMemoryLimit {
+		return s.triedb.Cap(trieMemoryLimit)
+	}
+	return nil
+}
+
+// dropPeer disconnects a peer which sent invalid data, removing it from the
+// peer set right away so that no more tasks are assigned to it.
+func (s *Syncer) dropPeer(id string) {
+	s.lock.Lock()
+	delete(s.peers, id)
+	delete(s.idlers, id)
+	s.lock.Unlock()
+
+	if s.drop != nil {
+		s.drop(id)
+	}
+}
+
+// reportProgress periodically logs the progress of the range retrieval.
+func (s *Syncer) reportProgress(force bool) {
+	if !force && time.Since(s.logTime) < 8*time.Second {
+		return
+	}
+	s.logTime = time.Now()
+	log.Info("State sync in progress", "accounts", s.accountSynced, "slots", s.storageSynced, "codes", s.bytecodeSynced,
+		"pending storage", len(s.storageTasks), "pending codes", len(s.codeTasks))
+}
+
+// verifyRange checks that the keys of a range response are ordered and within
+// the requested bounds, and that the first and last leaves are proven by the
+// given nodes against root. An empty range must prove the absence of origin.
+func verifyRange(root, origin, limit common.Hash, keys []common.Hash, values [][]byte, proof [][]byte) error {
+	db := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	if len(keys) == 0 {
+		value, _, err := trie.VerifyProof(root, origin[:], db)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			return fmt.Errorf("empty range but origin %x exists", origin)
+		}
+		return nil
+	}
+	for i, key := range keys {
+		if i == 0 && bytes.Compare(key[:], origin[:]) < 0 {
+			return fmt.Errorf("key %x before origin %x", key, origin)
+		}
+		if i > 0 && bytes.Compare(keys[i-1][:], key[:]) >= 0 {
+			return fmt.Errorf("keys not ascending at %d", i)
+		}
+		if bytes.Compare(key[:], limit[:]) > 0 {
+			return fmt.Errorf("key %x beyond limit %x", key, limit)
+		}
+	}
+	for _, i := range []int{0, len(keys) - 1} {
+		value, _, err := trie.VerifyProof(root, keys[i][:], db)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(value, values[i]) {
+			return fmt.Errorf("proof mismatch for key %x", keys[i])
+		}
+	}
+	return nil
+}
+
+// incHash returns the hash following h, and whether it overflowed.
+func incHash(h common.Hash) (common.Hash, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h, false
+		}
+	}
+	return h, true
+}
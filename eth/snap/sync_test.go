@@ -0,0 +1,210 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/state"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/trie"
+)
+
+// makeTestState creates a state with plain accounts, contracts with code and
+// contracts with (partially shared) storage.
+func makeTestState(t *testing.T) (state.Database, common.Hash) {
+	db := state.NewDatabase(ethdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	for i := byte(0); i < 200; i++ {
+		addr := common.BytesToAddress([]byte{i, 1})
+		statedb.AddBalance(addr, big.NewInt(int64(i)+1))
+		statedb.SetNonce(addr, uint64(i))
+		if i%5 == 0 {
+			statedb.SetCode(addr, []byte{i, i, i})
+		}
+		if i%7 == 0 {
+			for j := byte(0); j < 50; j++ {
+				statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{i % 2, j + 1}))
+			}
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	return db, root
+}
+
+// connectSyncer attaches a snap peer serving the given state to the syncer.
+func connectSyncer(t *testing.T, syncer *Syncer, id byte, source state.Database) *p2p.MsgPipeRW {
+	local, remote := p2p.MsgPipe()
+	go handle(source, nil, newPeer(snap1, p2p.NewPeer(enode.ID{id}, "server", nil), remote))
+	go handle(nil, syncer, newPeer(snap1, p2p.NewPeer(enode.ID{id}, "client", nil), local))
+
+	for {
+		syncer.lock.RLock()
+		_, ok := syncer.peers[testPeerID(id)]
+		syncer.lock.RUnlock()
+		if ok {
+			return local
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testPeerID(id byte) string {
+	return fmt.Sprintf("%x", enode.ID{id}.Bytes()[:8])
+}
+
+// checkStateComplete verifies that every node of the state is present in db.
+func checkStateComplete(t *testing.T, db ethdb.Database, root common.Hash) {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("state root missing: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	if it.Error != nil {
+		t.Fatalf("state incomplete: %v", it.Error)
+	}
+}
+
+// Tests that the full state can be retrieved in ranges from a peer.
+func TestSync(t *testing.T) {
+	source, root := makeTestState(t)
+
+	db := ethdb.NewMemDatabase()
+	syncer := NewSyncer(db, nil)
+	rw := connectSyncer(t, syncer, 1, source)
+	defer rw.Close()
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	checkStateComplete(t, db, root)
+}
+
+// Tests that peers sending invalid ranges are dropped and the sync completes
+// with the honest peers.
+func TestSyncBadPeer(t *testing.T) {
+	source, root := makeTestState(t)
+
+	// Create a state with the same shape but different content for the bad peer
+	bad := state.NewDatabase(ethdb.NewMemDatabase())
+	badstate, _ := state.New(common.Hash{}, bad)
+	badstate.AddBalance(common.Address{1}, big.NewInt(1))
+	badroot, _ := badstate.Commit(false)
+	bad.TrieDB().Commit(badroot, false)
+
+	var (
+		db      = ethdb.NewMemDatabase()
+		dropped = make(chan string, 16)
+		syncer  = NewSyncer(db, func(id string) {
+			select {
+			case dropped <- id:
+			default:
+			}
+		})
+	)
+	// Serve the bad state for the requested root by aliasing its root node, which
+	// holds the single account inline
+	alias := ethdb.NewMemDatabase()
+	blob, _ := bad.TrieDB().Node(badroot)
+	alias.Put(root[:], blob)
+	badrw := connectSyncer(t, syncer, 1, state.NewDatabase(alias))
+	defer badrw.Close()
+	rw := connectSyncer(t, syncer, 2, source)
+	defer rw.Close()
+
+	if err := syncer.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	checkStateComplete(t, db, root)
+
+	select {
+	case id := <-dropped:
+		if id != testPeerID(1) {
+			t.Fatalf("wrong peer dropped: %s", id)
+		}
+	default:
+		t.Fatal("bad peer not dropped")
+	}
+}
+
+// Tests that the range proofs are checked against the requested bounds.
+func TestVerifyRange(t *testing.T) {
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	var keys []common.Hash
+	for i := byte(1); i <= 10; i++ {
+		key := common.BytesToHash([]byte{i * 16, i})
+		tr.Update(key[:], bytes.Repeat([]byte{i}, 32))
+		keys = append(keys, key)
+	}
+	root := tr.Hash()
+
+	prove := func(keys ...common.Hash) [][]byte {
+		db := ethdb.NewMemDatabase()
+		for _, key := range keys {
+			tr.Prove(key[:], 0, db)
+		}
+		var nodes [][]byte
+		for _, key := range db.Keys() {
+			node, _ := db.Get(key)
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	values := func(keys []common.Hash) [][]byte {
+		var vals [][]byte
+		for _, key := range keys {
+			vals = append(vals, tr.Get(key[:]))
+		}
+		return vals
+	}
+	tests := []struct {
+		origin, limit common.Hash
+		keys          []common.Hash
+		proof         [][]byte
+		valid         bool
+	}{
+		{common.Hash{}, maxHash, keys, prove(keys[0], keys[9]), true},
+		{common.Hash{}, maxHash, keys[2:5], prove(keys[2], keys[4]), true},
+		{common.Hash{}, maxHash, keys[2:5], prove(keys[2]), false},
+		{keys[3], maxHash, keys[2:5], prove(keys[2], keys[4]), false},
+		{common.Hash{}, keys[3], keys[2:5], prove(keys[2], keys[4]), false},
+		{common.Hash{}, maxHash, []common.Hash{keys[4], keys[2]}, prove(keys[2], keys[4]), false},
+		{maxHash, maxHash, nil, prove(maxHash), true},
+		{keys[3], maxHash, nil, prove(keys[3]), false},
+	}
+	for i, tt := range tests {
+		err := verifyRange(root, tt.origin, tt.limit, tt.keys, values(tt.keys), tt.proof)
+		if (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
@@ -53,3 +53,27 @@ func TestFastSyncDisabling(t *testing.T) {
 		t.Fatalf("fast sync not disabled after successful synchronisation")
 	}
 }
+
+// Tests that a fresh node reports itself as syncing to the readiness probe until
+// the initial synchronisation completes, even before any peer announced a head.
+func TestSyncingStatus(t *testing.T) {
+	pmEmpty, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pmFull, _ := newTestProtocolManagerMust(t, downloader.FullSync, 64, nil, nil)
+
+	eth := &Ethereum{protocolManager: pmEmpty}
+	if !eth.Syncing() {
+		t.Fatalf("fresh node reported as synced")
+	}
+	// Sync up the two peers
+	io1, io2 := p2p.MsgPipe()
+
+	go pmFull.handle(pmFull.newPeer(63, p2p.NewPeer(enode.ID{}, "empty", nil), io2))
+	go pmEmpty.handle(pmEmpty.newPeer(63, p2p.NewPeer(enode.ID{}, "full", nil), io1))
+
+	time.Sleep(250 * time.Millisecond)
+	pmEmpty.synchronise(pmEmpty.peers.BestPeer())
+
+	if eth.Syncing() {
+		t.Fatalf("node reported as syncing after successful synchronisation")
+	}
+}
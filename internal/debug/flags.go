@@ -27,6 +27,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
 	"git.pirl.io/bitcoiin/go-bitcoiin/metrics/exp"
+	"git.pirl.io/bitcoiin/go-bitcoiin/metrics/prometheus"
 	"github.com/fjl/memsize/memsizeui"
 	colorable "github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
@@ -55,6 +56,32 @@ var (
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
 	}
+	logjsonFlag = cli.BoolFlag{
+		Name:  "log.json",
+		Usage: "Format logs with JSON",
+	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "Write logs to a file in addition to the console",
+	}
+	logMaxSizeFlag = cli.IntFlag{
+		Name:  "log.maxsize",
+		Usage: "Maximum size in megabytes of the log file before it gets rotated (0 = unlimited)",
+		Value: 100,
+	}
+	logMaxAgeFlag = cli.DurationFlag{
+		Name:  "log.maxage",
+		Usage: "Maximum age of the log file before it gets rotated (0 = unlimited)",
+	}
+	logMaxBackupsFlag = cli.IntFlag{
+		Name:  "log.maxbackups",
+		Usage: "Maximum number of rotated log files to retain (0 = retain all)",
+		Value: 10,
+	}
+	logCompressFlag = cli.BoolFlag{
+		Name:  "log.compress",
+		Usage: "Compress rotated log files with gzip",
+	}
 	pprofFlag = cli.BoolFlag{
 		Name:  "pprof",
 		Usage: "Enable the pprof HTTP server",
@@ -91,6 +118,7 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	logjsonFlag, logFileFlag, logMaxSizeFlag, logMaxAgeFlag, logMaxBackupsFlag, logCompressFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -115,7 +143,13 @@ func init() {
 func Setup(ctx *cli.Context, logdir string) error {
 	// logging
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
+	if ctx.GlobalBool(logjsonFlag.Name) {
+		ostream = log.StreamHandler(os.Stderr, log.JSONFormat())
+	}
+	handler := ostream
 	if logdir != "" {
+		// The dashboard reads these files back as JSON lines, so they keep
+		// their format regardless of --log.json
 		rfh, err := log.RotatingFileHandler(
 			logdir,
 			262144,
@@ -124,8 +158,25 @@ func Setup(ctx *cli.Context, logdir string) error {
 		if err != nil {
 			return err
 		}
-		glogger.SetHandler(log.MultiHandler(ostream, rfh))
+		handler = log.MultiHandler(handler, rfh)
+	}
+	if logfile := ctx.GlobalString(logFileFlag.Name); logfile != "" {
+		writer, err := log.NewRotatingFileWriter(logfile, log.RotateConfig{
+			MaxSize:    uint(ctx.GlobalInt(logMaxSizeFlag.Name)) * 1024 * 1024,
+			MaxAge:     ctx.GlobalDuration(logMaxAgeFlag.Name),
+			MaxBackups: ctx.GlobalInt(logMaxBackupsFlag.Name),
+			Compress:   ctx.GlobalBool(logCompressFlag.Name),
+		})
+		if err != nil {
+			return err
+		}
+		format := log.LogfmtFormat()
+		if ctx.GlobalBool(logjsonFlag.Name) {
+			format = log.JSONFormat()
+		}
+		handler = log.MultiHandler(handler, log.StreamHandler(writer, format))
 	}
+	glogger.SetHandler(handler)
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
@@ -157,6 +208,7 @@ func StartPProf(address string) {
 	// Hook go-metrics into expvar on any /debug/metrics request, load all vars
 	// from the registry into expvar, and execute regular expvar handler.
 	exp.Exp(metrics.DefaultRegistry)
+	http.Handle("/debug/metrics/prometheus", prometheus.Handler(metrics.DefaultRegistry))
 	http.Handle("/memsize/", http.StripPrefix("/memsize", &Memsize))
 	log.Info("Starting pprof server", "addr", fmt.Sprintf("http://%s/debug/pprof", address))
 	go func() {
@@ -1114,6 +1114,34 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	return nil
 }
 
+// RPCDroppedTransaction represents a transaction that left the pool without being
+// included in the chain.
+type RPCDroppedTransaction struct {
+	Tx          *RPCTransaction `json:"transaction"`
+	Reason      string          `json:"reason"`
+	Replacement *common.Hash    `json:"replacement,omitempty"`
+	Time        hexutil.Uint64  `json:"time"`
+}
+
+// GetDroppedTransactionByHash returns a transaction recently removed from the
+// pool without being included in the chain, along with the reason of removal.
+func (s *PublicTransactionPoolAPI) GetDroppedTransactionByHash(ctx context.Context, hash common.Hash) *RPCDroppedTransaction {
+	drop := s.b.GetDroppedTransaction(hash)
+	if drop == nil {
+		return nil
+	}
+	result := &RPCDroppedTransaction{
+		Tx:     newRPCPendingTransaction(drop.Tx),
+		Reason: drop.Reason.String(),
+		Time:   hexutil.Uint64(drop.Time.Unix()),
+	}
+	if drop.Replacement != (common.Hash{}) {
+		replacement := drop.Replacement
+		result.Replacement = &replacement
+	}
+	return result
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	var tx *types.Transaction
@@ -1460,6 +1488,128 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	return common.Hash{}, fmt.Errorf("Transaction %#x not found", matchTx.Hash())
 }
 
+// TxStatusTransition reports the pool status of a transaction before and after
+// it was replaced.
+type TxStatusTransition struct {
+	Hash   common.Hash `json:"hash"`
+	Before string      `json:"before"`
+	After  string      `json:"after"`
+}
+
+// ReplaceTransactionResult is the outcome of speeding up or cancelling a pooled
+// transaction. If the sender is not managed by a local wallet (e.g. accounts
+// handled by clef), the replacement is returned unsigned for external signing
+// and nothing is submitted.
+type ReplaceTransactionResult struct {
+	Signed      bool                `json:"signed"`
+	Raw         hexutil.Bytes       `json:"raw,omitempty"`
+	Tx          *SendTxArgs         `json:"tx"`
+	Original    TxStatusTransition  `json:"original"`
+	Replacement *TxStatusTransition `json:"replacement,omitempty"`
+}
+
+// SpeedUpTransaction replaces a pooled transaction with an identical one paying
+// a higher gas price. If no gas price is given, the larger of the minimum price
+// accepted as replacement and the suggested gas price is used.
+func (s *PublicTransactionPoolAPI) SpeedUpTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big) (*ReplaceTransactionResult, error) {
+	return s.replaceTransaction(ctx, hash, gasPrice, false)
+}
+
+// CancelTransaction replaces a pooled transaction with an empty transfer from the
+// sender to itself using the same nonce and the minimum acceptable price bump.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash) (*ReplaceTransactionResult, error) {
+	return s.replaceTransaction(ctx, hash, nil, true)
+}
+
+// replaceTransaction assembles, signs and submits the replacement of a pooled
+// transaction, reporting the status transitions of both.
+func (s *PublicTransactionPoolAPI) replaceTransaction(ctx context.Context, hash common.Hash, gasPrice *hexutil.Big, cancel bool) (*ReplaceTransactionResult, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		if s.txStatus(hash) == core.TxStatusIncluded {
+			return nil, fmt.Errorf("transaction %#x already included in the chain", hash)
+		}
+		return nil, fmt.Errorf("transaction %#x not found in the pool", hash)
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	// Calculate the minimum gas price the pool accepts as a replacement
+	bump := s.b.GetPoolPriceBump()
+	minPrice := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(100+bump))
+	minPrice.Div(minPrice, big.NewInt(100))
+	if minPrice.Cmp(tx.GasPrice()) <= 0 {
+		minPrice.Add(tx.GasPrice(), common.Big1)
+	}
+	price := minPrice
+	if gasPrice != nil {
+		if (*big.Int)(gasPrice).Cmp(minPrice) < 0 {
+			return nil, fmt.Errorf("gas price too low for replacement: have %v, want at least %v (%d%% bump)", (*big.Int)(gasPrice), minPrice, bump)
+		}
+		price = (*big.Int)(gasPrice)
+	} else if !cancel {
+		suggested, err := s.b.SuggestPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if suggested.Cmp(price) > 0 {
+			price = suggested
+		}
+	}
+	// Assemble the replacement transaction
+	var (
+		nonce = hexutil.Uint64(tx.Nonce())
+		args  = &SendTxArgs{From: from, Nonce: &nonce, GasPrice: (*hexutil.Big)(price)}
+	)
+	if cancel {
+		gas, input := hexutil.Uint64(params.TxGas), hexutil.Bytes{}
+		args.To, args.Gas, args.Value, args.Input = &from, &gas, new(hexutil.Big), &input
+	} else {
+		gas, input := hexutil.Uint64(tx.Gas()), hexutil.Bytes(tx.Data())
+		args.To, args.Gas, args.Value, args.Input = tx.To(), &gas, (*hexutil.Big)(tx.Value()), &input
+	}
+	result := &ReplaceTransactionResult{
+		Tx:       args,
+		Original: TxStatusTransition{Hash: hash, Before: s.txStatus(hash).String()},
+	}
+	// Sign through the account manager, or hand the transaction back unsigned
+	// if no local wallet holds the sender
+	if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err != nil {
+		result.Original.After = result.Original.Before
+		return result, nil
+	}
+	signed, err := s.sign(from, args.toTransaction())
+	if err != nil {
+		return nil, err
+	}
+	if result.Raw, err = rlp.EncodeToBytes(signed); err != nil {
+		return nil, err
+	}
+	result.Signed = true
+	result.Replacement = &TxStatusTransition{Hash: signed.Hash(), Before: s.txStatus(signed.Hash()).String()}
+
+	if _, err := submitTransaction(ctx, s.b, signed); err != nil {
+		return nil, err
+	}
+	result.Original.After = s.txStatus(hash).String()
+	result.Replacement.After = s.txStatus(signed.Hash()).String()
+	return result, nil
+}
+
+// txStatus retrieves the status of a transaction, checking the chain for
+// included ones and the pool for the rest.
+func (s *PublicTransactionPoolAPI) txStatus(hash common.Hash) core.TxStatus {
+	if tx, _, _, _ := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
+		return core.TxStatusIncluded
+	}
+	return s.b.GetPoolStatus([]common.Hash{hash})[0]
+}
+
 // PublicDebugAPI is the collection of Ethereum APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
@@ -0,0 +1,278 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/accounts"
+	"git.pirl.io/bitcoiin/go-bitcoiin/accounts/keystore"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/hexutil"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/vm"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+)
+
+var (
+	replaceKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	replaceAddr    = crypto.PubkeyToAddress(replaceKey.PublicKey)
+	externalKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	externalAddr   = crypto.PubkeyToAddress(externalKey.PublicKey)
+)
+
+// replaceBackend is a Backend implementing only the methods needed to replace
+// pooled transactions, backed by a real chain and transaction pool.
+type replaceBackend struct {
+	Backend
+
+	db      ethdb.Database
+	chain   *core.BlockChain
+	pool    *core.TxPool
+	manager *accounts.Manager
+	price   *big.Int // Gas price suggested by the oracle
+	mined   *types.Transaction
+}
+
+// newReplaceBackend creates a chain with a single block containing a transaction
+// of the local account, and a pool on top of it. The local account is unlocked
+// in a keystore, the external one is funded but not held by any wallet.
+func newReplaceBackend(t *testing.T) (*replaceBackend, func()) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		config = params.TestChainConfig
+		gspec  = &core.Genesis{
+			Config: config,
+			Alloc: core.GenesisAlloc{
+				replaceAddr:  {Balance: big.NewInt(params.Ether)},
+				externalAddr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(config.ChainID)
+	)
+	mined, _ := types.SignTx(types.NewTransaction(0, common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, replaceKey)
+	blocks, _ := core.GenerateChain(config, genesis, ethash.NewFaker(), db, 1, func(i int, block *core.BlockGen) {
+		block.AddTx(mined)
+	})
+	chain, _ := core.NewBlockChain(db, nil, config, ethash.NewFaker(), vm.Config{}, nil)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, config, chain)
+
+	dir, err := ioutil.TempDir("", "ethapi-replace-")
+	if err != nil {
+		t.Fatalf("failed to create keystore dir: %v", err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(replaceKey, "")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	b := &replaceBackend{
+		db:      db,
+		chain:   chain,
+		pool:    pool,
+		manager: accounts.NewManager(ks),
+		price:   big.NewInt(1),
+		mined:   mined,
+	}
+	return b, func() {
+		pool.Stop()
+		chain.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func (b *replaceBackend) ChainDb() ethdb.Database                            { return b.db }
+func (b *replaceBackend) ChainConfig() *params.ChainConfig                   { return b.chain.Config() }
+func (b *replaceBackend) CurrentBlock() *types.Block                         { return b.chain.CurrentBlock() }
+func (b *replaceBackend) AccountManager() *accounts.Manager                  { return b.manager }
+func (b *replaceBackend) SuggestPrice(ctx context.Context) (*big.Int, error) { return b.price, nil }
+func (b *replaceBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	return b.pool.AddLocal(tx)
+}
+func (b *replaceBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
+	return b.pool.Get(hash)
+}
+func (b *replaceBackend) GetPoolStatus(hashes []common.Hash) []core.TxStatus {
+	return b.pool.Status(hashes)
+}
+func (b *replaceBackend) GetPoolPriceBump() uint64 {
+	return b.pool.PriceBump()
+}
+
+// addPooled signs a transaction with the given key and adds it to the pool.
+func (b *replaceBackend) addPooled(t *testing.T, tx *types.Transaction, key *ecdsa.PrivateKey) *types.Transaction {
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(b.chain.Config().ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := b.pool.AddLocal(signed); err != nil {
+		t.Fatalf("failed to pool transaction: %v", err)
+	}
+	return signed
+}
+
+// Tests that speeding up a transaction keeps everything but the gas price, and
+// that the price is bumped by at least the pool's replacement threshold.
+func TestSpeedUpTransaction(t *testing.T) {
+	tests := []struct {
+		suggested *big.Int     // Price suggested by the oracle
+		requested *hexutil.Big // Price requested by the caller
+		price     *big.Int     // Price of the replacement, nil if rejected
+	}{
+		{suggested: big.NewInt(1), price: big.NewInt(110)},                                             // Minimum bump
+		{suggested: big.NewInt(500), price: big.NewInt(500)},                                           // Oracle above the bump
+		{suggested: big.NewInt(1), requested: (*hexutil.Big)(big.NewInt(200)), price: big.NewInt(200)}, // Requested price
+		{suggested: big.NewInt(1), requested: (*hexutil.Big)(big.NewInt(110)), price: big.NewInt(110)}, // Requested exact bump
+		{suggested: big.NewInt(500), requested: (*hexutil.Big)(big.NewInt(109))},                       // Requested below the bump
+	}
+	for i, tt := range tests {
+		b, teardown := newReplaceBackend(t)
+		b.price = tt.suggested
+
+		to := common.Address{0xaa}
+		tx := b.addPooled(t, types.NewTransaction(1, to, big.NewInt(1000), 50000, big.NewInt(100), []byte{0x12, 0x34}), replaceKey)
+
+		api := NewPublicTransactionPoolAPI(b, new(AddrLocker))
+		result, err := api.SpeedUpTransaction(context.Background(), tx.Hash(), tt.requested)
+		if tt.price == nil {
+			if err == nil || !strings.Contains(err.Error(), "gas price too low") {
+				t.Errorf("test %d: underpriced replacement error mismatch: %v", i, err)
+			}
+			if status := b.pool.Status([]common.Hash{tx.Hash()})[0]; status != core.TxStatusPending {
+				t.Errorf("test %d: original replaced by rejected request: %v", i, status)
+			}
+			teardown()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to speed up transaction: %v", i, err)
+		}
+		if !result.Signed {
+			t.Fatalf("test %d: local transaction not signed", i)
+		}
+		replacement := b.pool.Get(result.Replacement.Hash)
+		if replacement == nil {
+			t.Fatalf("test %d: replacement missing from the pool", i)
+		}
+		if replacement.Nonce() != tx.Nonce() || *replacement.To() != to || replacement.Value().Cmp(tx.Value()) != 0 ||
+			replacement.Gas() != tx.Gas() || string(replacement.Data()) != string(tx.Data()) {
+			t.Errorf("test %d: replacement differs from original: have %v, want %v", i, replacement, tx)
+		}
+		if replacement.GasPrice().Cmp(tt.price) != 0 {
+			t.Errorf("test %d: gas price mismatch: have %v, want %v", i, replacement.GasPrice(), tt.price)
+		}
+		if result.Original.Before != "pending" || result.Original.After != "unknown" {
+			t.Errorf("test %d: original status mismatch: have %s -> %s, want pending -> unknown", i, result.Original.Before, result.Original.After)
+		}
+		if result.Replacement.Before != "unknown" || result.Replacement.After != "pending" {
+			t.Errorf("test %d: replacement status mismatch: have %s -> %s, want unknown -> pending", i, result.Replacement.Before, result.Replacement.After)
+		}
+		teardown()
+	}
+}
+
+// Tests that cancelling a transaction replaces it with an empty self-transfer at
+// the minimum price bump, regardless of the suggested gas price.
+func TestCancelTransaction(t *testing.T) {
+	b, teardown := newReplaceBackend(t)
+	defer teardown()
+	b.price = big.NewInt(1000)
+
+	tx := b.addPooled(t, types.NewTransaction(1, common.Address{0xaa}, big.NewInt(1000), 50000, big.NewInt(100), []byte{0x12, 0x34}), replaceKey)
+
+	api := NewPublicTransactionPoolAPI(b, new(AddrLocker))
+	result, err := api.CancelTransaction(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to cancel transaction: %v", err)
+	}
+	replacement := b.pool.Get(result.Replacement.Hash)
+	if replacement == nil {
+		t.Fatalf("cancellation missing from the pool")
+	}
+	if replacement.Nonce() != tx.Nonce() {
+		t.Errorf("nonce mismatch: have %d, want %d", replacement.Nonce(), tx.Nonce())
+	}
+	if replacement.To() == nil || *replacement.To() != replaceAddr {
+		t.Errorf("recipient mismatch: have %v, want %x", replacement.To(), replaceAddr)
+	}
+	if replacement.Value().Sign() != 0 || len(replacement.Data()) != 0 || replacement.Gas() != params.TxGas {
+		t.Errorf("cancellation not an empty transfer: value %v, data %x, gas %d", replacement.Value(), replacement.Data(), replacement.Gas())
+	}
+	if replacement.GasPrice().Cmp(big.NewInt(110)) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", replacement.GasPrice(), 110)
+	}
+	if b.pool.Get(tx.Hash()) != nil {
+		t.Errorf("original still pooled after cancellation")
+	}
+}
+
+// Tests that transactions which are not pooled can't be replaced, reporting why.
+func TestReplaceMissingTransaction(t *testing.T) {
+	b, teardown := newReplaceBackend(t)
+	defer teardown()
+
+	api := NewPublicTransactionPoolAPI(b, new(AddrLocker))
+	if _, err := api.SpeedUpTransaction(context.Background(), common.Hash{0xff}, nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown transaction error mismatch: %v", err)
+	}
+	if _, err := api.CancelTransaction(context.Background(), b.mined.Hash()); err == nil || !strings.Contains(err.Error(), "already included") {
+		t.Errorf("mined transaction error mismatch: %v", err)
+	}
+}
+
+// Tests that transactions of senders without a local wallet are handed back
+// unsigned instead of being submitted.
+func TestReplaceExternalTransaction(t *testing.T) {
+	b, teardown := newReplaceBackend(t)
+	defer teardown()
+
+	tx := b.addPooled(t, types.NewTransaction(0, common.Address{0xaa}, big.NewInt(1000), 50000, big.NewInt(100), nil), externalKey)
+
+	api := NewPublicTransactionPoolAPI(b, new(AddrLocker))
+	result, err := api.CancelTransaction(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to cancel transaction: %v", err)
+	}
+	if result.Signed || result.Raw != nil || result.Replacement != nil {
+		t.Fatalf("external transaction signed: %+v", result)
+	}
+	if result.Tx.From != externalAddr || *result.Tx.To != externalAddr || uint64(*result.Tx.Nonce) != tx.Nonce() {
+		t.Errorf("unsigned cancellation mismatch: %+v", result.Tx)
+	}
+	if b.pool.Get(tx.Hash()) == nil {
+		t.Errorf("original dropped without a submitted replacement")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	jaeger "github.com/uber/jaeger-client-go"
+)
+
+// FileReporter is a jaeger.Reporter which appends every finished span to a local
+// file as a JSON encoded Jaeger thrift span, one per line.
+type FileReporter struct {
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	lock sync.Mutex
+}
+
+// NewFileReporter opens (or creates) the given file for appending spans.
+func NewFileReporter(path string) (*FileReporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &FileReporter{file: file, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// Report implements jaeger.Reporter, serialising the span into the output file.
+func (r *FileReporter) Report(span *jaeger.Span) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	if err := r.enc.Encode(jaeger.BuildJaegerThrift(span)); err != nil {
+		log.Warn("Failed to write trace span", "err", err)
+	}
+}
+
+// Close implements jaeger.Reporter, flushing all pending spans to disk.
+func (r *FileReporter) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	if err := r.buf.Flush(); err != nil {
+		log.Warn("Failed to flush trace spans", "err", err)
+	}
+	r.file.Close()
+	r.file = nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing configures the global opentracing tracer used to instrument
+// block import, chain synchronisation and RPC handling.
+package tracing
+
+import (
+	"errors"
+	"io"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+	jaegercfg "github.com/uber/jaeger-client-go/config"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	tracingFlag = cli.BoolFlag{
+		Name:  "tracing",
+		Usage: "Enable distributed tracing of block import, sync and RPC handling",
+	}
+	tracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "Jaeger agent UDP endpoint to export spans to (empty = disabled)",
+		Value: "127.0.0.1:6831",
+	}
+	tracingFileFlag = cli.StringFlag{
+		Name:  "tracing.file",
+		Usage: "File to write finished spans to as JSON lines, for offline analysis",
+	}
+	tracingServiceFlag = cli.StringFlag{
+		Name:  "tracing.svc",
+		Usage: "Service name to report spans under",
+		Value: "bitcoiinGo",
+	}
+	tracingSampleFlag = cli.Float64Flag{
+		Name:  "tracing.sample",
+		Usage: "Fraction of root spans to sample, between 0 and 1",
+		Value: 1,
+	}
+)
+
+// Flags holds all command-line flags required for tracing.
+var Flags = []cli.Flag{
+	tracingFlag, tracingEndpointFlag, tracingFileFlag, tracingServiceFlag, tracingSampleFlag,
+}
+
+// closer flushes and terminates the configured tracer on exit.
+var closer io.Closer
+
+// Setup installs the global tracer based on the CLI flags. If tracing is not
+// enabled, the default no-op tracer is left in place.
+func Setup(ctx *cli.Context) error {
+	if !ctx.GlobalBool(tracingFlag.Name) {
+		return nil
+	}
+	var (
+		endpoint = ctx.GlobalString(tracingEndpointFlag.Name)
+		file     = ctx.GlobalString(tracingFileFlag.Name)
+	)
+	var reporters []jaeger.Reporter
+	if endpoint != "" {
+		transport, err := jaeger.NewUDPTransport(endpoint, 0)
+		if err != nil {
+			return err
+		}
+		reporters = append(reporters, jaeger.NewRemoteReporter(transport))
+	}
+	if file != "" {
+		reporter, err := NewFileReporter(file)
+		if err != nil {
+			return err
+		}
+		reporters = append(reporters, reporter)
+	}
+	if len(reporters) == 0 {
+		return errors.New("tracing enabled without an endpoint or output file")
+	}
+	cfg := jaegercfg.Configuration{
+		ServiceName: ctx.GlobalString(tracingServiceFlag.Name),
+		Sampler: &jaegercfg.SamplerConfig{
+			Type:  jaeger.SamplerTypeProbabilistic,
+			Param: ctx.GlobalFloat64(tracingSampleFlag.Name),
+		},
+	}
+	tracer, c, err := cfg.NewTracer(jaegercfg.Reporter(jaeger.NewCompositeReporter(reporters...)))
+	if err != nil {
+		return err
+	}
+	opentracing.SetGlobalTracer(tracer)
+	closer = c
+
+	log.Info("Enabled distributed tracing", "endpoint", endpoint, "file", file)
+	return nil
+}
+
+// Exit flushes all buffered spans and shuts down the tracer.
+func Exit() {
+	if closer != nil {
+		closer.Close()
+		closer = nil
+	}
+}
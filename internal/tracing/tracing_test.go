@@ -0,0 +1,147 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	opentracing "github.com/opentracing/opentracing-go"
+	jaeger "github.com/uber/jaeger-client-go"
+	jaegerthrift "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// newContext creates a CLI context with the tracing flags set to the given
+// command line arguments.
+func newContext(t *testing.T, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range Flags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	return cli.NewContext(nil, set, nil)
+}
+
+// readSpans decodes all the spans written by a file reporter.
+func readSpans(t *testing.T, path string) []*jaegerthrift.Span {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open span file: %v", err)
+	}
+	defer file.Close()
+
+	var spans []*jaegerthrift.Span
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		span := new(jaegerthrift.Span)
+		if err := json.Unmarshal(scanner.Bytes(), span); err != nil {
+			t.Fatalf("failed to decode span %q: %v", scanner.Text(), err)
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// Tests that the file reporter writes one JSON line per finished span, only
+// making them visible once flushed, and ignores spans after being closed.
+func TestFileReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "spans.json")
+	reporter, err := NewFileReporter(path)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	tracer, closer := jaeger.NewTracer("test", jaeger.NewConstSampler(true), reporter)
+
+	root := tracer.StartSpan("root")
+	root.SetTag("blocks", 2)
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	child.Finish()
+	root.Finish()
+
+	closer.Close() // Closes the reporter too
+	reporter.Close()
+	tracer.StartSpan("late").Finish()
+
+	spans := readSpans(t, path)
+	if len(spans) != 2 {
+		t.Fatalf("span count mismatch: have %d, want 2", len(spans))
+	}
+	if spans[0].OperationName != "child" || spans[1].OperationName != "root" {
+		t.Errorf("span order mismatch: have %s, %s, want child, root", spans[0].OperationName, spans[1].OperationName)
+	}
+	if spans[0].ParentSpanId != spans[1].SpanId {
+		t.Errorf("child span not linked to its parent")
+	}
+	found := false
+	for _, tag := range spans[1].Tags {
+		if tag.Key == "blocks" && tag.VLong != nil && *tag.VLong == 2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("root span tag missing: %v", spans[1].Tags)
+	}
+}
+
+// Tests that setting up tracing installs the global tracer only if requested,
+// and that the configured outputs receive the spans.
+func TestSetup(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	// Disabled tracing must leave the no-op tracer in place
+	if err := Setup(newContext(t)); err != nil {
+		t.Fatalf("failed to set up disabled tracing: %v", err)
+	}
+	if _, ok := opentracing.GlobalTracer().(opentracing.NoopTracer); !ok {
+		t.Fatalf("tracer installed while disabled")
+	}
+	// Enabled tracing needs somewhere to export the spans to
+	if err := Setup(newContext(t, "--tracing", "--tracing.endpoint", "")); err == nil {
+		t.Fatalf("tracing enabled without any output")
+	}
+	// Tracing into a file should record all spans
+	dir, err := ioutil.TempDir("", "tracing-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "spans.json")
+	if err := Setup(newContext(t, "--tracing", "--tracing.endpoint", "", "--tracing.file", path, "--tracing.svc", "tester")); err != nil {
+		t.Fatalf("failed to set up tracing: %v", err)
+	}
+	opentracing.StartSpan("test").Finish()
+	Exit()
+
+	spans := readSpans(t, path)
+	if len(spans) != 1 || spans[0].OperationName != "test" {
+		t.Fatalf("traced spans mismatch: %v", spans)
+	}
+}
@@ -28,6 +28,7 @@ var Modules = map[string]string{
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
+	"pool":       Pool_JS,
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
@@ -174,6 +175,28 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'addWhitelistedBlock',
+			call: 'admin_addWhitelistedBlock',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'removeWhitelistedBlock',
+			call: 'admin_removeWhitelistedBlock',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'addBlacklistedBlock',
+			call: 'admin_addBlacklistedBlock',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeBlacklistedBlock',
+			call: 'admin_removeBlacklistedBlock',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -209,10 +232,22 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'whitelistedBlocks',
+			getter: 'admin_whitelistedBlocks'
+		}),
+		new web3._extend.Property({
+			name: 'blacklistedBlocks',
+			getter: 'admin_blacklistedBlocks'
+		}),
 	]
 });
 `
@@ -469,6 +504,17 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'speedUpTransaction',
+			call: 'eth_speedUpTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'eth_cancelTransaction',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'eth_signTransaction',
@@ -486,6 +532,22 @@ web3._extend({
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'suggestGasPrices',
+			call: 'eth_suggestGasPrices',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getDroppedTransaction',
+			call: 'eth_getDroppedTransactionByHash',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -558,6 +620,12 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'getStats',
+			call: 'miner_getStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: []
 });
@@ -679,10 +747,64 @@ web3._extend({
 });
 `
 
+const Pool_JS = `
+web3._extend({
+	property: 'pool',
+	methods: [
+		new web3._extend.Method({
+			name: 'getBalance',
+			call: 'pool_getBalance',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlocks',
+			call: 'pool_getBlocks',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getPayments',
+			call: 'pool_getPayments',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'payout',
+			call: 'pool_payout',
+			params: 0
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'balances',
+			getter: 'pool_getBalances'
+		}),
+		new web3._extend.Property({
+			name: 'round',
+			getter: 'pool_getRound'
+		}),
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'addPriority',
+			call: 'txpool_addPriority',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'removePriority',
+			call: 'txpool_removePriority',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -693,6 +815,10 @@ web3._extend({
 			name: 'inspect',
 			getter: 'txpool_inspect'
 		}),
+		new web3._extend.Property({
+			name: 'priorities',
+			getter: 'txpool_priorities'
+		}),
 		new web3._extend.Property({
 			name: 'status',
 			getter: 'txpool_status',
@@ -131,6 +131,21 @@ func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (
 	return b.eth.txPool.GetNonce(ctx, addr)
 }
 
+func (b *LesApiBackend) GetPoolStatus(hashes []common.Hash) []core.TxStatus {
+	status := make([]core.TxStatus, len(hashes))
+	for i, hash := range hashes {
+		if b.eth.txPool.GetTransaction(hash) != nil {
+			status[i] = core.TxStatusPending
+		}
+	}
+	return status
+}
+
+func (b *LesApiBackend) GetPoolPriceBump() uint64 {
+	// Replacements are validated by the serving full nodes, assume their defaults
+	return core.DefaultTxPoolConfig.PriceBump
+}
+
 func (b *LesApiBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats(), 0
 }
@@ -143,6 +158,18 @@ func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeDroppedTxsEvent(ch chan<- core.DroppedTxsEvent) event.Subscription {
+	// The light pool doesn't drop transactions on its own, nothing to announce
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LesApiBackend) GetDroppedTransaction(hash common.Hash) *core.DroppedTx {
+	return nil
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainEvent(ch)
 }
@@ -32,6 +32,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/downloader"
+	"git.pirl.io/bitcoiin/go-bitcoiin/eth/ethgrpc"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/filters"
 	"git.pirl.io/bitcoiin/go-bitcoiin/eth/gasprice"
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
@@ -43,6 +44,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/discv5"
 	"git.pirl.io/bitcoiin/go-bitcoiin/params"
 	rpc "git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc/grpc"
 )
 
 type LightEthereum struct {
@@ -202,6 +204,11 @@ func (s *LightEthereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
 			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   gasprice.NewPublicGasPriceAPI(s.ApiBackend.gpo),
+			Public:    true,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -211,6 +218,12 @@ func (s *LightEthereum) APIs() []rpc.API {
 	}...)
 }
 
+// GRPCServices implements node.GRPCService, returning the gRPC services served
+// from the same backend as the RPC APIs.
+func (s *LightEthereum) GRPCServices() []grpc.Service {
+	return []grpc.Service{ethgrpc.NewService(s.ApiBackend, true)}
+}
+
 func (s *LightEthereum) ResetWithGenesisBlock(gb *types.Block) {
 	s.blockchain.ResetWithGenesisBlock(gb)
 }
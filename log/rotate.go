@@ -0,0 +1,238 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp format appended to the names of rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig contains the rotation policy of a RotatingFileWriter.
+type RotateConfig struct {
+	MaxSize    uint          // Maximum size of the live file in bytes before rotating (0 = unlimited)
+	MaxAge     time.Duration // Maximum age of the live file before rotating (0 = unlimited)
+	MaxBackups int           // Maximum number of rotated files to retain (0 = retain all)
+	Compress   bool          // Whether to gzip rotated files
+}
+
+// RotatingFileWriter is an io.WriteCloser which writes into a single file, moving
+// it aside whenever it grows too large or too old. Rotated files are named after
+// the live file, suffixed with the time of rotation and optionally compressed.
+type RotatingFileWriter struct {
+	path   string
+	config RotateConfig
+
+	file    *os.File  // Currently open live file
+	size    uint      // Number of bytes in the live file
+	created time.Time // Time when the live file was started
+
+	lock        sync.Mutex
+	cleanupLock sync.Mutex     // Serialises the post-processing of rotated files
+	cleanups    sync.WaitGroup // Pending post-processing runs, awaited on close
+}
+
+// NewRotatingFileWriter opens (or creates) the log file at path, appending to any
+// existing content, and rotates it according to the given policy.
+func NewRotatingFileWriter(path string, config RotateConfig) (*RotatingFileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	w := &RotatingFileWriter{path: path, config: config}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, rotating the live file before the write if needed.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.shouldRotate(uint(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += uint(n)
+	return n, err
+}
+
+// Close implements io.Closer, closing the live file and waiting for rotated files
+// to be compressed and pruned.
+func (w *RotatingFileWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+
+	w.cleanups.Wait()
+	return err
+}
+
+// open opens the live file, picking up the size and age of any previous content.
+func (w *RotatingFileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size, w.created = f, uint(info.Size()), time.Now()
+	if w.size > 0 {
+		w.created = info.ModTime()
+	}
+	return nil
+}
+
+// shouldRotate checks whether writing n more bytes would violate the policy.
+func (w *RotatingFileWriter) shouldRotate(n uint) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.config.MaxSize > 0 && w.size+n > w.config.MaxSize {
+		return true
+	}
+	if w.config.MaxAge > 0 && time.Since(w.created) > w.config.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate moves the live file aside, opens a fresh one and post-processes the
+// backups in the background.
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	ext := filepath.Ext(w.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(w.path, ext), time.Now().Format(backupTimeFormat), ext)
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.cleanups.Add(1)
+	go w.cleanup(backup)
+	return nil
+}
+
+// cleanup compresses the freshly rotated file if requested and drops the oldest
+// backups exceeding the retention limit. Runs are serialised, so that a backup
+// is never pruned or counted twice while it is being compressed.
+func (w *RotatingFileWriter) cleanup(backup string) {
+	defer w.cleanups.Done()
+
+	w.cleanupLock.Lock()
+	defer w.cleanupLock.Unlock()
+
+	if w.config.Compress {
+		if err := compressFile(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compress rotated log file %s: %v\n", backup, err)
+		}
+	}
+	if w.config.MaxBackups <= 0 {
+		return
+	}
+	stamps, backups, err := w.backups()
+	if err != nil || len(stamps) <= w.config.MaxBackups {
+		return
+	}
+	for _, stamp := range stamps[:len(stamps)-w.config.MaxBackups] {
+		for _, old := range backups[stamp] {
+			os.Remove(old)
+		}
+	}
+}
+
+// backups collects the rotated files of the live file, keyed by the timestamp in
+// their names. Only names consisting of the live file's name, a valid timestamp
+// and an optional compression suffix are considered, leaving unrelated files
+// sharing the prefix alone. The timestamps are returned oldest first.
+func (w *RotatingFileWriter) backups() ([]string, map[string][]string, error) {
+	dir, base := filepath.Dir(w.path), filepath.Base(w.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		stamps  []string
+		backups = make(map[string][]string)
+	)
+	for _, file := range files {
+		name := file.Name()
+		if !file.Mode().IsRegular() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+		if !strings.HasSuffix(rest, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(rest, ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		if _, ok := backups[stamp]; !ok {
+			stamps = append(stamps, stamp)
+		}
+		backups[stamp] = append(backups[stamp], filepath.Join(dir, name))
+	}
+	// Timestamps are zero padded, so they sort chronologically
+	sort.Strings(stamps)
+	return stamps, backups, nil
+}
+
+// compressFile gzips the file at path into path.gz and removes the original.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,174 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// rotateTestDir creates a temporary directory for the rotated files of a test.
+func rotateTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "log-rotate-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	return dir
+}
+
+// listDir returns the sorted names of all files in a directory.
+func listDir(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", dir, err)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rotateOnce writes data into the writer and forces a rotation of it, waiting a
+// bit so that consecutive backups get distinct timestamps.
+func rotateOnce(t *testing.T, w *RotatingFileWriter, data string) {
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.lock.Lock()
+	err := w.rotate()
+	w.lock.Unlock()
+	if err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+}
+
+// Tests that the live file is rotated once it would grow over the size limit or
+// got older than the age limit.
+func TestRotatingFileWriterRotation(t *testing.T) {
+	dir := rotateTestDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bitcoiin.log")
+	w, err := NewRotatingFileWriter(path, RotateConfig{MaxSize: 10, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("0123456\n"))
+	w.Write([]byte("7\n")) // Fills the file exactly up to the limit
+	if names := listDir(t, dir); len(names) != 1 {
+		t.Fatalf("rotated below the size limit: %v", names)
+	}
+	w.Write([]byte("abc\n"))
+	if names := listDir(t, dir); len(names) != 2 {
+		t.Fatalf("not rotated over the size limit: %v", names)
+	}
+	if blob, _ := ioutil.ReadFile(path); string(blob) != "abc\n" {
+		t.Fatalf("live file content mismatch: have %q, want %q", blob, "abc\n")
+	}
+	// Age the live file and ensure the next write rotates it
+	time.Sleep(2 * time.Millisecond)
+	w.lock.Lock()
+	w.created = time.Now().Add(-2 * time.Hour)
+	w.lock.Unlock()
+
+	w.Write([]byte("d\n"))
+	if names := listDir(t, dir); len(names) != 3 {
+		t.Fatalf("not rotated over the age limit: %v", names)
+	}
+	stamps, backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("failed to list backups: %v", err)
+	}
+	want := []string{"0123456\n7\n", "abc\n"}
+	for i, stamp := range stamps {
+		if blob, _ := ioutil.ReadFile(backups[stamp][0]); string(blob) != want[i] {
+			t.Errorf("backup %d content mismatch: have %q, want %q", i, blob, want[i])
+		}
+	}
+}
+
+// Tests that rotated files get compressed, with the originals removed.
+func TestRotatingFileWriterCompression(t *testing.T) {
+	dir := rotateTestDir(t)
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotatingFileWriter(filepath.Join(dir, "bitcoiin.log"), RotateConfig{Compress: true})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	rotateOnce(t, w, "compress me\n")
+	w.Close() // Waits for the compression
+
+	stamps, backups, err := w.backups()
+	if err != nil {
+		t.Fatalf("failed to list backups: %v", err)
+	}
+	if len(stamps) != 1 || len(backups[stamps[0]]) != 1 || filepath.Ext(backups[stamps[0]][0]) != ".gz" {
+		t.Fatalf("compressed backup missing: %v", listDir(t, dir))
+	}
+	f, err := os.Open(backups[stamps[0]][0])
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to decompress backup: %v", err)
+	}
+	if blob, _ := ioutil.ReadAll(gz); string(blob) != "compress me\n" {
+		t.Fatalf("backup content mismatch: have %q, want %q", blob, "compress me\n")
+	}
+}
+
+// Tests that only the newest backups are retained, and that files which merely
+// share the prefix of the live file are left alone.
+func TestRotatingFileWriterRetention(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := rotateTestDir(t)
+		defer os.RemoveAll(dir)
+
+		unrelated := []string{"bitcoiin-foo.log", "bitcoiin-2019.log", "bitcoiin-foo.log.gz", "other.log"}
+		for _, name := range unrelated {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("keep"), 0600); err != nil {
+				t.Fatalf("failed to create %s: %v", name, err)
+			}
+		}
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "bitcoiin.log"), RotateConfig{MaxBackups: 2, Compress: compress})
+		if err != nil {
+			t.Fatalf("failed to create writer: %v", err)
+		}
+		for _, data := range []string{"1\n", "2\n", "3\n", "4\n"} {
+			rotateOnce(t, w, data)
+		}
+		w.Close()
+
+		stamps, backups, err := w.backups()
+		if err != nil {
+			t.Fatalf("compress %v: failed to list backups: %v", compress, err)
+		}
+		if len(stamps) != 2 {
+			t.Fatalf("compress %v: retained backup count mismatch: have %d, want 2: %v", compress, len(stamps), listDir(t, dir))
+		}
+		if !compress {
+			for i, want := range []string{"3\n", "4\n"} {
+				if blob, _ := ioutil.ReadFile(backups[stamps[i]][0]); string(blob) != want {
+					t.Errorf("compress %v: backup %d content mismatch: have %q, want %q", compress, i, blob, want)
+				}
+			}
+		}
+		for _, name := range unrelated {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("compress %v: unrelated file %s removed: %v", compress, name, err)
+			}
+		}
+	}
+}
@@ -17,6 +17,12 @@ func NewHealthcheck(f func(Healthcheck)) Healthcheck {
 	return &StandardHealthcheck{nil, f}
 }
 
+// NewHealthcheckForced constructs a new Healthcheck which will use the given
+// function to update its status, no matter if the global switch is enabled or not.
+func NewHealthcheckForced(f func(Healthcheck)) Healthcheck {
+	return &StandardHealthcheck{nil, f}
+}
+
 // NilHealthcheck is a no-op.
 type NilHealthcheck struct{}
 
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
+)
+
+var (
+	typeGaugeTpl           = "# TYPE %s gauge\n"
+	typeCounterTpl         = "# TYPE %s counter\n"
+	typeSummaryTpl         = "# TYPE %s summary\n"
+	keyValueTpl            = "%s %v\n"
+	keyQuantileTagValueTpl = "%s{quantile=\"%s\"} %v\n"
+)
+
+// collector is a collection of byte buffers that aggregate Prometheus reports
+// for different metric types.
+type collector struct {
+	buff *bytes.Buffer
+}
+
+// newCollector creates a new Prometheus metric aggregator.
+func newCollector() *collector {
+	return &collector{
+		buff: &bytes.Buffer{},
+	}
+}
+
+func (c *collector) addCounter(name string, m metrics.Counter) {
+	c.writeCounter(name, m.Count())
+}
+
+func (c *collector) addGauge(name string, m metrics.Gauge) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addGaugeFloat64(name string, m metrics.GaugeFloat64) {
+	c.writeGauge(name, m.Value())
+}
+
+func (c *collector) addHistogram(name string, m metrics.Histogram) {
+	pv := []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+	ps := m.Percentiles(pv)
+	c.writeSummary(name, m.Count(), m.Sum(), pv, ps)
+}
+
+func (c *collector) addMeter(name string, m metrics.Meter) {
+	c.writeCounter(name, m.Count())
+}
+
+func (c *collector) addTimer(name string, m metrics.Timer) {
+	pv := []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+	ps := m.Percentiles(pv)
+	c.writeSummary(name, m.Count(), m.Sum(), pv, ps)
+}
+
+// resettingTimerState is what the Prometheus endpoint remembers of a resetting
+// timer between scrapes. Reading a resetting timer drains it, so the count and
+// sum are accumulated here to keep them monotonic, while the quantiles cover
+// only the values recorded since the previous scrape.
+type resettingTimerState struct {
+	count int64
+	sum   int64
+}
+
+func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer, state *resettingTimerState) {
+	pv := []float64{50, 95, 99}
+	quantiles := make([]float64, len(pv))
+	if values := m.Values(); len(values) > 0 {
+		for i, p := range m.Percentiles(pv) {
+			quantiles[i] = float64(p)
+		}
+		for _, v := range values {
+			state.sum += v
+		}
+		state.count += int64(len(values))
+	}
+	c.writeSummary(name, state.count, state.sum, []float64{0.50, 0.95, 0.99}, quantiles)
+}
+
+func (c *collector) writeGauge(name string, value interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+}
+
+func (c *collector) writeCounter(name string, value interface{}) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeCounterTpl, name))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, value))
+}
+
+func (c *collector) writeSummary(name string, count int64, sum int64, pv []float64, ps []float64) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeSummaryTpl, name))
+
+	for i := range pv {
+		c.buff.WriteString(fmt.Sprintf(keyQuantileTagValueTpl, name, strconv.FormatFloat(pv[i], 'f', -1, 64), ps[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf("%s_sum %d\n", name, sum))
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_count", count))
+}
+
+// mutateKey converts a go-metrics name into a valid Prometheus metric name,
+// replacing all characters outside of [a-zA-Z0-9_:] with underscores.
+func mutateKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if len(key) > 0 && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
@@ -39,6 +39,15 @@ type Backend interface {
 	TxPool() *core.TxPool
 }
 
+// Config is the configuration parameters of block creation.
+type Config struct {
+	Recommit    time.Duration    // The time interval for miner to re-create mining work
+	GasFloor    uint64           // Target gas floor for mined blocks
+	GasCeil     uint64           // Target gas ceiling for mined blocks
+	TxOrder     string           // Transaction ordering strategy (price, fifo or allowlist)
+	TxAllowlist []common.Address // Accounts included first by the allowlist ordering
+}
+
 // Miner creates blocks and searches for proof-of-work values.
 type Miner struct {
 	mux      *event.TypeMux
@@ -52,18 +61,22 @@ type Miner struct {
 	shouldStart int32 // should start indicates whether we should start after sync
 }
 
-func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine, recommit time.Duration, gasFloor, gasCeil uint64, isLocalBlock func(block *types.Block) bool) *Miner {
+func New(eth Backend, config *Config, chainConfig *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine, isLocalBlock func(block *types.Block) bool) (*Miner, error) {
+	worker, err := newWorker(config, chainConfig, engine, eth, mux, isLocalBlock)
+	if err != nil {
+		return nil, err
+	}
 	miner := &Miner{
 		eth:      eth,
 		mux:      mux,
 		engine:   engine,
 		exitCh:   make(chan struct{}),
-		worker:   newWorker(config, engine, eth, mux, recommit, gasFloor, gasCeil, isLocalBlock),
+		worker:   worker,
 		canStart: 1,
 	}
 	go miner.update()
 
-	return miner
+	return miner, nil
 }
 
 // update keeps track of the downloader events. Please be aware that this is a one shot type of update loop.
@@ -168,3 +181,9 @@ func (self *Miner) SetEtherbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setEtherbase(addr)
 }
+
+// SubscribeMinedBlocks registers a subscription for the fate of locally mined
+// blocks, posted once they exceed the unconfirmed depth allowance.
+func (self *Miner) SubscribeMinedBlocks(ch chan<- MinedBlockEvent) event.Subscription {
+	return self.worker.unconfirmed.feed.Subscribe(ch)
+}
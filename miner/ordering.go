@@ -0,0 +1,353 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+)
+
+const (
+	// TxOrderPrice includes the best paying transactions first.
+	TxOrderPrice = "price"
+
+	// TxOrderFIFO includes transactions in the order they arrived at the node.
+	TxOrderFIFO = "fifo"
+
+	// TxOrderAllowlist includes the transactions of the allowlisted accounts
+	// first, in the order of the list, and the rest by price.
+	TxOrderAllowlist = "allowlist"
+)
+
+// fifoMemory is the time the FIFO orderer remembers the arrival of a transaction.
+const fifoMemory = 12 * time.Hour
+
+// TxIterator iterates over a set of transactions in the order they should be
+// included in a block.
+type TxIterator interface {
+	// Peek returns the next transaction to include, or nil if none is left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one of the same account.
+	Shift()
+
+	// Pop removes the current transaction along with all the remaining ones of
+	// the same account, e.g. if it didn't fit into the block.
+	Pop()
+}
+
+// TxOrderer decides the order in which the pending transactions are included
+// in the mined blocks. The transactions of an account must always be returned
+// in nonce order.
+type TxOrderer interface {
+	// Order creates an iterator over the nonce sorted transactions of each account.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator
+}
+
+// txArrivalObserver is implemented by orderers needing to know when each of
+// the transactions entered the pending pool.
+type txArrivalObserver interface {
+	observe(txs []*types.Transaction)
+}
+
+// accountPrioritizer is implemented by orderers which include the transactions
+// of some accounts before all others, even the local ones.
+type accountPrioritizer interface {
+	prioritized() []common.Address
+}
+
+// NewTxOrderer creates the transaction orderer of the given strategy.
+func NewTxOrderer(strategy string, allowlist []common.Address) (TxOrderer, error) {
+	switch strategy {
+	case "", TxOrderPrice:
+		return NewPriceOrderer(), nil
+	case TxOrderFIFO:
+		return NewFIFOOrderer(), nil
+	case TxOrderAllowlist:
+		return NewAllowlistOrderer(allowlist), nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering %q", strategy)
+	}
+}
+
+// priceOrderer greedily includes the best paying transactions first.
+type priceOrderer struct{}
+
+// NewPriceOrderer creates a transaction orderer preferring the best paying
+// transactions. This is the default ordering of the worker.
+func NewPriceOrderer() TxOrderer {
+	return priceOrderer{}
+}
+
+// Order implements TxOrderer, returning a price and nonce ordered iterator.
+func (priceOrderer) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// fifoOrderer includes transactions in the order they were first seen.
+type fifoOrderer struct {
+	seen   map[common.Hash]txArrival // Arrival of the transactions seen recently
+	seq    uint64                    // Sequence number of the next arrival
+	pruned time.Time                 // Time of the last arrival cleanup
+	lock   sync.Mutex
+}
+
+// txArrival is the position of a transaction in the arrival order.
+type txArrival struct {
+	seq  uint64
+	time time.Time
+}
+
+// NewFIFOOrderer creates a transaction orderer including the transactions in
+// their order of arrival, regardless of the gas price they pay.
+func NewFIFOOrderer() TxOrderer {
+	return &fifoOrderer{
+		seen:   make(map[common.Hash]txArrival),
+		pruned: time.Now(),
+	}
+}
+
+// observe records the arrival of the given transactions.
+func (o *fifoOrderer) observe(txs []*types.Transaction) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	now := time.Now()
+	for _, tx := range txs {
+		o.arrival(tx.Hash(), now)
+	}
+	o.prune(now)
+}
+
+// arrival retrieves the arrival sequence of a transaction, assigning the next
+// one if not seen before.
+//
+// Note, the lock must be held by the caller.
+func (o *fifoOrderer) arrival(hash common.Hash, now time.Time) uint64 {
+	if seen, ok := o.seen[hash]; ok {
+		return seen.seq
+	}
+	o.seen[hash] = txArrival{seq: o.seq, time: now}
+	o.seq++
+	return o.seq - 1
+}
+
+// prune forgets the arrivals too old to matter every now and then.
+//
+// Note, the lock must be held by the caller.
+func (o *fifoOrderer) prune(now time.Time) {
+	if now.Sub(o.pruned) < time.Minute {
+		return
+	}
+	for hash, seen := range o.seen {
+		if now.Sub(seen.time) > fifoMemory {
+			delete(o.seen, hash)
+		}
+	}
+	o.pruned = now
+}
+
+// Order implements TxOrderer, returning an arrival and nonce ordered iterator.
+// Transactions not observed before are considered to be arriving now, in price
+// and nonce order.
+func (o *fifoOrderer) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	now := time.Now()
+	for it := types.NewTransactionsByPriceAndNonce(signer, copyTxs(txs)); it.Peek() != nil; it.Shift() {
+		o.arrival(it.Peek().Hash(), now)
+	}
+	arrivals := make(map[common.Hash]uint64)
+	for _, list := range txs {
+		for _, tx := range list {
+			arrivals[tx.Hash()] = o.seen[tx.Hash()].seq
+		}
+	}
+	return newTxsByHeads(txs, func(a, b txHead) bool {
+		return arrivals[a.tx.Hash()] < arrivals[b.tx.Hash()]
+	})
+}
+
+// allowlistOrderer includes the transactions of the allowlisted accounts first,
+// followed by the rest by price.
+type allowlistOrderer struct {
+	accounts []common.Address
+	index    map[common.Address]int
+}
+
+// NewAllowlistOrderer creates a transaction orderer including the transactions
+// of the given accounts before all others, in the order of the list. The rest
+// of the transactions are ordered by price.
+func NewAllowlistOrderer(accounts []common.Address) TxOrderer {
+	index := make(map[common.Address]int)
+	for i, account := range accounts {
+		if _, ok := index[account]; !ok {
+			index[account] = i
+		}
+	}
+	return &allowlistOrderer{
+		accounts: accounts,
+		index:    index,
+	}
+}
+
+// prioritized returns the allowlisted accounts.
+func (o *allowlistOrderer) prioritized() []common.Address {
+	return o.accounts
+}
+
+// Order implements TxOrderer, returning an iterator over the allowlisted
+// accounts followed by a price and nonce ordered one over the rest.
+func (o *allowlistOrderer) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	listed, rest := make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+	for account, list := range txs {
+		if _, ok := o.index[account]; ok {
+			listed[account] = list
+		} else {
+			rest[account] = list
+		}
+	}
+	first := newTxsByHeads(listed, func(a, b txHead) bool {
+		return o.index[a.from] < o.index[b.from]
+	})
+	return &chainedTxs{first, types.NewTransactionsByPriceAndNonce(signer, rest)}
+}
+
+// txHead is the next transaction of an account, along with its sender.
+type txHead struct {
+	tx   *types.Transaction
+	from common.Address
+}
+
+// txHeads is a heap of account head transactions sorted by an arbitrary ordering.
+type txHeads struct {
+	heads []txHead
+	less  func(a, b txHead) bool
+}
+
+func (h *txHeads) Len() int           { return len(h.heads) }
+func (h *txHeads) Less(i, j int) bool { return h.less(h.heads[i], h.heads[j]) }
+func (h *txHeads) Swap(i, j int)      { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+
+func (h *txHeads) Push(x interface{}) {
+	h.heads = append(h.heads, x.(txHead))
+}
+
+func (h *txHeads) Pop() interface{} {
+	old := h.heads
+	n := len(old)
+	x := old[n-1]
+	h.heads = old[0 : n-1]
+	return x
+}
+
+// txsByHeads is a TxIterator picking the next transaction among the heads of
+// the accounts by an arbitrary ordering.
+type txsByHeads struct {
+	txs   map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads *txHeads                              // Next transaction for each unique account
+}
+
+// newTxsByHeads creates an iterator over the given nonce sorted transactions,
+// picking the next one among the account heads by the given ordering.
+//
+// Note, the input map is reowned so the caller should not interact any more
+// with it after providing it to the constructor.
+func newTxsByHeads(txs map[common.Address]types.Transactions, less func(a, b txHead) bool) *txsByHeads {
+	it := &txsByHeads{
+		txs:   txs,
+		heads: &txHeads{heads: make([]txHead, 0, len(txs)), less: less},
+	}
+	for from, accTxs := range txs {
+		if len(accTxs) == 0 {
+			delete(txs, from)
+			continue
+		}
+		it.heads.heads = append(it.heads.heads, txHead{tx: accTxs[0], from: from})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(it.heads)
+	return it
+}
+
+// Peek implements TxIterator.
+func (it *txsByHeads) Peek() *types.Transaction {
+	if it.heads.Len() == 0 {
+		return nil
+	}
+	return it.heads.heads[0].tx
+}
+
+// Shift implements TxIterator.
+func (it *txsByHeads) Shift() {
+	from := it.heads.heads[0].from
+	if txs := it.txs[from]; len(txs) > 0 {
+		it.heads.heads[0].tx, it.txs[from] = txs[0], txs[1:]
+		heap.Fix(it.heads, 0)
+		return
+	}
+	heap.Pop(it.heads)
+}
+
+// Pop implements TxIterator.
+func (it *txsByHeads) Pop() {
+	heap.Pop(it.heads)
+}
+
+// chainedTxs is a TxIterator exhausting a list of iterators one after the other.
+type chainedTxs []TxIterator
+
+// Peek implements TxIterator.
+func (c *chainedTxs) Peek() *types.Transaction {
+	for len(*c) > 0 {
+		if tx := (*c)[0].Peek(); tx != nil {
+			return tx
+		}
+		*c = (*c)[1:]
+	}
+	return nil
+}
+
+// Shift implements TxIterator.
+func (c *chainedTxs) Shift() {
+	if c.Peek() != nil {
+		(*c)[0].Shift()
+	}
+}
+
+// Pop implements TxIterator.
+func (c *chainedTxs) Pop() {
+	if c.Peek() != nil {
+		(*c)[0].Pop()
+	}
+}
+
+// copyTxs creates a shallow copy of a set of transactions, to be handed over to
+// an iterator without giving up the original.
+func copyTxs(txs map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	cpy := make(map[common.Address]types.Transactions, len(txs))
+	for account, list := range txs {
+		cpy[account] = list
+	}
+	return cpy
+}
@@ -0,0 +1,91 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+)
+
+// countingSigner is a signer counting the sender recoveries. It doesn't equal
+// any other signer, so the senders cached in the transactions are never used.
+type countingSigner struct {
+	types.HomesteadSigner
+	recovered int
+}
+
+func (s *countingSigner) Equal(types.Signer) bool { return false }
+
+func (s *countingSigner) Sender(tx *types.Transaction) (common.Address, error) {
+	s.recovered++
+	return s.HomesteadSigner.Sender(tx)
+}
+
+// Tests that a worker with an unknown transaction ordering can't be created.
+func TestUnknownTxOrder(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	config := *testConfig
+	config.TxOrder = "random"
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine, 0)
+	if _, err := newWorker(&config, ethashChainConfig, engine, b, new(event.TypeMux), nil); err == nil {
+		t.Fatalf("worker created with unknown transaction ordering")
+	}
+}
+
+// Tests that the allowlist ordering picks the allowlisted accounts in order
+// without recovering the senders of the transactions again.
+func TestAllowlistOrdererSenders(t *testing.T) {
+	var (
+		signer    = new(countingSigner)
+		txs       = make(map[common.Address]types.Transactions)
+		allowlist []common.Address
+	)
+	for i := len(testOrderKeys) - 1; i >= 0; i-- {
+		addr := crypto.PubkeyToAddress(testOrderKeys[i].PublicKey)
+		allowlist = append(allowlist, addr)
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(1000), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testOrderKeys[i])
+			txs[addr] = append(txs[addr], tx)
+		}
+	}
+	var included []common.Address
+	for it := NewAllowlistOrderer(allowlist).Order(signer, txs); it.Peek() != nil; it.Shift() {
+		from, _ := types.Sender(types.HomesteadSigner{}, it.Peek())
+		included = append(included, from)
+	}
+	if len(included) != 3*len(allowlist) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(included), 3*len(allowlist))
+	}
+	for i, from := range included {
+		if want := allowlist[i/3]; from != want {
+			t.Errorf("transaction %d sender mismatch: have %x, want %x", i, from, want)
+		}
+	}
+	if signer.recovered != 0 {
+		t.Errorf("senders recovered while ordering: have %d, want 0", signer.recovered)
+	}
+}
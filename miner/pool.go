@@ -0,0 +1,675 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/accounts"
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rlp"
+)
+
+const (
+	// PoolSchemePPLNS splits block rewards between the last Window shares.
+	PoolSchemePPLNS = "pplns"
+
+	// PoolSchemePROP splits block rewards between the shares of the round that
+	// found the block.
+	PoolSchemePROP = "prop"
+)
+
+// PoolConfig contains the settings of the solo pool share accounting.
+type PoolConfig struct {
+	Enabled   bool          // Whether to account stratum shares and pay out rewards
+	Scheme    string        // Reward split scheme (pplns or prop)
+	Window    uint64        // Number of last shares rewarded under PPLNS
+	Fee       float64       // Percentage of block rewards retained by the etherbase
+	Threshold *big.Int      // Minimum balance in wei before a payout is issued
+	Interval  time.Duration // Interval between automatic payout rounds (0 = manual only)
+	GasPrice  *big.Int      `toml:",omitempty"` // Gas price of payout transactions (nil = miner gas price)
+}
+
+// DefaultPoolConfig contains the default settings of the solo pool.
+var DefaultPoolConfig = PoolConfig{
+	Scheme:    PoolSchemePPLNS,
+	Window:    10000,
+	Fee:       0,
+	Threshold: big.NewInt(params.Ether),
+	Interval:  time.Hour,
+}
+
+var (
+	errPoolNoShares     = errors.New("consensus engine does not provide stratum shares")
+	errPoolNoEtherbase  = errors.New("etherbase must be explicitly specified")
+	errPoolUnknownSplit = errors.New("unknown pool scheme")
+)
+
+// Database keys of the pool ledger. Shares, credited blocks and payments are
+// stored under sequential indices so the most recent ones can be retrieved
+// without iterating the database.
+var (
+	poolShareSeqKey   = []byte("pool-share-seq")   // Index of the next share
+	poolShareTailKey  = []byte("pool-share-tail")  // Index of the oldest retained share
+	poolRoundKey      = []byte("pool-round")       // Index of the first share of the current round
+	poolPendingKey    = []byte("pool-pending")     // Rounds whose block is still unconfirmed
+	poolAccountsKey   = []byte("pool-accounts")    // Addresses with a ledger balance
+	poolBlockSeqKey   = []byte("pool-block-seq")   // Number of credited blocks
+	poolPaymentSeqKey = []byte("pool-payment-seq") // Number of issued payments
+
+	poolSharePrefix   = []byte("pool-s") // poolSharePrefix + index (uint64 big endian) -> poolShare
+	poolBlockPrefix   = []byte("pool-b") // poolBlockPrefix + index (uint64 big endian) -> PoolBlock
+	poolPaymentPrefix = []byte("pool-p") // poolPaymentPrefix + index (uint64 big endian) -> PoolPayment
+	poolBalancePrefix = []byte("pool-a") // poolBalancePrefix + address -> PoolBalance
+)
+
+// poolShare is a share recorded in the ledger.
+type poolShare struct {
+	Login      common.Address
+	Worker     string
+	Difficulty *big.Int
+}
+
+// poolRound is a block solution found by a stratum worker, waiting for the
+// block to exceed the unconfirmed depth allowance.
+type poolRound struct {
+	SealHash common.Hash
+	Number   uint64
+	Start    uint64 // Index of the first share of the round
+	End      uint64 // Index of the share solving the block
+}
+
+// PoolCredit is the part of a block reward credited to an address.
+type PoolCredit struct {
+	Address common.Address
+	Amount  *big.Int
+}
+
+// PoolBlock is a mined block whose reward was split between the pool members.
+type PoolBlock struct {
+	Number  uint64
+	Hash    common.Hash
+	Status  string
+	Reward  *big.Int
+	Fee     *big.Int
+	Credits []*PoolCredit
+	Time    uint64
+}
+
+// PoolPayment is a payout transaction issued to a pool member.
+type PoolPayment struct {
+	Address common.Address
+	Amount  *big.Int
+	TxHash  common.Hash
+	Time    uint64
+}
+
+// PoolBalance is the ledger balance of a pool member.
+type PoolBalance struct {
+	Unpaid *big.Int
+	Paid   *big.Int
+}
+
+// shareSource is implemented by consensus engines accepting shares from remote
+// workers.
+type shareSource interface {
+	SubscribeShares(ch chan<- ethash.Share) event.Subscription
+}
+
+// Pool accounts the shares submitted by the stratum workers of a small mining
+// group, splits the rewards of the blocks they find once these are confirmed and
+// pays out the accumulated balances from the etherbase.
+type Pool struct {
+	config    PoolConfig
+	miner     *Miner
+	engine    consensus.Engine
+	db        ethdb.Database
+	chain     *core.BlockChain
+	txpool    *core.TxPool
+	am        *accounts.Manager
+	etherbase func() (common.Address, error)
+	gasPrice  *big.Int
+
+	shareCh chan ethash.Share
+	minedCh chan MinedBlockEvent
+	subs    []event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+
+	lock sync.Mutex // Serialises ledger updates
+}
+
+// NewPool creates a share accounting pool on top of the given miner. The engine
+// must provide stratum shares.
+func NewPool(config PoolConfig, miner *Miner, engine consensus.Engine, db ethdb.Database, chain *core.BlockChain, txpool *core.TxPool, am *accounts.Manager, etherbase func() (common.Address, error), gasPrice *big.Int) (*Pool, error) {
+	if _, ok := engine.(shareSource); !ok {
+		return nil, errPoolNoShares
+	}
+	if config.Scheme != PoolSchemePPLNS && config.Scheme != PoolSchemePROP {
+		return nil, fmt.Errorf("%v: %q", errPoolUnknownSplit, config.Scheme)
+	}
+	if config.Fee < 0 || config.Fee > 100 {
+		return nil, fmt.Errorf("invalid pool fee %v%%", config.Fee)
+	}
+	if config.Scheme == PoolSchemePPLNS && config.Window == 0 {
+		log.Warn("Sanitizing invalid pool window", "provided", config.Window, "updated", DefaultPoolConfig.Window)
+		config.Window = DefaultPoolConfig.Window
+	}
+	if config.Threshold == nil {
+		config.Threshold = new(big.Int).Set(DefaultPoolConfig.Threshold)
+	}
+	if config.GasPrice != nil {
+		gasPrice = config.GasPrice
+	}
+	return &Pool{
+		config:    config,
+		miner:     miner,
+		engine:    engine,
+		db:        db,
+		chain:     chain,
+		txpool:    txpool,
+		am:        am,
+		etherbase: etherbase,
+		gasPrice:  gasPrice,
+	}, nil
+}
+
+// Start subscribes to stratum shares and mined blocks and starts the accounting.
+func (p *Pool) Start() {
+	p.shareCh = make(chan ethash.Share, 256)
+	p.minedCh = make(chan MinedBlockEvent, 16)
+	p.quit = make(chan struct{})
+	p.subs = []event.Subscription{
+		p.engine.(shareSource).SubscribeShares(p.shareCh),
+		p.miner.SubscribeMinedBlocks(p.minedCh),
+	}
+	p.wg.Add(1)
+	go p.loop()
+
+	log.Info("Mining pool accounting started", "scheme", p.config.Scheme, "window", p.config.Window, "fee", p.config.Fee, "threshold", p.config.Threshold)
+}
+
+// Stop terminates the accounting.
+func (p *Pool) Stop() {
+	for _, sub := range p.subs {
+		sub.Unsubscribe()
+	}
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// loop records the incoming shares, credits confirmed blocks and periodically
+// pays out the balances exceeding the threshold.
+func (p *Pool) loop() {
+	defer p.wg.Done()
+
+	var payout <-chan time.Time
+	if p.config.Interval > 0 {
+		ticker := time.NewTicker(p.config.Interval)
+		defer ticker.Stop()
+		payout = ticker.C
+	}
+	for {
+		select {
+		case share := <-p.shareCh:
+			if err := p.recordShare(share); err != nil {
+				log.Error("Failed to record pool share", "login", share.Login, "worker", share.Worker, "err", err)
+			}
+		case ev := <-p.minedCh:
+			if err := p.confirm(ev); err != nil {
+				log.Error("Failed to credit mined block", "number", ev.Number, "hash", ev.Hash, "err", err)
+			}
+		case <-payout:
+			if _, err := p.Payout(); err != nil {
+				log.Warn("Failed to pay out pool balances", "err", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// recordShare appends a share to the ledger, closing the current round if the
+// share solved a block.
+func (p *Pool) recordShare(share ethash.Share) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	seq := readUint(p.db, poolShareSeqKey)
+	batch := p.db.NewBatch()
+	if err := putRLP(batch, poolIndexKey(poolSharePrefix, seq), &poolShare{share.Login, share.Worker, share.Difficulty}); err != nil {
+		return err
+	}
+	batch.Put(poolShareSeqKey, encodeUint(seq+1))
+
+	if share.Block {
+		pending := p.readPending()
+		pending = append(pending, &poolRound{
+			SealHash: share.SealHash,
+			Number:   share.Number,
+			Start:    readUint(p.db, poolRoundKey),
+			End:      seq,
+		})
+		if err := putRLP(batch, poolPendingKey, pending); err != nil {
+			return err
+		}
+		batch.Put(poolRoundKey, encodeUint(seq+1))
+	}
+	return batch.Write()
+}
+
+// confirm credits the reward of a mined block which exceeded the unconfirmed
+// depth allowance to the shares of the round which found it.
+func (p *Pool) confirm(ev MinedBlockEvent) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	header := p.chain.GetHeaderByHash(ev.Hash)
+	if header == nil {
+		return fmt.Errorf("unknown block")
+	}
+	sealhash := p.engine.SealHash(header)
+
+	// Find the round of the block, expiring any older rounds whose block never
+	// made it into the unconfirmed set (e.g. a stale solution)
+	var (
+		round   *poolRound
+		pending []*poolRound
+	)
+	for _, r := range p.readPending() {
+		switch {
+		case r.SealHash == sealhash:
+			round = r
+		case r.Number < ev.Number:
+			log.Info("Dropping stale pool round", "number", r.Number, "sealhash", r.SealHash)
+		default:
+			pending = append(pending, r)
+		}
+	}
+	batch := p.db.NewBatch()
+	if err := putRLP(batch, poolPendingKey, pending); err != nil {
+		return err
+	}
+	if round == nil {
+		// Block wasn't found by a stratum worker, the etherbase keeps the reward
+		return batch.Write()
+	}
+	var reward *big.Int
+	switch ev.Status {
+	case MinedBlockCanonical:
+		block := p.chain.GetBlock(ev.Hash, ev.Number)
+		if block == nil {
+			return fmt.Errorf("missing block body")
+		}
+		reward, _ = ethash.BlockRewards(p.chain.Config(), header, block.Uncles())
+		reward.Add(reward, blockFees(block, p.chain.GetReceiptsByHash(ev.Hash)))
+
+	case MinedBlockUncle:
+		reward = uncleReward(p.chain, header)
+
+	default:
+		log.Info("Pool block lost, shares not rewarded", "number", ev.Number, "hash", ev.Hash)
+		return batch.Write()
+	}
+	credited, err := p.credit(batch, round, reward)
+	if err != nil {
+		return err
+	}
+	credited.Number, credited.Hash, credited.Status = ev.Number, ev.Hash, ev.Status.String()
+	if err := p.appendBlock(batch, credited); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	p.prune()
+
+	log.Info("Credited pool block", "number", ev.Number, "hash", ev.Hash, "status", ev.Status, "reward", reward, "members", len(credited.Credits))
+	return nil
+}
+
+// uncleReward searches for the canonical block including the given uncle and
+// calculates the reward credited to the uncle's coinbase.
+func uncleReward(chain *core.BlockChain, uncle *types.Header) *big.Int {
+	for number := uncle.Number.Uint64() + 1; number <= uncle.Number.Uint64()+miningLogAtDepth; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		for i, included := range block.Uncles() {
+			if included.Hash() == uncle.Hash() {
+				_, rewards := ethash.BlockRewards(chain.Config(), block.Header(), block.Uncles())
+				return rewards[i]
+			}
+		}
+	}
+	return new(big.Int)
+}
+
+// credit splits the reward of a round between the rewarded shares according to
+// the configured scheme and adds the credits to the member balances.
+func (p *Pool) credit(batch ethdb.Batch, round *poolRound, reward *big.Int) (*PoolBlock, error) {
+	// Deduct the operator fee, which simply stays with the etherbase
+	fee := new(big.Int).Mul(reward, big.NewInt(int64(p.config.Fee*100)))
+	fee.Div(fee, big.NewInt(10000))
+	amount := new(big.Int).Sub(reward, fee)
+
+	// Gather the share difficulties of the rewarded window
+	first := p.firstShare(round)
+	if tail := readUint(p.db, poolShareTailKey); first < tail {
+		first = tail
+	}
+	var (
+		weights = make(map[common.Address]*big.Int)
+		order   []common.Address
+		total   = new(big.Int)
+	)
+	for seq := first; seq <= round.End; seq++ {
+		share := new(poolShare)
+		if err := readRLP(p.db, poolIndexKey(poolSharePrefix, seq), share); err != nil {
+			continue
+		}
+		if weights[share.Login] == nil {
+			weights[share.Login] = new(big.Int)
+			order = append(order, share.Login)
+		}
+		weights[share.Login].Add(weights[share.Login], share.Difficulty)
+		total.Add(total, share.Difficulty)
+	}
+	block := &PoolBlock{Reward: reward, Fee: fee, Time: uint64(time.Now().Unix())}
+	if total.Sign() == 0 {
+		return block, nil
+	}
+	// Credit every member proportionally, rounding leftovers stay with the etherbase
+	accounts := p.readAccounts()
+	for _, addr := range order {
+		share := new(big.Int).Mul(amount, weights[addr])
+		share.Div(share, total)
+
+		balance, known := p.readBalance(addr)
+		if !known {
+			accounts = append(accounts, addr)
+		}
+		balance.Unpaid.Add(balance.Unpaid, share)
+		if err := putRLP(batch, append(poolBalancePrefix, addr.Bytes()...), balance); err != nil {
+			return nil, err
+		}
+		block.Credits = append(block.Credits, &PoolCredit{Address: addr, Amount: share})
+	}
+	if err := putRLP(batch, poolAccountsKey, accounts); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// firstShare returns the index of the oldest share rewarded by a round.
+func (p *Pool) firstShare(round *poolRound) uint64 {
+	if p.config.Scheme == PoolSchemePROP {
+		return round.Start
+	}
+	if round.End+1 > p.config.Window {
+		return round.End + 1 - p.config.Window
+	}
+	return 0
+}
+
+// prune deletes the shares which can no longer be rewarded by the current or
+// any of the pending rounds.
+func (p *Pool) prune() {
+	seq, tail := readUint(p.db, poolShareSeqKey), readUint(p.db, poolShareTailKey)
+
+	// The current round ends with the next share at the earliest
+	keep := p.firstShare(&poolRound{Start: readUint(p.db, poolRoundKey), End: seq})
+	for _, round := range p.readPending() {
+		if first := p.firstShare(round); first < keep {
+			keep = first
+		}
+	}
+	if keep <= tail {
+		return
+	}
+	batch := p.db.NewBatch()
+	for i := tail; i < keep; i++ {
+		batch.Delete(poolIndexKey(poolSharePrefix, i))
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			batch.Write()
+			batch.Reset()
+		}
+	}
+	batch.Put(poolShareTailKey, encodeUint(keep))
+	if err := batch.Write(); err != nil {
+		log.Warn("Failed to prune pool shares", "err", err)
+	}
+}
+
+// Payout issues a transaction from the etherbase to every member whose unpaid
+// balance reached the payout threshold, returning the hashes of the transactions.
+func (p *Pool) Payout() ([]common.Hash, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	etherbase, err := p.etherbase()
+	if err != nil {
+		return nil, errPoolNoEtherbase
+	}
+	account := accounts.Account{Address: etherbase}
+	wallet, err := p.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var chainID *big.Int
+	if config := p.chain.Config(); config.IsEIP155(p.chain.CurrentBlock().Number()) {
+		chainID = config.ChainID
+	}
+	var hashes []common.Hash
+	for _, addr := range p.readAccounts() {
+		balance, _ := p.readBalance(addr)
+		if balance.Unpaid.Sign() == 0 || balance.Unpaid.Cmp(p.config.Threshold) < 0 {
+			continue
+		}
+		nonce := p.txpool.State().GetNonce(etherbase)
+		tx := types.NewTransaction(nonce, addr, balance.Unpaid, params.TxGas, p.gasPrice, nil)
+		signed, err := wallet.SignTx(account, tx, chainID)
+		if err != nil {
+			return hashes, err
+		}
+		if err := p.txpool.AddLocal(signed); err != nil {
+			return hashes, err
+		}
+		// Payment accepted into the pool, move the balance over to the paid side
+		payment := &PoolPayment{Address: addr, Amount: new(big.Int).Set(balance.Unpaid), TxHash: signed.Hash(), Time: uint64(time.Now().Unix())}
+		balance.Paid.Add(balance.Paid, balance.Unpaid)
+		balance.Unpaid = new(big.Int)
+
+		batch := p.db.NewBatch()
+		if err := putRLP(batch, append(poolBalancePrefix, addr.Bytes()...), balance); err != nil {
+			return hashes, err
+		}
+		seq := readUint(p.db, poolPaymentSeqKey)
+		if err := putRLP(batch, poolIndexKey(poolPaymentPrefix, seq), payment); err != nil {
+			return hashes, err
+		}
+		batch.Put(poolPaymentSeqKey, encodeUint(seq+1))
+		if err := batch.Write(); err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, signed.Hash())
+		log.Info("Issued pool payout", "address", addr, "amount", payment.Amount, "tx", signed.Hash())
+	}
+	return hashes, nil
+}
+
+// Balance returns the ledger balance of the given address.
+func (p *Pool) Balance(addr common.Address) *PoolBalance {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	balance, _ := p.readBalance(addr)
+	return balance
+}
+
+// Balances returns the ledger balances of all pool members.
+func (p *Pool) Balances() map[common.Address]*PoolBalance {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	balances := make(map[common.Address]*PoolBalance)
+	for _, addr := range p.readAccounts() {
+		balances[addr], _ = p.readBalance(addr)
+	}
+	return balances
+}
+
+// Round returns the share difficulty accumulated by each member in the current,
+// not yet solved round.
+func (p *Pool) Round() map[common.Address]*big.Int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	round := make(map[common.Address]*big.Int)
+	for seq, end := readUint(p.db, poolRoundKey), readUint(p.db, poolShareSeqKey); seq < end; seq++ {
+		share := new(poolShare)
+		if err := readRLP(p.db, poolIndexKey(poolSharePrefix, seq), share); err != nil {
+			continue
+		}
+		if round[share.Login] == nil {
+			round[share.Login] = new(big.Int)
+		}
+		round[share.Login].Add(round[share.Login], share.Difficulty)
+	}
+	return round
+}
+
+// Blocks returns the last count credited blocks, newest first.
+func (p *Pool) Blocks(count uint64) []*PoolBlock {
+	var blocks []*PoolBlock
+	for seq := readUint(p.db, poolBlockSeqKey); seq > 0 && uint64(len(blocks)) < count; seq-- {
+		block := new(PoolBlock)
+		if err := readRLP(p.db, poolIndexKey(poolBlockPrefix, seq-1), block); err == nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Payments returns the last count issued payments, newest first.
+func (p *Pool) Payments(count uint64) []*PoolPayment {
+	var payments []*PoolPayment
+	for seq := readUint(p.db, poolPaymentSeqKey); seq > 0 && uint64(len(payments)) < count; seq-- {
+		payment := new(PoolPayment)
+		if err := readRLP(p.db, poolIndexKey(poolPaymentPrefix, seq-1), payment); err == nil {
+			payments = append(payments, payment)
+		}
+	}
+	return payments
+}
+
+// appendBlock adds a credited block to the ledger.
+func (p *Pool) appendBlock(batch ethdb.Batch, block *PoolBlock) error {
+	seq := readUint(p.db, poolBlockSeqKey)
+	if err := putRLP(batch, poolIndexKey(poolBlockPrefix, seq), block); err != nil {
+		return err
+	}
+	return batch.Put(poolBlockSeqKey, encodeUint(seq+1))
+}
+
+// readBalance retrieves the balance of an address and whether it is known.
+func (p *Pool) readBalance(addr common.Address) (*PoolBalance, bool) {
+	balance := new(PoolBalance)
+	if err := readRLP(p.db, append(poolBalancePrefix, addr.Bytes()...), balance); err != nil {
+		return &PoolBalance{Unpaid: new(big.Int), Paid: new(big.Int)}, false
+	}
+	return balance, true
+}
+
+// readAccounts retrieves the addresses with a ledger balance.
+func (p *Pool) readAccounts() []common.Address {
+	var accounts []common.Address
+	readRLP(p.db, poolAccountsKey, &accounts)
+	return accounts
+}
+
+// readPending retrieves the rounds waiting for block confirmation.
+func (p *Pool) readPending() []*poolRound {
+	var pending []*poolRound
+	readRLP(p.db, poolPendingKey, &pending)
+	return pending
+}
+
+// readUint retrieves a counter from the database, defaulting to zero.
+func readUint(db ethdb.Database, key []byte) uint64 {
+	blob, err := db.Get(key)
+	if err != nil || len(blob) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(blob)
+}
+
+// readRLP retrieves and decodes an RLP encoded database entry.
+func readRLP(db ethdb.Database, key []byte, val interface{}) error {
+	blob, err := db.Get(key)
+	if err != nil {
+		return err
+	}
+	return rlp.DecodeBytes(blob, val)
+}
+
+// putRLP encodes a database entry and adds it to the batch.
+func putRLP(batch ethdb.Putter, key []byte, val interface{}) error {
+	blob, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	return batch.Put(key, blob)
+}
+
+// poolIndexKey = prefix + index (uint64 big endian)
+func poolIndexKey(prefix []byte, index uint64) []byte {
+	return append(append([]byte{}, prefix...), encodeUint(index)...)
+}
+
+// encodeUint encodes a number as big endian uint64.
+func encodeUint(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+// blockFees sums the transaction fees paid to the coinbase of a block.
+func blockFees(block *types.Block, receipts types.Receipts) *big.Int {
+	fees := new(big.Int)
+	for i, tx := range block.Transactions() {
+		if i >= len(receipts) {
+			break
+		}
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), tx.GasPrice()))
+	}
+	return fees
+}
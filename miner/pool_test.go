@@ -0,0 +1,129 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+)
+
+var (
+	poolTestAlice = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+	poolTestBob   = common.HexToAddress("0x0000000000000000000000000000000000000b0b")
+)
+
+// recordShares feeds a sequence of unit difficulty shares into the pool, the
+// last one solving the block with the given seal hash.
+func recordShares(t *testing.T, pool *Pool, sealhash common.Hash, logins ...common.Address) {
+	for i, login := range logins {
+		share := ethash.Share{Login: login, Worker: "rig", SealHash: sealhash, Difficulty: big.NewInt(1), Block: i == len(logins)-1}
+		if err := pool.recordShare(share); err != nil {
+			t.Fatalf("failed to record share %d: %v", i, err)
+		}
+	}
+}
+
+// creditRound credits the given reward to the oldest pending round.
+func creditRound(t *testing.T, pool *Pool, reward int64) *PoolBlock {
+	pending := pool.readPending()
+	if len(pending) == 0 {
+		t.Fatalf("no pending round")
+	}
+	batch := pool.db.NewBatch()
+	block, err := pool.credit(batch, pending[0], big.NewInt(reward))
+	if err != nil {
+		t.Fatalf("failed to credit round: %v", err)
+	}
+	if err := pool.appendBlock(batch, block); err != nil {
+		t.Fatalf("failed to append block: %v", err)
+	}
+	if err := putRLP(batch, poolPendingKey, pending[1:]); err != nil {
+		t.Fatalf("failed to update pending rounds: %v", err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	pool.prune()
+	return block
+}
+
+func checkUnpaid(t *testing.T, pool *Pool, addr common.Address, want int64) {
+	if have := pool.Balance(addr).Unpaid; have.Cmp(big.NewInt(want)) != 0 {
+		t.Errorf("%x: unpaid balance mismatch: have %v, want %d", addr, have, want)
+	}
+}
+
+// Tests that the PROP scheme splits block rewards between the shares of the
+// round which found the block, after deducting the operator fee.
+func TestPoolProportional(t *testing.T) {
+	pool := &Pool{config: PoolConfig{Scheme: PoolSchemePROP, Fee: 10}, db: ethdb.NewMemDatabase()}
+
+	recordShares(t, pool, common.Hash{1}, poolTestAlice, poolTestAlice, poolTestAlice, poolTestBob, poolTestBob)
+	block := creditRound(t, pool, 1000)
+	if block.Fee.Int64() != 100 {
+		t.Errorf("fee mismatch: have %v, want 100", block.Fee)
+	}
+	checkUnpaid(t, pool, poolTestAlice, 540)
+	checkUnpaid(t, pool, poolTestBob, 360)
+
+	// The next round only consists of Bob's shares
+	recordShares(t, pool, common.Hash{2}, poolTestBob, poolTestBob)
+	creditRound(t, pool, 1000)
+	checkUnpaid(t, pool, poolTestAlice, 540)
+	checkUnpaid(t, pool, poolTestBob, 1260)
+
+	// Rewarded shares should have been pruned
+	if tail := readUint(pool.db, poolShareTailKey); tail != 7 {
+		t.Errorf("share tail mismatch: have %d, want 7", tail)
+	}
+}
+
+// Tests that the PPLNS scheme splits block rewards between the last shares,
+// regardless of round boundaries, and that the ledger survives a restart.
+func TestPoolPPLNS(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	pool := &Pool{config: PoolConfig{Scheme: PoolSchemePPLNS, Window: 4}, db: db}
+
+	recordShares(t, pool, common.Hash{1}, poolTestAlice, poolTestAlice, poolTestAlice, poolTestBob)
+	recordShares(t, pool, common.Hash{2}, poolTestBob, poolTestBob)
+
+	// Both rounds are pending, the second one must still be able to reward the
+	// shares of the first one
+	creditRound(t, pool, 400)
+	checkUnpaid(t, pool, poolTestAlice, 300)
+	checkUnpaid(t, pool, poolTestBob, 100)
+
+	if tail := readUint(pool.db, poolShareTailKey); tail != 2 {
+		t.Errorf("share tail mismatch: have %d, want 2", tail)
+	}
+	// Reopen the ledger and credit the second round
+	pool = &Pool{config: PoolConfig{Scheme: PoolSchemePPLNS, Window: 4}, db: db}
+	creditRound(t, pool, 400)
+	checkUnpaid(t, pool, poolTestAlice, 400)
+	checkUnpaid(t, pool, poolTestBob, 400)
+
+	if blocks := pool.Blocks(10); len(blocks) != 2 {
+		t.Fatalf("credited block count mismatch: have %d, want 2", len(blocks))
+	}
+	if balances := pool.Balances(); len(balances) != 2 {
+		t.Fatalf("member count mismatch: have %d, want 2", len(balances))
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+)
+
+// Database keys of the mined block statistics. Blocks are stored under sequential
+// indices in the order they are confirmed, which follows their block numbers, so
+// ranges can be looked up without iterating the database.
+var (
+	statsBlockSeqKey = []byte("miner-stats-seq") // Number of recorded blocks
+	statsBlockPrefix = []byte("miner-stats-b")   // statsBlockPrefix + index (uint64 big endian) -> MinedBlockStats
+)
+
+var (
+	statsCanonicalMeter = metrics.NewRegisteredMeter("miner/blocks/canonical", nil)
+	statsUncleMeter     = metrics.NewRegisteredMeter("miner/blocks/uncle", nil)
+	statsLostMeter      = metrics.NewRegisteredMeter("miner/blocks/lost", nil)
+	statsRewardMeter    = metrics.NewRegisteredMeter("miner/reward", nil) // In gwei, fees included
+	statsGasMeter       = metrics.NewRegisteredMeter("miner/gas", nil)
+)
+
+// MinedBlockStats is the persisted outcome of a block sealed by the local miner.
+type MinedBlockStats struct {
+	Number  uint64
+	Hash    common.Hash
+	Status  string
+	Reward  *big.Int // Block reward including uncle inclusions, or the uncle reward
+	Fees    *big.Int // Transaction fees earned, only for canonical blocks
+	GasUsed uint64
+	Uncles  uint64 // Number of uncles included by the block
+	Time    uint64
+}
+
+// MiningStats aggregates the mined block statistics over a range of blocks.
+type MiningStats struct {
+	Blocks    []*MinedBlockStats
+	Canonical uint64
+	Uncles    uint64
+	Lost      uint64
+	Reward    *big.Int
+	Fees      *big.Int
+	GasUsed   uint64
+}
+
+// OrphanRate returns the fraction of the sealed blocks which didn't make it into
+// the canonical chain.
+func (s *MiningStats) OrphanRate() float64 {
+	total := s.Canonical + s.Uncles + s.Lost
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Uncles+s.Lost) / float64(total)
+}
+
+// Stats keeps a persistent record of the fate of every block sealed by the miner,
+// along with the rewards it earned.
+type Stats struct {
+	miner *Miner
+	db    ethdb.Database
+	chain *core.BlockChain
+
+	minedCh chan MinedBlockEvent
+	sub     event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+
+	lock sync.Mutex // Serialises record appends
+}
+
+// NewStats creates a mined block recorder on top of the given miner.
+func NewStats(miner *Miner, db ethdb.Database, chain *core.BlockChain) *Stats {
+	return &Stats{
+		miner: miner,
+		db:    db,
+		chain: chain,
+	}
+}
+
+// Start subscribes to the mined blocks and starts recording them.
+func (s *Stats) Start() {
+	s.minedCh = make(chan MinedBlockEvent, 16)
+	s.quit = make(chan struct{})
+	s.sub = s.miner.SubscribeMinedBlocks(s.minedCh)
+
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the recording.
+func (s *Stats) Stop() {
+	s.sub.Unsubscribe()
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// loop records the mined blocks as they leave the unconfirmed set.
+func (s *Stats) loop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case ev := <-s.minedCh:
+			if err := s.record(ev); err != nil {
+				log.Error("Failed to record mined block", "number", ev.Number, "hash", ev.Hash, "err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// record calculates the earnings of a mined block which exceeded the unconfirmed
+// depth allowance and appends it to the statistics.
+func (s *Stats) record(ev MinedBlockEvent) error {
+	block := s.chain.GetBlock(ev.Hash, ev.Number)
+	if block == nil {
+		return fmt.Errorf("unknown block")
+	}
+	stats := &MinedBlockStats{
+		Number:  ev.Number,
+		Hash:    ev.Hash,
+		Status:  ev.Status.String(),
+		Reward:  new(big.Int),
+		Fees:    new(big.Int),
+		GasUsed: block.GasUsed(),
+		Uncles:  uint64(len(block.Uncles())),
+		Time:    block.Time(),
+	}
+	switch ev.Status {
+	case MinedBlockCanonical:
+		stats.Reward, _ = ethash.BlockRewards(s.chain.Config(), block.Header(), block.Uncles())
+		stats.Fees = blockFees(block, s.chain.GetReceiptsByHash(ev.Hash))
+		statsCanonicalMeter.Mark(1)
+
+	case MinedBlockUncle:
+		stats.Reward = uncleReward(s.chain, block.Header())
+		statsUncleMeter.Mark(1)
+
+	default:
+		statsLostMeter.Mark(1)
+	}
+	earned := new(big.Int).Add(stats.Reward, stats.Fees)
+	statsRewardMeter.Mark(new(big.Int).Div(earned, big.NewInt(params.GWei)).Int64())
+	statsGasMeter.Mark(int64(stats.GasUsed))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seq := readUint(s.db, statsBlockSeqKey)
+	batch := s.db.NewBatch()
+	if err := putRLP(batch, poolIndexKey(statsBlockPrefix, seq), stats); err != nil {
+		return err
+	}
+	batch.Put(statsBlockSeqKey, encodeUint(seq+1))
+	return batch.Write()
+}
+
+// Range aggregates the statistics of the mined blocks numbered between from and
+// to, both inclusive.
+func (s *Stats) Range(from, to uint64) *MiningStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	result := &MiningStats{
+		Blocks: []*MinedBlockStats{},
+		Reward: new(big.Int),
+		Fees:   new(big.Int),
+	}
+	count := readUint(s.db, statsBlockSeqKey)
+
+	// Records are appended in block number order, find the first one in range
+	start := sort.Search(int(count), func(i int) bool {
+		block := new(MinedBlockStats)
+		if err := readRLP(s.db, poolIndexKey(statsBlockPrefix, uint64(i)), block); err != nil {
+			return true
+		}
+		return block.Number >= from
+	})
+	for seq := uint64(start); seq < count; seq++ {
+		block := new(MinedBlockStats)
+		if err := readRLP(s.db, poolIndexKey(statsBlockPrefix, seq), block); err != nil {
+			log.Error("Failed to read mined block stats", "index", seq, "err", err)
+			break
+		}
+		if block.Number > to {
+			break
+		}
+		if block.Number < from {
+			continue
+		}
+		switch block.Status {
+		case MinedBlockCanonical.String():
+			result.Canonical++
+		case MinedBlockUncle.String():
+			result.Uncles++
+		default:
+			result.Lost++
+		}
+		result.Blocks = append(result.Blocks, block)
+		result.Reward.Add(result.Reward, block.Reward)
+		result.Fees.Add(result.Fees, block.Fees)
+		result.GasUsed += block.GasUsed
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common"
+	"git.pirl.io/bitcoiin/go-bitcoiin/consensus/ethash"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/core/vm"
+	"git.pirl.io/bitcoiin/go-bitcoiin/ethdb"
+	"git.pirl.io/bitcoiin/go-bitcoiin/params"
+)
+
+// Tests that the outcome and earnings of mined blocks are recorded, survive a
+// restart and are aggregated over block ranges.
+func TestMinedBlockStats(t *testing.T) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = ethash.NewFaker()
+		gspec  = &core.Genesis{Config: params.TestChainConfig}
+	)
+	genesis := gspec.MustCommit(db)
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil)
+	defer chain.Stop()
+
+	// Create a side block at height 1 included as an uncle by the canonical chain
+	// and one at height 3 which is not included at all
+	uncles, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 3, func(i int, gen *core.BlockGen) {
+		if i == 1 {
+			gen.AddUncle(uncles[0].Header())
+		}
+	})
+	lost, _ := core.GenerateChain(gspec.Config, blocks[1], engine, db, 1, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x02})
+	})
+	for _, chunk := range [][]*types.Block{blocks, uncles, lost} {
+		if _, err := chain.InsertChain(chunk); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+	}
+	stats := NewStats(nil, db, chain)
+	for _, ev := range []MinedBlockEvent{
+		{Number: 1, Hash: blocks[0].Hash(), Status: MinedBlockCanonical},
+		{Number: 1, Hash: uncles[0].Hash(), Status: MinedBlockUncle},
+		{Number: 2, Hash: blocks[1].Hash(), Status: MinedBlockCanonical},
+		{Number: 3, Hash: lost[0].Hash(), Status: MinedBlockLost},
+	} {
+		if err := stats.record(ev); err != nil {
+			t.Fatalf("failed to record block %d: %v", ev.Number, err)
+		}
+	}
+	// Calculate the expected rewards as credited by the consensus engine
+	reward, _ := ethash.BlockRewards(gspec.Config, blocks[0].Header(), nil)
+	inclusion, uncleRewards := ethash.BlockRewards(gspec.Config, blocks[1].Header(), blocks[1].Uncles())
+
+	total := new(big.Int).Add(reward, inclusion)
+	total.Add(total, uncleRewards[0])
+
+	// Reopen the statistics and check the aggregates of various ranges
+	stats = NewStats(nil, db, chain)
+
+	tests := []struct {
+		from, to               uint64
+		canonical, uncle, lost uint64
+		reward                 *big.Int
+		rate                   float64
+	}{
+		{0, 10, 2, 1, 1, total, 0.5},
+		{1, 1, 1, 1, 0, new(big.Int).Add(reward, uncleRewards[0]), 0.5},
+		{2, 2, 1, 0, 0, inclusion, 0},
+		{3, 3, 0, 0, 1, new(big.Int), 1},
+		{4, 10, 0, 0, 0, new(big.Int), 0},
+	}
+	for i, tt := range tests {
+		res := stats.Range(tt.from, tt.to)
+		if res.Canonical != tt.canonical || res.Uncles != tt.uncle || res.Lost != tt.lost {
+			t.Errorf("test %d: block count mismatch: have %d/%d/%d, want %d/%d/%d", i, res.Canonical, res.Uncles, res.Lost, tt.canonical, tt.uncle, tt.lost)
+		}
+		if uint64(len(res.Blocks)) != tt.canonical+tt.uncle+tt.lost {
+			t.Errorf("test %d: block list length mismatch: have %d, want %d", i, len(res.Blocks), tt.canonical+tt.uncle+tt.lost)
+		}
+		if res.Reward.Cmp(tt.reward) != 0 {
+			t.Errorf("test %d: reward mismatch: have %v, want %v", i, res.Reward, tt.reward)
+		}
+		if rate := res.OrphanRate(); rate != tt.rate {
+			t.Errorf("test %d: orphan rate mismatch: have %v, want %v", i, rate, tt.rate)
+		}
+	}
+	if blocks := stats.Range(2, 2).Blocks; len(blocks) == 1 && blocks[0].Uncles != 1 {
+		t.Errorf("uncle count mismatch: have %d, want 1", blocks[0].Uncles)
+	}
+}
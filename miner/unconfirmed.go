@@ -22,9 +22,42 @@ import (
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/common"
 	"git.pirl.io/bitcoiin/go-bitcoiin/core/types"
+	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 )
 
+// MinedBlockStatus is the fate of a locally mined block once it exceeded the
+// unconfirmed depth allowance.
+type MinedBlockStatus int
+
+const (
+	MinedBlockCanonical MinedBlockStatus = iota // Block is part of the canonical chain
+	MinedBlockUncle                             // Block was included as an uncle
+	MinedBlockLost                              // Block was reorged out entirely
+)
+
+// String implements fmt.Stringer.
+func (status MinedBlockStatus) String() string {
+	switch status {
+	case MinedBlockCanonical:
+		return "canonical"
+	case MinedBlockUncle:
+		return "uncle"
+	case MinedBlockLost:
+		return "lost"
+	default:
+		return "unknown"
+	}
+}
+
+// MinedBlockEvent is posted when a locally mined block leaves the unconfirmed
+// set, reporting whether it made it into the chain.
+type MinedBlockEvent struct {
+	Number uint64
+	Hash   common.Hash
+	Status MinedBlockStatus
+}
+
 // chainRetriever is used by the unconfirmed block set to verify whether a previously
 // mined block is part of the canonical chain or not.
 type chainRetriever interface {
@@ -51,6 +84,8 @@ type unconfirmedBlocks struct {
 	depth  uint           // Depth after which to discard previous blocks
 	blocks *ring.Ring     // Block infos to allow canonical chain cross checks
 	lock   sync.RWMutex   // Protects the fields from concurrent access
+
+	feed event.Feed // Feed reporting the fate of blocks leaving the set
 }
 
 // newUnconfirmedBlocks returns new data structure to track currently unconfirmed blocks.
@@ -89,6 +124,12 @@ func (set *unconfirmedBlocks) Insert(index uint64, hash common.Hash) {
 // allowance, checking them against the canonical chain for inclusion or staleness
 // report.
 func (set *unconfirmedBlocks) Shift(height uint64) {
+	var events []MinedBlockEvent
+	defer func() {
+		for _, event := range events {
+			set.feed.Send(event)
+		}
+	}()
 	set.lock.Lock()
 	defer set.lock.Unlock()
 
@@ -105,6 +146,7 @@ func (set *unconfirmedBlocks) Shift(height uint64) {
 			log.Warn("Failed to retrieve header of mined block", "number", next.index, "hash", next.hash)
 		case header.Hash() == next.hash:
 			log.Info("🔗 block reached canonical chain", "number", next.index, "hash", next.hash)
+			events = append(events, MinedBlockEvent{Number: next.index, Hash: next.hash, Status: MinedBlockCanonical})
 		default:
 			// Block is not canonical, check whether we have an uncle or a lost block
 			included := false
@@ -120,8 +162,10 @@ func (set *unconfirmedBlocks) Shift(height uint64) {
 			}
 			if included {
 				log.Info("⑂ block became an uncle", "number", next.index, "hash", next.hash)
+				events = append(events, MinedBlockEvent{Number: next.index, Hash: next.hash, Status: MinedBlockUncle})
 			} else {
 				log.Info("😱 block lost", "number", next.index, "hash", next.hash)
+				events = append(events, MinedBlockEvent{Number: next.index, Hash: next.hash, Status: MinedBlockLost})
 			}
 		}
 		// Drop the block out of the ring
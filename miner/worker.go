@@ -129,6 +129,7 @@ type worker struct {
 
 	gasFloor uint64
 	gasCeil  uint64
+	orderer  TxOrderer
 
 	// Subscriptions
 	mux          *event.TypeMux
@@ -178,15 +179,20 @@ type worker struct {
 	resubmitHook func(time.Duration, time.Duration) // Method to call upon updating resubmitting interval.
 }
 
-func newWorker(config *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, recommit time.Duration, gasFloor, gasCeil uint64, isLocalBlock func(*types.Block) bool) *worker {
+func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(*types.Block) bool) (*worker, error) {
+	orderer, err := NewTxOrderer(config.TxOrder, config.TxAllowlist)
+	if err != nil {
+		return nil, err
+	}
 	worker := &worker{
-		config:             config,
+		config:             chainConfig,
 		engine:             engine,
 		eth:                eth,
 		mux:                mux,
 		chain:              eth.BlockChain(),
-		gasFloor:           gasFloor,
-		gasCeil:            gasCeil,
+		gasFloor:           config.GasFloor,
+		gasCeil:            config.GasCeil,
+		orderer:            orderer,
 		isLocalBlock:       isLocalBlock,
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
@@ -210,6 +216,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, eth Backend,
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
 
 	// Sanitize recommit interval if the user-specified one is too short.
+	recommit := config.Recommit
 	if recommit < minRecommitInterval {
 		log.Warn("Sanitizing miner recommit interval", "provided", recommit, "updated", minRecommitInterval)
 		recommit = minRecommitInterval
@@ -223,7 +230,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, eth Backend,
 	// Submit first work to initialize pending state.
 	worker.startCh <- struct{}{}
 
-	return worker
+	return worker, nil
 }
 
 // setEtherbase sets the etherbase used to initialize the block coinbase field.
@@ -449,6 +456,10 @@ func (w *worker) mainLoop() {
 			}
 
 		case ev := <-w.txsCh:
+			// Let the transaction orderer know about the arrivals
+			if observer, ok := w.orderer.(txArrivalObserver); ok {
+				observer.observe(ev.Txs)
+			}
 			// Apply transactions to the pending state if we're not mining.
 			//
 			// Note all transactions received may not be continuous with transactions
@@ -464,7 +475,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := w.orderer.Order(w.current.signer, txs)
 				w.commitTransactions(txset, coinbase, nil)
 				w.updateSnapshot()
 			} else {
@@ -702,7 +713,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TxIterator, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -919,22 +930,28 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.updateSnapshot()
 		return
 	}
-	// Split the pending transactions into locals and remotes
+	// Split the pending transactions into locals (along with any accounts the
+	// orderer prioritizes) and remotes
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
+
+	accounts := w.eth.TxPool().Locals()
+	if prioritizer, ok := w.orderer.(accountPrioritizer); ok {
+		accounts = append(accounts, prioritizer.prioritized()...)
+	}
+	for _, account := range accounts {
 		if txs := remoteTxs[account]; len(txs) > 0 {
 			delete(remoteTxs, account)
 			localTxs[account] = txs
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
+		txs := w.orderer.Order(w.current.signer, localTxs)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+		txs := w.orderer.Order(w.current.signer, remoteTxs)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 	"testing"
 	"time"
@@ -48,9 +49,17 @@ var (
 	testUserKey, _  = crypto.GenerateKey()
 	testUserAddress = crypto.PubkeyToAddress(testUserKey.PublicKey)
 
+	testOrderKeys = make([]*ecdsa.PrivateKey, 3) // Accounts racing for block space in the ordering tests
+
 	// Test transactions
 	pendingTxs []*types.Transaction
 	newTxs     []*types.Transaction
+
+	testConfig = &Config{
+		Recommit: time.Second,
+		GasFloor: params.GenesisGasLimit,
+		GasCeil:  params.GenesisGasLimit,
+	}
 )
 
 func init() {
@@ -62,6 +71,9 @@ func init() {
 		Period: 10,
 		Epoch:  30000,
 	}
+	for i := range testOrderKeys {
+		testOrderKeys[i], _ = crypto.GenerateKey()
+	}
 	tx1, _ := types.SignTx(types.NewTransaction(0, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
 	pendingTxs = append(pendingTxs, tx1)
 	tx2, _ := types.SignTx(types.NewTransaction(1, testUserAddress, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
@@ -85,6 +97,9 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 			Alloc:  core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
 		}
 	)
+	for _, key := range testOrderKeys {
+		gspec.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: testBankFunds}
+	}
 
 	switch engine.(type) {
 	case *clique.Clique:
@@ -133,7 +148,10 @@ func (b *testWorkerBackend) PostChainEvents(events []interface{}) {
 func newTestWorker(t *testing.T, chainConfig *params.ChainConfig, engine consensus.Engine, blocks int) (*worker, *testWorkerBackend) {
 	backend := newTestWorkerBackend(t, chainConfig, engine, blocks)
 	backend.txPool.AddLocals(pendingTxs)
-	w := newWorker(chainConfig, engine, backend, new(event.TypeMux), time.Second, params.GenesisGasLimit, params.GenesisGasLimit, nil)
+	w, err := newWorker(testConfig, chainConfig, engine, backend, new(event.TypeMux), nil)
+	if err != nil {
+		t.Fatalf("failed to create worker: %v", err)
+	}
 	w.setEtherbase(testBankAddress)
 	return w, backend
 }
@@ -452,3 +470,72 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+func TestTxOrderingPrice(t *testing.T) {
+	testTxOrdering(t, &Config{TxOrder: TxOrderPrice}, []int{1, 2, 0})
+}
+func TestTxOrderingFIFO(t *testing.T) {
+	testTxOrdering(t, &Config{TxOrder: TxOrderFIFO}, []int{0, 1, 2})
+}
+func TestTxOrderingAllowlist(t *testing.T) {
+	allowlist := []common.Address{crypto.PubkeyToAddress(testOrderKeys[2].PublicKey)}
+	testTxOrdering(t, &Config{TxOrder: TxOrderAllowlist, TxAllowlist: allowlist}, []int{2, 1, 0})
+}
+
+// testTxOrdering feeds transactions of increasing arrival time but mixed gas
+// prices into the pool and checks the order the worker includes them in, given
+// as indexes of the sending test accounts.
+func testTxOrdering(t *testing.T, config *Config, order []int) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	config.Recommit, config.GasFloor, config.GasCeil = testConfig.Recommit, testConfig.GasFloor, testConfig.GasCeil
+
+	b := newTestWorkerBackend(t, ethashChainConfig, engine, 0)
+	w, err := newWorker(config, ethashChainConfig, engine, b, new(event.TypeMux), nil)
+	if err != nil {
+		t.Fatalf("failed to create worker: %v", err)
+	}
+	w.setEtherbase(testBankAddress)
+	defer w.close()
+
+	blocks := make(chan *types.Block, 1)
+	w.newTaskHook = func(task *task) {
+		if len(task.block.Transactions()) > 0 {
+			select {
+			case blocks <- task.block:
+			default:
+			}
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+
+	// Wait until the worker is initialized, then add the transactions one by one
+	for b := w.pendingBlock(); b == nil || b.NumberU64() != 1; b = w.pendingBlock() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	txs := make([]*types.Transaction, len(testOrderKeys))
+	for i, price := range []int64{1, 3, 2} {
+		txs[i], _ = types.SignTx(types.NewTransaction(0, testUserAddress, big.NewInt(1000), params.TxGas, big.NewInt(price), nil), types.HomesteadSigner{}, testOrderKeys[i])
+		if err := b.txPool.AddRemote(txs[i]); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	w.start()
+
+	select {
+	case block := <-blocks:
+		included := block.Transactions()
+		if len(included) != len(order) {
+			t.Fatalf("transaction count mismatch: have %d, want %d", len(included), len(order))
+		}
+		for i, idx := range order {
+			if included[i].Hash() != txs[idx].Hash() {
+				t.Errorf("transaction %d mismatch: have %x, want %x (account %d)", i, included[i].Hash(), txs[idx].Hash(), idx)
+			}
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("no block sealing task with transactions")
+	}
+}
@@ -125,6 +125,22 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPReadiness configures the checks performed by the /ready endpoint of the
+	// HTTP RPC server. The zero value only requires the node to be running.
+	HTTPReadiness ReadinessConfig
+
+	// GRPCHost is the host interface on which to start the gRPC server. If this
+	// field is empty, no gRPC endpoint will be started.
+	GRPCHost string `toml:",omitempty"`
+
+	// GRPCPort is the TCP port number on which to start the gRPC server. The
+	// default zero value is valid and will pick a port number randomly.
+	GRPCPort int `toml:",omitempty"`
+
+	// GRPCModules is a list of API modules to expose via the gRPC interface, with
+	// the same semantics as HTTPModules: if empty, all public services are exposed.
+	GRPCModules []string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -219,6 +235,21 @@ func DefaultHTTPEndpoint() string {
 	return config.HTTPEndpoint()
 }
 
+// GRPCEndpoint resolves a gRPC endpoint based on the configured host interface
+// and port parameters.
+func (c *Config) GRPCEndpoint() string {
+	if c.GRPCHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.GRPCHost, c.GRPCPort)
+}
+
+// DefaultGRPCEndpoint returns the gRPC endpoint used by default.
+func DefaultGRPCEndpoint() string {
+	config := &Config{GRPCHost: DefaultGRPCHost, GRPCPort: DefaultGRPCPort}
+	return config.GRPCEndpoint()
+}
+
 // WSEndpoint resolves a websocket endpoint based on the configured host interface
 // and port parameters.
 func (c *Config) WSEndpoint() string {
@@ -32,6 +32,8 @@ const (
 	DefaultHTTPPort = 6588        // Default TCP port for the HTTP RPC server
 	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 6589        // Default TCP port for the websocket RPC server
+	DefaultGRPCHost = "localhost" // Default host interface for the gRPC server
+	DefaultGRPCPort = 6590        // Default TCP port for the gRPC server
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -43,6 +45,7 @@ var DefaultConfig = Config{
 	HTTPTimeouts:     rpc.DefaultHTTPTimeouts,
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+	GRPCPort:         DefaultGRPCPort,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   100,
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/metrics"
+)
+
+const (
+	healthPath = "/health" // HTTP path of the liveness probe
+	readyPath  = "/ready"  // HTTP path of the readiness probe
+)
+
+// ReadinessConfig contains the conditions a node must satisfy before the /ready
+// endpoint reports it as able to serve traffic.
+type ReadinessConfig struct {
+	// MinPeers is the minimum number of connected peers required.
+	MinPeers int `toml:",omitempty"`
+
+	// MaxHeadAge is the maximum allowed age of the latest header, measured from
+	// its timestamp. Zero disables the check.
+	MaxHeadAge time.Duration `toml:",omitempty"`
+
+	// RequireSynced fails the check while the node is synchronising with the network.
+	RequireSynced bool `toml:",omitempty"`
+}
+
+// ChainStatusReporter is implemented by services which maintain a chain and are
+// able to report its state to the readiness probe.
+type ChainStatusReporter interface {
+	// HeadTime returns the timestamp of the latest header known to the service.
+	HeadTime() time.Time
+
+	// Syncing reports whether the service is currently synchronising its chain.
+	Syncing() bool
+}
+
+// healthStatus is the JSON body returned by the health and readiness endpoints.
+type healthStatus struct {
+	Healthy bool              `json:"healthy"`
+	Checks  map[string]string `json:"checks,omitempty"`
+}
+
+// healthRoutes returns the HTTP handlers of the liveness and readiness probes.
+func (n *Node) healthRoutes() map[string]http.Handler {
+	return map[string]http.Handler{
+		healthPath: http.HandlerFunc(n.serveHealth),
+		readyPath:  http.HandlerFunc(n.serveReady),
+	}
+}
+
+// serveHealth reports whether the node is up, regardless of its chain state.
+func (n *Node) serveHealth(w http.ResponseWriter, r *http.Request) {
+	n.lock.RLock()
+	running := n.server != nil
+	n.lock.RUnlock()
+
+	status := healthStatus{Healthy: running}
+	if !running {
+		status.Checks = map[string]string{"node": ErrNodeStopped.Error()}
+	}
+	writeHealthStatus(w, status)
+}
+
+// serveReady runs all configured readiness checks and reports their outcome.
+func (n *Node) serveReady(w http.ResponseWriter, r *http.Request) {
+	status := healthStatus{Healthy: true, Checks: make(map[string]string)}
+	for name, check := range n.readinessChecks() {
+		check.Check()
+		if err := check.Error(); err != nil {
+			status.Healthy = false
+			status.Checks[name] = err.Error()
+		} else {
+			status.Checks[name] = "ok"
+		}
+	}
+	writeHealthStatus(w, status)
+}
+
+// readinessChecks assembles the health checks enabled by the readiness config.
+func (n *Node) readinessChecks() map[string]metrics.Healthcheck {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	config := n.config.HTTPReadiness
+	if n.server == nil {
+		return map[string]metrics.Healthcheck{
+			"node": failedHealthcheck(ErrNodeStopped),
+		}
+	}
+	checks := make(map[string]metrics.Healthcheck)
+	if config.MinPeers > 0 {
+		server := n.server
+		checks["peers"] = metrics.NewHealthcheckForced(func(h metrics.Healthcheck) {
+			if peers := server.PeerCount(); peers < config.MinPeers {
+				h.Unhealthy(fmt.Errorf("%d peers connected, need at least %d", peers, config.MinPeers))
+				return
+			}
+			h.Healthy()
+		})
+	}
+	if config.MaxHeadAge == 0 && !config.RequireSynced {
+		return checks
+	}
+	var reporter ChainStatusReporter
+	for _, service := range n.services {
+		if r, ok := service.(ChainStatusReporter); ok {
+			reporter = r
+			break
+		}
+	}
+	if reporter == nil {
+		checks["chain"] = failedHealthcheck(fmt.Errorf("no service reports chain status"))
+		return checks
+	}
+	if config.MaxHeadAge > 0 {
+		checks["head"] = metrics.NewHealthcheckForced(func(h metrics.Healthcheck) {
+			if age := time.Since(reporter.HeadTime()); age > config.MaxHeadAge {
+				h.Unhealthy(fmt.Errorf("head is %v old, allowed at most %v", age.Round(time.Second), config.MaxHeadAge))
+				return
+			}
+			h.Healthy()
+		})
+	}
+	if config.RequireSynced {
+		checks["sync"] = metrics.NewHealthcheckForced(func(h metrics.Healthcheck) {
+			if reporter.Syncing() {
+				h.Unhealthy(fmt.Errorf("chain synchronisation in progress"))
+				return
+			}
+			h.Healthy()
+		})
+	}
+	return checks
+}
+
+// failedHealthcheck creates a health check which always fails with the given error.
+func failedHealthcheck(err error) metrics.Healthcheck {
+	return metrics.NewHealthcheckForced(func(h metrics.Healthcheck) { h.Unhealthy(err) })
+}
+
+// writeHealthStatus serialises a probe result, answering 503 for unhealthy nodes.
+func writeHealthStatus(w http.ResponseWriter, status healthStatus) {
+	w.Header().Set("content-type", "application/json")
+	if status.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
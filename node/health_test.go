@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// chainStatusService is a no-op service reporting a configurable chain status.
+type chainStatusService struct {
+	NoopService
+
+	head    time.Time
+	syncing bool
+}
+
+func (s *chainStatusService) HeadTime() time.Time { return s.head }
+func (s *chainStatusService) Syncing() bool       { return s.syncing }
+
+// queryHealth runs a single request against one of the node's health routes.
+func queryHealth(t *testing.T, stack *Node, path string) (int, healthStatus) {
+	rec := httptest.NewRecorder()
+	stack.healthRoutes()[path].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var status healthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode %s response: %v", path, err)
+	}
+	return rec.Code, status
+}
+
+// Tests that the liveness probe tracks the running state of the node.
+func TestHealthEndpoint(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if code, status := queryHealth(t, stack, healthPath); code != http.StatusServiceUnavailable || status.Healthy {
+		t.Fatalf("stopped node health mismatch: have %d/%v, want %d/false", code, status.Healthy, http.StatusServiceUnavailable)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	defer stack.Stop()
+
+	if code, status := queryHealth(t, stack, healthPath); code != http.StatusOK || !status.Healthy {
+		t.Fatalf("running node health mismatch: have %d/%v, want %d/true", code, status.Healthy, http.StatusOK)
+	}
+}
+
+// Tests that the readiness probe reports each failing check separately.
+func TestReadyEndpoint(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPReadiness = ReadinessConfig{
+		MinPeers:      1,
+		MaxHeadAge:    time.Minute,
+		RequireSynced: true,
+	}
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	service := &chainStatusService{head: time.Now().Add(-time.Hour), syncing: true}
+	if err := stack.Register(func(*ServiceContext) (Service, error) { return service, nil }); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	defer stack.Stop()
+
+	code, status := queryHealth(t, stack, readyPath)
+	if code != http.StatusServiceUnavailable || status.Healthy {
+		t.Fatalf("readiness mismatch: have %d/%v, want %d/false", code, status.Healthy, http.StatusServiceUnavailable)
+	}
+	for _, check := range []string{"peers", "head", "sync"} {
+		if status.Checks[check] == "ok" || status.Checks[check] == "" {
+			t.Errorf("check %q: have %q, want failure", check, status.Checks[check])
+		}
+	}
+	// Fix up the chain status and drop the peer requirement, node should be ready
+	service.head, service.syncing = time.Now(), false
+	stack.config.HTTPReadiness.MinPeers = 0
+
+	code, status = queryHealth(t, stack, readyPath)
+	if code != http.StatusOK || !status.Healthy {
+		t.Fatalf("readiness mismatch: have %d/%v (%v), want %d/true", code, status.Healthy, status.Checks, http.StatusOK)
+	}
+}
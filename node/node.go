@@ -33,6 +33,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc/grpc"
 	"github.com/prometheus/prometheus/util/flock"
 )
 
@@ -63,6 +64,10 @@ type Node struct {
 	httpListener  net.Listener // HTTP RPC listener socket to server API requests
 	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
 
+	grpcEndpoint string       // gRPC endpoint (interface + port) to listen at (empty = gRPC disabled)
+	grpcListener net.Listener // gRPC listener socket to serve requests
+	grpcHandler  *grpc.Server // gRPC request handler to process the requests
+
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
@@ -115,6 +120,7 @@ func New(conf *Config) (*Node, error) {
 		serviceFuncs:      []ServiceConstructor{},
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
+		grpcEndpoint:      conf.GRPCEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
@@ -274,6 +280,19 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
+	var grpcServices []grpc.Service
+	for _, service := range services {
+		if service, ok := service.(GRPCService); ok {
+			grpcServices = append(grpcServices, service.GRPCServices()...)
+		}
+	}
+	if err := n.startGRPC(n.grpcEndpoint, grpcServices, n.config.GRPCModules); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
 	// All API endpoints started successfully
 	n.rpcAPIs = apis
 	return nil
@@ -336,7 +355,7 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, handler, err := rpc.StartHTTPEndpointWithRoutes(endpoint, apis, modules, cors, vhosts, timeouts, n.healthRoutes())
 	if err != nil {
 		return err
 	}
@@ -363,6 +382,39 @@ func (n *Node) stopHTTP() {
 	}
 }
 
+// startGRPC initializes and starts the gRPC endpoint.
+func (n *Node) startGRPC(endpoint string, services []grpc.Service, modules []string) error {
+	// Short circuit if the gRPC endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	listener, handler, err := grpc.StartEndpoint(endpoint, services, modules)
+	if err != nil {
+		return err
+	}
+	n.log.Info("gRPC endpoint opened", "url", listener.Addr())
+	// All listeners booted successfully
+	n.grpcEndpoint = endpoint
+	n.grpcListener = listener
+	n.grpcHandler = handler
+
+	return nil
+}
+
+// stopGRPC terminates the gRPC endpoint.
+func (n *Node) stopGRPC() {
+	if n.grpcListener != nil {
+		n.grpcListener.Close()
+		n.grpcListener = nil
+
+		n.log.Info("gRPC endpoint closed", "url", n.grpcEndpoint)
+	}
+	if n.grpcHandler != nil {
+		n.grpcHandler.Stop()
+		n.grpcHandler = nil
+	}
+}
+
 // startWS initializes and starts the websocket RPC endpoint.
 func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
 	// Short circuit if the WS endpoint isn't being exposed
@@ -408,6 +460,7 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopGRPC()
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
@@ -558,6 +611,17 @@ func (n *Node) HTTPEndpoint() string {
 	return n.httpEndpoint
 }
 
+// GRPCEndpoint retrieves the current gRPC endpoint used by the protocol stack.
+func (n *Node) GRPCEndpoint() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.grpcListener != nil {
+		return n.grpcListener.Addr().String()
+	}
+	return n.grpcEndpoint
+}
+
 // WSEndpoint retrieves the current WS endpoint used by the protocol stack.
 func (n *Node) WSEndpoint() string {
 	n.lock.Lock()
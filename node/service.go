@@ -24,6 +24,7 @@ import (
 	"git.pirl.io/bitcoiin/go-bitcoiin/event"
 	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
 	"git.pirl.io/bitcoiin/go-bitcoiin/rpc"
+	"git.pirl.io/bitcoiin/go-bitcoiin/rpc/grpc"
 )
 
 // ServiceContext is a collection of service independent options inherited from
@@ -96,3 +97,10 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// GRPCService is implemented by services that offer gRPC services on the node's
+// gRPC endpoint in addition to their RPC APIs.
+type GRPCService interface {
+	// GRPCServices retrieves the list of gRPC services the service provides.
+	GRPCServices() []grpc.Service
+}
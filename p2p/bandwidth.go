@@ -0,0 +1,207 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/mclock"
+)
+
+// bandwidthChunk is the largest amount of data a single connection may reserve
+// from a bandwidth limiter in one go. Keeping it small interleaves the transfers
+// of concurrently active peers, sharing the available bandwidth evenly.
+const bandwidthChunk = 4 * 1024
+
+// bandwidthLimiter is a token bucket capping the aggregate throughput of all
+// connections sharing it. Tokens are reserved in arrival order, with the bucket
+// going into debt if needed, so every waiting connection is served in turn.
+type bandwidthLimiter struct {
+	rate  float64 // Bytes per second allowed through the limiter
+	burst float64 // Maximum number of tokens accumulated while idle
+	clock mclock.Clock
+
+	tokens float64        // Available tokens, negative if reservations are pending
+	last   mclock.AbsTime // Last time the bucket was refilled
+	lock   sync.Mutex
+}
+
+// newBandwidthLimiter creates a limiter allowing rate bytes per second through.
+// A nil limiter (non-positive rate) places no restriction on the traffic.
+func newBandwidthLimiter(rate int, clock mclock.Clock) *bandwidthLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	burst := float64(rate)
+	if burst < bandwidthChunk {
+		burst = bandwidthChunk
+	}
+	return &bandwidthLimiter{
+		rate:   float64(rate),
+		burst:  burst,
+		clock:  clock,
+		tokens: burst,
+		last:   clock.Now(),
+	}
+}
+
+// reserve takes n tokens from the bucket and returns how long the caller needs
+// to wait before it may transfer the data.
+func (l *bandwidthLimiter) reserve(n int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	l.tokens += time.Duration(now-l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// wait blocks until n bytes may be transferred. The data is accounted in chunks
+// so that large messages don't starve other connections. The return value is
+// whether the caller was held back at all.
+func (l *bandwidthLimiter) wait(n int) bool {
+	if l == nil {
+		return false
+	}
+	throttled := false
+	for n > 0 {
+		chunk := n
+		if chunk > bandwidthChunk {
+			chunk = bandwidthChunk
+		}
+		if delay := l.reserve(chunk); delay > 0 {
+			l.clock.Sleep(delay)
+			throttled = true
+		}
+		n -= chunk
+	}
+	return throttled
+}
+
+// trafficConn wraps a network connection, counting the bytes passing through it
+// and throttling them according to the optional bandwidth limiters.
+//
+// Deadlines set on the connection limit the time spent on the network, not the
+// time spent waiting for the limiters: whenever a transfer is held back, the
+// last deadline is pushed out by the timeout it was originally set with.
+type trafficConn struct {
+	ingress      uint64 // Bytes read from the connection (atomic, keep 64 bit aligned)
+	egress       uint64 // Bytes written to the connection (atomic, keep 64 bit aligned)
+	readTimeout  int64  // Timeout of the last read deadline, zero if none (atomic)
+	writeTimeout int64  // Timeout of the last write deadline, zero if none (atomic)
+
+	net.Conn
+	readLimit  *bandwidthLimiter
+	writeLimit *bandwidthLimiter
+}
+
+// newTrafficConn wraps fd with traffic accounting and throttling.
+func newTrafficConn(fd net.Conn, readLimit, writeLimit *bandwidthLimiter) *trafficConn {
+	return &trafficConn{Conn: fd, readLimit: readLimit, writeLimit: writeLimit}
+}
+
+// Read delegates a network read to the underlying connection, accounting for
+// the bytes retrieved. Throttling happens after the fact, holding back further
+// reads so the remote side is slowed down by the transport's flow control.
+func (c *trafficConn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	atomic.AddUint64(&c.ingress, uint64(n))
+	if c.readLimit.wait(n) {
+		if timeout := atomic.LoadInt64(&c.readTimeout); timeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(time.Duration(timeout)))
+		}
+	}
+	return n, err
+}
+
+// Write delegates a network write to the underlying connection, sending the
+// data in chunks each waiting for the bandwidth limiter's permission.
+func (c *trafficConn) Write(b []byte) (n int, err error) {
+	if c.writeLimit == nil {
+		n, err = c.Conn.Write(b)
+		atomic.AddUint64(&c.egress, uint64(n))
+		return n, err
+	}
+	for len(b) > 0 {
+		chunk := len(b)
+		if chunk > bandwidthChunk {
+			chunk = bandwidthChunk
+		}
+		if c.writeLimit.wait(chunk) {
+			if timeout := atomic.LoadInt64(&c.writeTimeout); timeout > 0 {
+				c.Conn.SetWriteDeadline(time.Now().Add(time.Duration(timeout)))
+			}
+		}
+		written, err := c.Conn.Write(b[:chunk])
+		atomic.AddUint64(&c.egress, uint64(written))
+		if n += written; err != nil {
+			return n, err
+		}
+		b = b[chunk:]
+	}
+	return n, nil
+}
+
+// SetDeadline sets the read and write deadlines of the underlying connection,
+// remembering the timeouts for extending them while throttled.
+func (c *trafficConn) SetDeadline(t time.Time) error {
+	storeTimeout(&c.readTimeout, t)
+	storeTimeout(&c.writeTimeout, t)
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection,
+// remembering the timeout for extending it while throttled.
+func (c *trafficConn) SetReadDeadline(t time.Time) error {
+	storeTimeout(&c.readTimeout, t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection,
+// remembering the timeout for extending it while throttled.
+func (c *trafficConn) SetWriteDeadline(t time.Time) error {
+	storeTimeout(&c.writeTimeout, t)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// storeTimeout records the time left until a deadline. Cleared and expired
+// deadlines are recorded as zero, as those must never be extended.
+func storeTimeout(timeout *int64, deadline time.Time) {
+	left := time.Until(deadline)
+	if deadline.IsZero() || left < 0 {
+		left = 0
+	}
+	atomic.StoreInt64(timeout, int64(left))
+}
+
+// traffic returns the total number of bytes read from and written to the
+// connection.
+func (c *trafficConn) traffic() (ingress, egress uint64) {
+	return atomic.LoadUint64(&c.ingress), atomic.LoadUint64(&c.egress)
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/common/mclock"
+)
+
+// Tests that the bandwidth limiter allows an initial burst, after which data is
+// only let through at the configured rate.
+func TestBandwidthLimiterRate(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter := newBandwidthLimiter(8192, clock)
+
+	if delay := limiter.reserve(8192); delay != 0 {
+		t.Fatalf("burst delayed: %v", delay)
+	}
+	if delay := limiter.reserve(4096); delay != 500*time.Millisecond {
+		t.Fatalf("delay mismatch: have %v, want %v", delay, 500*time.Millisecond)
+	}
+	// Pending reservations must be served first, even if time passes
+	clock.Run(250 * time.Millisecond)
+	if delay := limiter.reserve(2048); delay != 500*time.Millisecond {
+		t.Fatalf("delay mismatch: have %v, want %v", delay, 500*time.Millisecond)
+	}
+	// Idle time should not accumulate tokens beyond the burst size
+	clock.Run(time.Hour)
+	if delay := limiter.reserve(8192); delay != 0 {
+		t.Fatalf("burst delayed: %v", delay)
+	}
+	if delay := limiter.reserve(1); delay == 0 {
+		t.Fatalf("limit exceeded without delay")
+	}
+}
+
+// Tests that a disabled limiter doesn't restrict traffic.
+func TestBandwidthLimiterDisabled(t *testing.T) {
+	limiter := newBandwidthLimiter(0, mclock.System{})
+	if limiter != nil {
+		t.Fatalf("limiter created for zero rate")
+	}
+	start := time.Now()
+	limiter.wait(100 * 1024 * 1024)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("unlimited transfer throttled for %v", elapsed)
+	}
+}
+
+// sinkConn is a network connection accepting and discarding all writes.
+type sinkConn struct {
+	net.Conn
+}
+
+func (sinkConn) Write(b []byte) (int, error) { return len(b), nil }
+
+// Tests that connections sharing a limiter are served fairly, with a small
+// transfer not having to wait for a large one started earlier to finish.
+func TestBandwidthLimiterFairness(t *testing.T) {
+	limiter := newBandwidthLimiter(64*1024, mclock.System{})
+	limiter.reserve(64 * 1024) // drain the initial burst
+
+	large := newTrafficConn(sinkConn{}, nil, limiter)
+	small := newTrafficConn(sinkConn{}, nil, limiter)
+
+	start := time.Now()
+	largeDone := make(chan time.Duration)
+	go func() {
+		large.Write(make([]byte, 128*1024))
+		largeDone <- time.Since(start)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	small.Write(make([]byte, 8*1024))
+	smallTime := time.Since(start)
+
+	largeTime := <-largeDone
+	if smallTime >= largeTime {
+		t.Fatalf("small transfer starved: small %v, large %v", smallTime, largeTime)
+	}
+	if largeTime < 2*time.Second {
+		t.Fatalf("rate limit exceeded: 128KB transferred in %v", largeTime)
+	}
+	if _, egress := small.traffic(); egress != 8*1024 {
+		t.Fatalf("egress mismatch: have %d, want %d", egress, 8*1024)
+	}
+}
+
+// Tests that a large message written at a low rate is sent in chunks, with the
+// write deadline covering the network transfer only and not the throttling.
+func TestBandwidthLimiterWriteDeadline(t *testing.T) {
+	fd1, fd2 := net.Pipe()
+	defer fd1.Close()
+	defer fd2.Close()
+
+	conn := newTrafficConn(fd1, nil, newBandwidthLimiter(16*1024, mclock.System{}))
+
+	// Drain the other end, recording the size of every write
+	reads := make(chan int, 64)
+	go func() {
+		defer close(reads)
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := fd2.Read(buf)
+			if err != nil {
+				return
+			}
+			reads <- n
+		}
+	}()
+	// Send a message taking about a second at the configured rate, way over
+	// the write timeout
+	conn.SetWriteDeadline(time.Now().Add(250 * time.Millisecond))
+
+	start := time.Now()
+	if n, err := conn.Write(make([]byte, 32*1024)); err != nil {
+		t.Fatalf("throttled write failed after %d bytes: %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 750*time.Millisecond {
+		t.Fatalf("rate limit exceeded: 32KB transferred in %v", elapsed)
+	}
+	fd1.Close()
+
+	total := 0
+	for n := range reads {
+		if n > bandwidthChunk {
+			t.Errorf("write not chunked: %d bytes at once", n)
+		}
+		total += n
+	}
+	if total != 32*1024 {
+		t.Fatalf("transferred data mismatch: have %d, want %d", total, 32*1024)
+	}
+	if _, egress := conn.traffic(); egress != 32*1024 {
+		t.Fatalf("egress mismatch: have %d, want %d", egress, 32*1024)
+	}
+}
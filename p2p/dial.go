@@ -71,6 +71,10 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	filters     []func(*enode.Node) bool // Protocol filters of dynamic dial candidates
+	reputation  *reputation              // Node scores preferring good and skipping banned candidates
+	policy      ConnPolicy               // Connection policies restricting dynamic dial candidates
+	sources     []NodeSource             // Protocol sources of dynamic dial candidates
 	self        enode.ID
 
 	lookupRunning bool
@@ -135,7 +139,7 @@ func newDialState(self enode.ID, static []*enode.Node, bootnodes []*enode.Node,
 		static:      make(map[enode.ID]*dialTask),
 		dialing:     make(map[enode.ID]connFlag),
 		bootnodes:   make([]*enode.Node, len(bootnodes)),
-		randomNodes: make([]*enode.Node, maxdyn/2),
+		randomNodes: make([]*enode.Node, maxdyn),
 		hist:        new(dialHistory),
 	}
 	copy(s.bootnodes, bootnodes)
@@ -166,10 +170,22 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 
 	var newtasks []task
 	addDial := func(flag connFlag, n *enode.Node) bool {
+		if s.reputation.banned(n.ID()) {
+			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", errBanned)
+			return false
+		}
+		if err := s.checkFilters(n); err != nil {
+			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
+			return false
+		}
 		if err := s.checkDial(n, peers); err != nil {
 			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
 			return false
 		}
+		if err := s.policy.checkSubnet(n.IP(), peers); err != nil {
+			log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
+			return false
+		}
 		s.dialing[n.ID()] = flag
 		newtasks = append(newtasks, &dialTask{flags: flag, dest: n})
 		return true
@@ -216,10 +232,29 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 		}
 	}
 	// Use random nodes from the table for half of the necessary
-	// dynamic dials.
+	// dynamic dials, trying the ones with the best reputation first.
 	randomCandidates := needDynDials / 2
-	if randomCandidates > 0 {
+	if randomCandidates > 0 && s.ntab != nil {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
+		s.reputation.sortByScore(s.randomNodes[:n])
+		for i := 0; i < randomCandidates && i < n; i++ {
+			if addDial(dynDialedConn, s.randomNodes[i]) {
+				needDynDials--
+			}
+		}
+	}
+	// Use random nodes from the protocol supplied sources for half of the
+	// remaining dynamic dials, each.
+	for _, src := range s.sources {
+		randomCandidates := needDynDials / 2
+		if s.ntab == nil {
+			randomCandidates = needDynDials // No lookups to fill the rest
+		}
+		if randomCandidates == 0 {
+			break
+		}
+		n := src.ReadRandomNodes(s.randomNodes)
+		s.reputation.sortByScore(s.randomNodes[:n])
 		for i := 0; i < randomCandidates && i < n; i++ {
 			if addDial(dynDialedConn, s.randomNodes[i]) {
 				needDynDials--
@@ -236,7 +271,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	}
 	s.lookupBuf = s.lookupBuf[:copy(s.lookupBuf, s.lookupBuf[i:])]
 	// Launch a discovery lookup if more candidates are needed.
-	if len(s.lookupBuf) < needDynDials && !s.lookupRunning {
+	if len(s.lookupBuf) < needDynDials && !s.lookupRunning && s.ntab != nil {
 		s.lookupRunning = true
 		newtasks = append(newtasks, &discoverTask{})
 	}
@@ -258,8 +293,20 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errFiltered         = errors.New("rejected by protocol filter")
+	errBanned           = errors.New("banned for bad reputation")
 )
 
+// checkFilters runs a dynamic dial candidate through the protocol filters.
+func (s *dialstate) checkFilters(n *enode.Node) error {
+	for _, filter := range s.filters {
+		if !filter(n) {
+			return errFiltered
+		}
+	}
+	return nil
+}
+
 func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 	_, dialing := s.dialing[n.ID()]
 	switch {
@@ -442,6 +442,61 @@ func TestDialStateNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that candidates rejected by a protocol filter are not dialed.
+func TestDialStateNodeFilter(t *testing.T) {
+	// This table always returns the same random nodes
+	// in the order given below.
+	table := fakeTable{
+		newNode(uintID(1), nil),
+		newNode(uintID(2), nil),
+		newNode(uintID(3), nil),
+		newNode(uintID(4), nil),
+	}
+	state := newDialState(enode.ID{}, nil, nil, table, 10, nil)
+	state.filters = append(state.filters, func(n *enode.Node) bool {
+		return n.ID() == uintID(2) || n.ID() == uintID(4)
+	})
+	runDialTest(t, dialtest{
+		init: state,
+		rounds: []round{
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: table[1]},
+					&dialTask{flags: dynDialedConn, dest: table[3]},
+					&discoverTask{},
+				},
+			},
+		},
+	})
+}
+
+// This test checks that protocol supplied dial candidates are dialed, even if
+// there is no discovery table.
+func TestDialStateDialCandidates(t *testing.T) {
+	source := fakeTable{
+		newNode(uintID(1), nil),
+		newNode(uintID(2), nil),
+		newNode(uintID(3), nil),
+		newNode(uintID(4), nil),
+		newNode(uintID(5), nil),
+	}
+	state := newDialState(enode.ID{}, nil, nil, nil, 3, nil)
+	state.sources = append(state.sources, source)
+
+	runDialTest(t, dialtest{
+		init: state,
+		rounds: []round{
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: source[0]},
+					&dialTask{flags: dynDialedConn, dest: source[1]},
+					&dialTask{flags: dynDialedConn, dest: source[2]},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that static dials are launched.
 func TestDialStateStaticDial(t *testing.T) {
 	wantStatic := []*enode.Node{
@@ -0,0 +1,371 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Client discovers nodes by querying DNS servers.
+type Client struct {
+	cfg     Config
+	entries *lru.Cache
+
+	lock  sync.Mutex
+	trees map[string]*clientTree // Synced trees by domain, including linked ones
+	nodes []*enode.Node          // Nodes of all synced trees, served to the dialer
+
+	quit   chan struct{}
+	loopWG sync.WaitGroup
+}
+
+// Config holds configuration options for the client.
+type Config struct {
+	Timeout         time.Duration      // timeout used for DNS lookups (default 5s)
+	RecheckInterval time.Duration      // time between tree root update checks (default 30min)
+	CacheLimit      int                // maximum number of cached records (default 1000)
+	ValidSchemes    enr.IdentityScheme // acceptable ENR identity schemes (default enode.ValidSchemes)
+	Resolver        Resolver           // the DNS resolver to use (defaults to system DNS)
+	Logger          log.Logger         // destination of client log messages (defaults to root logger)
+}
+
+// Resolver is a DNS resolver that can query TXT records.
+type Resolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+func (cfg Config) withDefaults() Config {
+	const (
+		defaultTimeout = 5 * time.Second
+		defaultRecheck = 30 * time.Minute
+		defaultCache   = 1000
+	)
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RecheckInterval == 0 {
+		cfg.RecheckInterval = defaultRecheck
+	}
+	if cfg.CacheLimit == 0 {
+		cfg.CacheLimit = defaultCache
+	}
+	if cfg.ValidSchemes == nil {
+		cfg.ValidSchemes = enode.ValidSchemes
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = new(net.Resolver)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Root()
+	}
+	return cfg
+}
+
+// clientTree is the sync state of a single tree known to the client.
+type clientTree struct {
+	link  *linkEntry
+	root  *rootEntry    // Last synced root, nil if never synced
+	nodes []*enode.Node // Nodes contained in the last synced tree
+}
+
+// NewClient creates a client which periodically syncs the trees at the given
+// enrtree:// URLs, and any trees linked from them, once started.
+func NewClient(cfg Config, urls ...string) (*Client, error) {
+	cfg = cfg.withDefaults()
+	cache, err := lru.New(cfg.CacheLimit)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		cfg:     cfg,
+		entries: cache,
+		trees:   make(map[string]*clientTree),
+		quit:    make(chan struct{}),
+	}
+	for _, url := range urls {
+		le, err := parseLink(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enrtree URL %q: %v", url, err)
+		}
+		c.trees[le.domain] = &clientTree{link: le}
+	}
+	return c, nil
+}
+
+// Start launches the background sync of the configured trees.
+func (c *Client) Start() {
+	c.loopWG.Add(1)
+	go c.loop()
+}
+
+// Stop terminates the background sync and waits for it to return.
+func (c *Client) Stop() {
+	close(c.quit)
+	c.loopWG.Wait()
+}
+
+// loop syncs the known trees every recheck interval until the client is stopped.
+func (c *Client) loop() {
+	defer c.loopWG.Done()
+
+	recheck := time.NewTicker(c.cfg.RecheckInterval)
+	defer recheck.Stop()
+
+	for {
+		c.refresh()
+
+		select {
+		case <-recheck.C:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// refresh checks the roots of all known trees and resyncs the ones that changed,
+// including the trees newly discovered through links during the refresh.
+func (c *Client) refresh() {
+	synced := make(map[string]bool)
+	for {
+		// Gather the trees not yet checked during this refresh
+		var pending []*clientTree
+		c.lock.Lock()
+		for domain, ct := range c.trees {
+			if !synced[domain] {
+				pending = append(pending, ct)
+			}
+		}
+		c.lock.Unlock()
+
+		if len(pending) == 0 {
+			break
+		}
+		for _, ct := range pending {
+			synced[ct.link.domain] = true
+			if err := c.syncClientTree(ct); err != nil {
+				c.cfg.Logger.Debug("DNS discovery sync failed", "tree", ct.link.url(), "err", err)
+			}
+			select {
+			case <-c.quit:
+				return
+			default:
+			}
+		}
+	}
+	// Collect all nodes into the dial candidate set
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var nodes []*enode.Node
+	for _, ct := range c.trees {
+		nodes = append(nodes, ct.nodes...)
+	}
+	c.nodes = nodes
+}
+
+// syncClientTree resyncs a single tree if its root changed since the last sync.
+func (c *Client) syncClientTree(ct *clientTree) error {
+	ctx := context.Background()
+
+	root, err := c.resolveRoot(ctx, ct.link)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	unchanged := ct.root != nil && ct.root.seq == root.seq && ct.root.eroot == root.eroot && ct.root.lroot == root.lroot
+	c.lock.Unlock()
+	if unchanged {
+		return nil
+	}
+	t, err := c.syncTree(ctx, ct.link, root)
+	if err != nil {
+		return err
+	}
+	nodes := t.Nodes()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ct.root, ct.nodes = &root, nodes
+	for _, link := range t.Links() {
+		le, _ := parseLink(link)
+		if _, ok := c.trees[le.domain]; !ok {
+			c.trees[le.domain] = &clientTree{link: le}
+		}
+	}
+	c.cfg.Logger.Debug("Synced DNS discovery tree", "tree", ct.link.url(), "seq", root.seq, "nodes", len(nodes))
+	return nil
+}
+
+// SyncTree downloads the entire node tree at the given URL. This doesn't add the
+// tree to the set synced in the background.
+func (c *Client) SyncTree(url string) (*Tree, error) {
+	le, err := parseLink(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enrtree URL: %v", err)
+	}
+	ctx := context.Background()
+
+	root, err := c.resolveRoot(ctx, le)
+	if err != nil {
+		return nil, err
+	}
+	return c.syncTree(ctx, le, root)
+}
+
+// ReadRandomNodes fills the given slice with random nodes of the synced trees,
+// returning the number of nodes written. It never blocks on the network.
+func (c *Client) ReadRandomNodes(buf []*enode.Node) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := 0
+	for _, i := range rand.Perm(len(c.nodes)) {
+		if n == len(buf) {
+			break
+		}
+		buf[n] = c.nodes[i]
+		n++
+	}
+	return n
+}
+
+// Nodes returns all nodes of the synced trees.
+func (c *Client) Nodes() []*enode.Node {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]*enode.Node(nil), c.nodes...)
+}
+
+// syncTree downloads all entries of the tree with the given root.
+func (c *Client) syncTree(ctx context.Context, loc *linkEntry, root rootEntry) (*Tree, error) {
+	t := &Tree{root: &root, entries: make(map[string]entry)}
+	if err := c.syncSubtree(ctx, loc.domain, root.lroot, true, t); err != nil {
+		return nil, err
+	}
+	if err := c.syncSubtree(ctx, loc.domain, root.eroot, false, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// syncSubtree downloads all entries below the given hash, checking that the
+// leaves are of the kind the subtree is supposed to contain.
+func (c *Client) syncSubtree(ctx context.Context, domain, hash string, link bool, t *Tree) error {
+	missing := []string{hash}
+	for len(missing) > 0 {
+		hash := missing[0]
+		missing = missing[1:]
+		if _, ok := t.entries[hash]; ok {
+			continue
+		}
+		e, err := c.resolveEntry(ctx, domain, hash)
+		if err != nil {
+			return err
+		}
+		t.entries[hash] = e
+
+		switch e := e.(type) {
+		case *branchEntry:
+			missing = append(missing, e.children...)
+		case *enrEntry:
+			if link {
+				return errENRInLinkTree
+			}
+		case *linkEntry:
+			if !link {
+				return errLinkInENRTree
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRoot retrieves a root entry via DNS and verifies its signature.
+func (c *Client) resolveRoot(ctx context.Context, loc *linkEntry) (rootEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, loc.domain)
+	if err != nil {
+		return rootEntry{}, err
+	}
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, rootPrefix) {
+			e, err := parseRoot(txt)
+			if err != nil {
+				return e, nameError{loc.domain, err}
+			}
+			if !e.verifySignature(loc.pubkey) {
+				return e, nameError{loc.domain, entryError{typ: "root", err: errInvalidSig}}
+			}
+			return e, nil
+		}
+	}
+	return rootEntry{}, nameError{loc.domain, errNoRoot}
+}
+
+// resolveEntry retrieves an entry from the cache or fetches it from the network
+// if it isn't cached.
+func (c *Client) resolveEntry(ctx context.Context, domain, hash string) (entry, error) {
+	cacheKey := hash + "." + domain
+	if e, ok := c.entries.Get(cacheKey); ok {
+		return e.(entry), nil
+	}
+	wantHash, err := b32format.DecodeString(hash)
+	if err != nil {
+		return nil, nameError{cacheKey, errInvalidChild}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	txts, err := c.cfg.Resolver.LookupTXT(ctx, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, txt := range txts {
+		e, err := parseEntry(txt, c.cfg.ValidSchemes)
+		if err == errUnknownEntry {
+			continue
+		}
+		if !bytes.HasPrefix(crypto.Keccak256([]byte(txt)), wantHash) {
+			err = nameError{cacheKey, errHashMismatch}
+		} else if err != nil {
+			err = nameError{cacheKey, err}
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.entries.Add(cacheKey, e)
+		return e, nil
+	}
+	return nil, nameError{cacheKey, errNoEntry}
+}
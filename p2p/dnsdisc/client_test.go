@@ -0,0 +1,206 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enr"
+)
+
+const (
+	signingKeySeed = 0x111111
+	nodesSeed1     = 0x2945237
+	nodesSeed2     = 0x4567299
+)
+
+// Tests that a whole tree can be downloaded from DNS.
+func TestClientSyncTree(t *testing.T) {
+	var (
+		key   = testKey(signingKeySeed)
+		nodes = testNodes(nodesSeed1, 30)
+		link  = (&linkEntry{"other.example.org", &testKey(signingKeySeed + 1).PublicKey}).url()
+	)
+	tree, url := makeTestTree(t, key, "n", 1, nodes, []string{link})
+
+	c, _ := NewClient(Config{Resolver: newMapResolver(tree.ToTXT("n"))})
+	synced, err := c.SyncTree(url)
+	if err != nil {
+		t.Fatal("sync error:", err)
+	}
+	if !reflect.DeepEqual(synced.Nodes(), sortByID(nodes)) {
+		t.Errorf("wrong nodes in synced tree")
+	}
+	if !reflect.DeepEqual(synced.Links(), []string{link}) {
+		t.Errorf("wrong links in synced tree: %v", synced.Links())
+	}
+	if synced.Seq() != 1 {
+		t.Errorf("synced tree has wrong seq: %d", synced.Seq())
+	}
+}
+
+// Tests that syncing a tree fails on tampered entries and signatures.
+func TestClientSyncTreeTampered(t *testing.T) {
+	var (
+		key   = testKey(signingKeySeed)
+		nodes = testNodes(nodesSeed1, 3)
+	)
+	tree, url := makeTestTree(t, key, "n", 1, nodes, nil)
+
+	// Replace a node record by a different one, breaking its hash
+	records := tree.ToTXT("n")
+	for name, txt := range records {
+		if txt == (&enrEntry{nodes[0]}).String() {
+			records[name] = (&enrEntry{testNode(nodesSeed2)}).String()
+		}
+	}
+	c, _ := NewClient(Config{Resolver: newMapResolver(records)})
+	if _, err := c.SyncTree(url); err == nil || err.(nameError).err != errHashMismatch {
+		t.Fatalf("expected hash mismatch error, got %v", err)
+	}
+	// Sign the tree with a different key than the one in the URL
+	_, other := makeTestTree(t, testKey(signingKeySeed+1), "n", 1, nodes, nil)
+	c, _ = NewClient(Config{Resolver: newMapResolver(tree.ToTXT("n"))})
+	if _, err := c.SyncTree(other); err == nil {
+		t.Fatal("tree with invalid signature accepted")
+	}
+}
+
+// Tests that the client keeps the dial candidates of all configured and linked
+// trees, and picks up tree updates on refresh.
+func TestClientRefresh(t *testing.T) {
+	var (
+		key1   = testKey(signingKeySeed)
+		key2   = testKey(signingKeySeed + 1)
+		nodes1 = testNodes(nodesSeed1, 10)
+		nodes2 = testNodes(nodesSeed2, 10)
+	)
+	tree2, url2 := makeTestTree(t, key2, "n2", 1, nodes2, nil)
+	tree1, url1 := makeTestTree(t, key1, "n1", 1, nodes1, []string{url2})
+
+	resolver := newMapResolver(tree1.ToTXT("n1"), tree2.ToTXT("n2"))
+	c, err := NewClient(Config{Resolver: resolver}, url1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.refresh()
+	checkNodes(t, c.Nodes(), append(append([]*enode.Node{}, nodes1...), nodes2...))
+
+	buf := make([]*enode.Node, 5)
+	if n := c.ReadRandomNodes(buf); n != len(buf) {
+		t.Fatalf("wrong number of random nodes: have %d, want %d", n, len(buf))
+	}
+	// Update the linked tree and ensure the change is picked up
+	updated := testNodes(nodesSeed2+1, 4)
+	tree2, _ = makeTestTree(t, key2, "n2", 2, updated, nil)
+	resolver.add(tree2.ToTXT("n2"))
+
+	c.refresh()
+	checkNodes(t, c.Nodes(), append(append([]*enode.Node{}, nodes1...), updated...))
+
+	buf = make([]*enode.Node, 50)
+	if n := c.ReadRandomNodes(buf); n != len(nodes1)+len(updated) {
+		t.Fatalf("wrong number of random nodes: have %d, want %d", n, len(nodes1)+len(updated))
+	}
+}
+
+func checkNodes(t *testing.T, have, want []*enode.Node) {
+	t.Helper()
+	if !reflect.DeepEqual(sortByID(have), sortByID(want)) {
+		t.Fatalf("wrong nodes: have %d nodes, want %d", len(have), len(want))
+	}
+}
+
+func makeTestTree(t *testing.T, key *ecdsa.PrivateKey, domain string, seq uint, nodes []*enode.Node, links []string) (*Tree, string) {
+	tree, err := MakeTree(seq, nodes, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, url
+}
+
+// testKeys creates deterministic private keys for testing.
+func testKeys(seed int64, n int) []*ecdsa.PrivateKey {
+	rand := rand.New(rand.NewSource(seed))
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := 0; i < n; i++ {
+		key, err := ecdsa.GenerateKey(crypto.S256(), rand)
+		if err != nil {
+			panic("can't generate key: " + err.Error())
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func testKey(seed int64) *ecdsa.PrivateKey {
+	return testKeys(seed, 1)[0]
+}
+
+func testNodes(seed int64, n int) []*enode.Node {
+	keys := testKeys(seed, n)
+	nodes := make([]*enode.Node, n)
+	for i, key := range keys {
+		var r enr.Record
+		r.Set(enr.IP(net.IP{127, 0, 0, byte(i + 1)}))
+		r.Set(enr.TCP(30303))
+		r.Set(enr.UDP(30303))
+		r.SetSeq(uint64(i))
+		enode.SignV4(&r, key)
+		nodes[i], _ = enode.New(enode.ValidSchemes, &r)
+	}
+	return nodes
+}
+
+func testNode(seed int64) *enode.Node {
+	return testNodes(seed, 1)[0]
+}
+
+// mapResolver is an in-memory DNS resolver serving a fixed set of TXT records.
+type mapResolver map[string]string
+
+func newMapResolver(maps ...map[string]string) mapResolver {
+	mr := make(mapResolver)
+	for _, m := range maps {
+		mr.add(m)
+	}
+	return mr
+}
+
+func (mr mapResolver) add(m map[string]string) {
+	for k, v := range m {
+		mr[k] = v
+	}
+}
+
+func (mr mapResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if record, ok := mr[name]; ok {
+		return []string{record}, nil
+	}
+	return nil, nil
+}
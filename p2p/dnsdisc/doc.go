@@ -0,0 +1,37 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+/*
+Package dnsdisc implements node discovery via DNS (EIP-1459).
+
+Node lists are published as merkle trees of signed node records stored in DNS
+TXT records. The root of a tree lives at the domain itself:
+
+	enrtree-root:v1 e=<enr-root> l=<link-root> seq=<sequence-number> sig=<signature>
+
+All other entries live at subdomains named after the abbreviated base32 keccak256
+hash of their content, and are either branches listing child hashes, node records
+or links to other trees:
+
+	enrtree-branch:<h1>,<h2>,...,<hN>
+	enr:<node-record>
+	enrtree://<key>@<fqdn>
+
+Trees are referenced by enrtree:// URLs containing the compressed public key
+that signs the root, so clients only need to trust the URL, not the DNS
+servers publishing the tree.
+*/
+package dnsdisc
@@ -0,0 +1,197 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dnsdisc
+
+import (
+	"reflect"
+	"testing"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/crypto"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+func TestParseRoot(t *testing.T) {
+	root := rootEntry{
+		eroot: "QFT4PBCRX4XQCV3VUYJ6BTCEPU",
+		lroot: "JGUFMSAGI7KZYB3P7IZW4S5Y3A",
+		seq:   3,
+		sig:   make([]byte, sigLength),
+	}
+	tests := []struct {
+		input string
+		e     rootEntry
+		err   error
+	}{
+		{
+			input: "wrong",
+			err:   entryError{"root", errSyntax},
+		},
+		{
+			input: "enrtree-root:v1 e=QFT4PBCRX4XQCV3VUYJ6BTCEPU l=1 seq=3 sig=AAAA",
+			err:   entryError{"root", errInvalidChild},
+		},
+		{
+			input: "enrtree-root:v1 e=QFT4PBCRX4XQCV3VUYJ6BTCEPU l=JGUFMSAGI7KZYB3P7IZW4S5Y3A seq=3 sig=AAAA",
+			err:   entryError{"root", errInvalidSig},
+		},
+		{
+			input: root.String(),
+			e:     root,
+		},
+	}
+	for i, test := range tests {
+		e, err := parseRoot(test.input)
+		if !reflect.DeepEqual(e, test.e) {
+			t.Errorf("test %d: wrong entry %v, want %v", i, e, test.e)
+		}
+		if err != test.err {
+			t.Errorf("test %d: wrong error %q, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	var (
+		key  = testKey(signingKeySeed)
+		link = &linkEntry{"nodes.example.org", &key.PublicKey}
+		node = testNode(nodesSeed1)
+	)
+	tests := []struct {
+		input string
+		e     entry
+		err   error
+	}{
+		// Subtrees:
+		{
+			input: "enrtree-branch:1,2",
+			err:   entryError{"branch", errInvalidChild},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAAAAAAAA",
+			err:   entryError{"branch", errInvalidChild},
+		},
+		{
+			input: "enrtree-branch:",
+			e:     &branchEntry{},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAAAAAAAA",
+			e:     &branchEntry{[]string{"AAAAAAAAAAAAAAAAAAAAAAAAAA"}},
+		},
+		{
+			input: "enrtree-branch:AAAAAAAAAAAAAAAAAAAAAAAAAA,BBBBBBBBBBBBBBBBBBBBBBBBBB",
+			e:     &branchEntry{[]string{"AAAAAAAAAAAAAAAAAAAAAAAAAA", "BBBBBBBBBBBBBBBBBBBBBBBBBB"}},
+		},
+		// Links
+		{
+			input: link.String(),
+			e:     link,
+		},
+		{
+			input: "enrtree://nodes.example.org",
+			err:   entryError{"link", errNoPubkey},
+		},
+		{
+			input: "enrtree://AP62DT7WOTEQZGQZOU474PP3KMEGVTTE7A7NPRXKX3DUD57@nodes.example.org",
+			err:   entryError{"link", errBadPubkey},
+		},
+		{
+			input: "enrtree://AP62DT7WONEQZGQZOU474PP3KMEGVTTE7A7NPRXKX3DUD57TQHGIA@nodes.example.org",
+			err:   entryError{"link", errBadPubkey},
+		},
+		// ENRs
+		{
+			input: (&enrEntry{node}).String(),
+			e:     &enrEntry{node},
+		},
+		{
+			input: "enr:!!",
+			err:   entryError{"enr", errInvalidENR},
+		},
+		// Invalid:
+		{input: "", err: errUnknownEntry},
+		{input: "foo", err: errUnknownEntry},
+		{input: "enrtree", err: errUnknownEntry},
+		{input: "enrtree-x=", err: errUnknownEntry},
+	}
+	for i, test := range tests {
+		e, err := parseEntry(test.input, enode.ValidSchemes)
+		if !reflect.DeepEqual(e, test.e) {
+			t.Errorf("test %d: wrong entry %v, want %v", i, e, test.e)
+		}
+		if err != test.err {
+			t.Errorf("test %d: wrong error %q, want %q", i, err, test.err)
+		}
+	}
+}
+
+func TestMakeTree(t *testing.T) {
+	nodes := testNodes(nodesSeed2, 50)
+	tree, err := MakeTree(2, nodes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := tree.ToTXT("")
+	if len(txt) < len(nodes)+1 {
+		t.Fatal("too few TXT records in output")
+	}
+	if have := tree.Nodes(); !reflect.DeepEqual(have, sortByID(nodes)) {
+		t.Fatal("tree nodes mismatch")
+	}
+	for name, record := range txt {
+		if e, ok := tree.entries[name]; ok {
+			if len(e.String()) > 370 {
+				t.Errorf("record %s too long: %d bytes", name, len(record))
+			}
+		}
+	}
+}
+
+func TestTreeSignature(t *testing.T) {
+	var (
+		key   = testKey(signingKeySeed)
+		nodes = testNodes(nodesSeed1, 10)
+	)
+	tree, err := MakeTree(7, nodes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, err := tree.Sign(key, "n")
+	if err != nil {
+		t.Fatal("sign failed:", err)
+	}
+	domain, pubkey, err := ParseURL(url)
+	if err != nil {
+		t.Fatalf("invalid signed tree URL %q: %v", url, err)
+	}
+	if domain != "n" || !reflect.DeepEqual(pubkey, &key.PublicKey) {
+		t.Fatalf("signed tree URL mismatch: have %s %x", domain, crypto.FromECDSAPub(pubkey))
+	}
+	// Rebuild the tree and check the signature carries over
+	rebuilt, _ := MakeTree(7, nodes, nil)
+	if err := rebuilt.SetSignature(pubkey, tree.Signature()); err != nil {
+		t.Fatalf("signature not accepted on rebuilt tree: %v", err)
+	}
+	if !reflect.DeepEqual(rebuilt.ToTXT("n"), tree.ToTXT("n")) {
+		t.Fatal("rebuilt tree records mismatch")
+	}
+	// Changing the sequence number must invalidate the signature
+	changed, _ := MakeTree(8, nodes, nil)
+	if err := changed.SetSignature(pubkey, tree.Signature()); err != errInvalidSig {
+		t.Fatalf("signature accepted on modified tree: %v", err)
+	}
+}
@@ -40,6 +40,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbScorePrefix  = "score:"
 	dbDiscoverRoot = "v4"
 
 	// These fields are stored per ID and IP, the full key is "n:<ID>:v4:<IP>:findfail".
@@ -52,12 +53,20 @@ const (
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
 	dbLocalSeq = "seq"
+
+	// Reputation is keyed by ID only, the full key is "score:<ID>:value". It is kept
+	// apart from the node entries so it survives the expiration of the discovery data.
+	// Use scoreItemKey to create those keys.
+	dbScoreValue   = "value"
+	dbScoreUpdated = "updated"
+	dbScoreBanned  = "banned"
 )
 
 const (
-	dbNodeExpiration = 24 * time.Hour // Time after which an unseen node should be dropped.
-	dbCleanupCycle   = time.Hour      // Time period for running the expiration task.
-	dbVersion        = 8
+	dbNodeExpiration  = 24 * time.Hour     // Time after which an unseen node should be dropped.
+	dbScoreExpiration = 7 * 24 * time.Hour // Time after which an unchanged node score should be dropped.
+	dbCleanupCycle    = time.Hour          // Time period for running the expiration task.
+	dbVersion         = 8
 )
 
 var zeroIP = make(net.IP, 16)
@@ -180,6 +189,21 @@ func localItemKey(id ID, field string) []byte {
 	return key
 }
 
+// scoreItemKey returns the key of a node reputation item.
+func scoreItemKey(id ID, field string) []byte {
+	key := append([]byte(dbScorePrefix), id[:]...)
+	key = append(key, ':')
+	key = append(key, field...)
+	return key
+}
+
+// splitScoreItemKey returns the components of a key created by scoreItemKey.
+func splitScoreItemKey(key []byte) (id ID, field string) {
+	item := key[len(dbScorePrefix):]
+	copy(id[:], item[:len(id)])
+	return id, string(item[len(id)+1:])
+}
+
 // fetchInt64 retrieves an integer associated with a particular key.
 func (db *DB) fetchInt64(key []byte) int64 {
 	blob, err := db.lvl.Get(key, nil)
@@ -300,6 +324,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireScores()
 		case <-db.quit:
 			return
 		}
@@ -345,6 +370,20 @@ func (db *DB) expireNodes() {
 	}
 }
 
+// expireScores deletes the reputation of all nodes whose score wasn't changed for
+// some time, unless they are still banned.
+func (db *DB) expireScores() {
+	var (
+		now       = time.Now()
+		threshold = now.Add(-dbScoreExpiration)
+	)
+	for id := range db.ScoredNodes() {
+		if db.ScoreUpdated(id).Before(threshold) && !db.BannedUntil(id).After(now) {
+			db.DeleteScore(id)
+		}
+	}
+}
+
 // LastPingReceived retrieves the time of the last ping packet received from
 // a remote node.
 func (db *DB) LastPingReceived(id ID, ip net.IP) time.Time {
@@ -378,6 +417,60 @@ func (db *DB) UpdateFindFails(id ID, ip net.IP, fails int) error {
 	return db.storeInt64(nodeItemKey(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// Score retrieves the reputation score of a node.
+func (db *DB) Score(id ID) int64 {
+	return db.fetchInt64(scoreItemKey(id, dbScoreValue))
+}
+
+// ScoreUpdated retrieves the time of the last change to the score of a node.
+func (db *DB) ScoreUpdated(id ID) time.Time {
+	return time.Unix(db.fetchInt64(scoreItemKey(id, dbScoreUpdated)), 0)
+}
+
+// UpdateScore stores the reputation score of a node, along with the time of the change.
+func (db *DB) UpdateScore(id ID, score int64, instance time.Time) error {
+	if err := db.storeInt64(scoreItemKey(id, dbScoreValue), score); err != nil {
+		return err
+	}
+	return db.storeInt64(scoreItemKey(id, dbScoreUpdated), instance.Unix())
+}
+
+// BannedUntil retrieves the time until which a node is banned. The zero time is
+// returned for nodes which were never banned.
+func (db *DB) BannedUntil(id ID) time.Time {
+	if t := db.fetchInt64(scoreItemKey(id, dbScoreBanned)); t != 0 {
+		return time.Unix(t, 0)
+	}
+	return time.Time{}
+}
+
+// UpdateBannedUntil stores the time until which a node is banned. Storing the zero
+// time lifts the ban.
+func (db *DB) UpdateBannedUntil(id ID, instance time.Time) error {
+	if instance.IsZero() {
+		return db.lvl.Delete(scoreItemKey(id, dbScoreBanned), nil)
+	}
+	return db.storeInt64(scoreItemKey(id, dbScoreBanned), instance.Unix())
+}
+
+// DeleteScore deletes the reputation of a node.
+func (db *DB) DeleteScore(id ID) {
+	deleteRange(db.lvl, scoreItemKey(id, ""))
+}
+
+// ScoredNodes returns the IDs of all nodes with a stored reputation.
+func (db *DB) ScoredNodes() map[ID]struct{} {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbScorePrefix)), nil)
+	defer it.Release()
+
+	ids := make(map[ID]struct{})
+	for it.Next() {
+		id, _ := splitScoreItemKey(it.Key())
+		ids[id] = struct{}{}
+	}
+	return ids
+}
+
 // LocalSeq retrieves the local record sequence counter.
 func (db *DB) localSeq(id ID) uint64 {
 	return db.fetchUint64(nodeItemKey(id, zeroIP, dbLocalSeq))
@@ -462,3 +462,47 @@ func TestDBExpiration(t *testing.T) {
 		}
 	}
 }
+
+// Tests that node scores are kept apart from the discovery data and are only
+// expired when stale and not banned.
+func TestDBScoreExpiration(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		now    = time.Now()
+		stale  = ID{1}
+		banned = ID{2}
+		recent = ID{3}
+		tooOld = now.Add(-dbScoreExpiration - time.Minute)
+		fresh  = now.Add(-time.Minute)
+	)
+	db.UpdateScore(stale, -5, tooOld)
+	db.UpdateScore(banned, -100, tooOld)
+	db.UpdateBannedUntil(banned, now.Add(time.Hour))
+	db.UpdateScore(recent, 7, fresh)
+
+	// Expiring the discovery data must not touch the scores
+	db.expireNodes()
+	if have := len(db.ScoredNodes()); have != 3 {
+		t.Fatalf("wrong number of scored nodes after node expiration: have %d, want 3", have)
+	}
+	db.expireScores()
+
+	if _, ok := db.ScoredNodes()[stale]; ok {
+		t.Errorf("stale score not expired")
+	}
+	if score := db.Score(banned); score != -100 {
+		t.Errorf("banned node score changed: have %d, want -100", score)
+	}
+	if until := db.BannedUntil(banned); !until.After(now) {
+		t.Errorf("banned node ban lifted: until %v", until)
+	}
+	if score, updated := db.Score(recent), db.ScoreUpdated(recent); score != 7 || !updated.Equal(fresh.Truncate(time.Second)) {
+		t.Errorf("recent score changed: have %d at %v", score, updated)
+	}
+	db.UpdateBannedUntil(banned, time.Time{})
+	if until := db.BannedUntil(banned); !until.IsZero() {
+		t.Errorf("ban not lifted: until %v", until)
+	}
+}
@@ -38,6 +38,9 @@ const (
 	MetricsOutboundConnects = "p2p/OutboundConnects" // Name for the registered outbound connects meter
 	MetricsOutboundTraffic  = "p2p/OutboundTraffic"  // Name for the registered outbound traffic meter
 
+	MetricsInboundProtocolTraffic  = "p2p/ingress" // Prefix of the per protocol and message inbound traffic meters
+	MetricsOutboundProtocolTraffic = "p2p/egress"  // Prefix of the per protocol and message outbound traffic meters
+
 	MeteredPeerLimit = 1024 // This amount of peers are individually metered
 )
 
@@ -54,6 +57,17 @@ var (
 	meteredPeerCount int32      // Actually stored peer connection count
 )
 
+// meterProtocolMessage marks the payload size of a sub-protocol message in the
+// traffic meters of its protocol and message code, e.g. p2p/ingress/eth/63/0x05.
+func meterProtocolMessage(prefix string, proto *protoRW, code uint64, size uint32) {
+	if !metrics.Enabled {
+		return
+	}
+	name := fmt.Sprintf("%s/%s/%d", prefix, proto.Name, proto.Version)
+	metrics.GetOrRegisterMeter(name, nil).Mark(int64(size))
+	metrics.GetOrRegisterMeter(fmt.Sprintf("%s/0x%02x", name, code), nil).Mark(int64(size))
+}
+
 // MeteredPeerEventType is the type of peer events emitted by a metered connection.
 type MeteredPeerEventType int
 
@@ -23,6 +23,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/common/mclock"
@@ -113,6 +114,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// rep stores the reputation of the remote node if set
+	rep *reputation
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -176,6 +180,22 @@ func (p *Peer) Inbound() bool {
 	return p.rw.is(inboundConn)
 }
 
+// Score returns the reputation score of the remote node.
+func (p *Peer) Score() int64 {
+	return p.rep.score(p.ID())
+}
+
+// AdjustScore changes the reputation score of the remote node by delta, with
+// positive values rewarding useful and negative values penalizing useless or
+// invalid data. Peers whose score falls too low are disconnected and banned
+// for a while, unless they are trusted or static.
+func (p *Peer) AdjustScore(delta int64, reason string) {
+	if p.rep.adjust(p.ID(), delta, reason) && !p.rw.is(trustedConn|staticDialedConn) {
+		p.log.Debug("Disconnecting banned peer", "reason", reason)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 func newPeer(conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
@@ -298,6 +318,9 @@ func (p *Peer) handle(msg Msg) error {
 		if err != nil {
 			return fmt.Errorf("msg code out of range: %v", msg.Code)
 		}
+		atomic.AddUint64(&proto.ingress, uint64(msg.Size))
+		meterProtocolMessage(MetricsInboundProtocolTraffic, proto, msg.Code-proto.offset, msg.Size)
+
 		select {
 		case proto.in <- msg:
 			return nil
@@ -383,6 +406,9 @@ func (p *Peer) getProto(code uint64) (*protoRW, error) {
 }
 
 type protoRW struct {
+	ingress uint64 // Message payload bytes received (atomic, keep 64 bit aligned)
+	egress  uint64 // Message payload bytes sent (atomic, keep 64 bit aligned)
+
 	Protocol
 	in     chan Msg        // receives read messages
 	closed <-chan struct{} // receives when peer is shutting down
@@ -396,10 +422,15 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code, size := msg.Code, msg.Size
 	msg.Code += rw.offset
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil {
+			atomic.AddUint64(&rw.egress, uint64(size))
+			meterProtocolMessage(MetricsOutboundProtocolTraffic, rw, code, size)
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -437,6 +468,18 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   struct {
+		Ingress   uint64                      `json:"ingress"`   // Bytes received on the wire, including framing
+		Egress    uint64                      `json:"egress"`    // Bytes sent on the wire, including framing
+		Protocols map[string]*ProtocolTraffic `json:"protocols"` // Message payload bytes per sub-protocol
+	} `json:"traffic"`
+}
+
+// ProtocolTraffic is the amount of message payload exchanged with a peer over a
+// single sub-protocol.
+type ProtocolTraffic struct {
+	Ingress uint64 `json:"ingress"` // Payload bytes received from the peer
+	Egress  uint64 `json:"egress"`  // Payload bytes sent to the peer
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -472,5 +515,16 @@ func (p *Peer) Info() *PeerInfo {
 		}
 		info.Protocols[proto.Name] = protoInfo
 	}
+	// Gather the bandwidth used by the connection and its protocols
+	if counter, ok := p.rw.transport.(interface{ traffic() (uint64, uint64) }); ok {
+		info.Traffic.Ingress, info.Traffic.Egress = counter.traffic()
+	}
+	info.Traffic.Protocols = make(map[string]*ProtocolTraffic)
+	for _, proto := range p.running {
+		info.Traffic.Protocols[proto.Name] = &ProtocolTraffic{
+			Ingress: atomic.LoadUint64(&proto.ingress),
+			Egress:  atomic.LoadUint64(&proto.egress),
+		}
+	}
 	return info
 }
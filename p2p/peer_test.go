@@ -309,3 +309,41 @@ func TestMatchProtocols(t *testing.T) {
 		}
 	}
 }
+
+func TestPeerTrafficInfo(t *testing.T) {
+	sent, done := make(chan struct{}), make(chan struct{})
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			if err := ExpectMsg(rw, 2, []uint{1}); err != nil {
+				t.Error(err)
+			}
+			if err := SendItems(rw, 3, []byte{1, 2, 3}); err != nil {
+				t.Error(err)
+			}
+			close(sent)
+			<-done
+			return nil
+		},
+	}
+	closer, rw, peer, _ := testPeer([]Protocol{proto})
+	defer closer()
+	defer close(done)
+
+	Send(rw, baseProtocolLength+2, []uint{1})
+	if err := ExpectMsg(rw, baseProtocolLength+3, []interface{}{[]byte{1, 2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	<-sent
+	traffic := peer.Info().Traffic.Protocols["a"]
+	if traffic == nil {
+		t.Fatalf("missing protocol traffic")
+	}
+	if traffic.Ingress != 2 { // rlp([1])
+		t.Errorf("ingress mismatch: have %d, want %d", traffic.Ingress, 2)
+	}
+	if traffic.Egress != 5 { // rlp([[1,2,3]])
+		t.Errorf("egress mismatch: have %d, want %d", traffic.Egress, 5)
+	}
+}
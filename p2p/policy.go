@@ -0,0 +1,149 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/netutil"
+)
+
+// policySubnetBits is the prefix length of the networks limited by the
+// per-subnet peer cap, i.e. IPv4 /24 ranges.
+const policySubnetBits = 24
+
+var (
+	errSubnetLimit   = errors.New("too many peers in subnet")
+	errClientLimit   = errors.New("too many peers running the same client")
+	errOldProtocol   = errors.New("protocol version below policy minimum")
+	errPolicyInvalid = errors.New("invalid connection policy")
+)
+
+// ConnPolicy restricts the composition of the peer set beyond the plain peer
+// limits. Trusted peers are exempt from all policies, as are static nodes from
+// the subnet and client limits.
+type ConnPolicy struct {
+	// MaxPeersPerSubnet caps the number of peers connected from the same /24
+	// network range. Zero means unlimited.
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// ClientShares caps the percentage of the MaxPeers slots which may be taken
+	// by a single client, keyed by the case insensitive client name advertised
+	// in the handshake (the part before the first '/'), e.g. {"Geth": 30}.
+	ClientShares map[string]int `toml:",omitempty"`
+
+	// MinProtocolVersions rejects peers advertising a protocol only in versions
+	// older than the given minimum, e.g. {"eth": 63}.
+	MinProtocolVersions map[string]uint `toml:",omitempty"`
+
+	// ReservedStaticSlots is the number of peer slots never taken by dynamic
+	// dials or inbound connections, keeping them available for static nodes.
+	ReservedStaticSlots int `toml:",omitempty"`
+}
+
+// validate sanity checks the policy values.
+func (p *ConnPolicy) validate() error {
+	if p.MaxPeersPerSubnet < 0 || p.ReservedStaticSlots < 0 {
+		return errPolicyInvalid
+	}
+	for _, share := range p.ClientShares {
+		if share < 0 || share > 100 {
+			return errPolicyInvalid
+		}
+	}
+	return nil
+}
+
+// checkSubnet verifies that a new peer with the given IP address wouldn't exceed
+// the per-subnet limit of the current peer set.
+func (p *ConnPolicy) checkSubnet(ip net.IP, peers map[enode.ID]*Peer) error {
+	if p.MaxPeersPerSubnet == 0 || ip == nil {
+		return nil
+	}
+	set := netutil.DistinctNetSet{Subnet: policySubnetBits, Limit: uint(p.MaxPeersPerSubnet)}
+	for _, peer := range peers {
+		if peerIP := remoteIP(peer.RemoteAddr()); peerIP != nil {
+			set.Add(peerIP)
+		}
+	}
+	if !set.Add(ip) {
+		return errSubnetLimit
+	}
+	return nil
+}
+
+// checkClient verifies that a new peer with the given name wouldn't take more
+// than its client's share of the peer slots.
+func (p *ConnPolicy) checkClient(name string, maxPeers int, peers map[enode.ID]*Peer) error {
+	client := clientName(name)
+	for key, share := range p.ClientShares {
+		if !strings.EqualFold(key, client) {
+			continue
+		}
+		count := 0
+		for _, peer := range peers {
+			if strings.EqualFold(clientName(peer.Name()), client) {
+				count++
+			}
+		}
+		if count >= maxPeers*share/100 {
+			return errClientLimit
+		}
+	}
+	return nil
+}
+
+// checkProtocols verifies that the given capabilities satisfy the minimum
+// protocol versions.
+func (p *ConnPolicy) checkProtocols(caps []Cap) error {
+	for name, min := range p.MinProtocolVersions {
+		advertised, recent := false, false
+		for _, cap := range caps {
+			if cap.Name == name {
+				advertised = true
+				if cap.Version >= min {
+					recent = true
+				}
+			}
+		}
+		if advertised && !recent {
+			return errOldProtocol
+		}
+	}
+	return nil
+}
+
+// clientName extracts the client implementation from a devp2p node name, e.g.
+// "Geth" from "Geth/v1.8.27-stable/linux-amd64/go1.11".
+func clientName(name string) string {
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// remoteIP returns the IP address of a TCP endpoint, or nil for other kinds of
+// connections (e.g. in-memory pipes).
+func remoteIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return nil
+}
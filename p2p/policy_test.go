@@ -0,0 +1,137 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+// addrConn is a network connection stub reporting a fixed remote address.
+type addrConn struct {
+	net.Conn
+	ip net.IP
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return &net.TCPAddr{IP: c.ip, Port: 30303} }
+
+// Tests that dynamic dial candidates are skipped if their subnet is already full.
+func TestPolicyDialSubnetLimit(t *testing.T) {
+	var (
+		full  = newNode(uintID(1), net.IP{10, 0, 0, 1})
+		other = newNode(uintID(2), net.IP{10, 0, 1, 1})
+		peer  = &Peer{rw: &conn{fd: addrConn{ip: net.IP{10, 0, 0, 9}}, flags: inboundConn, node: newNode(uintID(3), nil)}}
+		peers = map[enode.ID]*Peer{peer.ID(): peer}
+	)
+	dialer := newDialState(enode.ID{}, nil, nil, fakeTable{full, other}, 10, nil)
+	dialer.policy = ConnPolicy{MaxPeersPerSubnet: 1}
+
+	dialed := make(map[enode.ID]bool)
+	for _, task := range dialer.newTasks(0, peers, time.Now()) {
+		if dt, ok := task.(*dialTask); ok {
+			dialed[dt.dest.ID()] = true
+		}
+	}
+	if dialed[full.ID()] {
+		t.Errorf("node in full subnet dialed")
+	}
+	if !dialed[other.ID()] {
+		t.Errorf("node in free subnet not dialed")
+	}
+	// Inbound connections from the full subnet should be rejected too, unless trusted
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	srv := &Server{Config: Config{MaxPeers: 10, Policy: dialer.policy}}
+	srv.localnode = enode.NewLocalNode(db, newkey())
+
+	c := &conn{fd: addrConn{ip: net.IP{10, 0, 0, 1}}, flags: inboundConn, node: full}
+	if err := srv.encHandshakeChecks(peers, 1, c); err != DiscTooManyPeers {
+		t.Errorf("inbound peer from full subnet accepted: %v", err)
+	}
+	c.flags |= trustedConn
+	if err := srv.encHandshakeChecks(peers, 1, c); err != nil {
+		t.Errorf("trusted peer from full subnet rejected: %v", err)
+	}
+}
+
+// Tests that slots reserved for static nodes are not used for dynamic dials or
+// inbound connections.
+func TestPolicyReservedStaticSlots(t *testing.T) {
+	tests := []struct {
+		maxPeers, reserved, want int
+	}{
+		{maxPeers: 30, reserved: 0, want: 10},
+		{maxPeers: 30, reserved: 4, want: 6},
+		{maxPeers: 30, reserved: 20, want: 0},
+	}
+	for i, tt := range tests {
+		srv := &Server{Config: Config{MaxPeers: tt.maxPeers, Policy: ConnPolicy{ReservedStaticSlots: tt.reserved}}}
+		if have := srv.maxDynamicDials(); have != tt.want {
+			t.Errorf("test %d: dynamic dial slots mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	// Inbound connections should leave the reserved slots free too
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	srv := &Server{Config: Config{MaxPeers: 3, NoDial: true, Policy: ConnPolicy{ReservedStaticSlots: 1}}}
+	srv.localnode = enode.NewLocalNode(db, newkey())
+
+	peers := make(map[enode.ID]*Peer)
+	for i := 1; i <= 2; i++ {
+		p := &Peer{rw: &conn{fd: addrConn{ip: net.IP{10, 0, byte(i), 1}}, flags: inboundConn, node: newNode(uintID(uint32(i)), nil)}}
+		peers[p.ID()] = p
+	}
+	inbound := &conn{fd: addrConn{ip: net.IP{10, 0, 9, 1}}, flags: inboundConn, node: newNode(uintID(9), nil)}
+	if err := srv.encHandshakeChecks(peers, 2, inbound); err != DiscTooManyPeers {
+		t.Errorf("inbound peer took reserved slot: %v", err)
+	}
+	static := &conn{fd: addrConn{ip: net.IP{10, 0, 8, 1}}, flags: staticDialedConn, node: newNode(uintID(8), nil)}
+	if err := srv.encHandshakeChecks(peers, 2, static); err != nil {
+		t.Errorf("static peer rejected from reserved slot: %v", err)
+	}
+	// Once the static node fills its reserved slot, the limit is back to MaxPeers
+	peers[static.node.ID()] = &Peer{rw: static}
+	delete(peers, uintID(1))
+	if err := srv.encHandshakeChecks(peers, 1, inbound); err != nil {
+		t.Errorf("inbound peer rejected from free slot: %v", err)
+	}
+}
+
+// Tests that client names are extracted from devp2p node names and matched case
+// insensitively against the configured shares.
+func TestPolicyClientShare(t *testing.T) {
+	peers := make(map[enode.ID]*Peer)
+	for i, name := range []string{"Geth/v1.8.27/linux-amd64/go1.11", "geth/v1.9.0", "Parity-Ethereum/v2.5.1"} {
+		p := &Peer{rw: &conn{name: name, node: newNode(uintID(uint32(i)), nil)}}
+		peers[p.ID()] = p
+	}
+	policy := ConnPolicy{ClientShares: map[string]int{"GETH": 20}}
+	if err := policy.checkClient("Geth/v1.9.1", 10, peers); err != errClientLimit {
+		t.Errorf("client above its share accepted: %v", err)
+	}
+	if err := policy.checkClient("Geth/v1.9.1", 10, nil); err != nil {
+		t.Errorf("client below its share rejected: %v", err)
+	}
+	if err := policy.checkClient("Parity-Ethereum/v2.5.1", 10, peers); err != nil {
+		t.Errorf("unrestricted client rejected: %v", err)
+	}
+}
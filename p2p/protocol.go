@@ -56,6 +56,23 @@ type Protocol struct {
 
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
+
+	// NodeFilter is an optional function rejecting discovered nodes before they
+	// are dialed, e.g. based on the protocol specific entries of their records.
+	// Static and trusted nodes are dialed regardless.
+	NodeFilter func(n *enode.Node) bool
+
+	// DialCandidates is an optional source of dynamic dial candidates for this
+	// protocol besides the discovery table, e.g. nodes found via DNS discovery.
+	DialCandidates NodeSource
+}
+
+// NodeSource is a supplier of dial candidates. ReadRandomNodes is called by the
+// dialer in the server loop, so it must not block.
+type NodeSource interface {
+	// ReadRandomNodes fills the given slice with random nodes, returning the
+	// number of nodes written.
+	ReadRandomNodes([]*enode.Node) int
 }
 
 func (p Protocol) cap() Cap {
@@ -0,0 +1,186 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+const (
+	// Scores decay towards zero, halving every scoreHalfLife, so that old
+	// misbehaviour (and old merit) is eventually forgotten.
+	scoreHalfLife = time.Hour
+
+	// Nodes whose score drops to scoreBanThreshold are banned for banDuration.
+	// The ban is neither dialed nor accepted inbound, and its score is reset
+	// to half the threshold so that repeated offenders are banned again quickly.
+	scoreBanThreshold = -100
+	banDuration       = time.Hour
+
+	// Scores are capped at scoreMax, so that a long history of useful data
+	// doesn't shield a node from the ban when it starts misbehaving.
+	scoreMax = 100
+
+	// Inbound connections from nodes with a negative score are only accepted
+	// while less than this fraction of the inbound slots is in use.
+	negativeInboundRatio = 2
+)
+
+// reputation tracks the scores of remote nodes in the node database. Protocols
+// adjust the scores through Peer.AdjustScore based on the usefulness of the data
+// received from a peer.
+type reputation struct {
+	db   *enode.DB
+	lock sync.Mutex
+	now  func() time.Time // Replaceable clock for testing
+	log  log.Logger
+}
+
+func newReputation(db *enode.DB, logger log.Logger) *reputation {
+	return &reputation{db: db, now: time.Now, log: logger}
+}
+
+// score returns the current, decayed score of a node.
+func (r *reputation) score(id enode.ID) int64 {
+	if r == nil {
+		return 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.currentScore(id, r.now())
+}
+
+func (r *reputation) currentScore(id enode.ID, now time.Time) int64 {
+	return decayScore(r.db.Score(id), now.Sub(r.db.ScoreUpdated(id)))
+}
+
+// adjust changes the score of a node by delta, capped at scoreMax, banning the
+// node if the score falls to the ban threshold. It reports whether the node got
+// banned.
+func (r *reputation) adjust(id enode.ID, delta int64, reason string) bool {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	score := r.currentScore(id, now) + delta
+	if score > scoreMax {
+		score = scoreMax
+	}
+	banned := score <= scoreBanThreshold
+	if banned {
+		score = scoreBanThreshold / 2
+		r.db.UpdateBannedUntil(id, now.Add(banDuration))
+		r.log.Debug("Banning node for bad reputation", "id", id, "reason", reason, "duration", banDuration)
+	} else {
+		r.log.Trace("Adjusted node score", "id", id, "delta", delta, "score", score, "reason", reason)
+	}
+	r.db.UpdateScore(id, score, now)
+	return banned
+}
+
+// banned reports whether a node is currently banned.
+func (r *reputation) banned(id enode.ID) bool {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.db.BannedUntil(id).After(r.now())
+}
+
+// sortByScore orders the nodes by descending score, retaining the relative order
+// of equally scored nodes.
+func (r *reputation) sortByScore(nodes []*enode.Node) {
+	if r == nil || len(nodes) < 2 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now    = r.now()
+		scores = make(map[enode.ID]int64, len(nodes))
+	)
+	for _, n := range nodes {
+		scores[n.ID()] = r.currentScore(n.ID(), now)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return scores[nodes[i].ID()] > scores[nodes[j].ID()]
+	})
+}
+
+// PeerScore is the reputation of a remote node.
+type PeerScore struct {
+	ID          string     `json:"id"`
+	Score       int64      `json:"score"`
+	Updated     time.Time  `json:"updated"`
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"` // nil if not banned
+}
+
+// scores returns the reputation of all nodes with a stored score.
+func (r *reputation) scores() []*PeerScore {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now    = r.now()
+		result []*PeerScore
+	)
+	for id := range r.db.ScoredNodes() {
+		s := &PeerScore{
+			ID:      id.String(),
+			Score:   r.currentScore(id, now),
+			Updated: r.db.ScoreUpdated(id),
+		}
+		if until := r.db.BannedUntil(id); until.After(now) {
+			s.BannedUntil = &until
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// decayScore halves the score for every elapsed half-life.
+func decayScore(score int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 {
+		return score
+	}
+	halvings := uint(elapsed / scoreHalfLife)
+	if halvings >= 63 {
+		return 0
+	}
+	return score / (1 << halvings)
+}
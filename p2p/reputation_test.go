@@ -0,0 +1,164 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+)
+
+func newTestReputation(t *testing.T) (*reputation, *time.Time) {
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	r := newReputation(db, log.New())
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestReputationDecay(t *testing.T) {
+	r, now := newTestReputation(t)
+	defer r.db.Close()
+
+	id := enode.ID{1}
+	r.adjust(id, 40, "test")
+	if score := r.score(id); score != 40 {
+		t.Fatalf("wrong score: have %d, want 40", score)
+	}
+	*now = now.Add(2*scoreHalfLife + time.Minute)
+	if score := r.score(id); score != 10 {
+		t.Fatalf("wrong decayed score: have %d, want 10", score)
+	}
+	r.adjust(id, -5, "test")
+	if score := r.score(id); score != 5 {
+		t.Fatalf("wrong adjusted score: have %d, want 5", score)
+	}
+}
+
+func TestReputationCap(t *testing.T) {
+	r, _ := newTestReputation(t)
+	defer r.db.Close()
+
+	id := enode.ID{1}
+	for i := 0; i < 2*scoreMax; i++ {
+		r.adjust(id, 1, "test")
+	}
+	if score := r.score(id); score != scoreMax {
+		t.Fatalf("score not capped: have %d, want %d", score, scoreMax)
+	}
+	// Accumulated merit must not outweigh a ban-worthy penalty
+	if !r.adjust(id, scoreBanThreshold-scoreMax, "test") {
+		t.Fatal("capped node not banned at threshold")
+	}
+}
+
+func TestReputationBan(t *testing.T) {
+	r, now := newTestReputation(t)
+	defer r.db.Close()
+
+	id := enode.ID{1}
+	if r.adjust(id, scoreBanThreshold+1, "test") {
+		t.Fatal("node banned above threshold")
+	}
+	if !r.adjust(id, -1, "test") {
+		t.Fatal("node not banned at threshold")
+	}
+	if !r.banned(id) {
+		t.Fatal("ban not recorded")
+	}
+	if score := r.score(id); score != scoreBanThreshold/2 {
+		t.Fatalf("wrong score after ban: have %d, want %d", score, scoreBanThreshold/2)
+	}
+	scores := r.scores()
+	if len(scores) != 1 || scores[0].BannedUntil == nil {
+		t.Fatalf("ban missing from listed scores: %+v", scores)
+	}
+	*now = now.Add(banDuration + time.Second)
+	if r.banned(id) {
+		t.Fatal("ban not lifted after its duration")
+	}
+	if scores := r.scores(); len(scores) != 1 || scores[0].BannedUntil != nil {
+		t.Fatalf("lifted ban still listed: %+v", scores)
+	}
+}
+
+func TestReputationSortByScore(t *testing.T) {
+	r, _ := newTestReputation(t)
+	defer r.db.Close()
+
+	nodes := []*enode.Node{newNode(uintID(1), nil), newNode(uintID(2), nil), newNode(uintID(3), nil), newNode(uintID(4), nil)}
+	r.adjust(nodes[1].ID(), -10, "test")
+	r.adjust(nodes[3].ID(), 10, "test")
+
+	r.sortByScore(nodes)
+	want := []enode.ID{uintID(4), uintID(1), uintID(3), uintID(2)}
+	for i, n := range nodes {
+		if n.ID() != want[i] {
+			t.Fatalf("wrong order at %d: have %v, want %v", i, n.ID(), want[i])
+		}
+	}
+}
+
+// Tests that banned nodes are neither dialed nor accepted, and that nodes with
+// a negative score only get the first half of the inbound slots.
+func TestServerReputationChecks(t *testing.T) {
+	r, _ := newTestReputation(t)
+	defer r.db.Close()
+
+	srv := &Server{Config: Config{MaxPeers: 10, NoDial: true}, reputation: r}
+	srv.localnode = enode.NewLocalNode(r.db, newkey())
+
+	var (
+		banned = newNode(uintID(1), net.IP{127, 0, 0, 1})
+		bad    = newNode(uintID(2), net.IP{127, 0, 0, 1})
+		good   = newNode(uintID(3), net.IP{127, 0, 0, 1})
+		peers  = make(map[enode.ID]*Peer)
+	)
+	r.adjust(banned.ID(), scoreBanThreshold, "test")
+	r.adjust(bad.ID(), -1, "test")
+
+	inbound := func(n *enode.Node) *conn { return &conn{node: n, flags: inboundConn} }
+	if err := srv.encHandshakeChecks(peers, 0, inbound(banned)); err != DiscUselessPeer {
+		t.Errorf("banned node accepted: %v", err)
+	}
+	if err := srv.encHandshakeChecks(peers, 0, &conn{node: banned, flags: staticDialedConn}); err != nil {
+		t.Errorf("banned static node rejected: %v", err)
+	}
+	if err := srv.encHandshakeChecks(peers, 4, inbound(bad)); err != nil {
+		t.Errorf("negative score node rejected below half the inbound slots: %v", err)
+	}
+	if err := srv.encHandshakeChecks(peers, 5, inbound(bad)); err != DiscTooManyPeers {
+		t.Errorf("negative score node accepted above half the inbound slots: %v", err)
+	}
+	if err := srv.encHandshakeChecks(peers, 5, inbound(good)); err != nil {
+		t.Errorf("good node rejected: %v", err)
+	}
+
+	dialer := newDialState(enode.ID{}, nil, nil, fakeTable{banned, good}, 10, nil)
+	dialer.reputation = r
+	for _, task := range dialer.newTasks(0, peers, time.Now()) {
+		if dt, ok := task.(*dialTask); ok && dt.dest.ID() == banned.ID() {
+			t.Errorf("banned node dialed")
+		}
+	}
+}
@@ -84,8 +84,24 @@ type rlpx struct {
 }
 
 func newRLPX(fd net.Conn) transport {
+	return newLimitedRLPX(fd, nil, nil)
+}
+
+// newLimitedRLPX creates an RLPx transport whose reads and writes are throttled
+// by the given (optionally nil) bandwidth limiters. The limiters are meant to be
+// shared by all connections of a server, capping its aggregate traffic.
+func newLimitedRLPX(fd net.Conn, ingress, egress *bandwidthLimiter) transport {
 	fd.SetDeadline(time.Now().Add(handshakeTimeout))
-	return &rlpx{fd: fd}
+	return &rlpx{fd: newTrafficConn(fd, ingress, egress)}
+}
+
+// traffic returns the number of bytes received from and sent to the remote
+// peer on the wire, including the RLPx handshake and framing overhead.
+func (t *rlpx) traffic() (ingress, egress uint64) {
+	if conn, ok := t.fd.(*trafficConn); ok {
+		return conn.traffic()
+	}
+	return 0, 0
 }
 
 func (t *rlpx) ReadMsg() (Msg, error) {
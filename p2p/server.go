@@ -79,6 +79,12 @@ type Config struct {
 	// Setting DialRatio to zero defaults it to 3.
 	DialRatio int `toml:",omitempty"`
 
+	// MaxUploadRate and MaxDownloadRate cap the aggregate bandwidth used by all
+	// peer connections, in bytes per second. The limits are enforced by the RLPx
+	// transport and shared fairly between peers. Zero means unlimited.
+	MaxUploadRate   int `toml:",omitempty"`
+	MaxDownloadRate int `toml:",omitempty"`
+
 	// NoDiscovery can be used to disable the peer discovery mechanism.
 	// Disabling is useful for protocol debugging (manual topology).
 	NoDiscovery bool
@@ -113,6 +119,10 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// Policy restricts the composition of the peer set, e.g. by limiting the
+	// number of peers per subnet or client implementation.
+	Policy ConnPolicy `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -165,6 +175,7 @@ type Server struct {
 
 	nodedb       *enode.DB
 	localnode    *enode.LocalNode
+	reputation   *reputation
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
@@ -349,6 +360,15 @@ func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
 }
 
+// LocalNode returns the local node record, which protocols may update with
+// their own entries. It returns nil if the server is not running.
+func (srv *Server) LocalNode() *enode.LocalNode {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	return srv.localnode
+}
+
 // Self returns the local node's endpoint information.
 func (srv *Server) Self() *enode.Node {
 	srv.lock.Lock()
@@ -426,8 +446,13 @@ func (srv *Server) Start() (err error) {
 	if srv.PrivateKey == nil {
 		return errors.New("Server.PrivateKey must be set to a non-nil key")
 	}
+	if err := srv.Policy.validate(); err != nil {
+		return err
+	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		ingress := newBandwidthLimiter(srv.MaxDownloadRate, mclock.System{})
+		egress := newBandwidthLimiter(srv.MaxUploadRate, mclock.System{})
+		srv.newTransport = func(fd net.Conn) transport { return newLimitedRLPX(fd, ingress, egress) }
 	}
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
@@ -455,8 +480,18 @@ func (srv *Server) Start() (err error) {
 		return err
 	}
 
-	dynPeers := srv.maxDialedConns()
+	dynPeers := srv.maxDynamicDials()
 	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	for _, p := range srv.Protocols {
+		if p.NodeFilter != nil {
+			dialer.filters = append(dialer.filters, p.NodeFilter)
+		}
+		if p.DialCandidates != nil {
+			dialer.sources = append(dialer.sources, p.DialCandidates)
+		}
+	}
+	dialer.reputation = srv.reputation
+	dialer.policy = srv.Policy
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -477,6 +512,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputation(db, srv.log)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	srv.localnode.Set(capsByNameAndVersion(srv.ourHandshake.Caps))
@@ -729,6 +765,7 @@ running:
 				if srv.EnableMsgEvents {
 					p.events = &srv.peerFeed
 				}
+				p.rep = srv.reputation
 				name := truncateName(c.name)
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
@@ -784,6 +821,13 @@ func (srv *Server) protoHandshakeChecks(peers map[enode.ID]*Peer, inboundCount i
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
 		return DiscUselessPeer
 	}
+	// Enforce the connection policies depending on the advertised metadata.
+	if !c.is(trustedConn) && srv.Policy.checkProtocols(c.caps) != nil {
+		return DiscUselessPeer
+	}
+	if !c.is(trustedConn|staticDialedConn) && srv.Policy.checkClient(c.name, srv.MaxPeers, peers) != nil {
+		return DiscTooManyPeers
+	}
 	// Repeat the encryption handshake checks because the
 	// peer set might have changed between the handshakes.
 	return srv.encHandshakeChecks(peers, inboundCount, c)
@@ -793,12 +837,20 @@ func (srv *Server) encHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int
 	switch {
 	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
+	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers-srv.unusedStaticSlots(peers):
+		return DiscTooManyPeers
 	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
 		return DiscTooManyPeers
 	case peers[c.node.ID()] != nil:
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn|staticDialedConn) && srv.reputation.banned(c.node.ID()):
+		return DiscUselessPeer
+	case !c.is(trustedConn|staticDialedConn) && srv.Policy.MaxPeersPerSubnet > 0 && srv.Policy.checkSubnet(remoteIP(c.fd.RemoteAddr()), peers) != nil:
+		return DiscTooManyPeers
+	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns()/negativeInboundRatio && srv.reputation.score(c.node.ID()) < 0:
+		return DiscTooManyPeers
 	default:
 		return nil
 	}
@@ -808,7 +860,7 @@ func (srv *Server) maxInboundConns() int {
 	return srv.MaxPeers - srv.maxDialedConns()
 }
 func (srv *Server) maxDialedConns() int {
-	if srv.NoDiscovery || srv.NoDial {
+	if srv.NoDial || (srv.NoDiscovery && !srv.hasDialCandidates()) {
 		return 0
 	}
 	r := srv.DialRatio
@@ -818,6 +870,42 @@ func (srv *Server) maxDialedConns() int {
 	return srv.MaxPeers / r
 }
 
+// maxDynamicDials returns the number of outbound slots available to dynamic
+// dials, excluding the ones reserved for static nodes.
+func (srv *Server) maxDynamicDials() int {
+	if n := srv.maxDialedConns() - srv.Policy.ReservedStaticSlots; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// unusedStaticSlots returns the number of reserved static slots not yet taken
+// by a connected static node. Inbound and dynamically dialed peers may not use
+// these slots.
+func (srv *Server) unusedStaticSlots(peers map[enode.ID]*Peer) int {
+	n := srv.Policy.ReservedStaticSlots
+	for _, p := range peers {
+		if n == 0 {
+			break
+		}
+		if p.rw.is(staticDialedConn) {
+			n--
+		}
+	}
+	return n
+}
+
+// hasDialCandidates reports whether any protocol supplies dial candidates besides
+// the discovery table.
+func (srv *Server) hasDialCandidates() bool {
+	for _, p := range srv.Protocols {
+		if p.DialCandidates != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
 func (srv *Server) listenLoop() {
@@ -1013,6 +1101,18 @@ func (srv *Server) runPeer(p *Peer) {
 	srv.delpeer <- peerDrop{p, err, remoteRequested}
 }
 
+// PeerScores returns the reputation of all nodes known to the server, ordered by
+// descending score.
+func (srv *Server) PeerScores() []*PeerScore {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running {
+		return nil
+	}
+	return srv.reputation.scores()
+}
+
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
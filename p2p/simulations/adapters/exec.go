@@ -105,6 +105,13 @@ func (e *ExecAdapter) NewNode(config *NodeConfig) (Node, error) {
 	conf.Stack.P2P.EnableMsgEvents = false
 	conf.Stack.P2P.NoDiscovery = true
 	conf.Stack.P2P.NAT = nil
+	conf.Stack.P2P.Policy = config.Policy
+	if config.MaxPeers > 0 {
+		conf.Stack.P2P.MaxPeers = config.MaxPeers
+	}
+	if config.ClientName != "" {
+		conf.Stack.Name = config.ClientName
+	}
 	conf.Stack.NoUSB = true
 
 	// listen on a localhost port, which we set when we
@@ -87,13 +87,19 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 		}
 	}
 
+	maxPeers := config.MaxPeers
+	if maxPeers == 0 {
+		maxPeers = math.MaxInt32
+	}
 	n, err := node.New(&node.Config{
+		Name: config.ClientName,
 		P2P: p2p.Config{
 			PrivateKey:      config.PrivateKey,
-			MaxPeers:        math.MaxInt32,
+			MaxPeers:        maxPeers,
 			NoDiscovery:     true,
 			Dialer:          s,
 			EnableMsgEvents: config.EnableMsgEvents,
+			Policy:          config.Policy,
 		},
 		NoUSB:  true,
 		Logger: log.New("node.id", id.String()),
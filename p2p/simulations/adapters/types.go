@@ -100,6 +100,15 @@ type NodeConfig struct {
 	Reachable func(id enode.ID) bool
 
 	Port uint16
+
+	// MaxPeers limits the number of peers of the node (unlimited if zero)
+	MaxPeers int
+
+	// ClientName overrides the client name advertised in the devp2p handshake
+	ClientName string
+
+	// Policy restricts the peers the node accepts and dials
+	Policy p2p.ConnPolicy
 }
 
 // nodeConfigJSON is used to encode and decode NodeConfig as JSON by encoding
@@ -111,6 +120,10 @@ type nodeConfigJSON struct {
 	Services        []string `json:"services"`
 	EnableMsgEvents bool     `json:"enable_msg_events"`
 	Port            uint16   `json:"port"`
+
+	MaxPeers   int            `json:"max_peers,omitempty"`
+	ClientName string         `json:"client_name,omitempty"`
+	Policy     p2p.ConnPolicy `json:"policy"`
 }
 
 // MarshalJSON implements the json.Marshaler interface by encoding the config
@@ -122,6 +135,9 @@ func (n *NodeConfig) MarshalJSON() ([]byte, error) {
 		Services:        n.Services,
 		Port:            n.Port,
 		EnableMsgEvents: n.EnableMsgEvents,
+		MaxPeers:        n.MaxPeers,
+		ClientName:      n.ClientName,
+		Policy:          n.Policy,
 	}
 	if n.PrivateKey != nil {
 		confJSON.PrivateKey = hex.EncodeToString(crypto.FromECDSA(n.PrivateKey))
@@ -159,6 +175,9 @@ func (n *NodeConfig) UnmarshalJSON(data []byte) error {
 	n.Services = confJSON.Services
 	n.Port = confJSON.Port
 	n.EnableMsgEvents = confJSON.EnableMsgEvents
+	n.MaxPeers = confJSON.MaxPeers
+	n.ClientName = confJSON.ClientName
+	n.Policy = confJSON.Policy
 
 	return nil
 }
@@ -0,0 +1,131 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulations
+
+import (
+	"testing"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/node"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/enode"
+	"git.pirl.io/bitcoiin/go-bitcoiin/p2p/simulations/adapters"
+)
+
+// newPolicyNetwork creates a simulation network whose nodes are connected over
+// loopback TCP, so that connection policies see real (127.0.0.0/24) addresses.
+func newPolicyNetwork() *Network {
+	adapter := adapters.NewTCPAdapter(adapters.Services{
+		"noop": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			return NewNoopService(nil), nil
+		},
+	})
+	return NewNetwork(adapter, &NetworkConfig{DefaultService: "noop"})
+}
+
+// startPolicyNode creates and starts a simulation node with the given settings.
+func startPolicyNode(t *testing.T, net *Network, client string, maxPeers int, policy p2p.ConnPolicy) enode.ID {
+	conf := adapters.RandomNodeConfig()
+	conf.ClientName = client
+	conf.MaxPeers = maxPeers
+	conf.Policy = policy
+
+	node, err := net.NewNodeWithConfig(conf)
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	if err := net.Start(node.ID()); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	return node.ID()
+}
+
+// checkPeerCount waits for a node to reach the expected number of peers and
+// ensures that no further peers are accepted afterwards.
+func checkPeerCount(t *testing.T, net *Network, id enode.ID, want int) {
+	t.Helper()
+
+	server := net.GetNode(id).Node.(*adapters.SimNode).Server()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.PeerCount() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if have := server.PeerCount(); have != want {
+		t.Fatalf("peer count mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that inbound peers from the same subnet are capped, whereas static
+// nodes dialed by the node itself are exempt.
+func TestPolicySubnetLimit(t *testing.T) {
+	net := newPolicyNetwork()
+	defer net.Shutdown()
+
+	hub := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{MaxPeersPerSubnet: 2})
+	for i := 0; i < 4; i++ {
+		id := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{})
+		if err := net.Connect(id, hub); err != nil {
+			t.Fatalf("failed to connect node %d: %v", i, err)
+		}
+	}
+	checkPeerCount(t, net, hub, 2)
+
+	static := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{})
+	if err := net.Connect(hub, static); err != nil {
+		t.Fatalf("failed to connect static node: %v", err)
+	}
+	checkPeerCount(t, net, hub, 3)
+}
+
+// Tests that a single client implementation can't take more than its share of
+// the peer slots.
+func TestPolicyClientShare(t *testing.T) {
+	net := newPolicyNetwork()
+	defer net.Shutdown()
+
+	hub := startPolicyNode(t, net, "", 4, p2p.ConnPolicy{ClientShares: map[string]int{"Alpha": 50}})
+	for i, client := range []string{"alpha", "alpha", "alpha", "beta"} {
+		id := startPolicyNode(t, net, client, 0, p2p.ConnPolicy{})
+		if err := net.Connect(id, hub); err != nil {
+			t.Fatalf("failed to connect node %d: %v", i, err)
+		}
+	}
+	checkPeerCount(t, net, hub, 3)
+}
+
+// Tests that peers advertising only outdated protocol versions are rejected.
+func TestPolicyMinProtocolVersion(t *testing.T) {
+	net := newPolicyNetwork()
+	defer net.Shutdown()
+
+	hub := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{MinProtocolVersions: map[string]uint{"noop": 667}})
+	for i := 0; i < 2; i++ {
+		id := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{})
+		if err := net.Connect(id, hub); err != nil {
+			t.Fatalf("failed to connect node %d: %v", i, err)
+		}
+	}
+	checkPeerCount(t, net, hub, 0)
+
+	current := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{MinProtocolVersions: map[string]uint{"noop": 666}})
+	id := startPolicyNode(t, net, "", 0, p2p.ConnPolicy{})
+	if err := net.Connect(id, current); err != nil {
+		t.Fatalf("failed to connect node: %v", err)
+	}
+	checkPeerCount(t, net, current, 1)
+}
@@ -18,12 +18,21 @@ package rpc
 
 import (
 	"net"
+	"net/http"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
 )
 
 // StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
 func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+	return StartHTTPEndpointWithRoutes(endpoint, apis, modules, cors, vhosts, timeouts, nil)
+}
+
+// StartHTTPEndpointWithRoutes starts the HTTP RPC endpoint just like StartHTTPEndpoint,
+// but additionally serves the given plain HTTP handlers on their request paths. All
+// other paths are answered by the JSON-RPC server. The CORS and virtual host checks
+// only guard the JSON-RPC server, the extra routes are served to any client.
+func StartHTTPEndpointWithRoutes(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, routes map[string]http.Handler) (net.Listener, *Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -47,7 +56,9 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return nil, nil, err
 	}
-	go NewHTTPServer(cors, vhosts, timeouts, handler).Serve(listener)
+	server := newHTTPServer(cors, vhosts, timeouts, handler)
+	server.Handler = withRoutes(server.Handler, routes)
+	go server.Serve(listener)
 	return listener, handler, err
 }
 
@@ -0,0 +1,76 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	"golang.org/x/net/http2"
+)
+
+// StartEndpoint starts a gRPC endpoint serving the given services. Services are
+// filtered by module exactly like the HTTP RPC endpoint: a service is offered if
+// its namespace is whitelisted, or if no modules are given and it is public.
+func StartEndpoint(endpoint string, services []Service, modules []string) (net.Listener, *Server, error) {
+	// Generate the whitelist based on the allowed modules
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	// Register all the services exposed by the node
+	handler := NewServer()
+	for _, service := range services {
+		if whitelist[service.Namespace] || (len(whitelist) == 0 && service.Public) {
+			if err := handler.RegisterService(service); err != nil {
+				return nil, nil, err
+			}
+			log.Debug("gRPC registered", "namespace", service.Namespace, "service", service.Name)
+		}
+	}
+	// All services registered, start the listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go serve(listener, handler)
+	return listener, handler, nil
+}
+
+// serve accepts connections until the listener is closed, serving HTTP/2 on
+// each of them. gRPC clients connecting over plain text expect HTTP/2 with prior
+// knowledge, without the HTTP/1 upgrade dance.
+func serve(listener net.Listener, handler http.Handler) {
+	var (
+		server = new(http2.Server)
+		opts   = &http2.ServeConnOpts{Handler: handler}
+	)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				log.Debug("Temporary gRPC accept error", "err", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return
+		}
+		go server.ServeConn(conn, opts)
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package grpc implements a gRPC server on top of golang.org/x/net/http2.
+//
+// The server speaks the gRPC over HTTP/2 wire protocol, so it can be used with
+// any standard gRPC client connecting over plain text. Messages are encoded with
+// github.com/golang/protobuf. Unary and server streaming methods are supported;
+// compressed messages are rejected.
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	contentType    = "application/grpc"
+	maxMessageSize = 4 * 1024 * 1024 // Maximum size of a request message, same as grpc-go
+	frameHeaderLen = 5               // Compression flag and message length prefix
+)
+
+// Handler implements a single method. The request message has already been
+// decoded. Replies are delivered by calling send: exactly once for unary methods
+// and any number of times for streaming ones. send must not be called
+// concurrently.
+type Handler func(ctx context.Context, req proto.Message, send func(proto.Message) error) error
+
+// Method describes a single method of a service.
+type Method struct {
+	Name       string               // Method name, as used in the request path
+	NewRequest func() proto.Message // Constructor of an empty request message
+	Handler    Handler              // Implementation of the method
+	Streaming  bool                 // Whether the server streams multiple replies
+}
+
+// Service is a set of methods exposed under a fully qualified service name. The
+// namespace and public flag have the same meaning as for rpc.API and are used to
+// decide which services an endpoint offers.
+type Service struct {
+	Namespace string   // Module the service belongs to, e.g. "eth"
+	Name      string   // Fully qualified service name, e.g. "bitcoiin.eth.Eth"
+	Methods   []Method // Methods offered by the service
+	Public    bool     // Whether the service is offered when no modules are configured
+}
+
+// Server dispatches gRPC requests to the registered services.
+type Server struct {
+	methods map[string]*Method // Registered methods keyed by request path
+	lock    sync.RWMutex
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// NewServer creates a gRPC server without any services.
+func NewServer() *Server {
+	return &Server{
+		methods: make(map[string]*Method),
+		quit:    make(chan struct{}),
+	}
+}
+
+// RegisterService makes the methods of the given service available.
+func (s *Server) RegisterService(service Service) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if service.Name == "" {
+		return fmt.Errorf("no service name for %q namespace", service.Namespace)
+	}
+	for i := range service.Methods {
+		method := service.Methods[i]
+		if method.NewRequest == nil || method.Handler == nil {
+			return fmt.Errorf("method %s/%s incomplete", service.Name, method.Name)
+		}
+		path := "/" + service.Name + "/" + method.Name
+		if _, ok := s.methods[path]; ok {
+			return fmt.Errorf("method %s already registered", path)
+		}
+		s.methods[path] = &method
+	}
+	return nil
+}
+
+// Stop aborts all running calls. The server answers new requests with an
+// Unavailable status afterwards.
+func (s *Server) Stop() {
+	s.quitOnce.Do(func() { close(s.quit) })
+}
+
+// ServeHTTP implements http.Handler, serving a single gRPC call.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("content-type"), contentType) {
+		http.Error(w, "invalid gRPC request", http.StatusUnsupportedMediaType)
+		return
+	}
+	s.lock.RLock()
+	method := s.methods[r.URL.Path]
+	s.lock.RUnlock()
+
+	// Tie the lifetime of the call to the client, its deadline and the server
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	if timeout := r.Header.Get("grpc-timeout"); timeout != "" {
+		if d, err := parseTimeout(timeout); err == nil {
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+	}
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	w.Header().Set("content-type", contentType)
+
+	// Declare the status trailers up front, the response might have no body
+	w.Header().Set("trailer", "Grpc-Status, Grpc-Message")
+
+	var err error
+	select {
+	case <-s.quit:
+		err = Errorf(Unavailable, "server stopped")
+	default:
+		err = s.serve(ctx, w, r, method)
+	}
+	code, msg := statusOf(err)
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+	if msg != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(msg))
+	}
+}
+
+// serve decodes the request message and runs the method handler on it.
+func (s *Server) serve(ctx context.Context, w http.ResponseWriter, r *http.Request, method *Method) error {
+	if method == nil {
+		return Errorf(Unimplemented, "unknown method %s", r.URL.Path)
+	}
+	data, err := readFrame(r.Body)
+	if err != nil {
+		return err
+	}
+	req := method.NewRequest()
+	if err := proto.Unmarshal(data, req); err != nil {
+		return Errorf(InvalidArgument, "invalid request: %v", err)
+	}
+	flusher, _ := w.(http.Flusher)
+
+	// Streams may stay silent for a long time, send the headers right away so
+	// the client knows the call was accepted
+	if method.Streaming && flusher != nil {
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+	sent := 0
+	send := func(msg proto.Message) error {
+		if !method.Streaming && sent > 0 {
+			return Errorf(Internal, "multiple replies to unary method")
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := writeFrame(w, msg); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		sent++
+		return nil
+	}
+	if err := method.Handler(ctx, req, send); err != nil {
+		return err
+	}
+	if !method.Streaming && sent == 0 {
+		return Errorf(Internal, "no reply to unary method")
+	}
+	return nil
+}
+
+// readFrame reads a single length prefixed message from the request stream.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeaderLen]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, Errorf(InvalidArgument, "missing request message: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, Errorf(Unimplemented, "compressed messages not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, Errorf(ResourceExhausted, "request message too large: %d > %d", size, maxMessageSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, Errorf(InvalidArgument, "truncated request message: %v", err)
+	}
+	return data, nil
+}
+
+// writeFrame encodes a message and writes it with its length prefix.
+func writeFrame(w io.Writer, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return Errorf(Internal, "failed to encode reply: %v", err)
+	}
+	frame := make([]byte, frameHeaderLen+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	copy(frame[frameHeaderLen:], data)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// parseTimeout decodes the value of a grpc-timeout header.
+func parseTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, fmt.Errorf("invalid timeout %q", s)
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid timeout unit in %q", s)
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid timeout %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
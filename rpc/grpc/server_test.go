@@ -0,0 +1,260 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+)
+
+// echoMessage is the request and reply message of the test service.
+type echoMessage struct {
+	Text  string `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Count uint64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (m *echoMessage) Reset()         { *m = echoMessage{} }
+func (m *echoMessage) String() string { return proto.CompactTextString(m) }
+func (*echoMessage) ProtoMessage()    {}
+
+func newEchoMessage() proto.Message { return new(echoMessage) }
+
+// testServices returns a public echo service and a private one with a failing method.
+func testServices() []Service {
+	return []Service{
+		{
+			Namespace: "test",
+			Name:      "test.Echo",
+			Public:    true,
+			Methods: []Method{
+				{
+					Name:       "Echo",
+					NewRequest: newEchoMessage,
+					Handler: func(ctx context.Context, req proto.Message, send func(proto.Message) error) error {
+						return send(req)
+					},
+				}, {
+					Name:       "Repeat",
+					NewRequest: newEchoMessage,
+					Streaming:  true,
+					Handler: func(ctx context.Context, req proto.Message, send func(proto.Message) error) error {
+						msg := req.(*echoMessage)
+						for i := uint64(0); i < msg.Count; i++ {
+							if err := send(&echoMessage{Text: msg.Text, Count: i}); err != nil {
+								return err
+							}
+						}
+						return nil
+					},
+				},
+			},
+		}, {
+			Namespace: "private",
+			Name:      "test.Private",
+			Methods: []Method{
+				{
+					Name:       "Fail",
+					NewRequest: newEchoMessage,
+					Handler: func(ctx context.Context, req proto.Message, send func(proto.Message) error) error {
+						return Errorf(NotFound, "nothing for %s", req.(*echoMessage).Text)
+					},
+				},
+			},
+		},
+	}
+}
+
+// startTestEndpoint starts a gRPC endpoint with the test services on a random
+// port, returning its URL and a function to stop it.
+func startTestEndpoint(t *testing.T, modules []string) (string, func()) {
+	listener, server, err := StartEndpoint("127.0.0.1:0", testServices(), modules)
+	if err != nil {
+		t.Fatalf("failed to start endpoint: %v", err)
+	}
+	stop := func() {
+		server.Stop()
+		listener.Close()
+	}
+	return "http://" + listener.Addr().String(), stop
+}
+
+// newClient creates an HTTP client speaking HTTP/2 over plain text connections,
+// the same way a generated gRPC client would over an insecure connection.
+func newClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+}
+
+// callResult is the outcome of a raw gRPC call.
+type callResult struct {
+	replies []*echoMessage
+	code    Code
+	message string
+}
+
+// call performs a gRPC call using a plain HTTP/2 client, the same way a generated
+// gRPC client would over an insecure connection.
+func call(t *testing.T, url, path string, req proto.Message) callResult {
+	client := newClient()
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+	frame := make([]byte, frameHeaderLen+len(body))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+	copy(frame[frameHeaderLen:], body)
+
+	httpReq, _ := http.NewRequest(http.MethodPost, url+path, bytes.NewReader(frame))
+	httpReq.Header.Set("content-type", "application/grpc+proto")
+	httpReq.Header.Set("te", "trailers")
+	res, err := client.Do(httpReq)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.ProtoMajor != 2 {
+		t.Fatalf("response protocol mismatch: have HTTP/%d, want HTTP/2", res.ProtoMajor)
+	}
+	var result callResult
+	for {
+		data, err := readFrame(res.Body)
+		if err != nil {
+			break
+		}
+		msg := new(echoMessage)
+		if err := proto.Unmarshal(data, msg); err != nil {
+			t.Fatalf("failed to decode reply: %v", err)
+		}
+		result.replies = append(result.replies, msg)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+
+	code, err := strconv.Atoi(res.Trailer.Get("grpc-status"))
+	if err != nil {
+		t.Fatalf("missing grpc-status trailer: %v", res.Trailer)
+	}
+	result.code, result.message = Code(code), res.Trailer.Get("grpc-message")
+	return result
+}
+
+func TestUnaryCall(t *testing.T) {
+	url, stop := startTestEndpoint(t, nil)
+	defer stop()
+
+	res := call(t, url, "/test.Echo/Echo", &echoMessage{Text: "hello", Count: 7})
+	if res.code != OK {
+		t.Fatalf("status mismatch: have %d (%s), want %d", res.code, res.message, OK)
+	}
+	if len(res.replies) != 1 || res.replies[0].Text != "hello" || res.replies[0].Count != 7 {
+		t.Fatalf("reply mismatch: have %v", res.replies)
+	}
+}
+
+func TestStreamingCall(t *testing.T) {
+	url, stop := startTestEndpoint(t, nil)
+	defer stop()
+
+	res := call(t, url, "/test.Echo/Repeat", &echoMessage{Text: "tick", Count: 5})
+	if res.code != OK {
+		t.Fatalf("status mismatch: have %d (%s), want %d", res.code, res.message, OK)
+	}
+	if len(res.replies) != 5 {
+		t.Fatalf("reply count mismatch: have %d, want %d", len(res.replies), 5)
+	}
+	for i, reply := range res.replies {
+		if reply.Text != "tick" || reply.Count != uint64(i) {
+			t.Errorf("reply %d mismatch: have %v", i, reply)
+		}
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	url, stop := startTestEndpoint(t, nil)
+	defer stop()
+
+	res := call(t, url, "/test.Echo/Missing", &echoMessage{})
+	if res.code != Unimplemented {
+		t.Fatalf("status mismatch: have %d, want %d", res.code, Unimplemented)
+	}
+}
+
+// Tests that services are filtered by module the same way as HTTP APIs are.
+func TestModuleWhitelist(t *testing.T) {
+	// Without modules only public services are offered
+	url, stop := startTestEndpoint(t, nil)
+	defer stop()
+	if res := call(t, url, "/test.Private/Fail", &echoMessage{Text: "x"}); res.code != Unimplemented {
+		t.Fatalf("private service exposed: status %d", res.code)
+	}
+	// Whitelisting a module offers only its services
+	url, stop = startTestEndpoint(t, []string{"private"})
+	defer stop()
+	if res := call(t, url, "/test.Echo/Echo", &echoMessage{}); res.code != Unimplemented {
+		t.Fatalf("non whitelisted service exposed: status %d", res.code)
+	}
+	res := call(t, url, "/test.Private/Fail", &echoMessage{Text: "x"})
+	if res.code != NotFound {
+		t.Fatalf("status mismatch: have %d, want %d", res.code, NotFound)
+	}
+	if res.message != "nothing%20for%20x" {
+		t.Fatalf("message mismatch: have %q", res.message)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		fail bool
+	}{
+		{in: "1S", want: 1e9},
+		{in: "250m", want: 250e6},
+		{in: "3H", want: 3 * 3600e9},
+		{in: "10", fail: true},
+		{in: "S", fail: true},
+		{in: "123456789S", fail: true},
+	}
+	for _, tt := range tests {
+		d, err := parseTimeout(tt.in)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tt.in, d)
+			}
+			continue
+		}
+		if err != nil || int64(d) != tt.want {
+			t.Errorf("%q: have %v (%v), want %v", tt.in, d, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Code is a gRPC status code, as defined by the gRPC over HTTP/2 specification.
+type Code uint32
+
+// Status codes used by the server. The numeric values are fixed by the protocol.
+const (
+	OK                Code = 0
+	Canceled          Code = 1
+	Unknown           Code = 2
+	InvalidArgument   Code = 3
+	DeadlineExceeded  Code = 4
+	NotFound          Code = 5
+	ResourceExhausted Code = 8
+	Unimplemented     Code = 12
+	Internal          Code = 13
+	Unavailable       Code = 14
+)
+
+// Error is an error carrying a gRPC status code, which is reported to the client
+// in the grpc-status trailer.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("grpc error %d: %s", e.Code, e.Message)
+}
+
+// Errorf creates a status error with the given code and formatted message.
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// statusOf converts an error returned by a method handler into the status code
+// and message sent to the client.
+func statusOf(err error) (Code, string) {
+	switch err {
+	case nil:
+		return OK, ""
+	case context.Canceled:
+		return Canceled, err.Error()
+	case context.DeadlineExceeded:
+		return DeadlineExceeded, err.Error()
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Code, e.Message
+	}
+	return Unknown, err.Error()
+}
+
+// encodeMessage percent-encodes a status message for the grpc-message trailer.
+func encodeMessage(msg string) string {
+	return url.PathEscape(msg)
+}
@@ -32,6 +32,8 @@ import (
 	"time"
 
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"github.com/rs/cors"
 )
 
@@ -199,6 +201,12 @@ func (t *httpReadWriteNopCloser) Close() error {
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv *Server) *http.Server {
+	return newHTTPServer(cors, vhosts, timeouts, srv)
+}
+
+// newHTTPServer creates a new HTTP server around an arbitrary handler, protected
+// by the same CORS and virtual host checks as the JSON-RPC endpoint.
+func newHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv http.Handler) *http.Server {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
 	handler = newVHostHandler(vhosts, handler)
@@ -248,6 +256,13 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
+	// Continue any trace propagated by the caller through the request headers
+	carrier := opentracing.HTTPHeadersCarrier(r.Header)
+	if remote, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, carrier); err == nil {
+		span := opentracing.StartSpan("rpc.http", ext.RPCServerOption(remote))
+		defer span.Finish()
+		ctx = opentracing.ContextWithSpan(ctx, span)
+	}
 
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
@@ -284,7 +299,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return http.StatusUnsupportedMediaType, err
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -298,6 +313,23 @@ func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
 	return c.Handler(srv)
 }
 
+// withRoutes multiplexes the given plain HTTP handlers next to the wrapped server.
+// The extra routes bypass any checks the server is wrapped in, so that e.g. load
+// balancer probes addressing the node by a service name are not rejected by the
+// virtual host filter. If no extra routes are requested, the server is returned
+// as is.
+func withRoutes(srv http.Handler, routes map[string]http.Handler) http.Handler {
+	if len(routes) == 0 {
+		return srv
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	for path, handler := range routes {
+		mux.Handle(path, handler)
+	}
+	return mux
+}
+
 // virtualHostHandler is a handler which validates the Host-header of incoming requests.
 // The virtualHostHandler can prevent DNS rebinding attacks, which do not utilize CORS-headers,
 // since they do in-domain requests against the RPC api. Instead, we can see on the Host-header
@@ -52,3 +52,41 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+// Tests that extra routes of the HTTP endpoint are served regardless of the
+// virtual host whitelist, while the JSON-RPC server stays protected by it.
+func TestHTTPRoutesBypassVirtualHosts(t *testing.T) {
+	routes := map[string]http.Handler{
+		"/health": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	}
+	listener, server, err := StartHTTPEndpointWithRoutes("127.0.0.1:0", nil, nil, nil, []string{"localhost"}, DefaultHTTPTimeouts, routes)
+	if err != nil {
+		t.Fatalf("failed to start endpoint: %v", err)
+	}
+	defer server.Stop()
+	defer listener.Close()
+
+	query := func(method, path string) int {
+		req, err := http.NewRequest(method, "http://"+listener.Addr().String()+path, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Host = "bitcoiin.default.svc" // Probes address the node by service name
+		req.Header.Set("content-type", contentType)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to query %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := query(http.MethodGet, "/health"); code != http.StatusTeapot {
+		t.Errorf("route response code mismatch: have %d, want %d", code, http.StatusTeapot)
+	}
+	if code := query(http.MethodPost, "/"); code != http.StatusForbidden {
+		t.Errorf("JSON-RPC response code mismatch: have %d, want %d", code, http.StatusForbidden)
+	}
+}
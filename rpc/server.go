@@ -27,6 +27,7 @@ import (
 
 	mapset "github.com/deckarep/golang-set"
 	"git.pirl.io/bitcoiin/go-bitcoiin/log"
+	opentracing "github.com/opentracing/opentracing-go"
 )
 
 const MetadataApi = "rpc"
@@ -297,6 +298,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// trace the method call, continuing any trace started by the transport
+	span, ctx := opentracing.StartSpanFromContext(ctx, req.svcname+serviceMethodSeparator+formatName(req.callb.method.Name))
+	defer span.Finish()
+
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -313,6 +318,8 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			span.SetTag("error", true)
+			span.LogKV("err", e)
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}